@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/config"
+)
+
+// runAlias implements the run-alias subcommand, which expands a named alias
+// from the config file and runs the resulting command line. Any extra
+// arguments are appended after the alias, so they override its flags.
+func runAlias(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("alias name is required (usage: sql-loader run-alias <name> [flags])")
+	}
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		return runHelp(ctx, []string{"run-alias"})
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	expanded, err := cfg.Alias(args[0])
+	if err != nil {
+		return err
+	}
+	if expanded[0] == "run-alias" {
+		return fmt.Errorf("alias %q cannot invoke another alias", args[0])
+	}
+
+	return run(ctx, append(append([]string{}, expanded...), args[1:]...))
+}
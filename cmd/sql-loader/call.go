@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/procedure"
+	"github.com/obstreperous-ai/sql-loader-go/internal/report"
+)
+
+// runCall implements the call subcommand, which calls a PostgreSQL stored
+// procedure or function and prints its OUT parameters or result rows.
+func runCall(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("routine name is required (usage: sql-loader call <routine> [flags])")
+	}
+	name, args := args[0], args[1:]
+	if strings.HasPrefix(name, "-") {
+		// Flags only, such as -h: parse them, then ask for the name.
+		name, args = "", append([]string{name}, args...)
+	}
+
+	fs := newFlagSet("call")
+	var (
+		conn        = addConnectionFlags(fs)
+		argsFile    = fs.String("args-file", "", "Read the arguments from this JSON file (- for stdin): an object of named arguments or an array of positional ones")
+		stmtTimeout = fs.Duration("statement-timeout", 0, "Cancel the call if it runs longer than this duration, e.g. 5m (0 = none)")
+		reportPath  = fs.String("report", "", "Write the call and its outputs to this JSON file, even if it fails")
+		named       stringList
+	)
+	fs.Var(&named, "arg", "Pass a named argument, as name=value; the server converts the value to the parameter's type (repeatable)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("routine name is required (usage: sql-loader call <routine> [flags])")
+	}
+
+	callArgs, err := parseCallArgs(named, *argsFile)
+	if err != nil {
+		return err
+	}
+
+	driver, dsn, err := conn.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	if driver != "postgres" {
+		return fmt.Errorf("call requires the postgres driver; %s has no stored procedures", driver)
+	}
+	if err := conn.confirmWrite(*argsFile != "-"); err != nil {
+		return err
+	}
+	if dsn, err = conn.primary(ctx, driver, dsn); err != nil {
+		return err
+	}
+
+	db, err := conn.connect(ctx, driver, dsn, warnf)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", closeErr)
+		}
+	}()
+
+	callCtx := ctx
+	if *stmtTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, *stmtTimeout)
+		defer cancel()
+	}
+	res, callErr := procedure.Call(callCtx, db, name, callArgs)
+	if callErr != nil && callCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		callErr = fmt.Errorf("statement timeout of %s exceeded: %w", *stmtTimeout, callErr)
+	}
+
+	if *reportPath != "" {
+		rep := report.New(false)
+		if res != nil {
+			rep.Call(res.Routine, res.Outputs(), res.Elapsed, nil)
+		} else {
+			rep.Call(name, nil, 0, callErr)
+		}
+		if callErr != nil {
+			rep.Error = callErr.Error()
+		}
+		if err := writeReport(*reportPath, rep); err != nil {
+			if callErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				return callErr
+			}
+			return err
+		}
+	}
+	if callErr != nil {
+		return callErr
+	}
+	return res.Write(os.Stdout)
+}
+
+// parseCallArgs returns the arguments given as -arg name=value flags or in
+// the -args-file JSON, which may not be combined.
+func parseCallArgs(named []string, path string) (procedure.Args, error) {
+	if path != "" {
+		if len(named) > 0 {
+			return procedure.Args{}, fmt.Errorf("-arg and -args-file cannot be combined")
+		}
+		r, closeFile, err := openArgsFile(path)
+		if err != nil {
+			return procedure.Args{}, err
+		}
+		defer closeFile()
+		return procedure.ParseArgs(r)
+	}
+	args := procedure.Args{Named: map[string]any{}}
+	for _, a := range named {
+		name, value, ok := strings.Cut(a, "=")
+		if !ok || name == "" {
+			return procedure.Args{}, fmt.Errorf("invalid -arg %q (want name=value)", a)
+		}
+		if _, dup := args.Named[name]; dup {
+			return procedure.Args{}, fmt.Errorf("-arg %s is given more than once", name)
+		}
+		args.Named[name] = value
+	}
+	return args, nil
+}
+
+// openArgsFile opens the -args-file at path, or stdin for -.
+func openArgsFile(path string) (io.Reader, func(), error) {
+	if path == "-" {
+		return os.Stdin, func() {}, nil
+	}
+	// #nosec G304 -- Arguments file path is intentionally provided by the user as part of the CLI interface
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open arguments file: %w", err)
+	}
+	return f, func() { _ = f.Close() }, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/catalog"
+)
+
+// runCatalog implements the catalog subcommand, which writes a JSON or YAML
+// description of the target database schema.
+func runCatalog(ctx context.Context, args []string) error {
+	fs := newFlagSet("catalog")
+	var (
+		conn   = addConnectionFlags(fs)
+		output = fs.String("output", "-", "Output file (- for stdout)")
+		format = fs.String("format", "json", "Output format (json, yaml)")
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "yaml" {
+		return fmt.Errorf("unsupported format %q (use json or yaml)", *format)
+	}
+
+	driver, dsn, err := conn.resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	db, err := conn.connect(ctx, driver, dsn, warnf)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", closeErr)
+		}
+	}()
+
+	cat, err := catalog.Inspect(db, driver)
+	if err != nil {
+		return fmt.Errorf("failed to inspect database: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer func() {
+			if closeErr := f.Close(); closeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close output file: %v\n", closeErr)
+			}
+		}()
+		w = f
+	}
+
+	write := cat.WriteJSON
+	if *format == "yaml" {
+		write = cat.WriteYAML
+	}
+	if err := write(w); err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// command is a sql-loader subcommand.
+type command struct {
+	name    string
+	aliases []string
+	// args describes the command's arguments in its usage line.
+	args string
+	// summary is the one-line description shown by help.
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+// commands returns the subcommands in the order help lists them.
+func commands() []command {
+	return []command{
+		{name: "exec", summary: "Execute SQL scripts (the default when no command is given)", run: runExec},
+		{name: "migrate", args: "[down|plan] [flags]", summary: "Apply versioned migrations, recording each in the database, revert them, or plan a run", run: runMigrate},
+		{name: "verify", summary: "Check scripts against checksums and limits without connecting", run: runVerify},
+		{name: "load-data", summary: "Load JSON Lines or CSV records into a table", run: runLoadData},
+		{name: "load-csv", aliases: []string{"csv"}, summary: "Bulk import a CSV file into a table", run: runLoadCSV},
+		{name: "call", args: "<routine> [flags]", summary: "Call a stored procedure or function and print its OUT parameters or results", run: runCall},
+		{name: "grants", summary: "Apply a grants file of role privileges as GRANT and REVOKE statements", run: runGrants},
+		{name: "listen", summary: "Run scripts on PostgreSQL notifications", run: runListen},
+		{name: "wait", summary: "Wait until the database accepts connections", run: runWait},
+		{name: "catalog", summary: "Export the schema catalog", run: runCatalog},
+		{name: "doctor", summary: "Diagnose a target database", run: runDoctor},
+		{name: "history", summary: "Show the local run history", run: func(_ context.Context, args []string) error { return runHistory(args) }},
+		{name: "lint", args: "script.sql...", summary: "Check scripts for common mistakes", run: func(_ context.Context, args []string) error { return runLint(args) }},
+		{name: "run-alias", args: "<name> [flags]", summary: "Run a command line defined as an alias in the config file", run: runAlias},
+		{name: "version", summary: "Show version information", run: runVersion},
+		{name: "help", args: "[command]", summary: "Show help for sql-loader or a command", run: runHelp},
+	}
+}
+
+// lookupCommand returns the command with the given name or alias.
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd, true
+		}
+		for _, alias := range cmd.aliases {
+			if alias == name {
+				return cmd, true
+			}
+		}
+	}
+	return command{}, false
+}
+
+// newFlagSet returns the flag set of the named command, with help text
+// naming the command and its purpose.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	cmd, _ := lookupCommand(name)
+	fs.Usage = func() {
+		w := fs.Output()
+		if name == "exec" {
+			fmt.Fprintf(w, "Usage: sql-loader [exec] [flags]\n\n%s.\n\n", cmd.summary)
+			printCommands(w)
+		} else {
+			fmt.Fprintf(w, "%s\n\n%s.\n\n", cmd.usage(), cmd.summary)
+		}
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(w, "Flags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// usage returns the command's usage line.
+func (c command) usage() string {
+	args := c.args
+	if args == "" {
+		args = "[flags]"
+	}
+	return "Usage: sql-loader " + c.name + " " + args
+}
+
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands() {
+		name := cmd.name
+		if len(cmd.aliases) > 0 {
+			name += " (" + strings.Join(cmd.aliases, ", ") + ")"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, cmd.summary)
+	}
+	_ = tw.Flush()
+	fmt.Fprintln(w)
+}
+
+// runVersion implements the version subcommand.
+func runVersion(_ context.Context, _ []string) error {
+	fmt.Printf("sql-loader version %s (commit: %s, built: %s)\n", version, commit, date)
+	return nil
+}
+
+// runHelp implements the help subcommand: the list of commands, or the
+// flags of the named command.
+func runHelp(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Print("Usage: sql-loader <command> [flags]\n\n")
+		printCommands(os.Stdout)
+		fmt.Println("Run sql-loader help <command> for the flags of a command.")
+		return nil
+	}
+	cmd, ok := lookupCommand(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command %q (run sql-loader help for a list)", args[0])
+	}
+	// These commands have no flag set to print.
+	switch cmd.name {
+	case "version":
+		fmt.Printf("Usage: sql-loader version\n\n%s.\n", cmd.summary)
+		return nil
+	case "help", "run-alias":
+		fmt.Printf("%s\n\n%s.\n", cmd.usage(), cmd.summary)
+		return nil
+	}
+	return cmd.run(ctx, []string{"-h"})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/doctor"
+	"github.com/obstreperous-ai/sql-loader-go/internal/migrate"
+)
+
+// runDoctor implements the doctor subcommand, which diagnoses the target
+// database and prints actionable findings.
+func runDoctor(ctx context.Context, args []string) error {
+	fs := newFlagSet("doctor")
+	var (
+		conn    = addConnectionFlags(fs)
+		table   = fs.String("migrations-table", migrate.DefaultTable, "Table recording applied migrations")
+		schemas stringList
+	)
+	fs.Var(&schemas, "schema", "Schema loads will write to (repeatable, default public)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	driver, dsn, err := conn.resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	findings := doctor.Diagnose(ctx, driver, dsn, doctor.Options{Schemas: schemas, MigrationsTable: *table})
+	if err := doctor.Write(os.Stdout, findings); err != nil {
+		return fmt.Errorf("failed to write findings: %w", err)
+	}
+	if doctor.Failed(findings) {
+		return fmt.Errorf("one or more checks failed")
+	}
+	return nil
+}
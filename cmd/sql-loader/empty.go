@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/dataload"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+)
+
+// Values of -on-empty.
+const (
+	onEmptyError = "error"
+	onEmptySkip  = "skip"
+)
+
+// emptyFlag holds the -on-empty policy for a script with no statements, a
+// directory with no scripts, or a data file with no records. Failing is the
+// default, since empty input usually means a broken build upstream.
+type emptyFlag struct {
+	mode *string
+	// skipped lists the empty inputs passed over, for the run report.
+	skipped []emptyInput
+}
+
+// emptyInput is an input skipped by -on-empty skip.
+type emptyInput struct {
+	name, reason string
+}
+
+func addEmptyFlag(fs *flag.FlagSet) *emptyFlag {
+	return &emptyFlag{
+		mode: fs.String("on-empty", onEmptyError, "Handling of an empty script, directory, or data file (error, skip)"),
+	}
+}
+
+func (e *emptyFlag) validate() error {
+	switch *e.mode {
+	case onEmptyError, onEmptySkip:
+		return nil
+	default:
+		return fmt.Errorf("invalid -on-empty %q (use error or skip)", *e.mode)
+	}
+}
+
+// check fails on the empty input name, which has reason, such as no
+// statements, or records it as skipped under -on-empty skip.
+func (e *emptyFlag) check(name, reason string) error {
+	if *e.mode != onEmptySkip {
+		return fmt.Errorf("%s has %s (use -on-empty skip to allow empty input)", name, reason)
+	}
+	e.skipped = append(e.skipped, emptyInput{name: name, reason: reason})
+	return nil
+}
+
+// scripts checks a directory's scripts, returning those with statements to
+// run. A script is empty when it has nothing but whitespace and comments.
+func (e *emptyFlag) scripts(dir string, scripts []loader.Script) ([]loader.Script, error) {
+	if len(scripts) == 0 {
+		return nil, e.check(dir, "no .sql files")
+	}
+	nonEmpty := scripts[:0]
+	for _, s := range scripts {
+		if len(database.SplitStatements(s.Content)) > 0 {
+			nonEmpty = append(nonEmpty, s)
+			continue
+		}
+		if err := e.check(s.Path, "no statements"); err != nil {
+			return nil, err
+		}
+	}
+	return nonEmpty, nil
+}
+
+// records checks a data reader, as returned with err by a dataload
+// constructor, for input with no records, header-only CSV included. It
+// returns a reader that still yields every record, or nil when the input is
+// skipped.
+func (e *emptyFlag) records(name string, reader dataload.Reader, err error) (dataload.Reader, error) {
+	var first []any
+	if err == nil {
+		first, reader, err = dataload.Peek(reader)
+	}
+	if errors.Is(err, dataload.ErrNoRecords) || (err == nil && first == nil) {
+		return nil, e.check(name, "no records")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+	return reader, nil
+}
+
+// logSkipped reports the skipped inputs to log.
+func (e *emptyFlag) logSkipped(log *runLog) {
+	for _, in := range e.skipped {
+		log.emptySkipped(in.name, in.reason)
+	}
+}
+
+// printSkipped reports the skipped inputs for commands without a run log.
+func (e *emptyFlag) printSkipped() {
+	for _, in := range e.skipped {
+		fmt.Printf("Skipping %s: %s\n", in.name, in.reason)
+	}
+}
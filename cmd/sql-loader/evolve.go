@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/catalog"
+	"github.com/obstreperous-ai/sql-loader-go/internal/dataload"
+)
+
+// Schema evolution modes of -evolve-schema.
+const (
+	evolveNone   = "none"
+	evolvePrompt = "prompt"
+	evolveAuto   = "auto"
+)
+
+func parseEvolveMode(mode string) error {
+	switch mode {
+	case evolveNone, evolvePrompt, evolveAuto:
+		return nil
+	default:
+		return fmt.Errorf("unsupported -evolve-schema %q (use none, prompt, or auto)", mode)
+	}
+}
+
+// evolveSchema adds input columns missing from table, with types inferred
+// from the leading records and mapped through the configured types. In prompt mode the changes are confirmed
+// interactively first. It returns a reader that still yields every record.
+func evolveSchema(db *sql.DB, driver, table string, reader dataload.Reader, mode string, canPrompt bool, types map[string]string) (dataload.Reader, error) {
+	if mode == evolveNone {
+		return reader, nil
+	}
+	typeMap, err := dataload.ParseTypeMap(types)
+	if err != nil {
+		return nil, fmt.Errorf("invalid types.%s in config: %w", driver, err)
+	}
+	cat, err := catalog.Inspect(db, driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect database: %w", err)
+	}
+	t, ok := cat.Table(table)
+	if !ok {
+		return nil, fmt.Errorf("table %s does not exist", table)
+	}
+	samples, reader, err := dataload.PeekN(reader, dataload.EvolveSampleSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+	missing := dataload.MissingColumns(driver, t, reader.Columns(), samples, typeMap)
+	if len(missing) == 0 {
+		return reader, nil
+	}
+
+	statements := make([]string, len(missing))
+	for i, c := range missing {
+		statements[i] = dataload.AddColumnStatement(table, c)
+		fmt.Fprintf(os.Stderr, "Schema change: %s\n", statements[i])
+	}
+	if mode == evolvePrompt {
+		if !canPrompt || !isTerminal(os.Stdin) {
+			return nil, fmt.Errorf("input has %d new column(s); pass -evolve-schema %s to add them non-interactively", len(missing), evolveAuto)
+		}
+		fmt.Fprintf(os.Stderr, "Add %d column(s) to %s? [y/N] ", len(missing), table)
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && answer == "" {
+			return nil, fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return nil, fmt.Errorf("schema change declined; nothing was loaded")
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin schema change: %w", err)
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("failed to evolve schema: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit schema change: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Added %d column(s) to %s\n", len(missing), table)
+	return reader, nil
+}
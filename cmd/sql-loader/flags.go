@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/obstreperous-ai/sql-loader-go/internal/config"
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/envfile"
+	"github.com/obstreperous-ai/sql-loader-go/internal/secrets"
+)
+
+// stringList is a flag.Value collecting every occurrence of a repeatable flag.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// splitList splits a comma-separated flag value, trimming spaces and
+// dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// connectionFlags holds the flags shared by every command that connects to
+// a database.
+type connectionFlags struct {
+	fs       *flag.FlagSet
+	driver   *string
+	dsns     stringList
+	profile  *string
+	envFiles stringList
+
+	environment *string
+	unlockProd  *string
+
+	connectRetries *int
+	connectBackoff *time.Duration
+
+	// sqliteFast and sqlitePragmas set up each sqlite connection.
+	sqliteFast    *bool
+	sqlitePragmas stringList
+
+	// host through sslmode build the DSN from parts instead of -dsn.
+	host, port, user, passwordEnv, passwordFile, dbname, sslmode *string
+
+	// profileName and selected are the profile chosen by resolve, if any,
+	// and cfg is the config it was loaded from.
+	profileName string
+	selected    config.Profile
+	cfg         *config.Config
+	// targets are the repeated -dsn targets, secrets expanded, that primary
+	// probes.
+	targets []string
+}
+
+func addConnectionFlags(fs *flag.FlagSet) *connectionFlags {
+	c := &connectionFlags{
+		fs:      fs,
+		driver:  fs.String("driver", "postgres", "Database driver (postgres, sqlite)"),
+		profile: fs.String("profile", "", "Connection profile from the config file"),
+
+		environment: fs.String("environment", "", "Expected environment of the profile (dev, staging, prod); required for prod"),
+		unlockProd:  fs.String("unlock-prod", "", "Confirm writes to a prod profile non-interactively by repeating its name"),
+
+		connectRetries: fs.Int("connect-retries", 0, "Retry connecting up to N times while the database is unreachable"),
+		connectBackoff: fs.Duration("connect-backoff", time.Second, "Wait before the first connect retry, doubled with jitter after each, up to 30s"),
+
+		sqliteFast: fs.Bool("sqlite-fast", false, "Speed up sqlite loads with journal_mode=WAL and synchronous=NORMAL, and enforce foreign keys"),
+
+		host:         fs.String("host", "", "Database host or Unix socket directory, instead of -dsn"),
+		port:         fs.String("port", "", "Database port, instead of -dsn"),
+		user:         fs.String("user", "", "Database user, instead of -dsn"),
+		passwordEnv:  fs.String("password-env", "", "Environment variable holding the database password, instead of -dsn"),
+		passwordFile: fs.String("password-file", "", "File holding the database password, such as a mounted secret, instead of -dsn"),
+		dbname:       fs.String("dbname", "", "Database name, or the database file for sqlite, instead of -dsn"),
+		sslmode:      fs.String("sslmode", "", "PostgreSQL SSL mode, e.g. require or verify-full, instead of -dsn"),
+	}
+	fs.Var(&c.dsns, "dsn", "Database connection string (default $DATABASE_URL, or the file named by $DATABASE_URL_FILE; repeat to probe several hosts for the writable primary)")
+	fs.Var(&c.envFiles, "env-file", "Load environment variables from a dotenv file (repeatable)")
+	fs.Var(&c.sqlitePragmas, "sqlite-pragma", "Set a sqlite PRAGMA on each connection, as name=value, overriding -sqlite-fast (repeatable)")
+	fs.String("config", "", "Read flag values from this YAML file; flags given on the command line override them")
+	return c
+}
+
+// parseFlags parses args and then sets the flags they did not give from the
+// -config file, if fs has that flag and it was given, and then the log flags
+// still unset from the defaults of the config files. Config values never
+// override the command line.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := applyFlagFile(fs); err != nil {
+		return err
+	}
+	return applyDefaults(fs)
+}
+
+// applyDefaults sets -log-format and -color, if fs has them and they are
+// unset, from the defaults of the user and project config files.
+func applyDefaults(fs *flag.FlagSet) error {
+	if fs.Lookup("log-format") == nil {
+		return nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	for _, d := range []struct{ name, value string }{{"log-format", cfg.Defaults.LogFormat}, {"color", cfg.Defaults.Color}} {
+		if d.value == "" || isSet(fs, d.name) {
+			continue
+		}
+		if err := fs.Set(d.name, d.value); err != nil {
+			return fmt.Errorf("invalid value %q for defaults.%s: %w", d.value, d.name, err)
+		}
+	}
+	return nil
+}
+
+// applyFlagFile sets the flags args did not give from the -config file.
+func applyFlagFile(fs *flag.FlagSet) error {
+	f := fs.Lookup("config")
+	if f == nil || f.Value.String() == "" {
+		return nil
+	}
+	// Load the command line's env files first, so that the file's ${VAR}
+	// references can use them.
+	if envFiles, ok := fs.Lookup("env-file").Value.(*stringList); ok {
+		if err := envfile.Load(*envFiles...); err != nil {
+			return err
+		}
+	}
+	path := f.Value.String()
+	values, err := config.LoadFlagFile(path)
+	if err != nil {
+		return err
+	}
+	for _, name := range values.Names() {
+		if name == "config" {
+			return fmt.Errorf("%s: config files cannot set -config", path)
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown flag -%s", path, name)
+		}
+		if isSet(fs, name) {
+			continue
+		}
+		for _, value := range values[name] {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("%s: invalid value %q for -%s: %w", path, value, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// isSet reports whether the named flag was given on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// resolve loads the env files and config, and returns the driver and DSN to
+// connect with. Explicit flags, -dsn or DSN parts such as -host, win over
+// the selected profile, which wins over config defaults and DATABASE_URL or
+// DATABASE_URL_FILE.
+// When -dsn is repeated, the first target is returned; commands that write
+// pick the one that accepts writes with primary.
+// Secret references such as ${file:/run/secrets/db_password} are resolved in
+// every DSN.
+func (c *connectionFlags) resolve(ctx context.Context) (string, string, error) {
+	if err := envfile.Load(c.envFiles...); err != nil {
+		return "", "", err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return "", "", err
+	}
+
+	var profile config.Profile
+	profileName := *c.profile
+	if profileName == "" {
+		profileName = cfg.Defaults.Profile
+	}
+	if profileName != "" {
+		if profile, err = cfg.Profile(profileName); err != nil {
+			return "", "", err
+		}
+	}
+	c.profileName, c.selected, c.cfg = profileName, profile, cfg
+	if *c.environment != "" && *c.environment != profile.Environment {
+		if profileName == "" {
+			return "", "", fmt.Errorf("-environment %s requires a profile tagged with that environment", *c.environment)
+		}
+		return "", "", fmt.Errorf("profile %s is tagged environment %q, not %q", profileName, profile.Environment, *c.environment)
+	}
+
+	driver := *c.driver
+	if !isSet(c.fs, "driver") {
+		switch {
+		case profile.Driver != "":
+			driver = profile.Driver
+		case cfg.Defaults.Driver != "":
+			driver = cfg.Defaults.Driver
+		}
+	}
+
+	if parts, err := c.parts(ctx); err != nil || !parts.IsZero() {
+		if err != nil {
+			return "", "", err
+		}
+		if len(c.dsns) > 0 {
+			return "", "", fmt.Errorf("-dsn cannot be combined with -host, -port, -user, -password-env, -password-file, -dbname, or -sslmode")
+		}
+		dsn, err := database.BuildDSN(driver, parts)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to build DSN: %w", err)
+		}
+		return driver, dsn, nil
+	}
+
+	if len(c.dsns) > 0 {
+		dsns := make([]string, len(c.dsns))
+		for i, dsn := range c.dsns {
+			if dsns[i], err = secrets.Expand(ctx, dsn); err != nil {
+				return "", "", err
+			}
+		}
+		if len(dsns) > 1 {
+			c.targets = dsns
+		}
+		return driver, dsns[0], nil
+	}
+
+	dsn := profile.DSN
+	if dsn == "" {
+		if dsn, err = databaseURL(ctx); err != nil {
+			return "", "", err
+		}
+	}
+	if dsn == "" {
+		return "", "", fmt.Errorf("DSN is required (use -dsn flag, -profile, DATABASE_URL, or DATABASE_URL_FILE)")
+	}
+	if dsn, err = secrets.Expand(ctx, dsn); err != nil {
+		return "", "", err
+	}
+	return driver, dsn, nil
+}
+
+// databaseURL returns the DSN in DATABASE_URL or, for a DSN mounted as a
+// secret file, read from the file named by DATABASE_URL_FILE.
+func databaseURL(ctx context.Context) (string, error) {
+	dsn, path := os.Getenv("DATABASE_URL"), os.Getenv("DATABASE_URL_FILE")
+	if path == "" {
+		return dsn, nil
+	}
+	if dsn != "" {
+		return "", fmt.Errorf("DATABASE_URL and DATABASE_URL_FILE cannot both be set")
+	}
+	dsn, err := secrets.Resolve(ctx, "file:"+path)
+	if err != nil {
+		return "", fmt.Errorf("DATABASE_URL_FILE: %w", err)
+	}
+	return dsn, nil
+}
+
+// parts returns the DSN parts given as flags, reading the password from the
+// variable named by -password-env or the file named by -password-file.
+func (c *connectionFlags) parts(ctx context.Context) (database.DSNParts, error) {
+	parts := database.DSNParts{Host: *c.host, Port: *c.port, User: *c.user, DBName: *c.dbname, SSLMode: *c.sslmode}
+	if *c.passwordEnv != "" && *c.passwordFile != "" {
+		return parts, fmt.Errorf("-password-env and -password-file cannot be combined")
+	}
+	if *c.passwordFile != "" {
+		password, err := secrets.Resolve(ctx, "file:"+*c.passwordFile)
+		if err != nil {
+			return parts, fmt.Errorf("-password-file: %w", err)
+		}
+		if password == "" {
+			return parts, fmt.Errorf("-password-file %s is empty", *c.passwordFile)
+		}
+		parts.Password = password
+	}
+	if *c.passwordEnv != "" {
+		password, ok := os.LookupEnv(*c.passwordEnv)
+		if !ok {
+			return parts, fmt.Errorf("-password-env %s: environment variable is not set", *c.passwordEnv)
+		}
+		parts.Password = password
+	}
+	return parts, nil
+}
+
+// connect opens the database, retrying while it is unreachable as
+// configured by -connect-retries and -connect-backoff. Each retry is
+// reported through warn.
+func (c *connectionFlags) connect(ctx context.Context, driver, dsn string, warn func(format string, args ...any)) (*sql.DB, error) {
+	dsn, err := c.sqliteDSN(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	policy := database.ReconnectPolicy{Attempts: *c.connectRetries, Backoff: *c.connectBackoff}
+	return database.ConnectWithRetry(ctx, driver, dsn, policy, func(attempt int, wait time.Duration, err error) {
+		warn("database unreachable; retrying in %s (attempt %d of %d): %v", wait.Round(time.Millisecond), attempt, policy.Attempts, err)
+	})
+}
+
+// sqliteDSN applies -sqlite-fast and -sqlite-pragma to a sqlite DSN.
+func (c *connectionFlags) sqliteDSN(driver, dsn string) (string, error) {
+	if !*c.sqliteFast && len(c.sqlitePragmas) == 0 {
+		return dsn, nil
+	}
+	if driver != "sqlite" {
+		return "", fmt.Errorf("-sqlite-fast and -sqlite-pragma require the sqlite driver, not %s", driver)
+	}
+	var base, overrides []database.Pragma
+	if *c.sqliteFast {
+		base = database.FastPragmas
+	}
+	for _, s := range c.sqlitePragmas {
+		p, err := database.ParsePragma(s)
+		if err != nil {
+			return "", fmt.Errorf("-sqlite-pragma: %w", err)
+		}
+		overrides = append(overrides, p)
+	}
+	return database.PragmaDSN(dsn, database.MergePragmas(base, overrides)), nil
+}
+
+// warnf prints a warning to stderr.
+func warnf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// primary returns the first repeated -dsn target that accepts writes, or
+// dsn, as resolve returned it, when -dsn was not repeated. Only commands
+// that write call it, so read-only ones do not probe every target.
+func (c *connectionFlags) primary(ctx context.Context, driver, dsn string) (string, error) {
+	if len(c.targets) == 0 {
+		return dsn, nil
+	}
+	return database.FindPrimary(ctx, driver, c.targets)
+}
+
+// confirmWrite adds friction before writing to a prod-tagged profile: the
+// environment must be named with -environment, and the profile name must be
+// typed at a prompt or passed with -unlock-prod. canPrompt is false when
+// stdin carries data or is not a terminal.
+func (c *connectionFlags) confirmWrite(canPrompt bool) error {
+	if !c.selected.Protected() {
+		return nil
+	}
+	if *c.environment != config.EnvironmentProd {
+		return fmt.Errorf("profile %s is a prod target; pass -environment %s to confirm", c.profileName, config.EnvironmentProd)
+	}
+	if *c.unlockProd != "" {
+		if *c.unlockProd != c.profileName {
+			return fmt.Errorf("-unlock-prod %q does not match profile %s", *c.unlockProd, c.profileName)
+		}
+		return nil
+	}
+	if !canPrompt || !isTerminal(os.Stdin) {
+		return fmt.Errorf("profile %s is a prod target; pass -unlock-prod %s to confirm non-interactively", c.profileName, c.profileName)
+	}
+
+	fmt.Fprintf(os.Stderr, "You are about to write to prod profile %s. Type the profile name to continue: ", c.profileName)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(answer) != c.profileName {
+		return fmt.Errorf("confirmation did not match profile %s; aborting", c.profileName)
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
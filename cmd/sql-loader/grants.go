@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/grants"
+	"github.com/obstreperous-ai/sql-loader-go/internal/sandbox"
+)
+
+// grantsFlag holds the -grants file that exec and migrate apply after a
+// successful load.
+type grantsFlag struct {
+	path *string
+}
+
+func addGrantsFlag(fs *flag.FlagSet) *grantsFlag {
+	return &grantsFlag{
+		path: fs.String("grants", "", "Apply the GRANT and REVOKE statements of this grants file after a successful load (PostgreSQL)"),
+	}
+}
+
+// script returns the statements of the -grants file for driver, read after
+// checking its path against sb, or an empty script when none was given.
+func (f *grantsFlag) script(driver string, sb *sandbox.Sandbox) (string, error) {
+	if *f.path == "" {
+		return "", nil
+	}
+	file, err := grants.Load(*f.path, sb.Check)
+	if err != nil {
+		return "", err
+	}
+	return file.Script(driver)
+}
+
+// applyGrants runs the grants script on e, in a transaction of its own
+// unless e already is one, so that the roles end up with all the privileges
+// of the file or none of them.
+func applyGrants(ctx context.Context, e database.Execer, script string, inTx bool) (int, error) {
+	mode := database.TransactionAll
+	if inTx {
+		mode = database.TransactionNone
+	}
+	statements := 0
+	err := database.ExecuteScriptOn(ctx, e, script, database.ExecuteOptions{
+		Transaction: mode,
+		OnStatement: func(int, string, time.Duration, int64) { statements++ },
+	})
+	if err != nil {
+		return statements, fmt.Errorf("failed to apply grants: %w", err)
+	}
+	return statements, nil
+}
+
+// runGrants implements the grants subcommand, which applies a grants file
+// on its own or, with -print, prints its statements.
+func runGrants(ctx context.Context, args []string) error {
+	fs := newFlagSet("grants")
+	var (
+		conn      = addConnectionFlags(fs)
+		path      = fs.String("file", "", "Grants file listing the privileges of roles on schemas and their objects")
+		printOnly = fs.Bool("print", false, "Print the GRANT and REVOKE statements without connecting")
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return fmt.Errorf("grants file is required (use -file flag)")
+	}
+	file, err := grants.Load(*path, nil)
+	if err != nil {
+		return err
+	}
+	if *printOnly {
+		script, err := file.Script(*conn.driver)
+		if err != nil {
+			return err
+		}
+		fmt.Print(script)
+		return nil
+	}
+
+	driver, dsn, err := conn.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	script, err := file.Script(driver)
+	if err != nil {
+		return err
+	}
+	if err := conn.confirmWrite(true); err != nil {
+		return err
+	}
+	if dsn, err = conn.primary(ctx, driver, dsn); err != nil {
+		return err
+	}
+
+	db, err := conn.connect(ctx, driver, dsn, warnf)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", closeErr)
+		}
+	}()
+	if err := requireWritable(ctx, db, driver, "connect to the primary"); err != nil {
+		return err
+	}
+
+	statements, err := applyGrants(ctx, db, script, false)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Applied %d grant statement(s) from %s\n", statements, *path)
+	return nil
+}
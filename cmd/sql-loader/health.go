@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// serveHealth serves handler, built with health.Handler, on addr until ctx
+// is done. The returned function stops the server.
+func serveHealth(ctx context.Context, addr string, handler http.Handler) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on health address: %w", err)
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Warning: health server stopped: %v\n", err)
+		}
+	}()
+	fmt.Printf("Serving health endpoints on %s\n", ln.Addr())
+	stop := context.AfterFunc(ctx, func() { _ = srv.Close() })
+	return func() {
+		stop()
+		_ = srv.Close()
+	}, nil
+}
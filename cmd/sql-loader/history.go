@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/state"
+)
+
+// runHistory implements the history subcommand, which lists recent runs from
+// the local run history.
+func runHistory(args []string) error {
+	fs := newFlagSet("history")
+	var (
+		profile    = fs.String("profile", "", "Only show runs that used this connection profile")
+		since      = fs.String("since", "", "Only show runs started within this period (e.g. 36h, 7d, 2w) or since a date (2024-06-01)")
+		failedOnly = fs.Bool("failed-only", false, "Only show failed runs")
+		limit      = fs.Int("limit", 50, "Maximum number of runs to show (0 for all)")
+		stateFile  = addStateFlag(fs)
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter := state.Filter{Profile: *profile, FailedOnly: *failedOnly, Limit: *limit}
+	if *since != "" {
+		t, err := state.ParseSince(*since, time.Now())
+		if err != nil {
+			return err
+		}
+		filter.Since = t
+	}
+
+	store, err := openState(*stateFile)
+	if err != nil {
+		return fmt.Errorf("failed to open run history: %w", err)
+	}
+	defer closeState(store)
+
+	runs, err := store.ListRuns(filter)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Println("No matching runs")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tSTATUS\tDURATION\tKIND\tPROFILE\tSOURCE\tCHECKSUM\tTARGET\tCATEGORY\tERROR")
+	for _, r := range runs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.StartedAt.Local().Format("2006-01-02 15:04:05"), r.Status, r.Duration,
+			r.Kind, dashIfEmpty(r.Profile), r.Source, shortChecksum(r.Checksum), r.Target, dashIfEmpty(r.ErrorCategory), r.Error)
+	}
+	return tw.Flush()
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func shortChecksum(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
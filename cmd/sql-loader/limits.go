@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+)
+
+// sizeFlag is a flag.Value holding a byte size such as 64MiB.
+type sizeFlag int64
+
+func (s *sizeFlag) String() string {
+	return loader.FormatSize(int64(*s))
+}
+
+func (s *sizeFlag) Set(value string) error {
+	n, err := loader.ParseSize(value)
+	if err != nil {
+		return err
+	}
+	*s = sizeFlag(n)
+	return nil
+}
+
+func addSizeFlag(fs *flag.FlagSet, name string, value int64, usage string) *sizeFlag {
+	s := sizeFlag(value)
+	fs.Var(&s, name, usage)
+	return &s
+}
+
+// limitFlags holds the size limits of a command that executes scripts.
+type limitFlags struct {
+	script, statement *sizeFlag
+}
+
+func addLimitFlags(fs *flag.FlagSet) *limitFlags {
+	return &limitFlags{
+		script:    addSizeFlag(fs, "max-script-size", loader.DefaultMaxScriptSize, "Refuse scripts larger than this, e.g. 1GiB (0 = no limit)"),
+		statement: addSizeFlag(fs, "max-statement-size", loader.DefaultMaxStatementSize, "Refuse statements longer than this, e.g. 128MiB (0 = no limit)"),
+	}
+}
+
+// checkStatements fails if any statement of the scripts exceeds
+// -max-statement-size.
+func (l *limitFlags) checkStatements(scripts []loader.Script) error {
+	for _, s := range scripts {
+		if err := loader.CheckStatementSizes(s.Path, database.SplitStatements(s.Content), int64(*l.statement)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
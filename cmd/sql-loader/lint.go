@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/junit"
+	"github.com/obstreperous-ai/sql-loader-go/internal/lint"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+)
+
+// runLint implements the lint subcommand, which checks script files for
+// likely mistakes without connecting to a database.
+func runLint(args []string) (err error) {
+	fs := newFlagSet("lint")
+	ci := addReportFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := ci.validate(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("at least one script file is required")
+	}
+
+	suite := junit.Suite{Name: "lint"}
+	defer func() { err = ci.writeJUnit([]junit.Suite{suite}, err) }()
+	total := 0
+	for _, path := range fs.Args() {
+		script, err := loader.LoadScript(path)
+		if err != nil {
+			return fmt.Errorf("failed to load script: %w", err)
+		}
+		var findings []string
+		for _, f := range lint.Script(script) {
+			fmt.Printf("%s: %s\n", path, f)
+			ci.annotateFinding(path, f)
+			findings = append(findings, f.String())
+			total++
+		}
+		suite.Cases = append(suite.Cases, junit.Case{Name: path, Classname: "lint", Failure: strings.Join(findings, "\n")})
+	}
+	if total > 0 {
+		return fmt.Errorf("%d lint finding(s)", total)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/health"
+	"github.com/obstreperous-ai/sql-loader-go/internal/listen"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+)
+
+// runListen implements the listen subcommand, which executes a script each
+// time a notification arrives on a PostgreSQL channel.
+func runListen(ctx context.Context, args []string) (err error) {
+	fs := newFlagSet("listen")
+	var (
+		conn        = addConnectionFlags(fs)
+		channel     = fs.String("channel", "", "Channel to LISTEN on")
+		scriptFile  = fs.String("file", "", "SQL script to execute on every notification")
+		scriptDir   = fs.String("dir", "", "Allowlisted directory of scripts named by the notification payload")
+		healthAddr  = fs.String("health-addr", "", "Serve /healthz, /readyz, and /queue on this address, e.g. :8080")
+		allowed     = addSandboxFlag(fs)
+		limits      = addLimitFlags(fs)
+		concurrency = fs.Int("concurrency", 1, "Maximum number of scripts running at once")
+		logs        = addLogFlags(fs)
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	log, err := logs.runLog()
+	if err != nil {
+		return err
+	}
+	// Scripts run concurrently, so the log draws no progress report.
+	log = log.to(os.Stdout, os.Stderr)
+	defer func() { err = log.failed(err) }()
+
+	driver, dsn, err := conn.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	if driver != "postgres" {
+		return fmt.Errorf("listen requires the postgres driver")
+	}
+	if *channel == "" {
+		return fmt.Errorf("channel is required (use -channel flag)")
+	}
+	if (*scriptFile == "") == (*scriptDir == "") {
+		return fmt.Errorf("exactly one of -file or -dir is required")
+	}
+
+	sb, err := allowed.open()
+	if err != nil {
+		return err
+	}
+	for _, location := range []string{*scriptFile, *scriptDir} {
+		if err := checkLocation(sb, location); err != nil {
+			return err
+		}
+	}
+
+	if err := conn.confirmWrite(true); err != nil {
+		return err
+	}
+	if dsn, err = conn.primary(ctx, driver, dsn); err != nil {
+		return err
+	}
+
+	// Start serving health before connecting, so liveness probes pass
+	// while readiness waits for the database.
+	var (
+		connected atomic.Pointer[sql.DB]
+		listening atomic.Bool
+	)
+	queue := listen.NewQueue(*concurrency, func(ctx context.Context, path string) error {
+		if err := sb.Check(path); err != nil {
+			return err
+		}
+		script, err := loader.LoadScriptLimit(path, int64(*limits.script))
+		if err != nil {
+			return fmt.Errorf("failed to load script: %w", err)
+		}
+		scripts := []loader.Script{{Path: path, Content: script}}
+		if err := limits.checkStatements(scripts); err != nil {
+			return err
+		}
+		if err := parseScripts(scripts); err != nil {
+			return err
+		}
+		log.step("script started", "Executing "+path, "script", path)
+		if err := database.ExecuteScript(ctx, connected.Load(), script); err != nil {
+			return fmt.Errorf("failed to execute script %s: %w", path, err)
+		}
+		log.step("script finished", fmt.Sprintf("Script %s executed successfully", path), "script", path)
+		return nil
+	}, func(path string, err error) {
+		log.problem("script failed", fmt.Sprintf("Error: %s: %v", path, err), "script", path, "error", err.Error())
+	})
+	if *healthAddr != "" {
+		mux := health.Handler(map[string]health.Check{
+			"database": func(ctx context.Context) error {
+				db := connected.Load()
+				if db == nil {
+					return fmt.Errorf("not connected")
+				}
+				return db.PingContext(ctx)
+			},
+			"listener": func(context.Context) error {
+				if !listening.Load() {
+					return fmt.Errorf("not listening on channel %s", *channel)
+				}
+				return nil
+			},
+		})
+		mux.HandleFunc("GET /queue", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(queue.Status())
+		})
+		stop, err := serveHealth(ctx, *healthAddr, mux)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	db, err := conn.connect(ctx, driver, dsn, log.warn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.warn("failed to close database: %v", closeErr)
+		}
+	}()
+
+	if err := requireWritable(ctx, db, driver, "connect to the primary"); err != nil {
+		return err
+	}
+	connected.Store(db)
+
+	listener, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open listener connection: %w", err)
+	}
+	defer func() {
+		if closeErr := listener.Close(context.Background()); closeErr != nil {
+			log.warn("failed to close listener connection: %v", closeErr)
+		}
+	}()
+
+	if _, err := listener.Exec(ctx, "LISTEN "+database.QuoteIdent(*channel)); err != nil {
+		return fmt.Errorf("failed to listen on channel %s: %w", *channel, err)
+	}
+	listening.Store(true)
+	log.info("listening", "Listening for notifications on channel "+*channel, "channel", *channel)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	workers := make(chan struct{})
+	go func() {
+		queue.Run(ctx)
+		close(workers)
+	}()
+	// Stop the workers before the deferred Close of db.
+	defer func() {
+		cancel()
+		<-workers
+	}()
+
+	handle := func(_ context.Context, n *pgconn.Notification) error {
+		path := *scriptFile
+		if *scriptDir != "" {
+			resolved, err := listen.ResolveScript(*scriptDir, n.Payload)
+			if err != nil {
+				return err
+			}
+			path = resolved
+		}
+		if !queue.Add(path) {
+			log.step("notification coalesced", path+" is already queued; coalescing notification", "script", path)
+		}
+		return nil
+	}
+	onError := func(n *pgconn.Notification, err error) {
+		log.problem("notification failed", fmt.Sprintf("Error: notification %q on %s: %v", n.Payload, n.Channel, err),
+			"channel", n.Channel, "payload", n.Payload, "error", err.Error())
+	}
+
+	return listen.Run(ctx, listener, handle, onError)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/catalog"
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/dataload"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+	"github.com/obstreperous-ai/sql-loader-go/internal/state"
+	"github.com/obstreperous-ai/sql-loader-go/internal/storage"
+)
+
+// runLoadCSV implements the load-csv subcommand, which bulk-imports a CSV
+// file into a table: with COPY on PostgreSQL and batched INSERTs on SQLite.
+func runLoadCSV(ctx context.Context, args []string) (err error) {
+	fs := newFlagSet("load-csv")
+	var (
+		conn      = addConnectionFlags(fs)
+		table     = fs.String("table", "", "Target table")
+		csvFile   = fs.String("csv", "-", "CSV file to import (- for stdin)")
+		delimiter = fs.String("delimiter", ",", "Field delimiter, a single character or \\t for tab")
+		header    = fs.String("header", string(dataload.HeaderAuto), "Whether the first row names the columns (auto, yes, no)")
+		columns   = fs.String("columns", "", "Comma-separated column names for input without a header row [default: table columns]")
+		batchSize = fs.Int("batch-size", dataload.DefaultBatchSize, "Rows per INSERT statement (SQLite)")
+		stateFile = addStateFlag(fs)
+		guard     = addWindowFlags(fs)
+		text      = addTextFlags(fs)
+		nulls     = addNullFlags(fs)
+		allowed   = addSandboxFlag(fs)
+		maxSize   = addSizeFlag(fs, "max-file-size", 0, "Refuse input larger than this, e.g. 10GiB (0 = no limit)")
+		empty     = addEmptyFlag(fs)
+		idents    = fs.String("identifiers", string(database.IdentifierQuote), "How header names become table and column names (quote, preserve, snake)")
+		logs      = addLogFlags(fs)
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	log, err := logs.runLog()
+	if err != nil {
+		return err
+	}
+	defer func() { err = log.failed(err) }()
+
+	comma, err := parseDelimiter(*delimiter)
+	if err != nil {
+		return err
+	}
+	headerMode, err := dataload.ParseHeaderMode(*header)
+	if err != nil {
+		return err
+	}
+	policy, err := text.policy()
+	if err != nil {
+		return err
+	}
+	nullPolicy, err := nulls.policy()
+	if err != nil {
+		return err
+	}
+	identPolicy, err := database.ParseIdentifierPolicy(*idents)
+	if err != nil {
+		return err
+	}
+	if err := empty.validate(); err != nil {
+		return err
+	}
+
+	if err := guard.check(time.Now()); err != nil {
+		return err
+	}
+	driver, dsn, err := conn.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	if *table == "" {
+		return fmt.Errorf("table is required (use -table flag)")
+	}
+	if err := conn.confirmWrite(*csvFile != "-"); err != nil {
+		return err
+	}
+	if dsn, err = conn.primary(ctx, driver, dsn); err != nil {
+		return err
+	}
+
+	sb, err := allowed.open()
+	if err != nil {
+		return err
+	}
+	if err := checkLocation(sb, *csvFile); err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if *csvFile != "-" {
+		f, err := storage.Open(ctx, *csvFile)
+		if err != nil {
+			return fmt.Errorf("failed to open CSV file: %w", err)
+		}
+		defer func() {
+			if closeErr := f.Close(); closeErr != nil {
+				log.warn("failed to close CSV file: %v", closeErr)
+			}
+		}()
+		input = f
+	}
+	source := storage.Redact(*csvFile)
+	if source == "-" {
+		source = "<stdin>"
+	}
+	input = loader.LimitReader(input, source, int64(*maxSize))
+
+	db, err := conn.connect(ctx, driver, dsn, log.warn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.warn("failed to close database: %v", closeErr)
+		}
+	}()
+	if err := requireWritable(ctx, db, driver, "connect to the primary"); err != nil {
+		return err
+	}
+
+	*table = identPolicy.Qualified(driver, *table)
+	names := splitList(*columns)
+	var existing []string
+	if len(names) == 0 && headerMode != dataload.HeaderPresent {
+		if existing, err = tableColumns(db, driver, *table); err != nil {
+			return err
+		}
+		names = existing
+	}
+
+	hash := sha256.New()
+	var reader dataload.Reader
+	reader, err = dataload.NewCSVReader(io.TeeReader(policy.Reader(input), hash), dataload.CSVOptions{
+		Delimiter: comma,
+		Header:    headerMode,
+		Columns:   names,
+	})
+	if reader, err = empty.records(source, reader, err); err != nil {
+		return err
+	}
+	if reader == nil {
+		empty.logSkipped(log)
+		return nil
+	}
+	if identPolicy != database.IdentifierQuote {
+		if reader, err = renameColumns(reader, driver, identPolicy, existing); err != nil {
+			return err
+		}
+	}
+	if !nullPolicy.IsZero() {
+		if reader, err = dataload.ApplyNulls(reader, nullPolicy); err != nil {
+			return err
+		}
+	}
+
+	store := openStateOrWarn(*stateFile)
+	defer closeState(store)
+
+	started := time.Now()
+	var rows int64
+	if driver == "postgres" {
+		rows, err = dataload.Copy(ctx, db, reader, *table)
+	} else {
+		rows, err = dataload.Load(ctx, db, reader, dataload.Options{Driver: driver, Table: *table, BatchSize: *batchSize})
+	}
+	recordRun(store, state.Run{
+		Kind:      state.KindData,
+		Source:    source,
+		Checksum:  hex.EncodeToString(hash.Sum(nil)),
+		Target:    targetID(driver, dsn),
+		Profile:   conn.profileName,
+		StartedAt: started,
+	}, err, nil)
+	if err != nil {
+		return fmt.Errorf("failed to import CSV: %w", err)
+	}
+
+	log.info("data imported", fmt.Sprintf("Imported %d rows into %s", rows, *table), "table", *table, "rows", rows)
+	return nil
+}
+
+// parseDelimiter accepts a single character, or \t for tab.
+func parseDelimiter(value string) (rune, error) {
+	if value == `\t` {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(value)
+	if size == 0 || size != len(value) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("invalid -delimiter %q (use a single character, or \\t for tab)", value)
+	}
+	return r, nil
+}
+
+// tableColumns returns the column names of table in declaration order.
+func tableColumns(db *sql.DB, driver, table string) ([]string, error) {
+	cat, err := catalog.Inspect(db, driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect database: %w", err)
+	}
+	t, ok := cat.Table(table)
+	if !ok {
+		return nil, fmt.Errorf("table %s does not exist", table)
+	}
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	return names, nil
+}
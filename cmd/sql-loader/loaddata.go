@@ -0,0 +1,476 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/catalog"
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/dataload"
+	"github.com/obstreperous-ai/sql-loader-go/internal/estimate"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+	"github.com/obstreperous-ai/sql-loader-go/internal/preflight"
+	"github.com/obstreperous-ai/sql-loader-go/internal/state"
+	"github.com/obstreperous-ai/sql-loader-go/internal/storage"
+)
+
+// runLoadData implements the load-data subcommand, which streams records
+// from a file or stdin into a table.
+func runLoadData(ctx context.Context, args []string) (err error) {
+	fs := newFlagSet("load-data")
+	var (
+		conn      = addConnectionFlags(fs)
+		format    = fs.String("format", "jsonl", "Input format (csv, jsonl)")
+		table     = fs.String("table", "", "Target table")
+		dataFile  = fs.String("file", "-", "Data file to load (- for stdin)")
+		batchSize = fs.Int("batch-size", dataload.DefaultBatchSize, "Rows per INSERT statement")
+		check     = fs.Bool("preflight", false, "Verify the table and columns exist before loading")
+		estimates = fs.Bool("estimate", false, "Report data size, row count, and expected duration without loading")
+		stateFile = addStateFlag(fs)
+		guard     = addWindowFlags(fs)
+		text      = addTextFlags(fs)
+		nulls     = addNullFlags(fs)
+		allowed   = addSandboxFlag(fs)
+		maxSize   = addSizeFlag(fs, "max-file-size", 0, "Refuse input larger than this, e.g. 10GiB (0 = no limit)")
+		empty     = addEmptyFlag(fs)
+		keys      = fs.String("key", "", "Comma-separated key columns to check for duplicates before loading")
+		onDup     = fs.String("on-duplicate", string(dataload.DuplicateReject), "Handling of records repeating -key (error, first, last)")
+		preview   = fs.Int("preview", 0, "Print the first N records as they would be inserted, without loading")
+		stats     = fs.Bool("stats", false, "Report per-column NULL counts, distinct values, and min/max after loading")
+		evolve    = fs.String("evolve-schema", evolveNone, "Add input columns missing from the table (none, prompt, auto)")
+		increment = fs.String("incremental", "", "Load only records whose value in this column is beyond the feed's high-watermark, then advance it")
+		feed      = fs.String("feed", "", "Name of the feed in the watermark table (default: the table)")
+		markTable = fs.String("watermark-table", dataload.DefaultWatermarkTable, "Table recording the high-watermark of each incremental feed")
+		opColumn  = fs.String("op-column", "", "Apply a change file: this column holds each record's operation (I, U, D)")
+		idents    = fs.String("identifiers", string(database.IdentifierQuote), "How input names become table and column names (quote, preserve, snake)")
+		isolation = addIsolationFlag(fs)
+		mapSpec   = fs.String("map", "", "Load only these JSON Lines fields, as comma-separated column=path pairs, e.g. id=.id,name=.user.name")
+		logs      = addLogFlags(fs)
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	log, err := logs.runLog()
+	if err != nil {
+		return err
+	}
+	defer func() { err = log.failed(err) }()
+
+	dataFormat, err := dataload.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	policy, err := text.policy()
+	if err != nil {
+		return err
+	}
+	nullPolicy, err := nulls.policy()
+	if err != nil {
+		return err
+	}
+	dupMode, err := dataload.ParseDuplicateMode(*onDup)
+	if err != nil {
+		return err
+	}
+	if err := parseEvolveMode(*evolve); err != nil {
+		return err
+	}
+	if err := empty.validate(); err != nil {
+		return err
+	}
+	identPolicy, err := database.ParseIdentifierPolicy(*idents)
+	if err != nil {
+		return err
+	}
+	var mapping []dataload.FieldMapping
+	if *mapSpec != "" {
+		if dataFormat != dataload.FormatJSONL {
+			return fmt.Errorf("-map requires -format jsonl")
+		}
+		if mapping, err = dataload.ParseMapping(*mapSpec); err != nil {
+			return fmt.Errorf("invalid -map: %w", err)
+		}
+	}
+	if *opColumn != "" && *evolve != evolveNone {
+		return fmt.Errorf("-evolve-schema cannot be combined with -op-column")
+	}
+	if *increment == "" && (isSet(fs, "feed") || isSet(fs, "watermark-table")) {
+		return fmt.Errorf("-feed and -watermark-table require -incremental")
+	}
+	if *increment != "" && *opColumn != "" {
+		return fmt.Errorf("-incremental cannot be combined with -op-column")
+	}
+	txOpts, err := isolation.options()
+	if err != nil {
+		return err
+	}
+	if txOpts != nil && *increment == "" && *opColumn == "" {
+		return fmt.Errorf("-isolation requires -incremental or -op-column: other loads commit each batch of rows on its own")
+	}
+
+	sb, err := allowed.open()
+	if err != nil {
+		return err
+	}
+	if err := checkLocation(sb, *dataFile); err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if *dataFile != "-" {
+		f, err := storage.Open(ctx, *dataFile)
+		if err != nil {
+			return fmt.Errorf("failed to open data file: %w", err)
+		}
+		defer func() {
+			if closeErr := f.Close(); closeErr != nil {
+				log.warn("failed to close data file: %v", closeErr)
+			}
+		}()
+		input = f
+	}
+	source := storage.Redact(*dataFile)
+	if source == "-" {
+		source = "<stdin>"
+	}
+	input = policy.Reader(loader.LimitReader(input, source, int64(*maxSize)))
+
+	if *estimates {
+		e, err := estimate.Data(source, input, dataFormat)
+		if err != nil {
+			return fmt.Errorf("failed to read data: %w", err)
+		}
+		addHistoricalDuration(&e, *stateFile)
+		return e.Write(os.Stdout)
+	}
+
+	// The checksum covers the raw input as it is consumed, so history
+	// matches the checksum reported by -estimate.
+	hash := sha256.New()
+	var reader dataload.Reader
+	if mapping != nil {
+		reader, err = dataload.NewJSONLReader(io.TeeReader(input, hash), dataload.JSONLOptions{Mapping: mapping})
+	} else {
+		reader, err = dataload.NewReader(io.TeeReader(input, hash), dataFormat)
+	}
+	if reader, err = empty.records(source, reader, err); err != nil {
+		return err
+	}
+	if reader == nil {
+		empty.logSkipped(log)
+		return nil
+	}
+	var nullReader *dataload.NullReader
+	if !nullPolicy.IsZero() {
+		if nullReader, err = dataload.ApplyNulls(reader, nullPolicy); err != nil {
+			return err
+		}
+		reader = nullReader
+	}
+	// Change files repeat keys by design, so -key only names their keys.
+	if *keys != "" && *opColumn == "" {
+		if reader, err = dedupe(reader, splitList(*keys), dupMode, log); err != nil {
+			return err
+		}
+	}
+
+	if *preview > 0 {
+		_, err := dataload.Preview(os.Stdout, reader, *preview)
+		return err
+	}
+
+	if err := guard.check(time.Now()); err != nil {
+		return err
+	}
+
+	driver, dsn, err := conn.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	if *table == "" {
+		return fmt.Errorf("table is required (use -table flag)")
+	}
+	if err := conn.confirmWrite(*dataFile != "-"); err != nil {
+		return err
+	}
+	if dsn, err = conn.primary(ctx, driver, dsn); err != nil {
+		return err
+	}
+	if *feed == "" {
+		*feed = *table
+	}
+
+	var changes dataload.ChangeOptions
+	if *opColumn != "" {
+		changes = dataload.ChangeOptions{Driver: driver, Table: *table, OpColumn: *opColumn, Keys: splitList(*keys), TxOptions: txOpts}
+		if len(changes.Keys) == 0 {
+			changes.Keys = conn.cfg.Tables[*table].Keys
+		}
+		if len(changes.Keys) == 0 {
+			return fmt.Errorf("-op-column requires key columns (use -key or tables.%s.keys in the config file)", *table)
+		}
+	}
+	if identPolicy != database.IdentifierQuote {
+		*table = identPolicy.Qualified(driver, *table)
+		changes.Table = *table
+		changes.OpColumn = identPolicy.Name(driver, changes.OpColumn)
+		for i, key := range changes.Keys {
+			changes.Keys[i] = identPolicy.Name(driver, key)
+		}
+		if reader, err = renameColumns(reader, driver, identPolicy, nil); err != nil {
+			return err
+		}
+		if *increment != "" {
+			*increment = identPolicy.Name(driver, *increment)
+		}
+	}
+
+	db, err := conn.connect(ctx, driver, dsn, log.warn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.warn("failed to close database: %v", closeErr)
+		}
+	}()
+
+	if err := requireWritable(ctx, db, driver, "connect to the primary"); err != nil {
+		return err
+	}
+
+	if reader, err = evolveSchema(db, driver, *table, reader, *evolve, *dataFile != "-", conn.cfg.Types[driver]); err != nil {
+		return err
+	}
+	if *check {
+		if reader, err = preflightTable(db, driver, *table, reader, changes.OpColumn, log); err != nil {
+			return err
+		}
+	}
+
+	var (
+		marks  *dataload.WatermarkReader
+		markTx *sql.Tx
+	)
+	if *increment != "" {
+		if markTx, marks, err = beginIncremental(ctx, db, txOpts, driver, *markTable, *feed, *increment, reader); err != nil {
+			return err
+		}
+		defer func() { _ = markTx.Rollback() }()
+		reader = marks
+	}
+
+	store := openStateOrWarn(*stateFile)
+	defer closeState(store)
+
+	var statsReader *dataload.StatsReader
+	if *stats {
+		statsReader = dataload.CollectStats(reader)
+		reader = statsReader
+	}
+
+	started := time.Now()
+	var (
+		rows   int64
+		counts dataload.ChangeCounts
+	)
+	switch {
+	case *opColumn != "":
+		counts, err = dataload.ApplyChanges(ctx, db, reader, changes)
+	case marks != nil:
+		rows, err = loadIncremental(ctx, markTx, reader, marks, dataload.Options{
+			Driver:    driver,
+			Table:     *table,
+			BatchSize: *batchSize,
+		}, *markTable, *feed)
+	default:
+		rows, err = dataload.Load(ctx, db, reader, dataload.Options{
+			Driver:    driver,
+			Table:     *table,
+			BatchSize: *batchSize,
+		})
+	}
+	recordRun(store, state.Run{
+		Kind:      state.KindData,
+		Source:    source,
+		Checksum:  hex.EncodeToString(hash.Sum(nil)),
+		Target:    targetID(driver, dsn),
+		Profile:   conn.profileName,
+		StartedAt: started,
+	}, err, nil)
+	if err != nil && *opColumn != "" {
+		return fmt.Errorf("failed to apply changes; nothing was applied: %w", err)
+	}
+	if err != nil && marks != nil {
+		return fmt.Errorf("failed to load data; nothing was loaded and the watermark is unchanged: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load data after %d rows: %w", rows, err)
+	}
+
+	if *opColumn != "" {
+		log.info("changes applied", fmt.Sprintf("Applied changes to %s: %s", *table, counts), "table", *table,
+			"inserted", counts.Inserted, "updated", counts.Updated, "deleted", counts.Deleted, "missed", counts.Missed)
+	} else {
+		log.info("data loaded", fmt.Sprintf("Loaded %d rows into %s", rows, *table), "table", *table, "rows", rows)
+	}
+	if marks != nil {
+		if n := marks.Skipped(); n > 0 {
+			log.info("records skipped", fmt.Sprintf("Skipped %d record(s) at or below the watermark of feed %s", n, *feed),
+				"feed", *feed, "records", n, "reason", "at or below the watermark")
+		}
+		if max, ok := marks.Max(); ok {
+			log.info("watermark advanced", fmt.Sprintf("Watermark of feed %s is now %s", *feed, max), "feed", *feed, "watermark", max)
+		}
+	}
+	if nullReader != nil {
+		var total int64
+		coerced := nullReader.Coerced()
+		for _, n := range coerced {
+			total += n
+		}
+		if total > 0 {
+			log.info("values coerced to null", fmt.Sprintf("Coerced %d values to NULL (%s)", total, dataload.FormatCounts(coerced)),
+				"values", total, "by_column", coerced)
+		}
+	}
+	if statsReader != nil {
+		return log.columnStatistics(statsReader.Stats())
+	}
+	return nil
+}
+
+// beginIncremental opens the transaction of an incremental load and wraps
+// reader to keep only the records beyond the high-watermark of feed, which
+// must be tracked by column. The watermark is read inside the transaction,
+// so concurrent loads of the feed take turns instead of loading the same
+// records.
+func beginIncremental(ctx context.Context, db *sql.DB, txOpts *sql.TxOptions, driver, table, feed, column string, reader dataload.Reader) (*sql.Tx, *dataload.WatermarkReader, error) {
+	tx, err := db.BeginTx(ctx, txOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	mark, err := dataload.ReadWatermark(ctx, tx, driver, table, feed)
+	if err == nil && mark != nil && mark.Column != column {
+		err = fmt.Errorf("feed %s is tracked by column %s, not %s (use -feed to start another feed)", feed, mark.Column, column)
+	}
+	var marks *dataload.WatermarkReader
+	if err == nil {
+		var after *string
+		if mark != nil {
+			after = &mark.Value
+		}
+		marks, err = dataload.FilterWatermark(reader, column, after)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, nil, err
+	}
+	return tx, marks, nil
+}
+
+// loadIncremental loads the records of reader, which reads through marks,
+// and advances the watermark of feed in tx, so that the watermark never
+// passes records that were not loaded.
+func loadIncremental(ctx context.Context, tx *sql.Tx, reader dataload.Reader, marks *dataload.WatermarkReader, opts dataload.Options, table, feed string) (int64, error) {
+	rows, err := dataload.Load(ctx, tx, reader, opts)
+	if max, ok := marks.Max(); err == nil && ok {
+		err = dataload.SaveWatermark(ctx, tx, opts.Driver, table, feed, marks.Column(), max)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return rows, nil
+}
+
+// renameColumns applies the identifier policy to the input column names,
+// leaving those in keep, which are already table columns, unchanged.
+func renameColumns(reader dataload.Reader, driver string, policy database.IdentifierPolicy, keep []string) (dataload.Reader, error) {
+	reader, err := dataload.RenameColumns(reader, func(name string) string {
+		if slices.Contains(keep, name) {
+			return name
+		}
+		return policy.Name(driver, name)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply -identifiers %s: %w", policy, err)
+	}
+	return reader, nil
+}
+
+// maxReportedDuplicates caps the duplicate keys printed by dedupe.
+const maxReportedDuplicates = 20
+
+// dedupe checks the input for repeated key values before anything is
+// loaded, failing with the offending records or dropping the extras.
+func dedupe(reader dataload.Reader, keys []string, mode dataload.DuplicateMode, log *runLog) (dataload.Reader, error) {
+	reader, dups, err := dataload.Dedupe(reader, keys, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+	if len(dups) == 0 {
+		return reader, nil
+	}
+
+	var extra int
+	for i, d := range dups {
+		extra += len(d.Records) - 1
+		switch {
+		case i >= maxReportedDuplicates:
+		case mode == dataload.DuplicateReject:
+			log.problem("duplicate key", fmt.Sprintf("Duplicate %s", d), "duplicate", d.String())
+		default:
+			log.warn("duplicate %s", d)
+		}
+	}
+	if len(dups) > maxReportedDuplicates {
+		log.warn("... and %d more duplicate keys", len(dups)-maxReportedDuplicates)
+	}
+
+	if mode == dataload.DuplicateReject {
+		return nil, fmt.Errorf("%d key value(s) repeat in the input; nothing was loaded (use -on-duplicate first|last to keep one record per key)", len(dups))
+	}
+	log.warn("kept the %s record of each key, dropping %d duplicate records", mode, extra)
+	return reader, nil
+}
+
+// preflightTable checks the target table against the input columns and the
+// first record, printing type compatibility warnings. The ignore column, if
+// set, is not part of the table. It returns a reader that still yields the
+// first record.
+func preflightTable(db *sql.DB, driver, table string, reader dataload.Reader, ignore string, log *runLog) (dataload.Reader, error) {
+	cat, err := catalog.Inspect(db, driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect database: %w", err)
+	}
+	first, reader, err := dataload.Peek(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+	columns := reader.Columns()
+	if i := slices.Index(columns, ignore); i >= 0 {
+		columns = slices.Delete(slices.Clone(columns), i, i+1)
+		if first != nil {
+			first = slices.Delete(slices.Clone(first), i, i+1)
+		}
+	}
+	warnings, err := preflight.CheckTable(cat, table, columns, first)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		log.warn("%s", w)
+	}
+	return reader, nil
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/dataload"
+	"github.com/obstreperous-ai/sql-loader-go/internal/report"
+)
+
+// Values of -color.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// ANSI escapes of the colored parts of text output.
+const (
+	ansiYellow = "\033[33m"
+	ansiRed    = "\033[31m"
+	ansiReset  = "\033[0m"
+)
+
+// logFlags holds the flags choosing how a command reports its progress.
+// -log-format and -color default to the defaults of the config files.
+type logFlags struct {
+	format        *string
+	quiet         *bool
+	deterministic *bool
+	color         *string
+}
+
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	return &logFlags{
+		format:        fs.String("log-format", "text", "Progress output format: text, or json for one structured event per line"),
+		quiet:         fs.Bool("quiet", false, "Suppress per-statement and per-item progress output"),
+		deterministic: fs.Bool("deterministic", false, "Omit timestamps, durations, and the progress report from the output, for golden-file tests"),
+		color:         fs.String("color", colorAuto, "Color warnings and errors in text output: auto (on a terminal, unless NO_COLOR is set), always, or never"),
+	}
+}
+
+// runLog returns the run log the flags ask for, writing to stdout.
+func (f *logFlags) runLog() (*runLog, error) {
+	var color bool
+	switch *f.color {
+	case colorAuto:
+		color = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTerminal(os.Stderr)
+	case colorAlways:
+		color = true
+	case colorNever:
+	default:
+		return nil, fmt.Errorf("unsupported color mode %q (use auto, always, or never)", *f.color)
+	}
+	l, err := newRunLog(*f.format, os.Stdout, *f.quiet, *f.deterministic)
+	if err != nil {
+		return nil, err
+	}
+	l.color = color
+	return l, nil
+}
+
+// runLog reports the progress of a script run, as the plain text lines
+// sql-loader has always printed or, with -log-format json, as one JSON
+// event per line for log collectors. Quiet drops the per-statement
+// progress of either format. Deterministic drops timestamps, durations, and
+// the time-driven progress report, so that the output of a run can be
+// compared against a golden file.
+type runLog struct {
+	json          *slog.Logger
+	jsonOpts      *slog.HandlerOptions
+	progress      *progress
+	quiet         bool
+	deterministic bool
+	// color colors the warnings and errors of text output.
+	color bool
+	// out and errOut receive the text lines and warnings, and out the JSON
+	// events.
+	out, errOut io.Writer
+}
+
+func newRunLog(format string, w io.Writer, quiet, deterministic bool) (*runLog, error) {
+	switch format {
+	case "text":
+		l := &runLog{quiet: quiet, deterministic: deterministic, out: w, errOut: os.Stderr}
+		if !quiet && !deterministic {
+			l.progress = newProgress(os.Stderr, isTerminal(os.Stderr))
+		}
+		return l, nil
+	case "json":
+		var opts *slog.HandlerOptions
+		if deterministic {
+			opts = &slog.HandlerOptions{ReplaceAttr: dropTimes}
+		}
+		return &runLog{
+			json:          slog.New(slog.NewJSONHandler(w, opts)),
+			jsonOpts:      opts,
+			quiet:         quiet,
+			deterministic: deterministic,
+			out:           w,
+			errOut:        os.Stderr,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported log format %q (use text or json)", format)
+	}
+}
+
+// to returns a copy of l writing to out and errOut instead, without a
+// progress report, for a script whose output is held back.
+func (l *runLog) to(out, errOut io.Writer) *runLog {
+	c := *l
+	c.out, c.errOut, c.progress = out, errOut, nil
+	if l.json != nil {
+		c.json = slog.New(slog.NewJSONHandler(out, l.jsonOpts))
+	}
+	return &c
+}
+
+func (l *runLog) scriptStarted(path, driver string, statements int) {
+	if l.json != nil {
+		l.json.Info("script started", "script", path, "driver", driver, "statements", statements)
+		return
+	}
+	fmt.Fprintf(l.out, "Loading SQL script from %s into %s database\n", path, driver)
+	if l.progress != nil {
+		l.progress.start(statements)
+	}
+}
+
+// statement reports a completed statement, as a JSON event or by advancing
+// the text progress report.
+func (l *runLog) statement(path string, index int, stmt string, elapsed time.Duration, rows int64) {
+	if l.quiet {
+		return
+	}
+	if l.json == nil {
+		if l.progress != nil {
+			l.progress.statement(rows)
+		}
+		return
+	}
+	attrs := []any{
+		"script", path,
+		"statement", index,
+		"verb", database.DescribeStatement(stmt).Verb,
+		"duration_ms", milliseconds(elapsed),
+	}
+	if rows >= 0 {
+		attrs = append(attrs, "rows_affected", rows)
+	}
+	l.json.Info("statement executed", attrs...)
+}
+
+// notice reports a notice the server sent while statement index of path
+// ran, as it arrives. Text output prints it if show, as chosen by -notices,
+// a warning as a warning. JSON output has an event for every notice, of
+// which -quiet keeps only warnings.
+func (l *runLog) notice(path string, index int, n database.Notice, show bool) {
+	warning := n.Severity == "WARNING"
+	if l.json == nil {
+		switch {
+		case !show:
+		case warning:
+			l.warn("%s statement %d: %s", path, index, n)
+		default:
+			if l.progress != nil {
+				l.progress.clear()
+			}
+			fmt.Fprintf(l.errOut, "%s statement %d: %s\n", path, index, n)
+		}
+		return
+	}
+	if l.quiet && !warning {
+		return
+	}
+	attrs := []any{"script", path, "statement", index, "severity", n.Severity, "message", n.Message}
+	for _, a := range []struct{ key, value string }{{"code", n.Code}, {"detail", n.Detail}, {"hint", n.Hint}} {
+		if a.value != "" {
+			attrs = append(attrs, a.key, a.value)
+		}
+	}
+	level := slog.LevelInfo
+	if warning {
+		level = slog.LevelWarn
+	}
+	l.json.Log(context.Background(), level, "server notice", attrs...)
+}
+
+// batchCommitted reports a committed batch. Only JSON output has a line
+// per batch.
+func (l *runLog) batchCommitted(path string, batch, committed int) {
+	if l.json != nil && !l.quiet {
+		l.json.Info("batch committed", "script", path, "batch", batch, "statements_committed", committed)
+	}
+}
+
+// batchesCommitted summarizes the batches of a script in text output.
+func (l *runLog) batchesCommitted(path string, batches, size int) {
+	if l.json == nil {
+		fmt.Fprintf(l.out, "Committed %s in %d batches of up to %d statements\n", path, batches, size)
+	}
+}
+
+// scriptEnded ends the text progress report of a script, whether or not it
+// succeeded.
+func (l *runLog) scriptEnded() {
+	if l.progress != nil {
+		l.progress.finish()
+	}
+}
+
+func (l *runLog) scriptSkipped(path string, appliedAt time.Time) {
+	if l.json != nil {
+		l.json.Info("script skipped", "script", path, "applied_at", appliedAt, "reason", "identical script already applied")
+		return
+	}
+	if l.deterministic {
+		fmt.Fprintf(l.out, "Skipping %s: identical script already applied\n", path)
+		return
+	}
+	fmt.Fprintf(l.out, "Skipping %s: identical script already applied at %s\n", path, appliedAt.Format(time.RFC3339))
+}
+
+// emptySkipped reports an input skipped by -on-empty skip.
+func (l *runLog) emptySkipped(path, reason string) {
+	if l.json != nil {
+		l.json.Info("script skipped", "script", path, "reason", reason)
+		return
+	}
+	fmt.Fprintf(l.out, "Skipping %s: %s\n", path, reason)
+}
+
+// grantsApplied reports the statements of the -grants file applied after
+// the scripts.
+func (l *runLog) grantsApplied(path string, statements int) {
+	if l.json != nil {
+		l.json.Info("grants applied", "grants", path, "statements", statements)
+		return
+	}
+	fmt.Fprintf(l.out, "Applied %d grant statement(s) from %s\n", statements, path)
+}
+
+// verified reports the checks of the -post-verify script at path that
+// passed.
+func (l *runLog) verified(path string, checks int) {
+	if l.json != nil {
+		l.json.Info("post-verify passed", "checks", path, "passed", checks)
+		return
+	}
+	fmt.Fprintf(l.out, "Post-verify passed: %d check(s) from %s\n", checks, path)
+}
+
+// expected reports the expectations of path that matched, and those
+// -update-golden rewrote.
+func (l *runLog) expected(path string, met, updated int) {
+	if l.json != nil {
+		l.json.Info("expectations met", "expectations", path, "met", met, "updated", updated)
+		return
+	}
+	if updated > 0 {
+		fmt.Fprintf(l.out, "Expectations: %d met and %d updated from %s\n", met, updated, path)
+		return
+	}
+	fmt.Fprintf(l.out, "Expectations met: %d from %s\n", met, path)
+}
+
+// ownerSet reports the objects -set-owner handed over to role.
+func (l *runLog) ownerSet(role string, objects int) {
+	if l.json != nil {
+		l.json.Info("owner set", "owner", role, "objects", objects)
+		return
+	}
+	fmt.Fprintf(l.out, "Set the owner of %d created object(s) to %s\n", objects, role)
+}
+
+func (l *runLog) finished(scripts int) {
+	if l.json != nil {
+		l.json.Info("run finished", "scripts", scripts)
+		return
+	}
+	if scripts == 1 {
+		fmt.Fprintln(l.out, "Script executed successfully")
+	} else {
+		fmt.Fprintf(l.out, "%d scripts executed successfully\n", scripts)
+	}
+}
+
+// statistics prints the -stats report: a table in text output, or the
+// totals as one JSON event.
+func (l *runLog) statistics(r *report.Report) error {
+	if l.json != nil {
+		l.json.Info("run statistics", "statements", r.Totals.Statements, "rows_affected", r.Totals.RowsAffected, "by_type", r.Totals.ByType)
+		return nil
+	}
+	fmt.Fprintln(l.out)
+	return r.WriteTable(l.out)
+}
+
+// info reports an event of a command other than a script run: text as a
+// line of text output, or event with attrs as a JSON event.
+func (l *runLog) info(event, text string, attrs ...any) {
+	if l.json != nil {
+		l.json.Info(event, attrs...)
+		return
+	}
+	fmt.Fprintln(l.out, text)
+}
+
+// step is info for the progress of one item, such as a migration, which
+// -quiet drops.
+func (l *runLog) step(event, text string, attrs ...any) {
+	if !l.quiet {
+		l.info(event, text, attrs...)
+	}
+}
+
+// problem reports a failure that does not end the command by itself, such
+// as that of a script run by listen: text as a line on stderr, or event with
+// attrs as a JSON error event.
+func (l *runLog) problem(event, text string, attrs ...any) {
+	if l.json != nil {
+		l.json.Error(event, attrs...)
+		return
+	}
+	if l.progress != nil {
+		l.progress.clear()
+	}
+	if l.color {
+		text = ansiRed + text + ansiReset
+	}
+	fmt.Fprintln(l.errOut, text)
+}
+
+// columnStatistics prints the load-data -stats report: a table in text
+// output, or an event per column in JSON output.
+func (l *runLog) columnStatistics(stats []dataload.ColumnStats) error {
+	if l.json == nil {
+		fmt.Fprintln(l.out)
+		return dataload.WriteStats(l.out, stats)
+	}
+	for _, c := range stats {
+		l.json.Info("column statistics", "column", c.Name, "nulls", c.Nulls, "distinct", c.Distinct,
+			"distinct_capped", c.DistinctCapped, "min", c.Min, "max", c.Max)
+	}
+	return nil
+}
+
+func (l *runLog) warn(format string, args ...any) {
+	if l.json != nil {
+		l.json.Warn(fmt.Sprintf(format, args...))
+		return
+	}
+	if l.progress != nil {
+		l.progress.clear()
+	}
+	prefix := "Warning: "
+	if l.color {
+		prefix = ansiYellow + "Warning:" + ansiReset + " "
+	}
+	fmt.Fprintf(l.errOut, prefix+format+"\n", args...)
+}
+
+// failed reports err as a JSON event or, with color, as colored text,
+// returning it as a loggedError so that main does not print it again.
+// Other text output leaves err to main.
+func (l *runLog) failed(err error) error {
+	if err == nil || (l.json == nil && !l.color) {
+		return err
+	}
+	if l.json == nil {
+		fmt.Fprintf(l.errOut, "%sError:%s %v\n", ansiRed, ansiReset, err)
+		if hint := database.Hint(err); hint != "" {
+			fmt.Fprintf(l.errOut, "Hint: %s\n", hint)
+		}
+		return loggedError{err}
+	}
+	attrs := []any{"error", err.Error()}
+	var execErr *database.ExecError
+	if errors.As(err, &execErr) {
+		attrs = append(attrs, "statement", execErr.Index, "statements", execErr.Total)
+		if execErr.Committed > 0 {
+			attrs = append(attrs, "statements_committed", execErr.Committed, "batches_committed", execErr.Batches)
+		}
+	}
+	if class := database.Classify(err); class.Category != database.CategoryOther || class.SQLState != "" {
+		attrs = append(attrs, "category", string(class.Category))
+		if class.SQLState != "" {
+			attrs = append(attrs, "sqlstate", class.SQLState)
+		}
+	}
+	if hint := database.Hint(err); hint != "" {
+		attrs = append(attrs, "hint", hint)
+	}
+	l.json.Error("run failed", attrs...)
+	return loggedError{err}
+}
+
+// loggedError is an error the run log has already reported.
+type loggedError struct {
+	error
+}
+
+func (e loggedError) Unwrap() error {
+	return e.error
+}
+
+// dropTimes removes the attributes of JSON events that differ between
+// otherwise identical runs.
+func dropTimes(_ []string, a slog.Attr) slog.Attr {
+	switch a.Key {
+	case slog.TimeKey, "duration_ms", "applied_at":
+		return slog.Attr{}
+	}
+	return a
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
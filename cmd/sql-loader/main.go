@@ -3,12 +3,25 @@
 package main
 
 import (
-	"flag"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
+	// Embed time zone data so -allowed-window zones resolve on hosts
+	// without a zoneinfo database, such as Windows and slim containers.
+	_ "time/tzdata"
 
 	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/dryrun"
+	"github.com/obstreperous-ai/sql-loader-go/internal/estimate"
+	"github.com/obstreperous-ai/sql-loader-go/internal/lint"
 	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+	"github.com/obstreperous-ai/sql-loader-go/internal/preflight"
+	"github.com/obstreperous-ai/sql-loader-go/internal/report"
+	"github.com/obstreperous-ai/sql-loader-go/internal/state"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "modernc.org/sqlite"
@@ -21,58 +34,561 @@ var (
 )
 
 func main() {
-	if err := run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	ctx, stop := handleSignals(context.Background())
+	err := run(ctx, os.Args[1:])
+	var interrupted *interruptError
+	errors.As(context.Cause(ctx), &interrupted)
+	stop()
+	if err != nil {
+		if !errors.As(err, new(loggedError)) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if hint := database.Hint(err); hint != "" {
+				fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+			}
+		}
+		if interrupted != nil {
+			os.Exit(interrupted.exitCode())
+		}
 		os.Exit(1)
 	}
 }
 
-func run() error {
+func run(ctx context.Context, args []string) error {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, ok := lookupCommand(args[0])
+		if !ok {
+			return fmt.Errorf("unknown command %q (run sql-loader help for a list)", args[0])
+		}
+		return cmd.run(ctx, args[1:])
+	}
+	return runExec(ctx, args)
+}
+
+// runExec implements the exec subcommand, which executes SQL scripts. It is
+// also what sql-loader runs when no subcommand is given.
+func runExec(ctx context.Context, args []string) (err error) {
+	fs := newFlagSet("exec")
 	var (
-		showVersion = flag.Bool("version", false, "Show version information")
-		driver      = flag.String("driver", "postgres", "Database driver (postgres, sqlite)")
-		dsn         = flag.String("dsn", "", "Database connection string")
-		scriptFile  = flag.String("file", "", "SQL script file to execute")
+		showVersion = fs.Bool("version", false, "Show version information")
+		conn        = addConnectionFlags(fs)
+		source      = addScriptFlags(fs, "execute")
+		checkPrivs  = fs.Bool("preflight-privileges", false, "Verify the connected role holds the privileges the script needs before executing")
+		estimateRun = fs.Bool("estimate", false, "Report script size, statement counts, and expected duration without executing")
+		skipApplied = fs.Bool("skip-if-applied", false, "Skip the script if identical content already succeeded against this target")
+		stateFile   = addStateFlag(fs)
+		reconnect   = fs.Int("reconnect", 0, "Reconnect up to N times with backoff and resume at the interrupted statement if the connection drops")
+		guard       = addWindowFlags(fs)
+		maxRows     = fs.Float64("max-estimated-rows", 0, "Abort before any DML statement whose EXPLAIN row estimate exceeds this (PostgreSQL, 0 = off)")
+		maxCost     = fs.Float64("max-estimated-cost", 0, "Abort before any DML statement whose EXPLAIN total cost exceeds this (PostgreSQL, 0 = off)")
+		warnDups    = fs.Bool("warn-duplicates", false, "Warn about statements that repeat an earlier statement before executing")
+		readOnly    = fs.Bool("read-only", false, "Run a query-only script: allow read-only replicas and reject any write")
+		transaction = fs.String("transaction", string(database.TransactionNone), "Transaction scope: all (roll back the whole run on error), file (each script on its own), per-statement, or none")
+		retryTx     = fs.Int("retry-transaction", 0, "Rerun a transaction that fails with a serialization failure or deadlock up to N times with backoff (requires -transaction all or file)")
+		txOptions   = addTxOptionFlags(fs)
+		onError     = fs.String("on-error", onErrorStop, "After a script fails: stop, or continue with the next script and fail at the end")
+		batchSize   = fs.Int("batch-size", 0, "Commit every N statements, rolling back only the failed batch (0 = off)")
+		timeout     = fs.Duration("timeout", 0, "Cancel the run if it has not finished within this duration, e.g. 30m (0 = none)")
+		stmtTimeout = fs.Duration("statement-timeout", 0, "Cancel any statement running longer than this duration, e.g. 5m (0 = none)")
+		dryRun      = fs.Bool("dry-run", false, "Print the statements that would execute and check their syntax, without connecting")
+		dryRunFull  = fs.Bool("dry-run-full", false, "Like -dry-run, printing every statement in full")
+		logs        = addLogFlags(fs)
+		reports     = addReportFlags(fs)
+		notices     = addNoticeFlag(fs)
+		grantFile   = addGrantsFlag(fs)
+		owner       = addOwnerFlag(fs)
+		postVerify  = addPostVerifyFlag(fs)
+		parallel    = fs.Int("parallel", 1, "Run up to N independent scripts at once, each on its own connection, printing their output in order")
+		maxConns    = fs.Int("max-connections", 0, "Open at most N database connections at once, capping -parallel (0 = no limit)")
 	)
 
-	flag.Parse()
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
 
 	if *showVersion {
-		fmt.Printf("sql-loader version %s (commit: %s, built: %s)\n", version, commit, date)
+		return runVersion(ctx, nil)
+	}
+
+	txScope, err := parseRunTransaction(*transaction)
+	if err != nil {
+		return err
+	}
+	continueOnError, err := parseOnError(*onError)
+	if err != nil {
+		return err
+	}
+	if continueOnError && txScope.wholeRun {
+		return fmt.Errorf("-on-error continue cannot be combined with -transaction all, which rolls back the whole run (use -transaction file)")
+	}
+	if err := notices.validate(); err != nil {
+		return err
+	}
+	if err := reports.format.validate(); err != nil {
+		return err
+	}
+	if err := postVerify.validate(); err != nil {
+		return err
+	}
+	if *parallel < 1 {
+		return fmt.Errorf("-parallel must be at least 1")
+	}
+	if *parallel > 1 && txScope.wholeRun {
+		return fmt.Errorf("-parallel cannot be combined with -transaction all, which runs every script on one connection (use -transaction file)")
+	}
+	if *maxConns < 0 {
+		return fmt.Errorf("-max-connections must not be negative")
+	}
+	// A script run in a transaction explains its statements for the
+	// estimate limits on a second connection.
+	connsPerScript := 1
+	if (preflight.ExplainLimits{MaxRows: *maxRows, MaxCost: *maxCost}).Enabled() && (txScope.mode != database.TransactionNone || *batchSize > 0) {
+		connsPerScript = 2
+	}
+	if *maxConns > 0 && *maxConns < connsPerScript {
+		return fmt.Errorf("-max-connections must be at least %d: the estimate limits explain statements on a second connection while a transaction is open", connsPerScript)
+	}
+	if *retryTx < 0 {
+		return fmt.Errorf("-retry-transaction must not be negative")
+	}
+	if *retryTx > 0 && txScope.mode != database.TransactionAll {
+		return fmt.Errorf("-retry-transaction requires -transaction all or file: only a whole transaction can be rerun from its start")
+	}
+	txOpts, err := txOptions.options()
+	if err != nil {
+		return err
+	}
+	if txOpts != nil && txScope.mode == database.TransactionNone && *batchSize == 0 {
+		return fmt.Errorf("-isolation and -tx-read-only require -transaction or -batch-size: without them statements run in autocommit mode")
+	}
+	if *batchSize < 0 {
+		return fmt.Errorf("-batch-size must not be negative")
+	}
+	if *batchSize > 0 && txScope.mode != database.TransactionNone {
+		return fmt.Errorf("-batch-size cannot be combined with -transaction %s", *transaction)
+	}
+	policy, err := source.text.policy()
+	if err != nil {
+		return err
+	}
+	sb, err := source.allowed.open()
+	if err != nil {
+		return err
+	}
+	checks, err := postVerify.script(sb, source.limits, policy)
+	if err != nil {
+		return err
+	}
+	expected, err := postVerify.expectations(sb)
+	if err != nil {
+		return err
+	}
+	log, err := logs.runLog()
+	if err != nil {
+		return err
+	}
+	defer func() { err = log.failed(err) }()
+	rep := reports.new(*logs.deterministic)
+	defer func() { err = reports.write(rep, err) }()
+	defer func() { reports.format.annotate(err) }()
+	defer func() { err = reports.format.writeJUnit(postVerify.suites(checks, expected, err), err) }()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, *timeout,
+			fmt.Errorf("run timeout of %s exceeded: %w", *timeout, context.DeadlineExceeded))
+		defer cancel()
+	}
+
+	scripts, err := source.load(ctx)
+	if err != nil {
+		return err
+	}
+	for _, in := range source.empty.skipped {
+		log.emptySkipped(in.name, in.reason)
+		rep.Skip(in.name, in.reason)
+	}
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	if *warnDups {
+		for _, s := range scripts {
+			for _, f := range lint.Duplicates(database.SplitStatements(s.Content)) {
+				log.warn("%s: %s", s.Path, f)
+			}
+		}
+	}
+
+	if *estimateRun {
+		for _, s := range scripts {
+			e := estimate.Script(s.Path, s.Content)
+			addHistoricalDuration(&e, *stateFile)
+			if err := e.Write(os.Stdout); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 
-	if *dsn == "" {
-		return fmt.Errorf("DSN is required (use -dsn flag)")
+	if *dryRun || *dryRunFull {
+		problems := 0
+		for i, s := range scripts {
+			if i > 0 {
+				fmt.Println()
+			}
+			plan := dryrun.Script(s.Path, s.Content)
+			if err := plan.Write(os.Stdout, *dryRunFull); err != nil {
+				return err
+			}
+			problems += len(plan.Problems())
+		}
+		if problems > 0 {
+			return fmt.Errorf("dry run found %d syntax error(s)", problems)
+		}
+		return nil
 	}
 
-	if *scriptFile == "" {
-		return fmt.Errorf("script file is required (use -file flag)")
+	if err := parseScripts(scripts); err != nil {
+		return err
+	}
+	if err := guard.check(time.Now()); err != nil {
+		return err
 	}
 
-	// Load SQL script from file
-	script, err := loader.LoadScript(*scriptFile)
+	driver, dsn, err := conn.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	grantScript, err := grantFile.script(driver, sb)
 	if err != nil {
-		return fmt.Errorf("failed to load script: %w", err)
+		return err
+	}
+	if grantScript != "" && *readOnly {
+		return fmt.Errorf("-grants cannot be combined with -read-only")
+	}
+	if txOpts != nil && txOpts.ReadOnly && driver != "postgres" {
+		return fmt.Errorf("-tx-read-only requires the postgres driver; %s would ignore it and allow writes", driver)
+	}
+	if err := owner.validate(driver); err != nil {
+		return err
+	}
+	if *owner.role != "" && *readOnly {
+		return fmt.Errorf("-set-owner cannot be combined with -read-only")
+	}
+	if !*readOnly {
+		if err := conn.confirmWrite(!source.stdin()); err != nil {
+			return err
+		}
+		if dsn, err = conn.primary(ctx, driver, dsn); err != nil {
+			return err
+		}
+	}
+
+	target := targetID(driver, dsn)
+	var store *state.Store
+	if *skipApplied {
+		if store, err = openState(*stateFile); err != nil {
+			return fmt.Errorf("-skip-if-applied requires run history: %w", err)
+		}
+		pending := scripts[:0]
+		for _, s := range scripts {
+			last, err := store.LastApplied(loader.Checksum(s.Content), target)
+			if err != nil {
+				closeState(store)
+				return err
+			}
+			if last != nil {
+				log.scriptSkipped(s.Path, last.StartedAt)
+				rep.Skip(s.Path, "identical script already applied")
+				continue
+			}
+			pending = append(pending, s)
+		}
+		if scripts = pending; len(scripts) == 0 {
+			closeState(store)
+			return nil
+		}
+	} else {
+		store = openStateOrWarn(*stateFile)
+	}
+	defer closeState(store)
+
+	connectDSN := dsn
+	if *readOnly {
+		connectDSN = database.ReadOnlyDSN(driver, dsn)
 	}
 
 	// Connect to database
-	db, err := database.Connect(*driver, *dsn)
+	db, err := conn.connect(ctx, driver, connectDSN, log.warn)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 	defer func() {
 		if closeErr := db.Close(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", closeErr)
+			log.warn("failed to close database: %v", closeErr)
 		}
 	}()
+	if *maxConns > 0 {
+		db.SetMaxOpenConns(*maxConns)
+	}
 
-	// Execute script
-	fmt.Printf("Loading SQL script from %s into %s database\n", *scriptFile, *driver)
-	if err := database.ExecuteScript(db, script); err != nil {
-		return fmt.Errorf("failed to execute script: %w", err)
+	if !*readOnly {
+		if err := requireWritable(ctx, db, driver, "connect to the primary, or use -read-only for query-only scripts"); err != nil {
+			return err
+		}
 	}
 
-	fmt.Println("Script executed successfully")
+	if *checkPrivs {
+		var statements []string
+		for _, s := range scripts {
+			statements = append(statements, database.SplitStatements(s.Content)...)
+		}
+		if err := preflight.CheckPrivileges(db, driver, statements); err != nil {
+			return err
+		}
+	}
+
+	owned, err := owner.snapshot(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	// Execute scripts
+	opts := database.ExecuteOptions{
+		Transaction:      txScope.mode,
+		BatchSize:        *batchSize,
+		StatementTimeout: *stmtTimeout,
+		Reconnect:        database.ReconnectPolicy{Attempts: *reconnect},
+		RetryTransaction: database.ReconnectPolicy{Attempts: *retryTx},
+		TxOptions:        txOpts,
+	}
+	if limits := (preflight.ExplainLimits{MaxRows: *maxRows, MaxCost: *maxCost}); limits.Enabled() {
+		opts.BeforeStatement = preflight.ExplainGuard(db, driver, limits)
+	}
+
+	// A single script in one transaction needs no run transaction, and
+	// keeps the error reporting of database.TransactionAll, unless the
+	// post-verify checks or expectations must see it before it commits.
+	var (
+		execer database.Execer = db
+		tx     *database.Tx
+		held   []heldRun
+		runRep = rep
+	)
+	heldTx := txScope.wholeRun && (len(scripts) > 1 || postVerify.set())
+	if heldTx {
+		opts.Transaction = database.TransactionNone
+	}
+
+	// execScript runs s, reporting its progress to log and rep.
+	execScript := func(s loader.Script, log *runLog, rep *report.Report) scriptRun {
+		log.scriptStarted(s.Path, driver, len(database.SplitStatements(s.Content)))
+		// A retried transaction starts the script's report over.
+		scriptRep := reports.new(*logs.deterministic)
+		scriptRep.StartScript(s.Path)
+		res := scriptRun{run: state.Run{
+			Kind:      state.KindScript,
+			Source:    s.Path,
+			Checksum:  loader.Checksum(s.Content),
+			Target:    target,
+			Profile:   conn.profileName,
+			StartedAt: time.Now(),
+		}}
+		opts := opts
+		opts.OnStatement = func(index int, stmt string, elapsed time.Duration, rows int64) {
+			res.timings = append(res.timings, elapsed)
+			log.statement(s.Path, index, stmt, elapsed, rows)
+			scriptRep.Statement(index, stmt, elapsed, rows)
+		}
+		opts.OnBatch = func(batch, committed int) {
+			res.batches = batch
+			log.batchCommitted(s.Path, batch, committed)
+		}
+		opts.OnReconnect = func(index, attempt int, err error) {
+			log.warn("connection lost at statement %d (%v); reconnecting (attempt %d of %d)", index, err, attempt, *reconnect)
+		}
+		opts.OnRetryTransaction = func(attempt int, wait time.Duration, err error) {
+			log.warn("%s: %v; rerunning its transaction in %s (attempt %d of %d)", s.Path, err, wait.Round(time.Millisecond), attempt, *retryTx)
+			res.timings = nil
+			scriptRep = reports.new(*logs.deterministic)
+			scriptRep.StartScript(s.Path)
+		}
+		opts.OnNotice = func(index int, n database.Notice) {
+			log.notice(s.Path, index, n, notices.shows(n))
+			scriptRep.Notice(index, n)
+		}
+		res.err = database.ExecuteScriptOn(ctx, execer, s.Content, opts)
+		rep.Add(scriptRep)
+		log.scriptEnded()
+		res.run.Duration = time.Since(res.run.StartedAt)
+		return res
+	}
+
+	// finish records the run of s, returning the error to stop the run
+	// with, if any.
+	var failed []string
+	finish := func(s loader.Script, res scriptRun) error {
+		err := res.err
+		if tx != nil {
+			if err != nil {
+				_ = tx.Rollback()
+				err = rolledBack(err)
+				runRep.FailScript(err)
+				recordHeld(store, held, fmt.Errorf("rolled back after %s failed", s.Path))
+				recordRun(store, res.run, err, res.timings)
+				return execError(s.Path, s.Content, err)
+			}
+			held = append(held, heldRun{run: res.run, timings: res.timings})
+			return nil
+		}
+		recordRun(store, res.run, err, res.timings)
+		if err != nil {
+			rep.FailScript(err)
+			err = execError(s.Path, s.Content, err)
+			if !continueOnError {
+				return err
+			}
+			reports.format.annotate(err)
+			log.warn("%v; continuing with the next script", err)
+			failed = append(failed, s.Path)
+			return nil
+		}
+		if *batchSize > 0 {
+			log.batchesCommitted(s.Path, res.batches, *batchSize)
+		}
+		return nil
+	}
+
+	workers := *parallel
+	if *maxConns > 0 {
+		workers = min(workers, *maxConns/connsPerScript)
+	}
+	// runHeld runs every script in the run transaction, then applies grants
+	// and owners, runs the post-verify checks and expectations, and commits.
+	runHeld := func() error {
+		var err error
+		if tx, err = database.BeginTx(ctx, db, opts.TxOptions); err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		execer, held, runRep = tx, nil, reports.new(*logs.deterministic)
+		for _, s := range scripts {
+			if err := finish(s, execScript(s, log, runRep)); err != nil {
+				return err
+			}
+		}
+		if grantScript != "" {
+			n, err := applyGrants(ctx, tx, grantScript, true)
+			if err != nil {
+				_ = tx.Rollback()
+				recordHeld(store, held, fmt.Errorf("rolled back after grants failed: %w", err))
+				return err
+			}
+			log.grantsApplied(*grantFile.path, n)
+		}
+		if owned != nil {
+			n, err := owner.apply(ctx, tx, owned, true)
+			if err != nil {
+				_ = tx.Rollback()
+				recordHeld(store, held, fmt.Errorf("rolled back after setting owners failed: %w", err))
+				return err
+			}
+			log.ownerSet(*owner.role, n)
+		}
+		if checks != "" {
+			n, err := postVerify.verify(ctx, tx, checks)
+			if err != nil {
+				_ = tx.Rollback()
+				err = fmt.Errorf("post-verify %s failed (transaction rolled back; nothing applied): %w", *postVerify.path, err)
+				recordHeld(store, held, err)
+				return err
+			}
+			log.verified(*postVerify.path, n)
+		}
+		if expected != nil {
+			met, updated, err := postVerify.expect(ctx, tx, expected)
+			if err != nil {
+				_ = tx.Rollback()
+				err = fmt.Errorf("expectations %s failed (transaction rolled back; nothing applied): %w", *postVerify.expectPath, err)
+				recordHeld(store, held, err)
+				return err
+			}
+			log.expected(*postVerify.expectPath, met, updated)
+		}
+		if err := tx.Commit(); err != nil {
+			err = fmt.Errorf("failed to commit transaction: %w", err)
+			recordHeld(store, held, err)
+			return err
+		}
+		recordHeld(store, held, nil)
+		return nil
+	}
+
+	if heldTx {
+		err := database.RetrySerializationFailures(ctx, opts.RetryTransaction, func(attempt int, wait time.Duration, err error) {
+			log.warn("%v; rerunning the run transaction in %s (attempt %d of %d)", err, wait.Round(time.Millisecond), attempt, *retryTx)
+		}, runHeld)
+		// Only the last attempt is reported; earlier ones were rolled back.
+		rep.Add(runRep)
+		if err != nil {
+			return err
+		}
+	} else if workers > 1 && len(scripts) > 1 {
+		db.SetMaxIdleConns(workers)
+		newReport := func() *report.Report { return reports.new(*logs.deterministic) }
+		if err := runParallel(scripts, workers, continueOnError, log, rep, newReport, execScript, finish); err != nil {
+			return err
+		}
+	} else {
+		for _, s := range scripts {
+			if err := finish(s, execScript(s, log, rep)); err != nil {
+				return err
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d scripts failed: %s", len(failed), len(scripts), strings.Join(failed, ", "))
+	}
+	if grantScript != "" && tx == nil {
+		n, err := applyGrants(ctx, db, grantScript, false)
+		if err != nil {
+			return err
+		}
+		log.grantsApplied(*grantFile.path, n)
+	}
+	if owned != nil && tx == nil {
+		n, err := owner.apply(ctx, db, owned, false)
+		if err != nil {
+			return err
+		}
+		log.ownerSet(*owner.role, n)
+	}
+	if checks != "" && tx == nil {
+		n, err := postVerify.verify(ctx, db, checks)
+		if err != nil {
+			return fmt.Errorf("post-verify %s failed; the run stays applied (use -transaction all to roll it back): %w", *postVerify.path, err)
+		}
+		log.verified(*postVerify.path, n)
+	}
+	if expected != nil && tx == nil {
+		met, updated, err := postVerify.expect(ctx, db, expected)
+		if err != nil {
+			return fmt.Errorf("expectations %s failed; the run stays applied (use -transaction all to roll it back): %w", *postVerify.expectPath, err)
+		}
+		log.expected(*postVerify.expectPath, met, updated)
+	}
+
+	log.finished(len(scripts))
+	if *reports.stats {
+		return log.statistics(rep)
+	}
 	return nil
 }
+
+// requireWritable fails fast when the target is a read-only replica, adding
+// hint to the error.
+func requireWritable(ctx context.Context, db *sql.DB, driver, hint string) error {
+	err := database.CheckWritable(ctx, db, driver)
+	if errors.Is(err, database.ErrReadOnly) {
+		return fmt.Errorf("%w (%s)", err, hint)
+	}
+	return err
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+	"github.com/obstreperous-ai/sql-loader-go/internal/migrate"
+	"github.com/obstreperous-ai/sql-loader-go/internal/report"
+)
+
+// runMigrate implements the migrate subcommand, which applies the scripts of
+// a directory that the target has not yet recorded as applied or, as
+// migrate down, reverts the last applied ones with their down scripts. As
+// migrate plan, it lists what it would apply without changing anything.
+func runMigrate(ctx context.Context, args []string) (err error) {
+	down := len(args) > 0 && args[0] == "down"
+	plan := len(args) > 0 && args[0] == "plan"
+	if down || plan {
+		args = args[1:]
+	}
+	fs := newFlagSet("migrate")
+	var (
+		conn        = addConnectionFlags(fs)
+		dir         = fs.String("dir", "", "Directory of migration scripts, applied in lexical path order")
+		table       = fs.String("table", migrate.DefaultTable, "Table recording applied migrations")
+		text        = addTextFlags(fs)
+		allowed     = addSandboxFlag(fs)
+		limits      = addLimitFlags(fs)
+		empty       = addEmptyFlag(fs)
+		notices     = addNoticeFlag(fs)
+		grantFile   = addGrantsFlag(fs)
+		owner       = addOwnerFlag(fs)
+		postVerify  = addPostVerifyFlag(fs)
+		stmtTimeout = fs.Duration("statement-timeout", 0, "Cancel any statement running longer than this duration, e.g. 5m (0 = none)")
+		steps       = fs.Int("steps", 1, "With migrate down, the number of applied migrations to revert, latest first")
+		format      = fs.String("format", "text", "With migrate plan, the output format (text, json)")
+		ci          = addReportFormatFlag(fs)
+		logs        = addLogFlags(fs)
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return fmt.Errorf("migrations directory is required (use -dir flag)")
+	}
+	if !down && isSet(fs, "steps") {
+		return fmt.Errorf("-steps requires migrate down")
+	}
+	if down && *grantFile.path != "" {
+		return fmt.Errorf("-grants cannot be combined with migrate down")
+	}
+	if down && *owner.role != "" {
+		return fmt.Errorf("-set-owner cannot be combined with migrate down")
+	}
+	if plan && (*grantFile.path != "" || *owner.role != "") {
+		return fmt.Errorf("-grants and -set-owner cannot be combined with migrate plan")
+	}
+	if (down || plan) && postVerify.set() {
+		return fmt.Errorf("-post-verify and -expect cannot be combined with migrate down or plan")
+	}
+	if err := postVerify.validate(); err != nil {
+		return err
+	}
+	if (down || plan) && ci.junit() {
+		return fmt.Errorf("-report-format junit reports -post-verify checks and -expect expectations, which migrate down and plan do not run")
+	}
+	if !plan && isSet(fs, "format") {
+		return fmt.Errorf("-format requires migrate plan")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported format %q (use text or json)", *format)
+	}
+	if down && *steps < 1 {
+		return fmt.Errorf("-steps must be at least 1")
+	}
+	if err := empty.validate(); err != nil {
+		return err
+	}
+	if err := notices.validate(); err != nil {
+		return err
+	}
+	if err := ci.validate(); err != nil {
+		return err
+	}
+	log, err := logs.runLog()
+	if err != nil {
+		return err
+	}
+	defer func() { err = log.failed(err) }()
+	defer func() { ci.annotate(err) }()
+
+	policy, err := text.policy()
+	if err != nil {
+		return err
+	}
+	sb, err := allowed.open()
+	if err != nil {
+		return err
+	}
+	checks, err := postVerify.script(sb, limits, policy)
+	if err != nil {
+		return err
+	}
+	expected, err := postVerify.expectations(sb)
+	if err != nil {
+		return err
+	}
+	defer func() { err = ci.writeJUnit(postVerify.suites(checks, expected, err), err) }()
+	if err := sb.Check(*dir); err != nil {
+		return err
+	}
+	scripts, err := loader.LoadDirWith(*dir, loader.DirOptions{Check: sb.Check, MaxScriptSize: int64(*limits.script)})
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	for i := range scripts {
+		if scripts[i].Content, err = policy.Apply(scripts[i].Content); err != nil {
+			return fmt.Errorf("failed to load migration %s: %w", scripts[i].Path, err)
+		}
+	}
+	if err := limits.checkStatements(scripts); err != nil {
+		return err
+	}
+	if scripts, err = empty.scripts(*dir, scripts); err != nil {
+		return err
+	}
+	if err := parseScripts(scripts); err != nil {
+		return err
+	}
+	empty.logSkipped(log)
+	if len(scripts) == 0 && !down && !plan {
+		return nil
+	}
+
+	driver, dsn, err := conn.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	if plan {
+		return printPlan(ctx, conn, driver, dsn, scripts, *table, *format, ci, log)
+	}
+	grantScript, err := grantFile.script(driver, sb)
+	if err != nil {
+		return err
+	}
+	if err := owner.validate(driver); err != nil {
+		return err
+	}
+	if err := conn.confirmWrite(true); err != nil {
+		return err
+	}
+	if dsn, err = conn.primary(ctx, driver, dsn); err != nil {
+		return err
+	}
+
+	db, err := conn.connect(ctx, driver, dsn, log.warn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.warn("failed to close database: %v", closeErr)
+		}
+	}()
+
+	if err := requireWritable(ctx, db, driver, "connect to the primary"); err != nil {
+		return err
+	}
+
+	var (
+		result   migrate.Result
+		applying string
+		granted  int
+		owned    int
+		verified int
+		met      int
+		updated  int
+	)
+	opts := migrate.Options{
+		Table: *table,
+		Execute: database.ExecuteOptions{
+			StatementTimeout: *stmtTimeout,
+			OnNotice: func(index int, n database.Notice) {
+				log.notice(applying, index, n, notices.shows(n))
+			},
+		},
+		OnApply: func(version string) {
+			applying = version
+			log.step("migration started", "Applying "+version, "version", version)
+		},
+		OnRevert: func(version string) {
+			applying = version
+			log.step("migration revert started", "Reverting "+version, "version", version)
+		},
+	}
+	onWait := func() {
+		log.info("waiting for migration lock", "Waiting for another migration of this database to finish", "table", *table)
+	}
+	if down {
+		err = migrate.WithLock(ctx, db, driver, *table, onWait, func(conn *sql.Conn) error {
+			result, err = migrate.Down(ctx, conn, driver, scripts, *steps, opts)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		log.info("migrate down finished", fmt.Sprintf("%d migration(s) reverted", len(result.Reverted)), "reverted", len(result.Reverted))
+		return nil
+	}
+	err = migrate.WithLock(ctx, db, driver, *table, onWait, func(conn *sql.Conn) error {
+		before, err := owner.snapshot(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if result, err = migrate.Run(ctx, conn, driver, scripts, opts); err != nil {
+			if i := slices.IndexFunc(scripts, func(s loader.Script) bool { return s.Name == applying }); i >= 0 {
+				err = locateError(scripts[i].Path, scripts[i].Content, err)
+			}
+			// The migrations before the failed one stay applied; hand their
+			// objects over now, as the next run would count them as old.
+			if before != nil {
+				if _, ownErr := owner.apply(ctx, conn, before, false); ownErr != nil {
+					log.warn("%v", ownErr)
+				}
+			}
+			return err
+		}
+		if grantScript != "" {
+			if granted, err = applyGrants(ctx, conn, grantScript, false); err != nil {
+				return err
+			}
+		}
+		if before != nil {
+			if owned, err = owner.apply(ctx, conn, before, false); err != nil {
+				return err
+			}
+		}
+		if checks != "" {
+			if verified, err = postVerify.verify(ctx, conn, checks); err != nil {
+				if len(result.Applied) > 0 {
+					return fmt.Errorf("post-verify %s failed; the %d migration(s) applied stay applied: %w", *postVerify.path, len(result.Applied), err)
+				}
+				return fmt.Errorf("post-verify %s failed: %w", *postVerify.path, err)
+			}
+		}
+		if expected != nil {
+			if met, updated, err = postVerify.expect(ctx, conn, expected); err != nil {
+				if len(result.Applied) > 0 {
+					return fmt.Errorf("expectations %s failed; the %d migration(s) applied stay applied: %w", *postVerify.expectPath, len(result.Applied), err)
+				}
+				return fmt.Errorf("expectations %s failed: %w", *postVerify.expectPath, err)
+			}
+		}
+		return nil
+	})
+	if err = ci.writeSummary(migrateSummary(result, applying, err), err); err != nil {
+		return err
+	}
+	log.info("migrate finished", fmt.Sprintf("%d migration(s) applied, %d already applied", len(result.Applied), len(result.Skipped)),
+		"applied", len(result.Applied), "skipped", len(result.Skipped))
+	if grantScript != "" {
+		log.grantsApplied(*grantFile.path, granted)
+	}
+	if *owner.role != "" {
+		log.ownerSet(*owner.role, owned)
+	}
+	if checks != "" {
+		log.verified(*postVerify.path, verified)
+	}
+	if expected != nil {
+		log.expected(*postVerify.expectPath, met, updated)
+	}
+	return nil
+}
+
+// printPlan prints, in format, the migrations migrate would apply to the
+// database. It only reads the tracking table, so it also runs against a
+// read-only replica.
+func printPlan(ctx context.Context, conn *connectionFlags, driver, dsn string, scripts []loader.Script, table, format string, ci *reportFormatFlag, log *runLog) error {
+	db, err := conn.connect(ctx, driver, dsn, log.warn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.warn("failed to close database: %v", closeErr)
+		}
+	}()
+
+	plan, err := migrate.PlanRun(ctx, db, driver, scripts, table)
+	if err != nil {
+		return err
+	}
+	if err := ci.summary(plan.WriteMarkdown); err != nil {
+		return err
+	}
+	if format == "json" {
+		return plan.WriteJSON(os.Stdout)
+	}
+	return plan.WriteTable(os.Stdout)
+}
+
+// migrateSummary returns the writer of the job summary of a migrate run
+// that applied result and ended with runErr, with last the migration it
+// applied last or failed on.
+func migrateSummary(result migrate.Result, last string, runErr error) func(io.Writer) error {
+	return func(w io.Writer) error {
+		outcome := "succeeded"
+		if runErr != nil {
+			outcome = "failed"
+		}
+		fmt.Fprintf(w, "### sql-loader migrate %s\n\n", outcome)
+		failed := runErr != nil && last != "" && !slices.Contains(result.Applied, last)
+		if len(result.Applied) > 0 || failed {
+			fmt.Fprintln(w, "| Version | Result |")
+			fmt.Fprintln(w, "| --- | --- |")
+			for _, version := range result.Applied {
+				fmt.Fprintf(w, "| %s | applied |\n", report.MarkdownCell(version))
+			}
+			if failed {
+				fmt.Fprintf(w, "| %s | failed |\n", report.MarkdownCell(last))
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%d migration(s) applied, %d already applied\n", len(result.Applied), len(result.Skipped))
+		if runErr == nil {
+			return nil
+		}
+		return report.WriteMarkdownError(w, runErr.Error())
+	}
+}
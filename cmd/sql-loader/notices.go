@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// Values of -notices.
+const (
+	noticesWarning = "warning"
+	noticesAll     = "all"
+	noticesNone    = "none"
+)
+
+// noticeFlag holds the -notices choice of the server messages printed as
+// they arrive. Warnings are printed by default, since they can mean data was
+// silently changed; with all, so are NOTICE and INFO messages, such as the
+// RAISE NOTICE progress of a long DO block.
+type noticeFlag struct {
+	level *string
+}
+
+func addNoticeFlag(fs *flag.FlagSet) *noticeFlag {
+	return &noticeFlag{
+		level: fs.String("notices", noticesWarning, "Server messages to print as they arrive: warning, all (adding NOTICE and INFO, such as RAISE NOTICE progress), or none"),
+	}
+}
+
+func (f *noticeFlag) validate() error {
+	switch *f.level {
+	case noticesWarning, noticesAll, noticesNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid -notices %q (use warning, all, or none)", *f.level)
+	}
+}
+
+// shows reports whether n is printed.
+func (f *noticeFlag) shows(n database.Notice) bool {
+	switch *f.level {
+	case noticesAll:
+		return true
+	case noticesNone:
+		return false
+	default:
+		return n.Severity == "WARNING"
+	}
+}
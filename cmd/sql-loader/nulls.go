@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/dataload"
+)
+
+// nullFlags holds the NULL and empty-string policy of load-data.
+type nullFlags struct {
+	emptyAs      *string
+	tokens       stringList
+	columnEmpty  stringList
+	columnTokens stringList
+}
+
+func addNullFlags(fs *flag.FlagSet) *nullFlags {
+	n := &nullFlags{
+		emptyAs: fs.String("empty-as", "empty", "Load empty values as empty strings or NULL (empty, null)"),
+	}
+	fs.Var(&n.tokens, "null-token", "Load this value as NULL, e.g. \\N or NA (repeatable)")
+	fs.Var(&n.columnEmpty, "column-empty-as", "Per-column -empty-as, as COLUMN=empty|null (repeatable)")
+	fs.Var(&n.columnTokens, "column-null-token", "Per-column NULL token replacing -null-token, as COLUMN=TOKEN (repeatable)")
+	return n
+}
+
+func (n *nullFlags) policy() (dataload.NullPolicy, error) {
+	emptyAsNull, err := parseEmptyAs(*n.emptyAs)
+	if err != nil {
+		return dataload.NullPolicy{}, err
+	}
+	p := dataload.NullPolicy{EmptyAsNull: emptyAsNull, Tokens: n.tokens}
+
+	column := func(name string) dataload.ColumnNullPolicy {
+		if p.Columns == nil {
+			p.Columns = map[string]dataload.ColumnNullPolicy{}
+		}
+		return p.Columns[name]
+	}
+	for _, v := range n.columnEmpty {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return dataload.NullPolicy{}, fmt.Errorf("invalid -column-empty-as %q (use COLUMN=empty|null)", v)
+		}
+		asNull, err := parseEmptyAs(value)
+		if err != nil {
+			return dataload.NullPolicy{}, err
+		}
+		col := column(name)
+		col.EmptyAsNull = &asNull
+		p.Columns[name] = col
+	}
+	for _, v := range n.columnTokens {
+		name, token, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return dataload.NullPolicy{}, fmt.Errorf("invalid -column-null-token %q (use COLUMN=TOKEN)", v)
+		}
+		col := column(name)
+		col.Tokens = append(col.Tokens, token)
+		p.Columns[name] = col
+	}
+	return p, nil
+}
+
+func parseEmptyAs(value string) (bool, error) {
+	switch value {
+	case "empty":
+		return false, nil
+	case "null":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid empty value policy %q (use empty or null)", value)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/ownership"
+)
+
+// ownerFlag holds the -set-owner role that exec and migrate make the owner
+// of the objects a run creates.
+type ownerFlag struct {
+	role *string
+}
+
+func addOwnerFlag(fs *flag.FlagSet) *ownerFlag {
+	return &ownerFlag{
+		role: fs.String("set-owner", "", "Make this role the owner of the schemas, tables, views, sequences, types, and routines the run creates (PostgreSQL)"),
+	}
+}
+
+// validate checks that -set-owner, if given, can be applied on driver.
+func (f *ownerFlag) validate(driver string) error {
+	if *f.role != "" && driver != "postgres" {
+		return fmt.Errorf("-set-owner requires the postgres driver; %s has no object owners", driver)
+	}
+	return nil
+}
+
+// snapshot records the objects the connecting role owns before the run, or
+// returns nil when -set-owner was not given.
+func (f *ownerFlag) snapshot(ctx context.Context, q ownership.Queryer) (ownership.Snapshot, error) {
+	if *f.role == "" {
+		return nil, nil
+	}
+	return ownership.Take(ctx, q)
+}
+
+// ownerExecer is a connection or transaction to hand objects over on.
+type ownerExecer interface {
+	database.Execer
+	ownership.Queryer
+}
+
+// apply makes the -set-owner role the owner of the objects created since
+// before was taken, in a transaction of its own unless e already is one,
+// and returns the number of objects handed over.
+func (f *ownerFlag) apply(ctx context.Context, e ownerExecer, before ownership.Snapshot, inTx bool) (int, error) {
+	created, err := before.Created(ctx, e)
+	if err != nil || len(created) == 0 {
+		return 0, err
+	}
+	mode := database.TransactionAll
+	if inTx {
+		mode = database.TransactionNone
+	}
+	if err := database.ExecuteScriptOn(ctx, e, ownership.Script(created, *f.role), database.ExecuteOptions{Transaction: mode}); err != nil {
+		return 0, fmt.Errorf("failed to set the owner of created objects to %s: %w", *f.role, err)
+	}
+	return len(created), nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+	"github.com/obstreperous-ai/sql-loader-go/internal/report"
+	"github.com/obstreperous-ai/sql-loader-go/internal/state"
+)
+
+// scriptRun is the outcome of running one script.
+type scriptRun struct {
+	run     state.Run
+	timings []time.Duration
+	batches int
+	err     error
+}
+
+// runParallel runs scripts on up to workers connections at once, each
+// script's statements in order, with exec. Each script logs and reports to
+// its own runLog and report, whose output is held back and then printed and
+// added to log and rep in script order, so the output reads as if the
+// scripts had run one after another. finish is then called, in order, with
+// each script that ran.
+//
+// A script containing DDL runs exclusively: it starts once every earlier
+// script has finished, and no later script starts until it has finished, so
+// schema changes land before the data files that follow them and never
+// queue data changes behind their locks.
+//
+// Unless keepGoing, no script starts after one fails; those already running
+// finish. runParallel returns the first error finish returns.
+func runParallel(scripts []loader.Script, workers int, keepGoing bool, log *runLog, rep *report.Report,
+	newReport func() *report.Report,
+	exec func(s loader.Script, log *runLog, rep *report.Report) scriptRun,
+	finish func(s loader.Script, res scriptRun) error,
+) error {
+	type result struct {
+		out scriptOutput
+		rep *report.Report
+		res scriptRun
+		// skipped is set for a script that never started.
+		skipped bool
+		done    chan struct{}
+	}
+	results := make([]*result, len(scripts))
+	for i := range results {
+		results[i] = &result{done: make(chan struct{})}
+	}
+
+	exclusive := make([]bool, len(scripts))
+	for i, s := range scripts {
+		exclusive[i] = hasDDL(s.Content)
+	}
+
+	// Scripts are handed out in order, so the ones that run are always the
+	// first ones. After a failure, the rest are marked skipped.
+	var (
+		mu      sync.Mutex
+		idle    = sync.NewCond(&mu)
+		next    int
+		running int
+		// alone is set while an exclusive script runs.
+		alone   bool
+		stopped bool
+	)
+	take := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		for {
+			if stopped {
+				for _, r := range results[next:] {
+					r.skipped = true
+					close(r.done)
+				}
+				next = len(results)
+			}
+			if next == len(results) {
+				return 0, false
+			}
+			if !alone && (!exclusive[next] || running == 0) {
+				break
+			}
+			idle.Wait()
+		}
+		running++
+		alone = exclusive[next]
+		next++
+		return next - 1, true
+	}
+	for range min(workers, len(scripts)) {
+		go func() {
+			for i, ok := take(); ok; i, ok = take() {
+				r := results[i]
+				r.rep = newReport()
+				r.res = exec(scripts[i], log.to(r.out.writer(false), r.out.writer(true)), r.rep)
+				mu.Lock()
+				running--
+				alone = false
+				if r.res.err != nil && !keepGoing {
+					stopped = true
+				}
+				idle.Broadcast()
+				mu.Unlock()
+				close(r.done)
+			}
+		}()
+	}
+
+	var firstErr error
+	for i, r := range results {
+		<-r.done
+		if r.skipped {
+			continue
+		}
+		r.out.flush(log.out, log.errOut)
+		rep.Add(r.rep)
+		if err := finish(scripts[i], r.res); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// hasDDL reports whether any statement of script changes the schema.
+func hasDDL(script string) bool {
+	for _, stmt := range database.SplitStatements(script) {
+		if database.DescribeStatement(stmt).DDL() {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptOutput holds back what a script run in parallel writes to stdout
+// and stderr, in the order it was written, until it can be printed.
+type scriptOutput struct {
+	mu     sync.Mutex
+	chunks []outputChunk
+}
+
+type outputChunk struct {
+	stderr bool
+	b      []byte
+}
+
+// writer returns a writer holding back output for stdout or, if stderr,
+// for stderr.
+func (o *scriptOutput) writer(stderr bool) io.Writer {
+	return outputWriter{o: o, stderr: stderr}
+}
+
+// flush prints the held output to stdout and stderr.
+func (o *scriptOutput) flush(stdout, stderr io.Writer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, c := range o.chunks {
+		w := stdout
+		if c.stderr {
+			w = stderr
+		}
+		_, _ = w.Write(c.b)
+	}
+	o.chunks = nil
+}
+
+type outputWriter struct {
+	o      *scriptOutput
+	stderr bool
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	w.o.mu.Lock()
+	defer w.o.mu.Unlock()
+	w.o.chunks = append(w.o.chunks, outputChunk{stderr: w.stderr, b: append([]byte(nil), p...)})
+	return len(p), nil
+}
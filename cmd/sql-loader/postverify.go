@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/expect"
+	"github.com/obstreperous-ai/sql-loader-go/internal/junit"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+	"github.com/obstreperous-ai/sql-loader-go/internal/sandbox"
+	"github.com/obstreperous-ai/sql-loader-go/internal/textnorm"
+)
+
+// postVerifyFlag holds the -post-verify script of checks and the -expect
+// file of row-level expectations that exec and migrate run after a
+// successful run, and the outcome of the last time they ran.
+type postVerifyFlag struct {
+	path         *string
+	expectPath   *string
+	updateGolden *bool
+
+	ran    bool
+	passed int
+	err    error
+
+	expected []expect.Result
+	expErr   error
+}
+
+func addPostVerifyFlag(fs *flag.FlagSet) *postVerifyFlag {
+	return &postVerifyFlag{
+		path:         fs.String("post-verify", "", "After a successful run, run the checks of this script, each failing if it errors or returns a row; a failure rolls back only a -transaction all run, and migrate keeps the migrations applied"),
+		expectPath:   fs.String("expect", "", "After a successful run and any -post-verify checks, run the queries of this YAML expectations file, each failing unless it returns exactly the rows given inline or in its golden CSV file"),
+		updateGolden: fs.Bool("update-golden", false, "Rewrite the -expect rows and golden files that do not match with what their queries return, instead of failing"),
+	}
+}
+
+// validate rejects -update-golden without -expect.
+func (f *postVerifyFlag) validate() error {
+	if *f.updateGolden && *f.expectPath == "" {
+		return fmt.Errorf("-update-golden requires -expect")
+	}
+	return nil
+}
+
+// set reports whether -post-verify or -expect was given.
+func (f *postVerifyFlag) set() bool {
+	return *f.path != "" || *f.expectPath != ""
+}
+
+// script reads the checks as the command reads its other scripts: checked
+// against sb, within the -max-script-size and -max-statement-size limits,
+// and normalized by policy. It returns "" without -post-verify.
+func (f *postVerifyFlag) script(sb *sandbox.Sandbox, limits *limitFlags, policy textnorm.Policy) (string, error) {
+	if *f.path == "" {
+		return "", nil
+	}
+	if err := sb.Check(*f.path); err != nil {
+		return "", err
+	}
+	content, err := loader.LoadScriptLimit(*f.path, int64(*limits.script))
+	if err != nil {
+		return "", fmt.Errorf("failed to read post-verify script: %w", err)
+	}
+	if content == "" {
+		return "", fmt.Errorf("post-verify script %s is empty", *f.path)
+	}
+	if content, err = policy.Apply(content); err != nil {
+		return "", fmt.Errorf("failed to load post-verify script %s: %w", *f.path, err)
+	}
+	if err := limits.checkStatements([]loader.Script{{Path: *f.path, Content: content}}); err != nil {
+		return "", err
+	}
+	return content, nil
+}
+
+// expectations reads the -expect file and its golden files, checked against
+// sb. It returns nil without -expect.
+func (f *postVerifyFlag) expectations(sb *sandbox.Sandbox) (*expect.File, error) {
+	if *f.expectPath == "" {
+		return nil, nil
+	}
+	file, err := expect.Load(*f.expectPath, sb.Check)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expectations: %w", err)
+	}
+	return file, nil
+}
+
+// expect runs the expectations on db, recording the results for suites.
+// With -update-golden the ones that do not match are rewritten rather than
+// failing. It returns how many matched and how many were rewritten.
+func (f *postVerifyFlag) expect(ctx context.Context, db database.QueryExecer, file *expect.File) (met, updated int, err error) {
+	f.expected, f.expErr = file.Run(ctx, db)
+	if f.expErr != nil {
+		return 0, 0, f.expErr
+	}
+	var errs []error
+	for _, r := range f.expected {
+		switch {
+		case r.Passed():
+			met++
+		case r.Err != nil || !*f.updateGolden:
+			errs = append(errs, r.Error())
+		}
+	}
+	if f.expErr = errors.Join(errs...); f.expErr != nil {
+		return met, 0, f.expErr
+	}
+	if *f.updateGolden {
+		if updated, f.expErr = file.Update(f.expected); f.expErr != nil {
+			return met, updated, f.expErr
+		}
+	}
+	return met, updated, nil
+}
+
+// verify runs the checks on db, recording the outcome for suites.
+func (f *postVerifyFlag) verify(ctx context.Context, db database.QueryExecer, checks string) (int, error) {
+	f.ran = true
+	f.passed, f.err = database.Verify(ctx, db, checks)
+	return f.passed, f.err
+}
+
+// suites returns the JUnit suites of the checks and expectations of a run
+// that ended with runErr.
+func (f *postVerifyFlag) suites(checks string, file *expect.File, runErr error) []junit.Suite {
+	var suites []junit.Suite
+	if checks != "" {
+		suites = append(suites, f.checkSuite(checks, runErr))
+	}
+	if file != nil {
+		suites = append(suites, f.expectSuite(file, runErr))
+	}
+	return suites
+}
+
+// checkSuite returns the suite of the checks, a case per check: passed,
+// failed, or skipped when an earlier check or the run itself failed.
+func (f *postVerifyFlag) checkSuite(checks string, runErr error) junit.Suite {
+	statements := database.SplitStatements(checks)
+	lines := database.StatementLines(checks, statements)
+	suite := junit.Suite{Name: "post-verify " + *f.path}
+	var failed *database.CheckError
+	checkFailed := errors.As(f.err, &failed)
+	for i := range statements {
+		c := junit.Case{Name: fmt.Sprintf("check %d (line %d)", i+1, lines[i]), Classname: *f.path}
+		switch {
+		case !f.ran && runErr != nil:
+			c.Skipped = "not run: " + runErr.Error()
+		case !f.ran:
+			c.Skipped = "not run"
+		case i < f.passed:
+		case i == f.passed && checkFailed:
+			c.Failure = f.err.Error()
+		default:
+			c.Skipped = "not run: an earlier check failed"
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	if f.err != nil && !checkFailed {
+		suite.Cases = append(suite.Cases, junit.Case{Name: "post-verify", Classname: *f.path, Failure: f.err.Error()})
+	}
+	return suite
+}
+
+// expectSuite returns the suite of the expectations, a case per
+// expectation: passed, failed, or skipped when the run failed first.
+func (f *postVerifyFlag) expectSuite(file *expect.File, runErr error) junit.Suite {
+	suite := junit.Suite{Name: "expectations " + file.Path}
+	for i, e := range file.Expectations {
+		c := junit.Case{Name: e.Name, Classname: file.Path}
+		switch {
+		case i < len(f.expected):
+			if r := f.expected[i]; !r.Passed() && (r.Err != nil || !*f.updateGolden) {
+				c.Failure = r.Error().Error()
+			}
+		case runErr != nil:
+			c.Skipped = "not run: " + runErr.Error()
+		default:
+			c.Skipped = "not run"
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	if f.expErr != nil && len(f.expected) == 0 {
+		suite.Cases = append(suite.Cases, junit.Case{Name: "expectations", Classname: file.Path, Failure: f.expErr.Error()})
+	}
+	return suite
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// progressInterval is how often progress lines are written when the
+	// output is not a terminal, keeping CI logs short.
+	progressInterval = 5 * time.Second
+	// redrawInterval limits how often the terminal progress bar is redrawn.
+	redrawInterval = 100 * time.Millisecond
+	progressWidth  = 30
+)
+
+// progress reports statement N of M, the elapsed time, and the rows
+// affected while a script runs: as a bar redrawn in place on a terminal,
+// otherwise as a line every progressInterval. Scripts that finish before
+// the first report print nothing.
+type progress struct {
+	w   io.Writer
+	tty bool
+	now func() time.Time
+
+	total   int
+	done    int
+	rows    int64
+	started time.Time
+	last    time.Time
+	shown   bool
+}
+
+func newProgress(w io.Writer, tty bool) *progress {
+	return &progress{w: w, tty: tty, now: time.Now}
+}
+
+func (p *progress) start(total int) {
+	now := p.now()
+	p.total, p.done, p.rows = total, 0, 0
+	p.started, p.last, p.shown = now, now, false
+}
+
+// statement records a completed statement; rows is negative when the
+// driver does not report it.
+func (p *progress) statement(rows int64) {
+	p.done++
+	if rows > 0 {
+		p.rows += rows
+	}
+	interval := progressInterval
+	if p.tty {
+		interval = redrawInterval
+	}
+	if now := p.now(); now.Sub(p.last) >= interval {
+		p.last = now
+		p.report()
+	}
+}
+
+// finish ends the script's report. On a terminal the bar is redrawn one
+// last time and left in place; elsewhere a final line is written if any
+// progress was shown.
+func (p *progress) finish() {
+	if !p.shown {
+		return
+	}
+	p.report()
+	if p.tty {
+		fmt.Fprintln(p.w)
+	}
+	p.shown = false
+}
+
+// clear erases the bar so that a warning is not printed over it.
+func (p *progress) clear() {
+	if p.tty && p.shown {
+		fmt.Fprint(p.w, "\r\033[K")
+		p.shown = false
+	}
+}
+
+func (p *progress) report() {
+	elapsed := p.now().Sub(p.started).Round(100 * time.Millisecond)
+	status := fmt.Sprintf("statement %d/%d, %s elapsed, %d rows affected", p.done, p.total, elapsed, p.rows)
+	if p.tty {
+		filled := 0
+		if p.total > 0 {
+			filled = min(p.done*progressWidth/p.total, progressWidth)
+		}
+		bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressWidth-filled)
+		fmt.Fprintf(p.w, "\r\033[K[%s] %s", bar, status)
+	} else {
+		fmt.Fprintf(p.w, "Progress: %s\n", status)
+	}
+	p.shown = true
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/report"
+)
+
+// reportFlags holds the flags requesting a statement statistics report,
+// including the -report-format job summary made from it.
+type reportFlags struct {
+	stats  *bool
+	path   *string
+	format *reportFormatFlag
+}
+
+func addReportFlags(fs *flag.FlagSet) *reportFlags {
+	return &reportFlags{
+		stats:  fs.Bool("stats", false, "Print the type, rows affected, and duration of every statement, with totals, after the run"),
+		path:   fs.String("report", "", "Write the statement statistics of the run to this JSON file, even if it fails"),
+		format: addReportFormatFlag(fs),
+	}
+}
+
+// new returns an empty report, or nil when none was requested.
+func (f *reportFlags) new(deterministic bool) *report.Report {
+	if !*f.stats && *f.path == "" && !f.format.github() {
+		return nil
+	}
+	return report.New(deterministic)
+}
+
+// write writes r to -report and the job summary, recording runErr as the
+// reason the run failed. A report that cannot be written fails an
+// otherwise successful run.
+func (f *reportFlags) write(r *report.Report, runErr error) error {
+	if r == nil {
+		return runErr
+	}
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	runErr = f.format.writeSummary(r.WriteMarkdown, runErr)
+	if *f.path == "" {
+		return runErr
+	}
+	err := writeReport(*f.path, r)
+	if err == nil {
+		return runErr
+	}
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return runErr
+	}
+	return err
+}
+
+// #nosec G304 -- Report path is intentionally provided by the user as part of the CLI interface
+func writeReport(path string, r *report.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	if err := r.WriteJSON(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/junit"
+	"github.com/obstreperous-ai/sql-loader-go/internal/lint"
+)
+
+// Report formats accepted by -report-format.
+const (
+	reportFormatText   = "text"
+	reportFormatGitHub = "github"
+	reportFormatJUnit  = "junit"
+)
+
+// reportFormatFlag holds the -report-format flag, which adds output for a
+// CI system to the usual progress output.
+type reportFormatFlag struct {
+	format    *string
+	junitPath *string
+}
+
+func addReportFormatFlag(fs *flag.FlagSet) *reportFormatFlag {
+	return &reportFormatFlag{
+		format:    fs.String("report-format", reportFormatText, "Also report for CI: github adds workflow annotations for failures and a markdown job summary, junit writes lint, verify, -post-verify, and -expect results as JUnit XML"),
+		junitPath: fs.String("junit-file", "sql-loader-junit.xml", "File -report-format junit writes its JUnit XML report to"),
+	}
+}
+
+func (f *reportFormatFlag) validate() error {
+	switch *f.format {
+	case reportFormatText, reportFormatGitHub, reportFormatJUnit:
+		return nil
+	}
+	return fmt.Errorf("invalid -report-format %q: must be text, github, or junit", *f.format)
+}
+
+// github reports whether GitHub Actions output was requested.
+func (f *reportFormatFlag) github() bool {
+	return *f.format == reportFormatGitHub
+}
+
+// junit reports whether a JUnit XML report was requested.
+func (f *reportFormatFlag) junit() bool {
+	return *f.format == reportFormatJUnit
+}
+
+// writeJUnit writes suites to -junit-file for a run that ended with runErr.
+// A report that cannot be written fails an otherwise successful run. It
+// does nothing without -report-format junit.
+func (f *reportFormatFlag) writeJUnit(suites []junit.Suite, runErr error) error {
+	if !f.junit() {
+		return runErr
+	}
+	err := writeJUnitFile(*f.junitPath, suites)
+	if err == nil {
+		return runErr
+	}
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return runErr
+	}
+	return err
+}
+
+// #nosec G304 -- Report path is intentionally provided by the user as part of the CLI interface
+func writeJUnitFile(path string, suites []junit.Suite) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create JUnit report: %w", err)
+	}
+	if err := junit.Write(out, "sql-loader", suites); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	return nil
+}
+
+// annotate prints err to stderr, next to the error message, as an error
+// annotation on the script and line it names, if any. It does nothing for
+// a nil error or without -report-format github.
+func (f *reportFormatFlag) annotate(err error) {
+	if err == nil || !f.github() {
+		return
+	}
+	var props []string
+	var se *scriptError
+	if errors.As(err, &se) {
+		props = append(props, "file="+escapeProperty(se.path))
+		if se.line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", se.line))
+		}
+		if se.column > 0 {
+			props = append(props, fmt.Sprintf("col=%d", se.column))
+		}
+	}
+	printAnnotation(os.Stderr, "error", props, err.Error())
+}
+
+// annotateFinding prints a lint finding in path as an error annotation.
+func (f *reportFormatFlag) annotateFinding(path string, finding lint.Finding) {
+	if !f.github() {
+		return
+	}
+	props := []string{"file=" + escapeProperty(path)}
+	if finding.Line > 0 {
+		props = append(props, fmt.Sprintf("line=%d", finding.Line))
+	}
+	props = append(props, "title="+escapeProperty(finding.Rule))
+	printAnnotation(os.Stdout, "error", props, finding.String())
+}
+
+// summary appends what write writes to the job summary, the file named by
+// GITHUB_STEP_SUMMARY, or prints it to stderr when that is unset, keeping
+// stdout to the JSON a run may print. It does nothing without
+// -report-format github.
+func (f *reportFormatFlag) summary(write func(io.Writer) error) error {
+	if !f.github() {
+		return nil
+	}
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return write(os.Stderr)
+	}
+	out, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) // #nosec G304 -- the runner names the summary file
+	if err != nil {
+		return fmt.Errorf("failed to open job summary: %w", err)
+	}
+	if err := write(out); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to write job summary: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write job summary: %w", err)
+	}
+	return nil
+}
+
+// writeSummary writes the job summary of a run that ended with runErr. A
+// summary that cannot be written fails an otherwise successful run.
+func (f *reportFormatFlag) writeSummary(write func(io.Writer) error, runErr error) error {
+	err := f.summary(write)
+	if err == nil {
+		return runErr
+	}
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return runErr
+	}
+	return err
+}
+
+// printAnnotation prints a workflow command such as
+// ::error file=seed.sql,line=3::message.
+func printAnnotation(w io.Writer, level string, props []string, message string) {
+	command := level
+	if len(props) > 0 {
+		command += " " + strings.Join(props, ",")
+	}
+	fmt.Fprintf(w, "::%s::%s\n", command, escapeData(message))
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// scriptError is the failure of a script, with the line and column of the
+// statement or syntax error it failed at, when known. Without a verb it
+// reads as the error it wraps.
+type scriptError struct {
+	verb         string
+	path         string
+	line, column int
+	err          error
+}
+
+func (e *scriptError) Error() string {
+	if e.verb == "" {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("failed to %s script %s: %v", e.verb, e.path, e.err)
+}
+
+func (e *scriptError) Unwrap() error {
+	return e.err
+}
+
+// parseError returns the failure of parsing the script at path.
+func parseError(path string, err error) error {
+	se := &scriptError{verb: "parse", path: path, err: err}
+	var syntax *database.SyntaxError
+	if errors.As(err, &syntax) {
+		se.line, se.column = syntax.Line, syntax.Column
+	}
+	return se
+}
+
+// execError returns the failure of executing script, located at the line
+// of the failed statement.
+func execError(path, script string, err error) error {
+	se := locateError(path, script, err)
+	se.verb = "execute"
+	return se
+}
+
+// locateError locates err, the failure of script, at path and the line of
+// the statement it failed at.
+func locateError(path, script string, err error) *scriptError {
+	se := &scriptError{path: path, err: err}
+	var execErr *database.ExecError
+	if errors.As(err, &execErr) {
+		lines := database.StatementLines(script, database.SplitStatements(script))
+		if execErr.Index >= 1 && execErr.Index <= len(lines) {
+			se.line = lines[execErr.Index-1]
+		}
+	}
+	return se
+}
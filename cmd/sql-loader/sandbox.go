@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/sandbox"
+	"github.com/obstreperous-ai/sql-loader-go/internal/storage"
+)
+
+// sandboxFlag holds the -allow-path directories of a command that reads
+// scripts or data.
+type sandboxFlag struct {
+	paths stringList
+}
+
+func addSandboxFlag(fs *flag.FlagSet) *sandboxFlag {
+	f := &sandboxFlag{}
+	fs.Var(&f.paths, "allow-path", "Only read scripts and data from under this directory (repeatable)")
+	return f
+}
+
+// open returns the sandbox, which is nil when -allow-path is not given.
+func (f *sandboxFlag) open() (*sandbox.Sandbox, error) {
+	return sandbox.New(f.paths)
+}
+
+// checkLocation checks a -file style location against sb. Stdin and remote
+// URLs are not files and pass; file:// URLs are checked by path.
+func checkLocation(sb *sandbox.Sandbox, location string) error {
+	if location == "" || location == "-" {
+		return nil
+	}
+	if !storage.IsURL(location) {
+		return sb.Check(location)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("failed to parse location %s: %w", location, err)
+	}
+	if u.Scheme == "file" {
+		return sb.Check(u.Path)
+	}
+	return nil
+}
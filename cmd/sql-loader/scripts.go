@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+	"github.com/obstreperous-ai/sql-loader-go/internal/storage"
+)
+
+// scriptFlags holds the flags naming the scripts a command reads, and the
+// checks applied as they are read.
+type scriptFlags struct {
+	files              stringList
+	dir                *string
+	checksum, manifest *string
+
+	text    *textFlags
+	allowed *sandboxFlag
+	limits  *limitFlags
+	empty   *emptyFlag
+}
+
+// addScriptFlags adds the script flags, with help text using verb, such as
+// execute, for what the command does with the scripts.
+func addScriptFlags(fs *flag.FlagSet, verb string) *scriptFlags {
+	f := &scriptFlags{
+		dir:      fs.String("dir", "", fmt.Sprintf("%s every .sql file in this directory tree in lexical path order", strings.ToUpper(verb[:1])+verb[1:])),
+		checksum: fs.String("checksum", "", fmt.Sprintf("Refuse to %s a single -file script unless its content has this digest, e.g. sha256:<hex>", verb)),
+		manifest: fs.String("checksum-manifest", "", fmt.Sprintf("Refuse to %s any script whose digest does not match this sha256sum-format manifest", verb)),
+		text:     addTextFlags(fs),
+		allowed:  addSandboxFlag(fs),
+		limits:   addLimitFlags(fs),
+		empty:    addEmptyFlag(fs),
+	}
+	fs.Var(&f.files, "file", fmt.Sprintf("SQL script file or glob, such as 'seed/*.sql', to %s (repeatable, run in order; - for stdin, the default when stdin is piped)", verb))
+	return f
+}
+
+// stdin reports whether a script is read from stdin.
+func (f *scriptFlags) stdin() bool {
+	return slices.Contains(f.files, "-")
+}
+
+// load reads the -file scripts, every script under -dir, or stdin when
+// neither is given and stdin is piped. Each is checked against -allow-path
+// and the size limits, verified against -checksum and -checksum-manifest,
+// and normalized by the text flags. Empty scripts fail or, under -on-empty
+// skip, are dropped.
+func (f *scriptFlags) load(ctx context.Context) ([]loader.Script, error) {
+	if len(f.files) > 0 && *f.dir != "" {
+		return nil, fmt.Errorf("exactly one of -file or -dir is required")
+	}
+	if len(f.files) == 0 && *f.dir == "" {
+		if isTerminal(os.Stdin) {
+			return nil, fmt.Errorf("exactly one of -file or -dir is required, or pipe a script on stdin")
+		}
+		f.files = stringList{"-"}
+	}
+	if *f.checksum != "" && (*f.dir != "" || len(f.files) > 1) {
+		return nil, fmt.Errorf("-checksum verifies a single -file; use -checksum-manifest with -dir or several files")
+	}
+
+	if err := f.empty.validate(); err != nil {
+		return nil, err
+	}
+	policy, err := f.text.policy()
+	if err != nil {
+		return nil, err
+	}
+	sb, err := f.allowed.open()
+	if err != nil {
+		return nil, err
+	}
+	for _, location := range []string{*f.dir, *f.manifest} {
+		if err := checkLocation(sb, location); err != nil {
+			return nil, err
+		}
+	}
+
+	limit := int64(*f.limits.script)
+	var scripts []loader.Script
+	if *f.dir != "" {
+		if scripts, err = loader.LoadDirWith(*f.dir, loader.DirOptions{Check: sb.Check, MaxScriptSize: limit}); err != nil {
+			return nil, fmt.Errorf("failed to load scripts: %w", err)
+		}
+	} else {
+		locations, err := f.expandFiles()
+		if err != nil {
+			return nil, err
+		}
+		for _, location := range locations {
+			if err := checkLocation(sb, location); err != nil {
+				return nil, err
+			}
+			s, err := readScript(ctx, location, limit)
+			if err != nil {
+				return nil, err
+			}
+			scripts = append(scripts, s)
+		}
+		if len(scripts) == 0 {
+			// Every glob matched nothing, under -on-empty skip.
+			return nil, nil
+		}
+	}
+	if err := verifyChecksums(scripts, *f.checksum, *f.manifest); err != nil {
+		return nil, err
+	}
+	for i := range scripts {
+		if scripts[i].Content, err = policy.Apply(scripts[i].Content); err != nil {
+			return nil, fmt.Errorf("failed to load script %s: %w", scripts[i].Path, err)
+		}
+	}
+	if err := f.limits.checkStatements(scripts); err != nil {
+		return nil, err
+	}
+	return f.empty.scripts(*f.dir, scripts)
+}
+
+// expandFiles returns the -file locations in order, with each glob replaced
+// by its matches in lexical order. A file named more than once, as by
+// overlapping globs, runs only at its first position. A glob matching
+// nothing fails or, under -on-empty skip, is dropped.
+func (f *scriptFlags) expandFiles() ([]string, error) {
+	if slices.Contains(f.files, "-") && len(f.files) > 1 {
+		return nil, fmt.Errorf("-file - reads stdin and cannot be combined with other -file scripts")
+	}
+	var locations []string
+	seen := map[string]bool{}
+	for _, location := range f.files {
+		matches := []string{location}
+		if !storage.IsURL(location) && isGlob(location) {
+			var err error
+			if matches, err = filepath.Glob(location); err != nil {
+				return nil, fmt.Errorf("invalid -file pattern %q: %w", location, err)
+			}
+			if len(matches) == 0 {
+				if err := f.empty.check(location, "no matching files"); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, m := range matches {
+			key := m
+			if !storage.IsURL(m) {
+				key = filepath.Clean(m)
+			}
+			if !seen[key] {
+				seen[key] = true
+				locations = append(locations, m)
+			}
+		}
+	}
+	return locations, nil
+}
+
+// isGlob reports whether a -file path contains glob metacharacters.
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// readScript reads the script at a -file location: stdin, a URL, or a
+// local path.
+func readScript(ctx context.Context, location string, limit int64) (loader.Script, error) {
+	var (
+		path    = location
+		content string
+		err     error
+	)
+	if location == "-" {
+		path = "<stdin>"
+		content, err = loader.ReadScript(loader.LimitReader(os.Stdin, path, limit))
+	} else if storage.IsURL(location) {
+		path = storage.Redact(location)
+		content, err = fetchScript(ctx, location, path, limit)
+	} else {
+		content, err = loader.LoadScriptLimit(location, limit)
+	}
+	if err != nil {
+		return loader.Script{}, fmt.Errorf("failed to load script: %w", err)
+	}
+	return loader.Script{Path: path, Content: content}, nil
+}
+
+// verifyChecksums checks the scripts against -checksum and
+// -checksum-manifest before anything else reads them.
+func verifyChecksums(scripts []loader.Script, checksum, manifest string) error {
+	if checksum != "" {
+		if len(scripts) > 1 {
+			return fmt.Errorf("-checksum verifies a single script, but -file matched %d; use -checksum-manifest", len(scripts))
+		}
+		sum, err := loader.ParseDigest(checksum)
+		if err != nil {
+			return err
+		}
+		if err := loader.VerifyChecksum(scripts[0].Path, scripts[0].Content, sum); err != nil {
+			return err
+		}
+	}
+	if manifest != "" {
+		m, err := loader.ReadManifest(manifest)
+		if err != nil {
+			return err
+		}
+		if err := m.Verify(scripts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseScripts fails on a script with a string literal or comment left open,
+// which would otherwise run to the end of the script as one statement.
+func parseScripts(scripts []loader.Script) error {
+	for _, s := range scripts {
+		if _, err := database.SplitScript(s.Content); err != nil {
+			return parseError(s.Path, err)
+		}
+	}
+	return nil
+}
+
+// fetchScript reads a script of at most limit bytes from a URL such as
+// https://host/seed.sql, reported as name.
+func fetchScript(ctx context.Context, location, name string, limit int64) (string, error) {
+	rc, err := storage.Open(ctx, location)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rc.Close() }()
+	return loader.ReadScript(loader.LimitReader(rc, name, limit))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptError is the cancellation cause when sql-loader receives SIGINT
+// or SIGTERM.
+type interruptError struct {
+	sig os.Signal
+}
+
+func (e *interruptError) Error() string {
+	return fmt.Sprintf("interrupted by %s", signalName(e.sig))
+}
+
+// exitCode follows the shell convention of 128 plus the signal number, so
+// supervisors can tell an interrupted run from a failed one.
+func (e *interruptError) exitCode() int {
+	if s, ok := e.sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 130
+}
+
+func signalName(sig os.Signal) string {
+	switch sig {
+	case os.Interrupt:
+		return "SIGINT"
+	case syscall.SIGTERM:
+		return "SIGTERM"
+	}
+	return sig.String()
+}
+
+// handleSignals returns a context cancelled with an *interruptError on the
+// first SIGINT or SIGTERM. The running statement is then cancelled and any
+// open transaction rolled back; a second signal exits immediately.
+func handleSignals(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			// Restore the default behaviour so a second signal kills
+			// the process.
+			signal.Stop(signals)
+			fmt.Fprintf(os.Stderr, "Received %s: cancelling the running statement and rolling back (repeat to exit immediately)\n", signalName(sig))
+			cancel(&interruptError{sig: sig})
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(nil)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/estimate"
+	"github.com/obstreperous-ai/sql-loader-go/internal/state"
+)
+
+func addStateFlag(fs *flag.FlagSet) *string {
+	return fs.String("state-file", "", "Local run history file (default $XDG_STATE_HOME/sql-loader/state.db)")
+}
+
+// openState opens the local state store.
+func openState(path string) (*state.Store, error) {
+	if path == "" {
+		var err error
+		if path, err = state.DefaultPath(); err != nil {
+			return nil, err
+		}
+	}
+	return state.Open(path)
+}
+
+// openStateOrWarn opens the local state store, printing a warning and
+// returning nil if it is unavailable: run history must never block a load.
+func openStateOrWarn(path string) *state.Store {
+	store, err := openState(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: run history unavailable: %v\n", err)
+		return nil
+	}
+	return store
+}
+
+func closeState(store *state.Store) {
+	if store == nil {
+		return
+	}
+	if err := store.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close run history: %v\n", err)
+	}
+}
+
+// targetID identifies a database target in the run history without
+// recording credentials.
+func targetID(driver, dsn string) string {
+	return driver + ":" + database.RedactDSN(dsn)
+}
+
+// addHistoricalDuration fills in the expected duration of e from previous
+// runs of the same content.
+func addHistoricalDuration(e *estimate.Estimate, statePath string) {
+	store := openStateOrWarn(statePath)
+	if store == nil {
+		return
+	}
+	defer closeState(store)
+
+	d, n, err := store.EstimateDuration(e.Checksum)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	e.Duration, e.Runs = d, n
+}
+
+// recordRun stores a finished run in the history, warning on failure.
+func recordRun(store *state.Store, run state.Run, runErr error, timings []time.Duration) {
+	if store == nil {
+		return
+	}
+	if run.Duration == 0 {
+		run.Duration = time.Since(run.StartedAt)
+	}
+	run.Status = state.StatusSucceeded
+	if runErr != nil {
+		run.Status = state.StatusFailed
+		run.Error = runErr.Error()
+		run.ErrorCategory = string(database.Classify(runErr).Category)
+	}
+	if _, err := store.RecordRun(run, timings); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record run history: %v\n", err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/textnorm"
+)
+
+// textFlags holds the UTF-8 validation and normalization options shared by
+// commands that read script or data files.
+type textFlags struct {
+	invalid *string
+	nfc     *bool
+}
+
+func addTextFlags(fs *flag.FlagSet) *textFlags {
+	return &textFlags{
+		invalid: fs.String("invalid-utf8", string(textnorm.InvalidPass), "Handling of invalid UTF-8 in input (pass, reject, replace)"),
+		nfc:     fs.Bool("nfc", false, "Normalize input text to Unicode NFC"),
+	}
+}
+
+func (t *textFlags) policy() (textnorm.Policy, error) {
+	mode, err := textnorm.ParseInvalidMode(*t.invalid)
+	if err != nil {
+		return textnorm.Policy{}, err
+	}
+	return textnorm.Policy{Invalid: mode, NFC: *t.nfc}, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/state"
+)
+
+// transactionFile is the -transaction scope giving each script of a run
+// its own transaction. The database package only knows single scripts, for
+// which it is database.TransactionAll.
+const transactionFile = "file"
+
+// Values of -on-error.
+const (
+	onErrorStop     = "stop"
+	onErrorContinue = "continue"
+)
+
+// runTransaction is the parsed -transaction scope of a run.
+type runTransaction struct {
+	// mode groups the statements of each script.
+	mode database.TransactionMode
+	// wholeRun runs every script in one transaction, committed only once
+	// the last script succeeds. It is set for -transaction all.
+	wholeRun bool
+}
+
+func parseRunTransaction(name string) (runTransaction, error) {
+	switch name {
+	case transactionFile:
+		return runTransaction{mode: database.TransactionAll}, nil
+	case string(database.TransactionAll):
+		return runTransaction{mode: database.TransactionAll, wholeRun: true}, nil
+	}
+	mode, err := database.ParseTransactionMode(name)
+	if err != nil {
+		return runTransaction{}, fmt.Errorf("unsupported transaction mode %q (use all, file, per-statement, or none)", name)
+	}
+	return runTransaction{mode: mode}, nil
+}
+
+// txOptionFlags holds the -isolation level that exec and load-data open
+// their transactions with and, for exec, -tx-read-only.
+type txOptionFlags struct {
+	isolation *string
+	readOnly  *bool
+}
+
+func addIsolationFlag(fs *flag.FlagSet) *txOptionFlags {
+	return &txOptionFlags{
+		isolation: fs.String("isolation", "", "Isolation level of the transactions the run opens: serializable, repeatable-read, or read-committed (default: the server's)"),
+	}
+}
+
+func addTxOptionFlags(fs *flag.FlagSet) *txOptionFlags {
+	f := addIsolationFlag(fs)
+	f.readOnly = fs.Bool("tx-read-only", false, "Open the run's transactions read-only, so the server rejects any write")
+	return f
+}
+
+// options returns the options to begin transactions with, or nil when no
+// flag was given, for the server's defaults.
+func (f *txOptionFlags) options() (*sql.TxOptions, error) {
+	level, err := database.ParseIsolation(*f.isolation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -isolation: %w", err)
+	}
+	readOnly := f.readOnly != nil && *f.readOnly
+	if *f.isolation == "" && !readOnly {
+		return nil, nil
+	}
+	return &sql.TxOptions{Isolation: level, ReadOnly: readOnly}, nil
+}
+
+func parseOnError(name string) (continueOnError bool, err error) {
+	switch name {
+	case onErrorStop:
+		return false, nil
+	case onErrorContinue:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid -on-error %q (use stop or continue)", name)
+	}
+}
+
+// heldRun is a script run inside the run transaction, recorded in run
+// history only once the transaction commits or rolls back.
+type heldRun struct {
+	run     state.Run
+	timings []time.Duration
+}
+
+func recordHeld(store *state.Store, held []heldRun, err error) {
+	for _, h := range held {
+		recordRun(store, h.run, err, h.timings)
+	}
+}
+
+// rolledBack reports err, a script failure inside the run transaction, as
+// rolled back along with the scripts before it.
+func rolledBack(err error) error {
+	var execErr *database.ExecError
+	if errors.As(err, &execErr) {
+		execErr.RolledBack = true
+		return err
+	}
+	return fmt.Errorf("%w (transaction rolled back; nothing applied)", err)
+}
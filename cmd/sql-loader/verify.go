@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/junit"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+)
+
+// runVerify implements the verify subcommand, which applies every check a
+// run makes to its scripts before connecting, so that a release can be
+// checked in CI without a database.
+func runVerify(ctx context.Context, args []string) (err error) {
+	fs := newFlagSet("verify")
+	source := addScriptFlags(fs, "verify")
+	ci := addReportFormatFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := ci.validate(); err != nil {
+		return err
+	}
+	defer func() { ci.annotate(err) }()
+	var scripts []loader.Script
+	defer func() { err = ci.writeJUnit([]junit.Suite{verifySuite(scripts, err)}, err) }()
+
+	if scripts, err = source.load(ctx); err != nil {
+		return err
+	}
+	if err := parseScripts(scripts); err != nil {
+		return err
+	}
+	source.empty.printSkipped()
+	if len(scripts) == 1 {
+		fmt.Printf("Verified %s\n", scripts[0].Path)
+	} else {
+		fmt.Printf("Verified %d scripts\n", len(scripts))
+	}
+	return nil
+}
+
+// verifySuite returns the JUnit suite of a verify run that ended with
+// runErr: a case per script, failed for the script runErr names and
+// skipped for those after it, which were not checked. A failure
+// before the scripts were loaded, or naming none of them, is a case of its
+// own.
+func verifySuite(scripts []loader.Script, runErr error) junit.Suite {
+	suite := junit.Suite{Name: "verify"}
+	var se *scriptError
+	errors.As(runErr, &se)
+	named := false
+	for _, s := range scripts {
+		c := junit.Case{Name: s.Path, Classname: "verify"}
+		switch {
+		case named:
+			c.Skipped = "not checked: an earlier script failed"
+		case se != nil && se.path == s.Path:
+			c.Failure, named = runErr.Error(), true
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	if runErr != nil && !named {
+		suite.Cases = append(suite.Cases, junit.Case{Name: "verify", Classname: "verify", Failure: runErr.Error()})
+	}
+	return suite
+}
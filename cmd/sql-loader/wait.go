@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// runWait implements the wait subcommand, which blocks until the database
+// is ready, as a readiness gate in entrypoint scripts.
+func runWait(ctx context.Context, args []string) error {
+	fs := newFlagSet("wait")
+	var (
+		conn     = addConnectionFlags(fs)
+		timeout  = fs.Duration("timeout", time.Minute, "Give up if the database is not ready within this duration (0 = wait forever)")
+		interval = fs.Duration("interval", time.Second, "Wait between connection attempts")
+		quiet    = fs.Bool("quiet", false, "Do not report connection attempts")
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("-interval must be positive")
+	}
+
+	driver, dsn, err := conn.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, *timeout, fmt.Errorf("database not ready after %s", *timeout))
+		defer cancel()
+	}
+
+	start := time.Now()
+	err = database.WaitReady(ctx, driver, dsn, *interval, func(err error) {
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "Waiting for database: %v\n", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !*quiet {
+		fmt.Printf("Database ready after %s\n", time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/window"
+)
+
+// windowFlags holds the maintenance window guard shared by commands that
+// write to the target.
+type windowFlags struct {
+	allowed *string
+	ignore  *bool
+}
+
+func addWindowFlags(fs *flag.FlagSet) *windowFlags {
+	return &windowFlags{
+		allowed: fs.String("allowed-window", "", "Only start inside this maintenance window, e.g. \"Sat 00:00-06:00 Europe/London\""),
+		ignore:  fs.Bool("ignore-window", false, "Start outside -allowed-window with a warning instead of refusing"),
+	}
+}
+
+// check refuses to start outside the allowed window, or warns when the
+// guard is overridden.
+func (w *windowFlags) check(now time.Time) error {
+	if *w.allowed == "" {
+		return nil
+	}
+	win, err := window.Parse(*w.allowed)
+	if err != nil {
+		return err
+	}
+	if win.Contains(now) {
+		return nil
+	}
+	if *w.ignore {
+		fmt.Fprintf(os.Stderr, "Warning: starting outside the allowed window %q\n", win)
+		return nil
+	}
+	return fmt.Errorf("refusing to start outside the allowed window %q (use -ignore-window to override)", win)
+}
@@ -0,0 +1,160 @@
+// Package catalog introspects a database and describes its tables, columns,
+// constraints, and indexes in a driver-neutral model.
+package catalog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// FormatVersion is the version of the catalog JSON format. It is raised
+// only when a field is removed or changes meaning; new fields are added
+// without a bump, so readers must ignore fields they do not know.
+const FormatVersion = 1
+
+// Catalog is the normalized description of a database schema.
+type Catalog struct {
+	// FormatVersion is the version of the JSON format the catalog was
+	// written in. Catalogs written before versioning have none and read
+	// as version 1.
+	FormatVersion int     `json:"format_version" yaml:"format_version"`
+	Driver        string  `json:"driver" yaml:"driver"`
+	Tables        []Table `json:"tables" yaml:"tables"`
+}
+
+// Table describes a single table.
+type Table struct {
+	Schema      string       `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Name        string       `json:"name" yaml:"name"`
+	Columns     []Column     `json:"columns" yaml:"columns"`
+	PrimaryKey  []string     `json:"primary_key,omitempty" yaml:"primary_key,omitempty"`
+	Constraints []Constraint `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+	Indexes     []Index      `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+}
+
+// Column describes a table column. Type is the lower-cased type name as
+// reported by the database.
+type Column struct {
+	Name     string  `json:"name" yaml:"name"`
+	Type     string  `json:"type" yaml:"type"`
+	Nullable bool    `json:"nullable" yaml:"nullable"`
+	Default  *string `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// Constraint types.
+const (
+	ConstraintUnique     = "unique"
+	ConstraintForeignKey = "foreign_key"
+	ConstraintCheck      = "check"
+)
+
+// Constraint describes a unique, foreign key, or check constraint.
+type Constraint struct {
+	Name       string     `json:"name,omitempty" yaml:"name,omitempty"`
+	Type       string     `json:"type" yaml:"type"`
+	Columns    []string   `json:"columns,omitempty" yaml:"columns,omitempty"`
+	References *Reference `json:"references,omitempty" yaml:"references,omitempty"`
+	Definition string     `json:"definition,omitempty" yaml:"definition,omitempty"`
+}
+
+// Reference is the target of a foreign key constraint.
+type Reference struct {
+	Table   string   `json:"table" yaml:"table"`
+	Columns []string `json:"columns" yaml:"columns"`
+}
+
+// Index describes an index that does not back a constraint.
+type Index struct {
+	Name    string   `json:"name" yaml:"name"`
+	Columns []string `json:"columns" yaml:"columns"`
+	Unique  bool     `json:"unique" yaml:"unique"`
+}
+
+// Inspect reads the schema of the connected database.
+func Inspect(db *sql.DB, driver string) (*Catalog, error) {
+	var (
+		tables []Table
+		err    error
+	)
+	switch driver {
+	case "sqlite":
+		tables, err = inspectSQLite(db)
+	case "postgres":
+		tables, err = inspectPostgres(db)
+	default:
+		return nil, fmt.Errorf("catalog is not supported for driver %q", driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tables == nil {
+		tables = []Table{}
+	}
+	return &Catalog{FormatVersion: FormatVersion, Driver: driver, Tables: tables}, nil
+}
+
+// Table returns the named table, matching either "name" or "schema.name".
+// Parts may be double-quoted, as for database.SplitQualified.
+func (c *Catalog) Table(name string) (*Table, bool) {
+	parts := database.SplitQualified(name)
+	for i := range c.Tables {
+		t := &c.Tables[i]
+		switch {
+		case t.Name == name,
+			len(parts) == 1 && t.Name == parts[0],
+			len(parts) == 2 && t.Schema != "" && t.Schema == parts[0] && t.Name == parts[1]:
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Column returns the named column of the table.
+func (t *Table) Column(name string) (*Column, bool) {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i], true
+		}
+	}
+	return nil, false
+}
+
+// WriteJSON writes the catalog as indented JSON.
+func (c *Catalog) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// WriteYAML writes the catalog as YAML, with the same field names as its
+// JSON.
+func (c *Catalog) WriteYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// ReadJSON reads a catalog written by WriteJSON in this or any earlier
+// format version, upgrading it to FormatVersion. Catalogs from newer
+// versions are rejected rather than misread.
+func ReadJSON(r io.Reader) (*Catalog, error) {
+	var c Catalog
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	if c.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("catalog format version %d is newer than supported version %d; upgrade sql-loader", c.FormatVersion, FormatVersion)
+	}
+	// Version 1 only added format_version itself.
+	c.FormatVersion = FormatVersion
+	return &c, nil
+}
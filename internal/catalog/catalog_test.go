@@ -0,0 +1,152 @@
+package catalog
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	_ "modernc.org/sqlite"
+)
+
+func TestInspectSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+
+	schema := `
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY,
+			email TEXT NOT NULL UNIQUE,
+			status TEXT DEFAULT 'active'
+		);
+		CREATE TABLE orders (
+			id INTEGER PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			total NUMERIC
+		);
+		CREATE INDEX idx_orders_user ON orders(user_id);`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	cat, err := Inspect(db, "sqlite")
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if len(cat.Tables) != 2 {
+		t.Fatalf("got %d tables, want 2", len(cat.Tables))
+	}
+
+	users, ok := cat.Table("users")
+	if !ok {
+		t.Fatal("users table not found")
+	}
+	if !reflect.DeepEqual(users.PrimaryKey, []string{"id"}) {
+		t.Errorf("users primary key = %v, want [id]", users.PrimaryKey)
+	}
+	email, ok := users.Column("email")
+	if !ok || email.Nullable || email.Type != "text" {
+		t.Errorf("email column = %+v", email)
+	}
+	status, _ := users.Column("status")
+	if status.Default == nil || *status.Default != "'active'" {
+		t.Errorf("status default = %v, want 'active'", status.Default)
+	}
+	if len(users.Constraints) != 1 || users.Constraints[0].Type != ConstraintUnique {
+		t.Errorf("users constraints = %+v, want one unique constraint", users.Constraints)
+	}
+
+	orders, _ := cat.Table("orders")
+	if len(orders.Constraints) != 1 || orders.Constraints[0].Type != ConstraintForeignKey {
+		t.Fatalf("orders constraints = %+v, want one foreign key", orders.Constraints)
+	}
+	if ref := orders.Constraints[0].References; ref.Table != "users" || !reflect.DeepEqual(ref.Columns, []string{"id"}) {
+		t.Errorf("foreign key references = %+v", ref)
+	}
+	want := []Index{{Name: "idx_orders_user", Columns: []string{"user_id"}}}
+	if !reflect.DeepEqual(orders.Indexes, want) {
+		t.Errorf("orders indexes = %+v, want %+v", orders.Indexes, want)
+	}
+
+	var buf bytes.Buffer
+	if err := cat.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var decoded Catalog
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteJSON() produced invalid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, cat) {
+		t.Errorf("round-tripped catalog differs")
+	}
+
+	buf.Reset()
+	if err := cat.WriteYAML(&buf); err != nil {
+		t.Fatalf("WriteYAML() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "format_version: 1\ndriver: sqlite\n") || !strings.Contains(buf.String(), "primary_key:") {
+		t.Errorf("WriteYAML() does not use the JSON field names:\n%s", buf.String())
+	}
+	decoded = Catalog{}
+	if err := yaml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteYAML() produced invalid YAML: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, cat) {
+		t.Errorf("round-tripped YAML catalog differs")
+	}
+}
+
+func TestInspectUnsupportedDriver(t *testing.T) {
+	if _, err := Inspect(nil, "oracle"); err == nil {
+		t.Error("Inspect() expected error for unsupported driver")
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *Catalog
+		wantErr bool
+	}{
+		{
+			name:  "current version",
+			input: `{"format_version": 1, "driver": "sqlite", "tables": [{"name": "users", "columns": []}]}`,
+			want:  &Catalog{FormatVersion: 1, Driver: "sqlite", Tables: []Table{{Name: "users", Columns: []Column{}}}},
+		},
+		{
+			name:  "unversioned catalog from an older release",
+			input: `{"driver": "postgres", "tables": []}`,
+			want:  &Catalog{FormatVersion: 1, Driver: "postgres", Tables: []Table{}},
+		},
+		{
+			name:  "unknown fields are ignored",
+			input: `{"format_version": 1, "driver": "sqlite", "tables": [], "generated_by": "future"}`,
+			want:  &Catalog{FormatVersion: 1, Driver: "sqlite", Tables: []Table{}},
+		},
+		{name: "newer version", input: `{"format_version": 2, "driver": "sqlite", "tables": []}`, wantErr: true},
+		{name: "invalid JSON", input: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadJSON(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadJSON() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package catalog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+const pgSchemaFilter = `n.nspname NOT IN ('pg_catalog', 'information_schema')
+	AND n.nspname NOT LIKE 'pg_toast%' AND n.nspname NOT LIKE 'pg_temp%'`
+
+const pgColumnsQuery = `SELECT n.nspname, c.relname, a.attname,
+	format_type(a.atttypid, a.atttypmod), NOT a.attnotnull,
+	pg_get_expr(d.adbin, d.adrelid)
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+LEFT JOIN pg_attrdef d ON d.adrelid = c.oid AND d.adnum = a.attnum
+WHERE c.relkind IN ('r', 'p') AND ` + pgSchemaFilter + `
+ORDER BY n.nspname, c.relname, a.attnum`
+
+const pgConstraintsQuery = `SELECT n.nspname, c.relname, con.conname, con.contype,
+	array_to_json(ARRAY(
+		SELECT a.attname FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+		ORDER BY k.ord))::text,
+	COALESCE(fn.nspname || '.' || fc.relname, ''),
+	array_to_json(ARRAY(
+		SELECT a.attname FROM unnest(con.confkey) WITH ORDINALITY k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+		ORDER BY k.ord))::text,
+	pg_get_constraintdef(con.oid)
+FROM pg_constraint con
+JOIN pg_class c ON c.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_class fc ON fc.oid = con.confrelid
+LEFT JOIN pg_namespace fn ON fn.oid = fc.relnamespace
+WHERE con.contype IN ('p', 'u', 'f', 'c') AND ` + pgSchemaFilter + `
+ORDER BY n.nspname, c.relname, con.conname`
+
+const pgIndexesQuery = `SELECT n.nspname, c.relname, ic.relname, i.indisunique,
+	array_to_json(ARRAY(
+		SELECT pg_get_indexdef(i.indexrelid, s, true)
+		FROM generate_series(1, i.indnkeyatts) s ORDER BY s))::text
+FROM pg_index i
+JOIN pg_class ic ON ic.oid = i.indexrelid
+JOIN pg_class c ON c.oid = i.indrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE ` + pgSchemaFilter + `
+	AND NOT EXISTS (
+		SELECT 1 FROM pg_constraint con
+		WHERE con.conindid = i.indexrelid AND con.conrelid = i.indrelid
+		AND con.contype IN ('p', 'u', 'x'))
+ORDER BY n.nspname, c.relname, ic.relname`
+
+func inspectPostgres(db *sql.DB) ([]Table, error) {
+	var (
+		tables []Table
+		index  = map[string]int{}
+	)
+
+	rows, err := db.Query(pgColumnsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns: %w", err)
+	}
+	for rows.Next() {
+		var (
+			schema, table string
+			col           Column
+			dflt          sql.NullString
+		)
+		if err := rows.Scan(&schema, &table, &col.Name, &col.Type, &col.Nullable, &dflt); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		if dflt.Valid {
+			col.Default = &dflt.String
+		}
+		key := schema + "." + table
+		i, ok := index[key]
+		if !ok {
+			i = len(tables)
+			index[key] = i
+			tables = append(tables, Table{Schema: schema, Name: table})
+		}
+		tables[i].Columns = append(tables[i].Columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, fmt.Errorf("failed to list columns: %w", err)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("failed to list columns: %w", err)
+	}
+
+	if err := inspectPostgresConstraints(db, tables, index); err != nil {
+		return nil, err
+	}
+	if err := inspectPostgresIndexes(db, tables, index); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+func inspectPostgresConstraints(db *sql.DB, tables []Table, index map[string]int) error {
+	rows, err := db.Query(pgConstraintsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to list constraints: %w", err)
+	}
+	for rows.Next() {
+		var (
+			schema, table, name, kind string
+			colsJSON, refTable        string
+			refColsJSON, definition   string
+		)
+		if err := rows.Scan(&schema, &table, &name, &kind, &colsJSON, &refTable, &refColsJSON, &definition); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan constraint: %w", err)
+		}
+		i, ok := index[schema+"."+table]
+		if !ok {
+			continue
+		}
+		var cols, refCols []string
+		if err := json.Unmarshal([]byte(colsJSON), &cols); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to decode constraint columns: %w", err)
+		}
+		if err := json.Unmarshal([]byte(refColsJSON), &refCols); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to decode referenced columns: %w", err)
+		}
+
+		t := &tables[i]
+		switch kind {
+		case "p":
+			t.PrimaryKey = cols
+		case "u":
+			t.Constraints = append(t.Constraints, Constraint{Name: name, Type: ConstraintUnique, Columns: cols})
+		case "f":
+			t.Constraints = append(t.Constraints, Constraint{
+				Name:       name,
+				Type:       ConstraintForeignKey,
+				Columns:    cols,
+				References: &Reference{Table: refTable, Columns: refCols},
+			})
+		case "c":
+			t.Constraints = append(t.Constraints, Constraint{Name: name, Type: ConstraintCheck, Columns: cols, Definition: definition})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return fmt.Errorf("failed to list constraints: %w", err)
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to list constraints: %w", err)
+	}
+	return nil
+}
+
+func inspectPostgresIndexes(db *sql.DB, tables []Table, index map[string]int) error {
+	rows, err := db.Query(pgIndexesQuery)
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+	for rows.Next() {
+		var (
+			schema, table, name, colsJSON string
+			idx                           Index
+		)
+		if err := rows.Scan(&schema, &table, &name, &idx.Unique, &colsJSON); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan index: %w", err)
+		}
+		i, ok := index[schema+"."+table]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal([]byte(colsJSON), &idx.Columns); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to decode index columns: %w", err)
+		}
+		idx.Name = name
+		tables[i].Indexes = append(tables[i].Indexes, idx)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+	return nil
+}
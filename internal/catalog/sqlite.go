@@ -0,0 +1,214 @@
+package catalog
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+func inspectSQLite(db *sql.DB) ([]Table, error) {
+	rows, err := db.Query(`SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	tables := make([]Table, 0, len(names))
+	for _, name := range names {
+		t, err := inspectSQLiteTable(db, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect table %s: %w", name, err)
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+func inspectSQLiteTable(db *sql.DB, name string) (Table, error) {
+	t := Table{Name: name}
+	quoted := database.QuoteIdent(name)
+
+	rows, err := db.Query("PRAGMA table_info(" + quoted + ")")
+	if err != nil {
+		return t, err
+	}
+	type pkCol struct {
+		pos  int
+		name string
+	}
+	var pk []pkCol
+	for rows.Next() {
+		var (
+			cid, notNull, pkPos int
+			col, typ            string
+			dflt                sql.NullString
+		)
+		if err := rows.Scan(&cid, &col, &typ, &notNull, &dflt, &pkPos); err != nil {
+			_ = rows.Close()
+			return t, err
+		}
+		c := Column{Name: col, Type: strings.ToLower(typ), Nullable: notNull == 0 && pkPos == 0}
+		if dflt.Valid {
+			c.Default = &dflt.String
+		}
+		t.Columns = append(t.Columns, c)
+		if pkPos > 0 {
+			pk = append(pk, pkCol{pos: pkPos, name: col})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return t, err
+	}
+	if err := rows.Close(); err != nil {
+		return t, err
+	}
+	sort.Slice(pk, func(i, j int) bool { return pk[i].pos < pk[j].pos })
+	for _, c := range pk {
+		t.PrimaryKey = append(t.PrimaryKey, c.name)
+	}
+
+	if err := inspectSQLiteForeignKeys(db, &t); err != nil {
+		return t, err
+	}
+	if err := inspectSQLiteIndexes(db, &t); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+func inspectSQLiteForeignKeys(db *sql.DB, t *Table) error {
+	rows, err := db.Query("PRAGMA foreign_key_list(" + database.QuoteIdent(t.Name) + ")")
+	if err != nil {
+		return err
+	}
+	byID := map[int]*Constraint{}
+	var order []int
+	for rows.Next() {
+		var (
+			id, seq                       int
+			table, from                   string
+			to                            sql.NullString
+			onUpdate, onDelete, matchType string
+		)
+		if err := rows.Scan(&id, &seq, &table, &from, &to, &onUpdate, &onDelete, &matchType); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		c, ok := byID[id]
+		if !ok {
+			c = &Constraint{Type: ConstraintForeignKey, References: &Reference{Table: table}}
+			byID[id] = c
+			order = append(order, id)
+		}
+		c.Columns = append(c.Columns, from)
+		c.References.Columns = append(c.References.Columns, to.String)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	sort.Ints(order)
+	for _, id := range order {
+		t.Constraints = append(t.Constraints, *byID[id])
+	}
+	return nil
+}
+
+func inspectSQLiteIndexes(db *sql.DB, t *Table) error {
+	rows, err := db.Query("PRAGMA index_list(" + database.QuoteIdent(t.Name) + ")")
+	if err != nil {
+		return err
+	}
+	type indexInfo struct {
+		name   string
+		unique bool
+		origin string
+	}
+	var indexes []indexInfo
+	for rows.Next() {
+		var (
+			seq, unique, partial int
+			name, origin         string
+		)
+		if err := rows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		indexes = append(indexes, indexInfo{name: name, unique: unique == 1, origin: origin})
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].name < indexes[j].name })
+
+	for _, idx := range indexes {
+		if idx.origin == "pk" {
+			continue
+		}
+		cols, err := sqliteIndexColumns(db, idx.name)
+		if err != nil {
+			return err
+		}
+		if idx.origin == "u" {
+			t.Constraints = append(t.Constraints, Constraint{Name: idx.name, Type: ConstraintUnique, Columns: cols})
+			continue
+		}
+		t.Indexes = append(t.Indexes, Index{Name: idx.name, Columns: cols, Unique: idx.unique})
+	}
+	return nil
+}
+
+func sqliteIndexColumns(db *sql.DB, index string) ([]string, error) {
+	rows, err := db.Query("PRAGMA index_info(" + database.QuoteIdent(index) + ")")
+	if err != nil {
+		return nil, err
+	}
+	var cols []string
+	for rows.Next() {
+		var (
+			seqno, cid int
+			name       sql.NullString
+		)
+		if err := rows.Scan(&seqno, &cid, &name); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		if !name.Valid {
+			name.String = "<expression>"
+		}
+		cols = append(cols, name.String)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	return cols, rows.Close()
+}
@@ -0,0 +1,238 @@
+// Package config provides functionality for loading sql-loader configuration
+// files. A user-level file under the XDG config directory holds defaults and
+// connection profiles, and an optional project file in the working directory
+// is merged over it.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile is the name of the project-level config file looked up in the
+// working directory.
+const ProjectFile = "sql-loader.yaml"
+
+// Config is the merged configuration.
+type Config struct {
+	Defaults Defaults            `yaml:"defaults"`
+	Profiles map[string]Profile  `yaml:"profiles"`
+	Aliases  map[string][]string `yaml:"aliases"`
+	Tables   map[string]Table    `yaml:"tables"`
+	// Types maps logical types to column types per driver, e.g.
+	// types.postgres.timestamp: timestamptz.
+	Types map[string]map[string]string `yaml:"types"`
+}
+
+// Defaults holds values applied when the corresponding flag is not given.
+type Defaults struct {
+	Driver  string `yaml:"driver"`
+	Profile string `yaml:"profile"`
+	// LogFormat and Color are the defaults of -log-format and -color.
+	LogFormat string `yaml:"log-format"`
+	Color     string `yaml:"color"`
+}
+
+// Profile is a named database connection.
+type Profile struct {
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+	// Environment tags the target, e.g. dev, staging, or prod.
+	Environment string `yaml:"environment"`
+}
+
+// Table holds per-table settings for data loads.
+type Table struct {
+	// Keys are the columns identifying a row, used to apply change files.
+	Keys []string `yaml:"keys"`
+}
+
+// EnvironmentProd is the environment tag that requires explicit
+// confirmation before any write.
+const EnvironmentProd = "prod"
+
+// Protected reports whether writes to the profile need confirmation.
+func (p Profile) Protected() bool {
+	return p.Environment == EnvironmentProd
+}
+
+// UserPath returns the location of the user-level config file:
+// $XDG_CONFIG_HOME/sql-loader/config.yaml, falling back to the platform's
+// user config directory.
+func UserPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		var err error
+		if dir, err = os.UserConfigDir(); err != nil {
+			return "", fmt.Errorf("failed to locate user config directory: %w", err)
+		}
+	}
+	return filepath.Join(dir, "sql-loader", "config.yaml"), nil
+}
+
+// Load reads the user-level config and merges the project config from the
+// working directory over it. Missing files are not an error.
+func Load() (*Config, error) {
+	userPath, err := UserPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadFile(userPath)
+	if err != nil {
+		return nil, err
+	}
+	project, err := LoadFile(ProjectFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Merge(project)
+	return cfg, nil
+}
+
+// LoadFile reads a single config file. A missing file yields an empty config.
+// #nosec G304 -- Config paths are derived from the user's environment or working directory
+func LoadFile(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg, err := Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes a YAML config document. Unknown keys are rejected so typos
+// surface immediately.
+func Parse(r io.Reader) (*Config, error) {
+	cfg := &Config{}
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Merge overlays the non-empty values of other onto c. Profiles are merged
+// by name, with other's definition replacing c's.
+func (c *Config) Merge(other *Config) {
+	if other.Defaults.Driver != "" {
+		c.Defaults.Driver = other.Defaults.Driver
+	}
+	if other.Defaults.Profile != "" {
+		c.Defaults.Profile = other.Defaults.Profile
+	}
+	if other.Defaults.LogFormat != "" {
+		c.Defaults.LogFormat = other.Defaults.LogFormat
+	}
+	if other.Defaults.Color != "" {
+		c.Defaults.Color = other.Defaults.Color
+	}
+	if len(other.Profiles) > 0 && c.Profiles == nil {
+		c.Profiles = map[string]Profile{}
+	}
+	for name, p := range other.Profiles {
+		c.Profiles[name] = p
+	}
+	if len(other.Aliases) > 0 && c.Aliases == nil {
+		c.Aliases = map[string][]string{}
+	}
+	for name, args := range other.Aliases {
+		c.Aliases[name] = args
+	}
+	if len(other.Tables) > 0 && c.Tables == nil {
+		c.Tables = map[string]Table{}
+	}
+	for name, t := range other.Tables {
+		c.Tables[name] = t
+	}
+	if len(other.Types) > 0 && c.Types == nil {
+		c.Types = map[string]map[string]string{}
+	}
+	for driver, types := range other.Types {
+		if c.Types[driver] == nil {
+			c.Types[driver] = map[string]string{}
+		}
+		for logical, typ := range types {
+			c.Types[driver][logical] = typ
+		}
+	}
+}
+
+// Profile returns the named profile with ${VAR} references in its DSN
+// expanded from the environment.
+func (c *Config) Profile(name string) (Profile, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, unknownName("profile", name, c.Profiles)
+	}
+	p.DSN = ExpandEnv(p.DSN)
+	return p, nil
+}
+
+// Alias returns the command-line arguments the named alias expands to.
+func (c *Config) Alias(name string) ([]string, error) {
+	args, ok := c.Aliases[name]
+	if !ok {
+		return nil, unknownName("alias", name, c.Aliases)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("alias %q is empty", name)
+	}
+	return args, nil
+}
+
+func unknownName[V any](kind, name string, known map[string]V) error {
+	names := make([]string, 0, len(known))
+	for n := range known {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return fmt.Errorf("unknown %s %q (none configured)", kind, name)
+	}
+	return fmt.Errorf("unknown %s %q (available: %s)", kind, name, strings.Join(names, ", "))
+}
+
+// ExpandEnv replaces ${VAR} references with values from the environment.
+// Bare $VAR references are left untouched so that literal dollar signs in
+// passwords survive, as are ${provider:ref} secret references, which are
+// resolved when connecting.
+func ExpandEnv(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			break
+		}
+		name := s[start+2 : start+end]
+		if strings.Contains(name, ":") {
+			b.WriteString(s[:start+end+1])
+			s = s[start+end+1:]
+			continue
+		}
+		b.WriteString(s[:start])
+		b.WriteString(os.Getenv(name))
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FlagFile holds flag values read from a -config file. Each top-level key
+// names a flag, without the leading dash, and a list value repeats it.
+type FlagFile map[string][]string
+
+// LoadFlagFile reads a flag file. Unlike LoadFile, a missing file is an
+// error, since it was named explicitly.
+// #nosec G304 -- File path is intentionally provided by the user as part of the CLI interface
+func LoadFlagFile(path string) (FlagFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	values, err := ParseFlagFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// ParseFlagFile decodes a YAML mapping of flag names to scalars or lists of
+// scalars. ${VAR} references in values are expanded from the environment.
+func ParseFlagFile(r io.Reader) (FlagFile, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return FlagFile{}, nil
+		}
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid config: line %d: expected a mapping of flag names to values", root.Line)
+	}
+
+	values := FlagFile{}
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if _, ok := values[key.Value]; ok {
+			return nil, fmt.Errorf("invalid config: line %d: %s is set more than once", key.Line, key.Value)
+		}
+		items := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			items = value.Content
+		}
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("invalid config: line %d: %s must be a value or a list of values", item.Line, key.Value)
+			}
+			list = append(list, ExpandEnv(item.Value))
+		}
+		values[key.Value] = list
+	}
+	return values, nil
+}
+
+// Names returns the flag names in the file in sorted order.
+func (f FlagFile) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
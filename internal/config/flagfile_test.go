@@ -0,0 +1,67 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFlagFile(t *testing.T) {
+	t.Setenv("LOADER_TEST_HOST", "db.internal")
+	tests := []struct {
+		name    string
+		input   string
+		want    FlagFile
+		wantErr string
+	}{
+		{
+			name: "scalars and lists",
+			input: `driver: postgres
+dsn: postgres://loader@${LOADER_TEST_HOST}/app
+dir: /opt/sql
+transaction: all
+reconnect: 3
+read-only: true
+allow-path: [/opt/sql, /tmp/data]
+`,
+			want: FlagFile{
+				"driver":      {"postgres"},
+				"dsn":         {"postgres://loader@db.internal/app"},
+				"dir":         {"/opt/sql"},
+				"transaction": {"all"},
+				"reconnect":   {"3"},
+				"read-only":   {"true"},
+				"allow-path":  {"/opt/sql", "/tmp/data"},
+			},
+		},
+		{name: "empty", input: "", want: FlagFile{}},
+		{name: "secret references kept", input: "dsn: postgres://loader:${file:/run/pw}@db/app\n",
+			want: FlagFile{"dsn": {"postgres://loader:${file:/run/pw}@db/app"}}},
+		{name: "not a mapping", input: "- file\n", wantErr: "expected a mapping"},
+		{name: "nested mapping", input: "dsn:\n  host: db\n", wantErr: "dsn must be a value or a list of values"},
+		{name: "duplicate key", input: "file: a.sql\nfile: b.sql\n", wantErr: "invalid config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFlagFile(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseFlagFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFlagFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFlagFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFlagFileMissing(t *testing.T) {
+	if _, err := LoadFlagFile("does-not-exist.yaml"); err == nil {
+		t.Error("LoadFlagFile() accepted a missing file")
+	}
+}
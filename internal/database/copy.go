@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// copyTerminator is the line ending the data rows of a COPY FROM STDIN.
+const copyTerminator = `\.`
+
+// skipCopyData skips the data rows of a COPY ... FROM STDIN statement, from
+// just after its semicolon through the \. line that ends them, reporting
+// whether that line was found. The rows start on the line after the
+// statement.
+func skipCopyData(s string, i int) (int, bool) {
+	nl := strings.IndexByte(s[i:], '\n')
+	if nl < 0 {
+		return len(s), false
+	}
+	for j := i + nl + 1; j < len(s); {
+		line, next := s[j:], len(s)
+		if end := strings.IndexByte(line, '\n'); end >= 0 {
+			line, next = line[:end], j+end+1
+		}
+		if strings.TrimSuffix(line, "\r") == copyTerminator {
+			return j + len(copyTerminator), true
+		}
+		j = next
+	}
+	return len(s), false
+}
+
+// copyData splits a COPY ... FROM STDIN statement, as SplitStatements
+// returns it, into the COPY command and its data rows. ok is false for
+// every other statement.
+func copyData(stmt string) (command, data string, ok bool) {
+	if DescribeStatement(stmt).Verb != "COPY" || !strings.HasSuffix(stmt, copyTerminator) {
+		return "", "", false
+	}
+	semi := statementEnd(stmt)
+	if semi < 0 {
+		return "", "", false
+	}
+	nl := strings.IndexByte(stmt[semi:], '\n')
+	if nl < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(stmt[:semi]), strings.TrimSuffix(stmt[semi+nl+1:], copyTerminator), true
+}
+
+// statementEnd returns the offset of the first semicolon of s outside
+// literals, quoted identifiers, and comments, or -1.
+func statementEnd(s string) int {
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ';':
+			return i
+		case strings.HasPrefix(s[i:], "--"):
+			i = skipLineComment(s, i)
+		case strings.HasPrefix(s[i:], "/*"):
+			i, _ = skipBlockComment(s, i)
+		case c == '\'':
+			i, _ = skipQuoted(s, i, isEscapeString(s, i))
+		case c == '"':
+			i, _ = skipQuoted(s, i, false)
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// rawConn is an Execer whose driver connection is reachable, as a COPY
+// needs: a *sql.Conn or a *Tx.
+type rawConn interface {
+	Execer
+	Raw(f func(driverConn any) error) error
+}
+
+// execCopy runs a COPY ... FROM STDIN command on e, streaming data with the
+// PostgreSQL copy protocol, and returns the rows copied.
+func execCopy(ctx context.Context, e Execer, command, data string) (int64, error) {
+	switch e := e.(type) {
+	case *sql.DB:
+		conn, err := e.Conn(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer func() { _ = conn.Close() }()
+		return copyOn(ctx, conn, command, data)
+	case rawConn:
+		return copyOn(ctx, e, command, data)
+	default:
+		return 0, fmt.Errorf("COPY FROM STDIN cannot run on a %T; use a *sql.DB, a *sql.Conn, or a transaction from BeginTx", e)
+	}
+}
+
+func copyOn(ctx context.Context, c rawConn, command, data string) (int64, error) {
+	var rows int64
+	err := c.Raw(func(driverConn any) error {
+		pc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("COPY FROM STDIN requires the PostgreSQL driver")
+		}
+		tag, err := pc.Conn().PgConn().CopyFrom(ctx, strings.NewReader(data), command)
+		rows = tag.RowsAffected()
+		return err
+	})
+	return rows, err
+}
+
+// Tx is a transaction pinned to its connection, so that the scripts run in
+// it with ExecuteScriptOn may contain COPY FROM STDIN blocks, which a plain
+// *sql.Tx cannot run. Commit and Rollback release the connection.
+type Tx struct {
+	*sql.Tx
+	conn *sql.Conn
+	// owned marks a connection taken from a pool, closed with the
+	// transaction.
+	owned bool
+}
+
+// BeginTx starts a transaction on a connection of db. A nil opts uses the
+// server's default isolation level.
+func BeginTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*Tx, error) {
+	return beginPinned(ctx, db, opts)
+}
+
+// BeginConnTx starts a transaction on conn, which stays open after Commit
+// or Rollback, as BeginTx does on a connection of a pool.
+func BeginConnTx(ctx context.Context, conn *sql.Conn, opts *sql.TxOptions) (*Tx, error) {
+	return beginPinned(ctx, conn, opts)
+}
+
+func beginPinned(ctx context.Context, db txBeginner, opts *sql.TxOptions) (*Tx, error) {
+	var (
+		conn  *sql.Conn
+		owned bool
+	)
+	switch db := db.(type) {
+	case *sql.Conn:
+		conn = db
+	case *sql.DB:
+		var err error
+		if conn, err = db.Conn(ctx); err != nil {
+			return nil, err
+		}
+		owned = true
+	default:
+		return nil, fmt.Errorf("cannot begin a transaction on a %T", db)
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		if owned {
+			_ = conn.Close()
+		}
+		return nil, err
+	}
+	return &Tx{Tx: tx, conn: conn, owned: owned}, nil
+}
+
+// Raw runs f on the driver connection of the transaction.
+func (t *Tx) Raw(f func(driverConn any) error) error {
+	return t.conn.Raw(f)
+}
+
+// Commit commits the transaction and releases its connection.
+func (t *Tx) Commit() error {
+	err := t.Tx.Commit()
+	t.release()
+	return err
+}
+
+// Rollback rolls the transaction back and releases its connection.
+func (t *Tx) Rollback() error {
+	err := t.Tx.Rollback()
+	t.release()
+	return err
+}
+
+func (t *Tx) release() {
+	if t.owned {
+		_ = t.conn.Close()
+	}
+}
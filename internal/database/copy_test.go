@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyData(t *testing.T) {
+	tests := []struct {
+		name        string
+		stmt        string
+		wantCommand string
+		wantData    string
+		wantOK      bool
+	}{
+		{
+			name:        "pg_dump block",
+			stmt:        "COPY public.t (id, note) FROM stdin;\n1\ta;b\n2\t\\N\n\\.",
+			wantCommand: "COPY public.t (id, note) FROM stdin",
+			wantData:    "1\ta;b\n2\t\\N\n",
+			wantOK:      true,
+		},
+		{
+			name:        "no rows",
+			stmt:        "-- empty table\ncopy t from stdin;\n\\.",
+			wantCommand: "-- empty table\ncopy t from stdin",
+			wantOK:      true,
+		},
+		{
+			name:        "quoted semicolon in the command",
+			stmt:        "COPY \"a;b\" FROM stdin WITH (DELIMITER ';');\n1;2\n\\.",
+			wantCommand: "COPY \"a;b\" FROM stdin WITH (DELIMITER ';')",
+			wantData:    "1;2\n",
+			wantOK:      true,
+		},
+		{name: "copy from a file", stmt: "COPY t FROM '/tmp/t.csv'"},
+		{name: "other statement", stmt: "SELECT '\\.'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, data, ok := copyData(tt.stmt)
+			if command != tt.wantCommand || data != tt.wantData || ok != tt.wantOK {
+				t.Errorf("copyData() = %q, %q, %v; want %q, %q, %v", command, data, ok, tt.wantCommand, tt.wantData, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCopyRequiresPostgres(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	err = ExecuteScript(context.Background(), db, "COPY t FROM stdin;\n1\n\\.\n")
+	if err == nil || !strings.Contains(err.Error(), "COPY FROM STDIN requires the PostgreSQL driver") {
+		t.Errorf("ExecuteScript() error = %v, want a PostgreSQL driver error", err)
+	}
+}
+
+func TestBeginTx(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "tx.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if _, err := db.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	for _, commit := range []bool{false, true} {
+		tx, err := BeginTx(ctx, db, nil)
+		if err != nil {
+			t.Fatalf("BeginTx() error = %v", err)
+		}
+		if err := ExecuteScriptOn(ctx, tx, "INSERT INTO t VALUES (1); INSERT INTO t VALUES (2);", ExecuteOptions{}); err != nil {
+			t.Fatalf("ExecuteScriptOn() error = %v", err)
+		}
+		end := tx.Rollback
+		if commit {
+			end = tx.Commit
+		}
+		if err := end(); err != nil {
+			t.Fatalf("ending the transaction: %v", err)
+		}
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil || count != 2 {
+		t.Errorf("%d rows, %v; want only the committed 2 rows", count, err)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("%d connections still in use after the transactions ended", n)
+	}
+}
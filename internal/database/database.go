@@ -3,23 +3,33 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
-// Connect establishes a database connection with the specified driver and DSN.
-func Connect(driver, dsn string) (*sql.DB, error) {
+// Connect establishes a database connection with the specified driver and
+// DSN, giving up when ctx is done.
+func Connect(ctx context.Context, driver, dsn string) (*sql.DB, error) {
 	if dsn == "" {
 		return nil, fmt.Errorf("DSN cannot be empty")
 	}
 
-	db, err := sql.Open(driver, dsn)
+	db, err := open(driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
 		if closeErr := db.Close(); closeErr != nil {
 			return nil, fmt.Errorf("failed to ping database: %w (close error: %v)", err, closeErr)
 		}
@@ -29,28 +39,240 @@ func Connect(driver, dsn string) (*sql.DB, error) {
 	return db, nil
 }
 
-// ExecuteScript executes a SQL script, splitting by semicolons and executing each statement.
-// Note: This is a simple implementation that splits statements by semicolons.
-// It does not handle semicolons within string literals, comments, or function definitions.
-// For complex SQL scripts with these features, consider using a proper SQL parser
-// or execute the script using database-native tools.
-func ExecuteScript(db *sql.DB, script string) error {
-	script = strings.TrimSpace(script)
-	if script == "" {
-		return nil
+// open opens a database without connecting. PostgreSQL connections route
+// the notices they receive to the handlers registered with withNotices.
+func open(driver, dsn string) (*sql.DB, error) {
+	if sqlDriverName(driver) != "pgx" {
+		return sql.Open(sqlDriverName(driver), dsn)
 	}
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	config.OnNotice = routeNotice
+	return stdlib.OpenDB(*config), nil
+}
+
+// sqlDriverName maps user-facing driver names onto the names registered with
+// database/sql. The pgx stdlib driver registers itself as "pgx".
+func sqlDriverName(driver string) string {
+	if driver == "postgres" {
+		return "pgx"
+	}
+	return driver
+}
+
+// ExecuteOptions controls how ExecuteScriptWithOptions runs a script.
+type ExecuteOptions struct {
+	// OnStatement, if set, is called after each statement completes with its
+	// 1-based index, execution time, and the number of rows it affected, or
+	// -1 when the driver does not report one.
+	OnStatement func(index int, stmt string, elapsed time.Duration, rows int64)
+	// Transaction groups statements into transactions; the zero value is
+	// TransactionNone.
+	Transaction TransactionMode
+	// TxOptions sets the isolation level and read-only mode of the
+	// transactions the script runs in: per statement, per batch, or for the
+	// whole script. Nil uses the server's defaults.
+	TxOptions *sql.TxOptions
+	// BatchSize, if positive, runs the statements in transactions of up to
+	// BatchSize statements, committing each before the next begins, so a
+	// failure rolls back only its own batch. It requires TransactionNone.
+	BatchSize int
+	// OnBatch, if set, is called after each batch commits with its 1-based
+	// number and the number of statements committed so far.
+	OnBatch func(batch, committed int)
+	// StatementTimeout, if positive, cancels any statement running longer.
+	StatementTimeout time.Duration
+	// Reconnect controls recovery when the connection drops mid-script.
+	// After reconnecting execution resumes with the interrupted statement.
+	// It does not apply to TransactionAll or batches, whose transaction is
+	// lost along with the connection.
+	Reconnect ReconnectPolicy
+	// OnReconnect, if set, is called before each reconnect attempt.
+	OnReconnect func(index, attempt int, err error)
+	// RetryTransaction reruns a TransactionAll script from its first
+	// statement when its transaction fails with a serialization failure or
+	// deadlock, up to RetryTransaction.Attempts times.
+	RetryTransaction ReconnectPolicy
+	// OnRetryTransaction, if set, is called before each rerun with the wait
+	// before it. OnStatement has already seen the failed attempt's
+	// statements, which were rolled back.
+	OnRetryTransaction func(attempt int, wait time.Duration, err error)
+	// BeforeStatement, if set, is called before each statement; an error
+	// aborts the script without executing the statement.
+	BeforeStatement func(index int, stmt string) error
+	// OnNotice, if set, is called with each notice or warning the server
+	// sends while statement index runs, as it arrives. Only PostgreSQL
+	// connections opened by Connect deliver them, and not through a
+	// caller's *sql.Tx.
+	OnNotice func(index int, n Notice)
+}
+
+// ExecError reports which statement of a script failed and how far the
+// script got.
+type ExecError struct {
+	// Index is the 1-based index of the failed statement.
+	Index int
+	// Total is the number of statements in the script.
+	Total int
+	// Disconnected reports whether the connection was lost, in which case the
+	// failed statement may or may not have been applied.
+	Disconnected bool
+	// RolledBack reports that the failed statement's transaction was rolled
+	// back: the whole script's, or with BatchSize its batch's.
+	RolledBack bool
+	// Committed is the number of statements committed in earlier batches,
+	// which stay applied, and Batches the number of those batches.
+	Committed, Batches int
+	Err                error
+}
 
-	statements := strings.Split(script, ";")
-	for _, stmt := range statements {
-		stmt = strings.TrimSpace(stmt)
-		if stmt == "" {
-			continue
+func (e *ExecError) Error() string {
+	if e.Disconnected && !e.RolledBack {
+		return fmt.Sprintf("connection lost at statement %d of %d (%d completed; statement %d may or may not have been applied): %v",
+			e.Index, e.Total, e.Index-1, e.Index, e.Err)
+	}
+	verb, progress := "failed to execute", fmt.Sprintf("%d completed", e.Index-1)
+	if e.Disconnected {
+		verb = "connection lost at"
+	}
+	if e.RolledBack {
+		progress = "transaction rolled back; nothing applied"
+		if e.Committed > 0 {
+			progress = fmt.Sprintf("batch rolled back; %d statements committed in %d batches", e.Committed, e.Batches)
 		}
+	}
+	class := Classify(e.Err)
+	if class.Category == CategoryOther && class.SQLState == "" {
+		return fmt.Sprintf("%s statement %d of %d (%s): %v", verb, e.Index, e.Total, progress, e.Err)
+	}
+	return fmt.Sprintf("%s statement %d of %d (%s) [%s]: %v", verb, e.Index, e.Total, progress, class, e.Err)
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// ExecuteScript executes a SQL script, splitting it with SplitStatements and
+// executing each statement in order. Cancelling ctx cancels the running
+// statement and stops the script. db is never closed, so callers may pass
+// their application's own pool, or a pgx pool wrapped with FromPool.
+func ExecuteScript(ctx context.Context, db *sql.DB, script string) error {
+	return ExecuteScriptWithOptions(ctx, db, script, ExecuteOptions{})
+}
 
-		if _, err := db.Exec(stmt); err != nil {
-			return fmt.Errorf("failed to execute statement: %w", err)
+// ExecuteScriptWithOptions executes a SQL script like ExecuteScript, applying
+// the given options. Failures are reported as *ExecError.
+func ExecuteScriptWithOptions(ctx context.Context, db *sql.DB, script string, opts ExecuteOptions) error {
+	return ExecuteScriptOn(ctx, db, script, opts)
+}
+
+// executeStatements runs statements on e, which must be a txBeginner for
+// every transaction mode but TransactionNone.
+func executeStatements(ctx context.Context, e Execer, statements []string, opts ExecuteOptions) error {
+	if opts.Transaction == TransactionAll {
+		return RetrySerializationFailures(ctx, opts.RetryTransaction, opts.OnRetryTransaction, func() error {
+			return executeInTransaction(ctx, e.(txBeginner), statements, 0, len(statements), opts)
+		})
+	}
+	if opts.BatchSize > 0 {
+		return executeInBatches(ctx, e.(txBeginner), statements, opts)
+	}
+	for i, stmt := range statements {
+		if opts.BeforeStatement != nil {
+			if err := opts.BeforeStatement(i+1, stmt); err != nil {
+				return &ExecError{Index: i + 1, Total: len(statements), Err: err}
+			}
+		}
+		start := time.Now()
+		rows, err := execWithReconnect(ctx, e, i+1, stmt, opts)
+		if err != nil {
+			return &ExecError{Index: i + 1, Total: len(statements), Disconnected: IsConnectionError(err), Err: err}
+		}
+		if opts.OnStatement != nil {
+			opts.OnStatement(i+1, stmt, time.Since(start), rows)
 		}
 	}
 
 	return nil
 }
+
+func execWithReconnect(ctx context.Context, e Execer, index int, stmt string, opts ExecuteOptions) (int64, error) {
+	backoff, limit := opts.Reconnect.delays()
+	for attempt := 1; ; attempt++ {
+		var rows int64
+		err := withTimeout(ctx, opts.StatementTimeout, func(ctx context.Context) error {
+			return withNotices(ctx, e, opts.noticeHandler(index), func(e Execer) error {
+				var err error
+				rows, err = execStatement(ctx, e, stmt, opts.Transaction == TransactionPerStatement, opts.TxOptions)
+				return err
+			})
+		})
+		if err == nil || !IsConnectionError(err) || attempt > opts.Reconnect.Attempts {
+			return rows, err
+		}
+		if opts.OnReconnect != nil {
+			opts.OnReconnect(index, attempt, err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return 0, context.Cause(ctx)
+		}
+		backoff = min(backoff*2, limit)
+		// Reconnect only applies to a *sql.DB. A failed ping leaves the
+		// error for the next Exec to report.
+		_ = e.(*sql.DB).PingContext(ctx)
+	}
+}
+
+// noticeHandler returns the handler for the notices of statement index, or
+// nil when they are not wanted.
+func (opts ExecuteOptions) noticeHandler(index int) func(Notice) {
+	if opts.OnNotice == nil {
+		return nil
+	}
+	return func(n Notice) { opts.OnNotice(index, n) }
+}
+
+// withTimeout calls fn with ctx, bounded by timeout when it is positive.
+// Drivers report cancellation inconsistently, so a failure after ctx or the
+// timeout expires is reported as the cause of the cancellation.
+func withTimeout(ctx context.Context, timeout time.Duration, fn func(context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout,
+			fmt.Errorf("statement timeout of %s exceeded: %w", timeout, context.DeadlineExceeded))
+		defer cancel()
+	}
+	err := fn(ctx)
+	if err != nil && ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return err
+}
+
+// RedactDSN returns dsn with any password masked, suitable for display and
+// for identifying a target without storing credentials. Both URL and
+// key=value DSNs are handled.
+func RedactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.Host != "" {
+		q := u.Query()
+		if q.Has("password") {
+			q.Set("password", "xxxxx")
+			u.RawQuery = q.Encode()
+		}
+		return u.Redacted()
+	}
+	if !strings.Contains(dsn, "=") {
+		return dsn
+	}
+	fields := strings.Fields(dsn)
+	for i, f := range fields {
+		if strings.HasPrefix(strings.ToLower(f), "password=") {
+			fields[i] = "password=xxxxx"
+		}
+	}
+	return strings.Join(fields, " ")
+}
@@ -1,8 +1,12 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -27,7 +31,7 @@ func TestConnect(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			db, err := Connect("sqlite", tt.dsn)
+			db, err := Connect(context.Background(), "sqlite", tt.dsn)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Connect() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -76,6 +80,20 @@ func TestExecuteScript(t *testing.T) {
 			INSERT INTO users (name) VALUES ('Bob');`,
 			wantErr: false,
 		},
+		{
+			name:    "windows line endings",
+			script:  "INSERT INTO users (name) VALUES ('Carol');\r\n\r\nINSERT INTO users (name) VALUES ('Dan');\r\n",
+			wantErr: false,
+		},
+		{
+			name: "semicolons in literals and trigger bodies",
+			script: `CREATE TABLE audit (note TEXT);
+			CREATE TRIGGER users_audit AFTER INSERT ON users BEGIN
+				INSERT INTO audit (note) VALUES ('added; ' || NEW.name);
+			END;
+			INSERT INTO users (name) VALUES ('Eve;');`,
+			wantErr: false,
+		},
 		{
 			name:    "invalid SQL",
 			script:  "INVALID SQL STATEMENT",
@@ -85,10 +103,120 @@ func TestExecuteScript(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := ExecuteScript(db, tt.script)
+			err := ExecuteScript(context.Background(), db, tt.script)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ExecuteScript() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestExecuteScriptWithOptions(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+
+	var (
+		indexes []int
+		rows    []int64
+	)
+	err = ExecuteScriptWithOptions(context.Background(), db, "CREATE TABLE t (id INTEGER); INSERT INTO t VALUES (1);", ExecuteOptions{
+		OnStatement: func(index int, _ string, _ time.Duration, affected int64) {
+			indexes = append(indexes, index)
+			rows = append(rows, affected)
+		},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteScriptWithOptions() error = %v", err)
+	}
+	if len(indexes) != 2 || indexes[0] != 1 || indexes[1] != 2 {
+		t.Errorf("OnStatement indexes = %v, want [1 2]", indexes)
+	}
+	if len(rows) != 2 || rows[1] != 1 {
+		t.Errorf("OnStatement rows = %v, want 1 row affected by the INSERT", rows)
+	}
+
+	err = ExecuteScriptWithOptions(context.Background(), db, "INSERT INTO t VALUES (2); INSERT INTO t VALUES (3);", ExecuteOptions{
+		BeforeStatement: func(index int, _ string) error {
+			if index == 2 {
+				return errors.New("blocked")
+			}
+			return nil
+		},
+	})
+	var execErr *ExecError
+	if !errors.As(err, &execErr) || execErr.Index != 2 {
+		t.Fatalf("ExecuteScriptWithOptions() error = %v, want ExecError at statement 2", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("table has %d rows, want 2 (blocked statement must not run)", count)
+	}
+}
+
+func TestExecuteScriptTimeouts(t *testing.T) {
+	const slow = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT COUNT(*) FROM c;"
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts ExecuteOptions
+		want string
+	}{
+		{name: "statement timeout", ctx: context.Background(), opts: ExecuteOptions{StatementTimeout: 50 * time.Millisecond}, want: "statement timeout of 50ms exceeded"},
+		{name: "statement timeout in transaction", ctx: context.Background(), opts: ExecuteOptions{StatementTimeout: 50 * time.Millisecond, Transaction: TransactionAll}, want: "statement timeout of 50ms exceeded"},
+		{name: "cancelled context", ctx: cancelled, want: "context canceled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := sql.Open("sqlite", ":memory:")
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			defer func() { _ = db.Close() }()
+
+			err = ExecuteScriptWithOptions(tt.ctx, db, "CREATE TABLE t (id INTEGER); "+slow, tt.opts)
+			var execErr *ExecError
+			if !errors.As(err, &execErr) || execErr.Disconnected || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ExecuteScriptWithOptions() error = %v, want ExecError containing %q", err, tt.want)
+			}
+			if Classify(err).Category != CategoryTimeout {
+				t.Errorf("Classify() = %v, want %s", Classify(err), CategoryTimeout)
+			}
+		})
+	}
+}
+
+func TestRedactDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{name: "url with password", dsn: "postgres://app:s3cret@db:5432/app?sslmode=disable", want: "postgres://app:xxxxx@db:5432/app?sslmode=disable"},
+		{name: "url without password", dsn: "postgres://app@db/app", want: "postgres://app@db/app"},
+		{name: "password query parameter", dsn: "postgres://db/app?password=s3cret", want: "postgres://db/app?password=xxxxx"},
+		{name: "key value", dsn: "host=db user=app password=s3cret dbname=app", want: "host=db user=app password=xxxxx dbname=app"},
+		{name: "sqlite path", dsn: "data/app.db", want: "data/app.db"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactDSN(tt.dsn); got != tt.want {
+				t.Errorf("RedactDSN() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+)
+
+// QuoteIdent quotes a single identifier using ANSI double quotes, escaping
+// any embedded double quotes. Both PostgreSQL and SQLite accept this form.
+func QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteQualified quotes a possibly schema-qualified name such as
+// "public.users", quoting each part of SplitQualified independently.
+func QuoteQualified(name string) string {
+	parts := SplitQualified(name)
+	for i, part := range parts {
+		parts[i] = QuoteIdent(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// SplitQualified splits a possibly schema-qualified name on dots. A part
+// starting with a double quote is read as a quoted identifier, which may
+// contain dots and "" for a quote, as in `public."v1.orders"`; quotes
+// elsewhere are literal.
+func SplitQualified(name string) []string {
+	var parts []string
+	var part strings.Builder
+	i := 0
+	for {
+		if i < len(name) && name[i] == '"' {
+			for i++; i < len(name); i++ {
+				if name[i] == '"' {
+					if i+1 < len(name) && name[i+1] == '"' {
+						i++
+					} else {
+						i++
+						break
+					}
+				}
+				part.WriteByte(name[i])
+			}
+		}
+		for ; i < len(name) && name[i] != '.'; i++ {
+			part.WriteByte(name[i])
+		}
+		parts = append(parts, part.String())
+		part.Reset()
+		if i >= len(name) {
+			return parts
+		}
+		i++
+	}
+}
+
+// JoinQualified is the inverse of SplitQualified, quoting only the parts
+// that contain dots or double quotes.
+func JoinQualified(parts []string) string {
+	joined := make([]string, len(parts))
+	for i, part := range parts {
+		if strings.ContainsAny(part, `."`) {
+			part = QuoteIdent(part)
+		}
+		joined[i] = part
+	}
+	return strings.Join(joined, ".")
+}
+
+// Placeholder returns the bind parameter placeholder for the n-th (1-based)
+// argument of a statement for the given driver.
+func Placeholder(driver string, n int) string {
+	if driver == "sqlite" {
+		return "?"
+	}
+	return "$" + strconv.Itoa(n)
+}
+
+// MaxParams returns the maximum number of bind parameters a single statement
+// may carry for the given driver.
+func MaxParams(driver string) int {
+	if driver == "sqlite" {
+		return 32766
+	}
+	return 65535
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestQuoteQualified(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "users", want: `"users"`},
+		{name: "qualified", in: "public.users", want: `"public"."users"`},
+		{name: "embedded quote", in: `we"ird`, want: `"we""ird"`},
+		{name: "reserved words", in: "order.user", want: `"order"."user"`},
+		{name: "quoted part with dot", in: `public."v1.orders"`, want: `"public"."v1.orders"`},
+		{name: "quoted part with doubled quotes", in: `"weird ""name"`, want: `"weird ""name"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QuoteQualified(tt.in); got != tt.want {
+				t.Errorf("QuoteQualified(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitQualified(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "users", want: []string{"users"}},
+		{in: "public.users", want: []string{"public", "users"}},
+		{in: `"Sales"."v1.orders"`, want: []string{"Sales", "v1.orders"}},
+		{in: `"weird ""name"`, want: []string{`weird "name`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := SplitQualified(tt.in)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SplitQualified(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if back := SplitQualified(JoinQualified(got)); !slices.Equal(back, tt.want) {
+				t.Errorf("SplitQualified(JoinQualified(%q)) = %q, want %q", got, back, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaceholder(t *testing.T) {
+	if got := Placeholder("sqlite", 3); got != "?" {
+		t.Errorf("Placeholder(sqlite) = %s, want ?", got)
+	}
+	if got := Placeholder("postgres", 3); got != "$3" {
+		t.Errorf("Placeholder(postgres) = %s, want $3", got)
+	}
+}
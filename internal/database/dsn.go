@@ -0,0 +1,73 @@
+package database
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// DSNParts are the components of a connection given separately, as with
+// the -host and -user flags, instead of as a connection string.
+type DSNParts struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	DBName   string
+	SSLMode  string
+}
+
+// IsZero reports whether no part is set.
+func (p DSNParts) IsZero() bool {
+	return p == DSNParts{}
+}
+
+// BuildDSN assembles a connection string for driver from parts, escaping
+// each part so that passwords and paths need no quoting. Parts left empty
+// are omitted, leaving them to the driver's defaults, such as the PGHOST and
+// PGPASSWORD environment variables for PostgreSQL.
+func BuildDSN(driver string, parts DSNParts) (string, error) {
+	switch driver {
+	case "postgres", "pgx":
+		u := url.URL{Scheme: "postgres", Path: "/" + parts.DBName}
+		if parts.User != "" {
+			u.User = url.User(parts.User)
+			if parts.Password != "" {
+				u.User = url.UserPassword(parts.User, parts.Password)
+			}
+		} else if parts.Password != "" {
+			return "", fmt.Errorf("a password requires a user")
+		}
+		q := url.Values{}
+		switch {
+		case strings.HasPrefix(parts.Host, "/"):
+			// A Unix socket directory cannot be the URL host.
+			q.Set("host", parts.Host)
+			if parts.Port != "" {
+				q.Set("port", parts.Port)
+			}
+		case parts.Port != "":
+			u.Host = net.JoinHostPort(parts.Host, parts.Port)
+		case strings.Contains(parts.Host, ":"):
+			u.Host = "[" + parts.Host + "]"
+		default:
+			u.Host = parts.Host
+		}
+		if parts.SSLMode != "" {
+			q.Set("sslmode", parts.SSLMode)
+		}
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	case "sqlite":
+		if parts.DBName == "" {
+			return "", fmt.Errorf("sqlite requires a database name, the path of the database file")
+		}
+		if parts != (DSNParts{DBName: parts.DBName}) {
+			return "", fmt.Errorf("sqlite takes only a database name, the path of the database file")
+		}
+		return parts.DBName, nil
+	default:
+		return "", fmt.Errorf("cannot build a connection string for driver %q", driver)
+	}
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Category is a driver-neutral class of database error.
+type Category string
+
+// Error categories.
+const (
+	CategoryConstraint    Category = "constraint_violation"
+	CategorySyntax        Category = "syntax_error"
+	CategoryPermission    Category = "permission_denied"
+	CategoryConnection    Category = "connection_error"
+	CategoryTimeout       Category = "timeout"
+	CategorySerialization Category = "serialization_failure"
+	CategoryOther         Category = "other"
+)
+
+// Classification describes a database error in stable terms.
+type Classification struct {
+	Category Category
+	// SQLState is the five-character SQLSTATE code, when the driver
+	// reports one.
+	SQLState string
+}
+
+func (c Classification) String() string {
+	if c.SQLState == "" {
+		return string(c.Category)
+	}
+	return string(c.Category) + " " + c.SQLState
+}
+
+// SQLite primary result codes used for classification.
+const (
+	sqliteError      = 1
+	sqlitePerm       = 3
+	sqliteBusy       = 5
+	sqliteLocked     = 6
+	sqliteReadOnly   = 8
+	sqliteCantOpen   = 14
+	sqliteConstraint = 19
+	sqliteAuth       = 23
+)
+
+// Classify maps a driver-specific error onto a Category. PostgreSQL errors
+// are classified by SQLSTATE and SQLite errors by result code.
+func Classify(err error) Classification {
+	if err == nil {
+		return Classification{}
+	}
+
+	var coded interface{ SQLState() string }
+	if errors.As(err, &coded) {
+		code := coded.SQLState()
+		return Classification{Category: classifySQLState(code), SQLState: code}
+	}
+
+	var sqliteErr interface{ Code() int }
+	if errors.As(err, &sqliteErr) {
+		return Classification{Category: classifySQLiteCode(sqliteErr.Code(), err.Error())}
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		return Classification{Category: CategoryTimeout}
+	case IsConnectionError(err):
+		return Classification{Category: CategoryConnection}
+	}
+	return Classification{Category: CategoryOther}
+}
+
+func classifySQLState(code string) Category {
+	switch {
+	case strings.HasPrefix(code, "23"):
+		return CategoryConstraint
+	case code == "42601" || code == "42000":
+		return CategorySyntax
+	case code == "42501" || strings.HasPrefix(code, "28"):
+		return CategoryPermission
+	case strings.HasPrefix(code, "08") || code == "57P01" || code == "57P02" || code == "57P03":
+		return CategoryConnection
+	case code == "57014" || code == "55P03":
+		return CategoryTimeout
+	case code == "40001" || code == "40P01":
+		return CategorySerialization
+	}
+	return CategoryOther
+}
+
+func classifySQLiteCode(code int, msg string) Category {
+	switch code & 0xff {
+	case sqliteConstraint:
+		return CategoryConstraint
+	case sqlitePerm, sqliteAuth, sqliteReadOnly:
+		return CategoryPermission
+	case sqliteBusy, sqliteLocked:
+		return CategoryTimeout
+	case sqliteCantOpen:
+		return CategoryConnection
+	case sqliteError:
+		if strings.Contains(msg, "syntax error") || strings.Contains(msg, "incomplete input") {
+			return CategorySyntax
+		}
+	}
+	return CategoryOther
+}
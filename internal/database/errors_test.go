@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Classification
+	}{
+		{"nil", nil, Classification{}},
+		{"unique violation", sqlStateError("23505"), Classification{CategoryConstraint, "23505"}},
+		{"syntax", sqlStateError("42601"), Classification{CategorySyntax, "42601"}},
+		{"insufficient privilege", sqlStateError("42501"), Classification{CategoryPermission, "42501"}},
+		{"auth failure", sqlStateError("28P01"), Classification{CategoryPermission, "28P01"}},
+		{"admin shutdown", sqlStateError("57P01"), Classification{CategoryConnection, "57P01"}},
+		{"statement timeout", sqlStateError("57014"), Classification{CategoryTimeout, "57014"}},
+		{"serialization", fmt.Errorf("wrapped: %w", sqlStateError("40001")), Classification{CategorySerialization, "40001"}},
+		{"undefined table", sqlStateError("42P01"), Classification{CategoryOther, "42P01"}},
+		{"deadline", context.DeadlineExceeded, Classification{Category: CategoryTimeout}},
+		{"dropped connection", io.ErrUnexpectedEOF, Classification{Category: CategoryConnection}},
+		{"plain error", errors.New("boom"), Classification{Category: CategoryOther}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifySQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	defer func() { _ = db.Close() }()
+	if _, err := db.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	tests := []struct {
+		stmt string
+		want Category
+	}{
+		{"INSERT INTO t VALUES (1); INSERT INTO t VALUES (1)", CategoryConstraint},
+		{"SELEC 1", CategorySyntax},
+		{"SELECT * FROM missing", CategoryOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			err := ExecuteScript(context.Background(), db, tt.stmt)
+			if err == nil {
+				t.Fatal("ExecuteScript() expected error")
+			}
+			if got := Classify(err).Category; got != tt.want {
+				t.Errorf("Classify(%v) = %s, want %s", err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Execer executes a statement. *sql.DB, *sql.Conn, and *sql.Tx satisfy it.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Querier runs a query returning at most one row. *sql.DB, *sql.Conn, and
+// *sql.Tx satisfy it.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// txBeginner is an Execer that can start transactions: a *sql.DB or a
+// *sql.Conn, but not a *sql.Tx.
+type txBeginner interface {
+	Execer
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// ExecuteScriptOn executes a script like ExecuteScriptWithOptions on any
+// Execer. Passing a *sql.Tx runs the script inside the caller's transaction,
+// which the caller then commits or rolls back; passing a *sql.Conn pins every
+// statement to one connection, so they share temporary tables and session
+// settings.
+//
+// Transaction modes other than TransactionNone need a *sql.DB or *sql.Conn.
+// opts.Reconnect applies only to a *sql.DB: a pinned connection's session
+// state does not survive a reconnect.
+func ExecuteScriptOn(ctx context.Context, e Execer, script string, opts ExecuteOptions) error {
+	return (&Executor{db: e, opts: opts, split: SplitStatements}).Execute(ctx, script)
+}
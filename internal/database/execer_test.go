@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecuteScriptOnTx(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "tx.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	if err := ExecuteScriptOn(ctx, tx, "INSERT INTO t VALUES (1); INSERT INTO t VALUES (2);", ExecuteOptions{}); err != nil {
+		t.Fatalf("ExecuteScriptOn() error = %v", err)
+	}
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM t").Scan(&count); err != nil || count != 2 {
+		t.Fatalf("inside the transaction: %d rows, %v; want 2 rows", count, err)
+	}
+	// The caller owns the transaction, so rolling it back undoes the script.
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil || count != 0 {
+		t.Errorf("after rollback: %d rows, %v; want 0 rows", count, err)
+	}
+}
+
+func TestExecuteScriptOnConn(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "conn.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn() error = %v", err)
+	}
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+
+	// Temporary tables are per connection, so both scripts must run on the
+	// pinned one.
+	if err := ExecuteScriptOn(ctx, conn, "CREATE TEMP TABLE scratch (id INTEGER);", ExecuteOptions{}); err != nil {
+		t.Fatalf("ExecuteScriptOn() error = %v", err)
+	}
+	script := "INSERT INTO scratch VALUES (1); INSERT INTO scratch VALUES (2);"
+	if err := ExecuteScriptOn(ctx, conn, script, ExecuteOptions{Transaction: TransactionAll}); err != nil {
+		t.Fatalf("ExecuteScriptOn() error = %v", err)
+	}
+	var count int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM scratch").Scan(&count); err != nil || count != 2 {
+		t.Errorf("scratch has %d rows, %v; want 2 rows", count, err)
+	}
+	if err := CheckWritable(ctx, conn, "sqlite"); err != nil {
+		t.Errorf("CheckWritable() error = %v", err)
+	}
+}
+
+func TestExecuteScriptOnTxRejectsTransactionModes(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, mode := range []TransactionMode{TransactionPerStatement, TransactionAll} {
+		err := ExecuteScriptOn(ctx, tx, "SELECT 1;", ExecuteOptions{Transaction: mode})
+		if err == nil || !strings.Contains(err.Error(), "inside an existing transaction") {
+			t.Errorf("ExecuteScriptOn(%s) error = %v, want transaction mode rejected", mode, err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Executor runs scripts on one Execer with a fixed set of options. It is the
+// stable way to embed script execution: options are added as new Option
+// functions, and existing ones keep their meaning, so callers never need to
+// change when a capability is added. ExecuteScript and its variants remain
+// as shorthands.
+type Executor struct {
+	db      Execer
+	opts    ExecuteOptions
+	split   func(string) []string
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// Option configures an Executor.
+type Option func(*Executor)
+
+// Hooks are callbacks an Executor invokes around each statement. Any may be
+// nil.
+type Hooks struct {
+	// BeforeStatement is called before each statement; an error aborts the
+	// script without executing the statement.
+	BeforeStatement func(index int, stmt string) error
+	// AfterStatement is called after each statement completes with its
+	// execution time and rows affected, or -1 when the driver does not
+	// report one.
+	AfterStatement func(index int, stmt string, elapsed time.Duration, rows int64)
+	// OnReconnect is called before each reconnect attempt.
+	OnReconnect func(index, attempt int, err error)
+	// OnRetryTransaction is called before each rerun of a transaction when
+	// WithTransactionRetry is set.
+	OnRetryTransaction func(attempt int, wait time.Duration, err error)
+	// OnBatch is called after each batch commits when WithBatchSize is set,
+	// with the number of statements committed so far.
+	OnBatch func(batch, committed int)
+	// OnNotice is called with each notice or warning a PostgreSQL server
+	// sends while a statement runs, on connections opened by Connect.
+	OnNotice func(index int, n Notice)
+}
+
+// NewExecutor returns an Executor for db, which may be a *sql.DB, *sql.Conn,
+// or *sql.Tx as described for ExecuteScriptOn. By default statements are
+// split with SplitStatements and run in autocommit mode without timeouts.
+func NewExecutor(db Execer, opts ...Option) *Executor {
+	x := &Executor{db: db, split: SplitStatements}
+	for _, opt := range opts {
+		opt(x)
+	}
+	return x
+}
+
+// WithTransactionMode groups statements into transactions.
+func WithTransactionMode(mode TransactionMode) Option {
+	return func(x *Executor) { x.opts.Transaction = mode }
+}
+
+// WithSplitter replaces SplitStatements, for scripts in dialects it does not
+// understand or statements already split by the caller.
+func WithSplitter(split func(script string) []string) Option {
+	return func(x *Executor) { x.split = split }
+}
+
+// WithLogger logs each statement at debug level and each reconnect attempt
+// and transaction retry as a warning. Failures are returned, not logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(x *Executor) { x.logger = logger }
+}
+
+// WithHooks sets callbacks run around each statement.
+func WithHooks(hooks Hooks) Option {
+	return func(x *Executor) {
+		x.opts.BeforeStatement = hooks.BeforeStatement
+		x.opts.OnStatement = hooks.AfterStatement
+		x.opts.OnReconnect = hooks.OnReconnect
+		x.opts.OnRetryTransaction = hooks.OnRetryTransaction
+		x.opts.OnBatch = hooks.OnBatch
+		x.opts.OnNotice = hooks.OnNotice
+	}
+}
+
+// WithBatchSize commits every n statements, so a failure rolls back only
+// the batch it occurred in. It cannot be combined with a transaction mode.
+func WithBatchSize(n int) Option {
+	return func(x *Executor) { x.opts.BatchSize = n }
+}
+
+// WithTimeout bounds each call to Execute. A zero duration means no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(x *Executor) { x.timeout = d }
+}
+
+// WithStatementTimeout bounds each statement. A zero duration means no
+// limit.
+func WithStatementTimeout(d time.Duration) Option {
+	return func(x *Executor) { x.opts.StatementTimeout = d }
+}
+
+// WithReconnect retries statements that fail because the connection
+// dropped. It applies only to a *sql.DB.
+func WithReconnect(policy ReconnectPolicy) Option {
+	return func(x *Executor) { x.opts.Reconnect = policy }
+}
+
+// WithTxOptions sets the isolation level and read-only mode of the
+// transactions the script runs in.
+func WithTxOptions(opts *sql.TxOptions) Option {
+	return func(x *Executor) { x.opts.TxOptions = opts }
+}
+
+// WithTransactionRetry reruns a script in TransactionAll mode from its first
+// statement when its transaction hits a serialization failure or deadlock.
+func WithTransactionRetry(policy ReconnectPolicy) Option {
+	return func(x *Executor) { x.opts.RetryTransaction = policy }
+}
+
+// Execute runs a script. Failures of individual statements are reported as
+// *ExecError.
+func (x *Executor) Execute(ctx context.Context, script string) error {
+	opts := x.opts
+	if opts.Transaction != "" && opts.Transaction != TransactionNone {
+		if _, ok := x.db.(txBeginner); !ok {
+			return fmt.Errorf("transaction mode %q cannot be used on a %T; use %q inside an existing transaction", opts.Transaction, x.db, TransactionNone)
+		}
+	}
+	if opts.BatchSize > 0 {
+		if opts.Transaction != "" && opts.Transaction != TransactionNone {
+			return fmt.Errorf("batches cannot be combined with transaction mode %q", opts.Transaction)
+		}
+		if _, ok := x.db.(txBeginner); !ok {
+			return fmt.Errorf("batches cannot be used on a %T; commit the existing transaction instead", x.db)
+		}
+	}
+	if _, ok := x.db.(*sql.DB); !ok {
+		opts.Reconnect = ReconnectPolicy{}
+	}
+	if x.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, x.timeout,
+			fmt.Errorf("script timeout of %s exceeded: %w", x.timeout, context.DeadlineExceeded))
+		defer cancel()
+	}
+	if x.logger != nil {
+		opts.OnStatement = x.logStatement(opts.OnStatement)
+		opts.OnReconnect = x.logReconnect(opts.OnReconnect)
+		opts.OnBatch = x.logBatch(opts.OnBatch)
+		opts.OnRetryTransaction = x.logRetryTransaction(opts.OnRetryTransaction)
+	}
+	return executeStatements(ctx, x.db, x.split(script), opts)
+}
+
+func (x *Executor) logStatement(next func(int, string, time.Duration, int64)) func(int, string, time.Duration, int64) {
+	return func(index int, stmt string, elapsed time.Duration, rows int64) {
+		x.logger.Debug("statement executed", "statement", index, "verb", DescribeStatement(stmt).Verb,
+			"duration", elapsed, "rows_affected", rows)
+		if next != nil {
+			next(index, stmt, elapsed, rows)
+		}
+	}
+}
+
+func (x *Executor) logReconnect(next func(int, int, error)) func(int, int, error) {
+	return func(index, attempt int, err error) {
+		x.logger.Warn("connection lost; reconnecting", "statement", index, "attempt", attempt, "error", err)
+		if next != nil {
+			next(index, attempt, err)
+		}
+	}
+}
+
+func (x *Executor) logRetryTransaction(next func(int, time.Duration, error)) func(int, time.Duration, error) {
+	return func(attempt int, wait time.Duration, err error) {
+		x.logger.Warn("transaction failed; retrying", "attempt", attempt, "wait", wait, "error", err)
+		if next != nil {
+			next(attempt, wait, err)
+		}
+	}
+}
+
+func (x *Executor) logBatch(next func(int, int)) func(int, int) {
+	return func(batch, committed int) {
+		x.logger.Debug("batch committed", "batch", batch, "committed", committed)
+		if next != nil {
+			next(batch, committed)
+		}
+	}
+}
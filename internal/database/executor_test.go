@@ -0,0 +1,109 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecutor(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+	// Each connection to :memory: is a separate database.
+	db.SetMaxOpenConns(1)
+
+	var (
+		logs  bytes.Buffer
+		calls []string
+	)
+	x := NewExecutor(db,
+		WithTransactionMode(TransactionAll),
+		// Split on lines, so semicolons inside a statement are left alone.
+		WithSplitter(func(script string) []string { return strings.Split(script, "\n") }),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		WithHooks(Hooks{
+			BeforeStatement: func(_ int, _ string) error {
+				calls = append(calls, "before")
+				return nil
+			},
+			AfterStatement: func(_ int, _ string, _ time.Duration, _ int64) {
+				calls = append(calls, "after")
+			},
+		}),
+	)
+	if err := x.Execute(context.Background(), "CREATE TABLE t (v TEXT)\nINSERT INTO t VALUES ('a;b')"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := strings.Join(calls, ","); got != "before,after,before,after" {
+		t.Errorf("hooks called as %s, want before,after twice", got)
+	}
+	if !strings.Contains(logs.String(), "statement=2 verb=INSERT") || !strings.Contains(logs.String(), "rows_affected=1") {
+		t.Errorf("log output missing the INSERT statement:\n%s", logs.String())
+	}
+	var v string
+	if err := db.QueryRow("SELECT v FROM t").Scan(&v); err != nil || v != "a;b" {
+		t.Errorf("t.v = %q, %v; want a;b", v, err)
+	}
+}
+
+func TestExecutorTimeout(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+
+	slow := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n;"
+	err = NewExecutor(db, WithTimeout(50*time.Millisecond)).Execute(context.Background(), slow)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "script timeout of 50ms exceeded") {
+		t.Errorf("Execute() error = %v, want the script timeout", err)
+	}
+}
+
+func TestExecutorBatchSizeValidation(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	tests := []struct {
+		name string
+		x    *Executor
+	}{
+		{"with a transaction mode", NewExecutor(db, WithBatchSize(10), WithTransactionMode(TransactionAll))},
+		{"inside a transaction", NewExecutor(tx, WithBatchSize(10))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.x.Execute(context.Background(), "SELECT 1"); err == nil || !strings.Contains(err.Error(), "batches cannot") {
+				t.Errorf("Execute() error = %v, want a batches error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// extensionFunctions maps functions and types commonly used without their
+// extension installed to the extension that provides them.
+var extensionFunctions = map[string]string{
+	"gen_random_bytes":   "pgcrypto",
+	"crypt":              "pgcrypto",
+	"gen_salt":           "pgcrypto",
+	"digest":             "pgcrypto",
+	"hmac":               "pgcrypto",
+	"pgp_sym_encrypt":    "pgcrypto",
+	"uuid_generate_v1":   "uuid-ossp",
+	"uuid_generate_v4":   "uuid-ossp",
+	"similarity":         "pg_trgm",
+	"unaccent":           "unaccent",
+	"citext":             "citext",
+	"hstore":             "hstore",
+	"vector":             "vector",
+	"geometry":           "postgis",
+	"geography":          "postgis",
+	"st_makepoint":       "postgis",
+	"st_geomfromtext":    "postgis",
+	"levenshtein":        "fuzzystrmatch",
+	"earth_distance":     "earthdistance",
+	"pg_stat_statements": "pg_stat_statements",
+}
+
+var (
+	functionPattern = regexp.MustCompile(`function ([\w.]+)\(`)
+	typePattern     = regexp.MustCompile(`type "([\w.]+)" does not exist`)
+	relationPattern = regexp.MustCompile(`(?:relation "([^"]+)" does not exist|no such table: ([\w.]+))`)
+)
+
+// Hint returns a targeted suggestion for a recognizable statement failure,
+// such as a missing extension or an unqualified table, or "" if there is
+// none.
+func Hint(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := err.Error()
+	lower := strings.ToLower(msg)
+	var code string
+	var coded interface{ SQLState() string }
+	if errors.As(err, &coded) {
+		code = coded.SQLState()
+	}
+
+	switch {
+	case code == "42883" || strings.Contains(lower, "no such function"):
+		if m := functionPattern.FindStringSubmatch(lower); m != nil {
+			if ext := extensionFor(m[1]); ext != "" {
+				return "function " + m[1] + " is provided by an extension: run CREATE EXTENSION IF NOT EXISTS \"" + ext + "\" or add it to the top of the script"
+			}
+		}
+		return "check the function name and argument types; casts may be needed for untyped literals, or the extension providing it may not be installed"
+	case code == "42704":
+		if m := typePattern.FindStringSubmatch(lower); m != nil {
+			if ext := extensionFor(m[1]); ext != "" {
+				return "type " + m[1] + " is provided by an extension: run CREATE EXTENSION IF NOT EXISTS \"" + ext + "\" or add it to the top of the script"
+			}
+		}
+	case code == "58P01" || (code == "0A000" && strings.Contains(lower, "extension")):
+		return "the extension is not installed on the server; install its package (e.g. postgresql-contrib) or ask the database administrator"
+	case code == "42P01" || strings.Contains(lower, "no such table"):
+		name := ""
+		if m := relationPattern.FindStringSubmatch(msg); m != nil {
+			name = m[1] + m[2]
+		}
+		if code == "42P01" && name != "" && !strings.Contains(name, ".") {
+			return "table " + name + " was not found on the search_path: schema-qualify it (e.g. app." + name + "), set search_path, or check that it was created earlier"
+		}
+		return "check the table name and that it was created before this statement"
+	case code == "3F000":
+		return "the schema does not exist: run CREATE SCHEMA first or check the schema name"
+	case code == "22021" || code == "22P05" || strings.Contains(lower, "invalid byte sequence"):
+		return "encoding mismatch: make sure the input is UTF-8 and the database and client_encoding match (e.g. SET client_encoding = 'UTF8')"
+	case code == "42501":
+		return "the role lacks a privilege: run sql-loader doctor or -preflight-privileges to list what is missing"
+	}
+	return ""
+}
+
+func extensionFor(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return extensionFunctions[name]
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// pgError mimics a pgconn.PgError with a message and SQLSTATE.
+type pgError struct {
+	msg  string
+	code string
+}
+
+func (e pgError) Error() string    { return "ERROR: " + e.msg + " (SQLSTATE " + e.code + ")" }
+func (e pgError) SQLState() string { return e.code }
+
+func TestHint(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"unrelated", errors.New("boom"), ""},
+		{"missing pgcrypto", pgError{"function gen_salt(unknown) does not exist", "42883"}, `CREATE EXTENSION IF NOT EXISTS "pgcrypto"`},
+		{"missing uuid-ossp", &ExecError{Index: 1, Total: 1, Err: pgError{"function uuid_generate_v4() does not exist", "42883"}}, `"uuid-ossp"`},
+		{"unknown function", pgError{"function frobnicate(integer) does not exist", "42883"}, "check the function name"},
+		{"missing type", pgError{`type "citext" does not exist`, "42704"}, `"citext"`},
+		{"unqualified table", pgError{`relation "orders" does not exist`, "42P01"}, "app.orders"},
+		{"qualified table", pgError{`relation "app.orders" does not exist`, "42P01"}, "created before this statement"},
+		{"sqlite table", errors.New("SQL logic error: no such table: orders (1)"), "created before this statement"},
+		{"missing schema", pgError{`schema "app" does not exist`, "3F000"}, "CREATE SCHEMA"},
+		{"encoding", pgError{`invalid byte sequence for encoding "UTF8": 0xe9 0x20 0x6c`, "22021"}, "client_encoding"},
+		{"privilege", pgError{"permission denied for table orders", "42501"}, "doctor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hint(tt.err)
+			if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+				t.Errorf("Hint() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// IdentifierPolicy controls how names taken from input files, such as CSV
+// headers, become table and column names.
+type IdentifierPolicy string
+
+// Identifier policies.
+const (
+	// IdentifierQuote uses names verbatim as quoted identifiers, so their
+	// case and punctuation are kept exactly.
+	IdentifierQuote IdentifierPolicy = "quote"
+	// IdentifierPreserve treats names as if written unquoted in SQL:
+	// PostgreSQL folds plain names to lower case. Names that cannot be
+	// written unquoted are kept verbatim.
+	IdentifierPreserve IdentifierPolicy = "preserve"
+	// IdentifierSnake converts names to lower_snake_case.
+	IdentifierSnake IdentifierPolicy = "snake"
+)
+
+// ParseIdentifierPolicy validates a user-supplied policy name.
+func ParseIdentifierPolicy(name string) (IdentifierPolicy, error) {
+	switch IdentifierPolicy(name) {
+	case IdentifierQuote, IdentifierPreserve, IdentifierSnake:
+		return IdentifierPolicy(name), nil
+	default:
+		return "", fmt.Errorf("unsupported identifier policy %q (use quote, preserve, or snake)", name)
+	}
+}
+
+// Name applies the policy to a single identifier for the driver.
+func (p IdentifierPolicy) Name(driver, name string) string {
+	switch p {
+	case IdentifierSnake:
+		return snakeCase(name)
+	case IdentifierPreserve:
+		// SQLite compares identifiers case-insensitively, so folding
+		// changes nothing there.
+		if driver == "postgres" && isPlainIdentifier(name) {
+			return strings.ToLower(name)
+		}
+	}
+	return name
+}
+
+// Qualified applies the policy to each part of a possibly schema-qualified
+// name such as "Sales.OrderLines".
+func (p IdentifierPolicy) Qualified(driver, name string) string {
+	parts := SplitQualified(name)
+	for i, part := range parts {
+		parts[i] = p.Name(driver, part)
+	}
+	return JoinQualified(parts)
+}
+
+// isPlainIdentifier reports whether name can be written unquoted.
+func isPlainIdentifier(name string) bool {
+	for i, c := range name {
+		if !(c == '_' || unicode.IsLetter(c) || i > 0 && (unicode.IsDigit(c) || c == '$')) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// snakeCase converts name to lower_snake_case: "OrderID" and "Order Id"
+// both become "order_id", and "HTTPStatus" becomes "http_status". Names
+// with no letters or digits are returned unchanged.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	pending := false
+	for i, c := range runes {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			pending = b.Len() > 0
+			continue
+		}
+		if unicode.IsUpper(c) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				pending = b.Len() > 0
+			}
+		}
+		if pending {
+			b.WriteByte('_')
+			pending = false
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	if b.Len() == 0 {
+		return name
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package database
+
+import "testing"
+
+func TestIdentifierPolicy(t *testing.T) {
+	tests := []struct {
+		policy IdentifierPolicy
+		driver string
+		in     string
+		want   string
+	}{
+		{policy: IdentifierQuote, driver: "postgres", in: "OrderID", want: "OrderID"},
+		{policy: IdentifierPreserve, driver: "postgres", in: "OrderID", want: "orderid"},
+		{policy: IdentifierPreserve, driver: "postgres", in: "Order ID", want: "Order ID"},
+		{policy: IdentifierPreserve, driver: "sqlite", in: "OrderID", want: "OrderID"},
+		{policy: IdentifierSnake, driver: "postgres", in: "OrderID", want: "order_id"},
+		{policy: IdentifierSnake, driver: "sqlite", in: "Order Id", want: "order_id"},
+		{policy: IdentifierSnake, driver: "postgres", in: "HTTPStatus", want: "http_status"},
+		{policy: IdentifierSnake, driver: "postgres", in: "firstName", want: "first_name"},
+		{policy: IdentifierSnake, driver: "postgres", in: " unit-price (EUR) ", want: "unit_price_eur"},
+		{policy: IdentifierSnake, driver: "postgres", in: "already_snake", want: "already_snake"},
+		{policy: IdentifierSnake, driver: "postgres", in: "#", want: "#"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy)+" "+tt.in, func(t *testing.T) {
+			if got := tt.policy.Name(tt.driver, tt.in); got != tt.want {
+				t.Errorf("Name(%s, %q) = %q, want %q", tt.driver, tt.in, got, tt.want)
+			}
+		})
+	}
+
+	if got := IdentifierSnake.Qualified("postgres", "Sales.OrderLines"); got != "sales.order_lines" {
+		t.Errorf("Qualified() = %q, want sales.order_lines", got)
+	}
+	if _, err := ParseIdentifierPolicy("upper"); err == nil {
+		t.Error("ParseIdentifierPolicy(upper) expected error")
+	}
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Notice is a non-fatal message the server sent while running a statement,
+// such as a PostgreSQL WARNING about a truncated value or a RAISE NOTICE.
+type Notice struct {
+	// Severity is WARNING, NOTICE, INFO, LOG, or DEBUG.
+	Severity string `json:"severity"`
+	// Code is the SQLSTATE of the message.
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// String formats n on one line, as psql prints it.
+func (n Notice) String() string {
+	s := n.Severity + ": " + n.Message
+	if n.Detail != "" {
+		s += " DETAIL: " + n.Detail
+	}
+	if n.Hint != "" {
+		s += " HINT: " + n.Hint
+	}
+	return s
+}
+
+// noticeHandlers maps the PostgreSQL connections running a script with
+// ExecuteOptions.OnNotice to the handler of the statement they are running.
+// pgx delivers notices per connection, set up when it connects, so
+// connections opened by Connect hand theirs to routeNotice.
+var noticeHandlers sync.Map // *pgconn.PgConn -> func(Notice)
+
+func routeNotice(c *pgconn.PgConn, n *pgconn.Notice) {
+	if h, ok := noticeHandlers.Load(c); ok {
+		h.(func(Notice))(Notice{Severity: n.Severity, Code: n.Code, Message: n.Message, Detail: n.Detail, Hint: n.Hint})
+	}
+}
+
+// withNotices calls fn with e, delivering the notices its connection
+// receives meanwhile to handler. A *sql.DB is pinned to one of its
+// connections for the call. Connections whose driver is not the PostgreSQL
+// one, and a *sql.Tx, whose connection is out of reach, report none.
+func withNotices(ctx context.Context, e Execer, handler func(Notice), fn func(Execer) error) error {
+	if handler == nil {
+		return fn(e)
+	}
+	if db, ok := e.(*sql.DB); ok {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = conn.Close() }()
+		e = conn
+	}
+	c, ok := e.(rawConn)
+	if !ok {
+		return fn(e)
+	}
+	var pg *pgconn.PgConn
+	_ = c.Raw(func(driverConn any) error {
+		if pc, ok := driverConn.(*stdlib.Conn); ok {
+			pg = pc.Conn().PgConn()
+		}
+		return nil
+	})
+	if pg == nil {
+		return fn(e)
+	}
+	noticeHandlers.Store(pg, handler)
+	defer noticeHandlers.Delete(pg)
+	return fn(e)
+}
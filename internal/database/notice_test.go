@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestNoticeString(t *testing.T) {
+	tests := []struct {
+		name   string
+		notice Notice
+		want   string
+	}{
+		{name: "message only", notice: Notice{Severity: "NOTICE", Message: "loaded 10 rows"}, want: "NOTICE: loaded 10 rows"},
+		{
+			name:   "detail and hint",
+			notice: Notice{Severity: "WARNING", Message: "value truncated", Detail: "column note", Hint: "widen it"},
+			want:   "WARNING: value truncated DETAIL: column note HINT: widen it",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.notice.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteNotice(t *testing.T) {
+	listening, other := &pgconn.PgConn{}, &pgconn.PgConn{}
+	var got []Notice
+	noticeHandlers.Store(listening, func(n Notice) { got = append(got, n) })
+	defer noticeHandlers.Delete(listening)
+
+	routeNotice(listening, &pgconn.Notice{Severity: "WARNING", Code: "01000", Message: "careful"})
+	routeNotice(other, &pgconn.Notice{Severity: "NOTICE", Message: "not for this script"})
+	if len(got) != 1 || got[0] != (Notice{Severity: "WARNING", Code: "01000", Message: "careful"}) {
+		t.Errorf("routed notices = %+v, want only the one for the listening connection", got)
+	}
+}
+
+func TestExecuteScriptOnNoticeWithoutPostgres(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	for _, mode := range []TransactionMode{TransactionNone, TransactionPerStatement, TransactionAll} {
+		t.Run(string(mode), func(t *testing.T) {
+			statements := 0
+			opts := ExecuteOptions{
+				Transaction: mode,
+				OnStatement: func(int, string, time.Duration, int64) { statements++ },
+				OnNotice:    func(index int, n Notice) { t.Errorf("unexpected notice %d: %v", index, n) },
+			}
+			script := "CREATE TABLE IF NOT EXISTS t (id INTEGER); INSERT INTO t VALUES (1);"
+			if err := ExecuteScriptOn(context.Background(), db, script, opts); err != nil {
+				t.Fatalf("ExecuteScriptOn() error = %v", err)
+			}
+			if statements != 2 {
+				t.Errorf("%d statements ran, want 2", statements)
+			}
+		})
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("%d connections still in use", n)
+	}
+}
+
+func TestConnectRejectsInvalidPostgresDSN(t *testing.T) {
+	_, err := Connect(context.Background(), "postgres", "postgres://db:notaport/app")
+	if err == nil || !strings.Contains(err.Error(), "failed to open database") {
+		t.Errorf("Connect() error = %v, want a failure to open", err)
+	}
+}
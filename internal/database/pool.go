@@ -0,0 +1,16 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// FromPool returns a *sql.DB that runs statements on connections borrowed
+// from an application's pgx pool, so scripts share its configuration,
+// tracer, and connection limits. Closing the returned DB does not close the
+// pool.
+func FromPool(pool *pgxpool.Pool) *sql.DB {
+	return stdlib.OpenDBFromPool(pool)
+}
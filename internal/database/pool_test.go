@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestFromPool(t *testing.T) {
+	// Nothing listens on port 1, so every connection attempt is refused
+	// without needing a server.
+	pool, err := pgxpool.New(context.Background(), "postgres://loader@127.0.0.1:1/app?connect_timeout=1")
+	if err != nil {
+		t.Fatalf("pgxpool.New() error = %v", err)
+	}
+	defer pool.Close()
+
+	for i := 0; i < 2; i++ {
+		db := FromPool(pool)
+		err := ExecuteScript(context.Background(), db, "SELECT 1;")
+		var execErr *ExecError
+		if !errors.As(err, &execErr) || !execErr.Disconnected {
+			t.Fatalf("ExecuteScript() error = %v, want a connection error from the pool", err)
+		}
+		// Closing the DB must leave the pool usable for the next round.
+		if err := db.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+}
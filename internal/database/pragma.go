@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pragma is a SQLite PRAGMA setting, such as journal_mode=WAL.
+type Pragma struct {
+	Name, Value string
+}
+
+// FastPragmas are the settings of the fast preset for bulk loads into
+// SQLite: a write-ahead log, which syncs to disk at checkpoints rather than
+// every commit, and enforced foreign keys, which SQLite leaves off by
+// default.
+var FastPragmas = []Pragma{
+	{Name: "journal_mode", Value: "WAL"},
+	{Name: "synchronous", Value: "NORMAL"},
+	{Name: "foreign_keys", Value: "ON"},
+}
+
+// ParsePragma parses a name=value setting. Names are identifiers and values
+// keywords or numbers, so that a setting cannot inject other parameters
+// into the DSN.
+func ParsePragma(s string) (Pragma, error) {
+	name, value, ok := strings.Cut(s, "=")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if !ok || name == "" || value == "" {
+		return Pragma{}, fmt.Errorf("invalid pragma %q (use name=value, e.g. journal_mode=WAL)", s)
+	}
+	if !isPragmaWord(name) {
+		return Pragma{}, fmt.Errorf("invalid pragma name %q", name)
+	}
+	if !isPragmaWord(strings.TrimPrefix(value, "-")) {
+		return Pragma{}, fmt.Errorf("invalid value %q for pragma %s (use a keyword or number)", value, name)
+	}
+	return Pragma{Name: strings.ToLower(name), Value: value}, nil
+}
+
+// isPragmaWord reports whether s is a non-empty run of ASCII letters,
+// digits, and underscores.
+func isPragmaWord(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c != '_' && (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// MergePragmas returns base with each of overrides replacing the setting of
+// the same name, or appended when base has none.
+func MergePragmas(base, overrides []Pragma) []Pragma {
+	merged := append([]Pragma(nil), base...)
+	for _, o := range overrides {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == o.Name {
+				merged[i], replaced = o, true
+			}
+		}
+		if !replaced {
+			merged = append(merged, o)
+		}
+	}
+	return merged
+}
+
+// PragmaDSN returns a SQLite dsn that applies pragmas, in order, to every
+// connection as it opens, before anything else runs on it.
+func PragmaDSN(dsn string, pragmas []Pragma) string {
+	for _, p := range pragmas {
+		dsn = appendQuery(dsn, "_pragma="+p.Name+"("+p.Value+")")
+	}
+	return dsn
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePragma(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Pragma
+		wantErr bool
+	}{
+		{in: "journal_mode=WAL", want: Pragma{Name: "journal_mode", Value: "WAL"}},
+		{in: " Cache_Size = -64000 ", want: Pragma{Name: "cache_size", Value: "-64000"}},
+		{in: "synchronous", wantErr: true},
+		{in: "=OFF", wantErr: true},
+		{in: "journal_mode=WAL)&_pragma=query_only(0", wantErr: true},
+		{in: "busy_timeout=5s", want: Pragma{Name: "busy_timeout", Value: "5s"}},
+		{in: "foreign-keys=ON", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePragma(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePragma() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePragma() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePragmas(t *testing.T) {
+	got := MergePragmas(FastPragmas, []Pragma{{Name: "synchronous", Value: "OFF"}, {Name: "cache_size", Value: "-64000"}})
+	want := []Pragma{
+		{Name: "journal_mode", Value: "WAL"},
+		{Name: "synchronous", Value: "OFF"},
+		{Name: "foreign_keys", Value: "ON"},
+		{Name: "cache_size", Value: "-64000"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergePragmas() = %+v, want %+v", got, want)
+	}
+	if FastPragmas[1].Value != "NORMAL" {
+		t.Error("MergePragmas() modified its base")
+	}
+}
+
+func TestPragmaDSN(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "fast.db")
+	db, err := Connect(ctx, "sqlite", PragmaDSN(path, FastPragmas))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Every connection of the pool gets the settings, not just the first.
+	for i := 0; i < 2; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn() error = %v", err)
+		}
+		defer func() { _ = conn.Close() }()
+		var (
+			mode string
+			sync int
+			fks  bool
+		)
+		_ = conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode)
+		_ = conn.QueryRowContext(ctx, "PRAGMA synchronous").Scan(&sync)
+		_ = conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&fks)
+		if !strings.EqualFold(mode, "wal") || sync != 1 || !fks {
+			t.Errorf("connection %d: journal_mode %q, synchronous %d, foreign_keys %v; want wal, 1, true", i+1, mode, sync, fks)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FindPrimary returns the first of dsns whose target accepts writes, for
+// drivers without native multi-host support or when each host needs its own
+// DSN. PostgreSQL DSNs may instead list several hosts themselves
+// (host=a,b,c target_session_attrs=read-write), which the driver handles.
+func FindPrimary(ctx context.Context, driver string, dsns []string) (string, error) {
+	if len(dsns) == 1 {
+		return dsns[0], nil
+	}
+
+	var problems []string
+	for _, dsn := range dsns {
+		db, err := Connect(ctx, driver, dsn)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", RedactDSN(dsn), err))
+			continue
+		}
+		err = CheckWritable(ctx, db, driver)
+		if closeErr := db.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			return dsn, nil
+		}
+		problems = append(problems, fmt.Sprintf("%s: %v", RedactDSN(dsn), err))
+	}
+	return "", fmt.Errorf("no writable primary among %d hosts:\n  %s", len(dsns), strings.Join(problems, "\n  "))
+}
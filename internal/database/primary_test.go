@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindPrimary(t *testing.T) {
+	dir := t.TempDir()
+	replica := ReadOnlyDSN("sqlite", filepath.Join(dir, "replica.db"))
+	primary := filepath.Join(dir, "primary.db")
+	missing := filepath.Join(dir, "missing", "gone.db")
+
+	got, err := FindPrimary(context.Background(), "sqlite", []string{missing, replica, primary})
+	if err != nil {
+		t.Fatalf("FindPrimary() error = %v", err)
+	}
+	if got != primary {
+		t.Errorf("FindPrimary() = %q, want %q", got, primary)
+	}
+
+	_, err = FindPrimary(context.Background(), "sqlite", []string{missing, replica})
+	if err == nil {
+		t.Fatal("FindPrimary() without a primary expected error")
+	}
+	if !strings.Contains(err.Error(), "no writable primary among 2 hosts") || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("FindPrimary() error = %v", err)
+	}
+
+	if got, err := FindPrimary(context.Background(), "sqlite", []string{missing}); err != nil || got != missing {
+		t.Errorf("FindPrimary() with one DSN = %q, %v, want it returned unprobed", got, err)
+	}
+}
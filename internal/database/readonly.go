@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrReadOnly reports that the target database does not accept writes.
+var ErrReadOnly = errors.New("target database is read-only")
+
+// CheckWritable returns an error wrapping ErrReadOnly when the target cannot
+// accept writes: a PostgreSQL hot standby, a session with
+// transaction_read_only on, or a SQLite connection with query_only set.
+// Checking at connect time gives a clear error instead of a confusing
+// failure on the first write. q may be a *sql.Conn or *sql.Tx, whose
+// session settings are then checked.
+func CheckWritable(ctx context.Context, q Querier, driver string) error {
+	switch driver {
+	case "postgres":
+		var (
+			inRecovery bool
+			readOnly   string
+		)
+		if err := q.QueryRowContext(ctx, "SELECT pg_is_in_recovery(), current_setting('transaction_read_only')").
+			Scan(&inRecovery, &readOnly); err != nil {
+			return fmt.Errorf("failed to check read-only status: %w", err)
+		}
+		if inRecovery {
+			return fmt.Errorf("%w: server is a hot standby replica", ErrReadOnly)
+		}
+		if readOnly == "on" {
+			return fmt.Errorf("%w: transaction_read_only = on", ErrReadOnly)
+		}
+	case "sqlite":
+		var queryOnly bool
+		if err := q.QueryRowContext(ctx, "PRAGMA query_only").Scan(&queryOnly); err != nil {
+			return fmt.Errorf("failed to check read-only status: %w", err)
+		}
+		if queryOnly {
+			return fmt.Errorf("%w: query_only is set", ErrReadOnly)
+		}
+	}
+	return nil
+}
+
+// ReadOnlyDSN returns dsn with the session forced read-only, so that the
+// server itself rejects any write a query script attempts. PostgreSQL
+// sessions get default_transaction_read_only=on and SQLite connections get
+// query_only.
+func ReadOnlyDSN(driver, dsn string) string {
+	switch driver {
+	case "postgres":
+		if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+			return appendQuery(dsn, "default_transaction_read_only=on")
+		}
+		return strings.TrimSpace(dsn) + " default_transaction_read_only=on"
+	case "sqlite":
+		return appendQuery(dsn, "_pragma=query_only(1)")
+	}
+	return dsn
+}
+
+func appendQuery(dsn, param string) string {
+	if strings.Contains(dsn, "?") {
+		return dsn + "&" + param
+	}
+	return dsn + "?" + param
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckWritableSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+
+	db, err := Connect(context.Background(), "sqlite", path)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := CheckWritable(context.Background(), db, "sqlite"); err != nil {
+		t.Errorf("CheckWritable() error = %v, want nil", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	ro, err := Connect(context.Background(), "sqlite", ReadOnlyDSN("sqlite", path))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer func() { _ = ro.Close() }()
+	if err := CheckWritable(context.Background(), ro, "sqlite"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CheckWritable() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := ro.Exec("CREATE TABLE t (id INTEGER)"); err == nil {
+		t.Error("write on read-only connection succeeded")
+	}
+}
+
+func TestReadOnlyDSN(t *testing.T) {
+	tests := []struct {
+		driver string
+		dsn    string
+		want   string
+	}{
+		{"postgres", "postgres://u@h/db", "postgres://u@h/db?default_transaction_read_only=on"},
+		{"postgres", "postgresql://u@h/db?sslmode=disable", "postgresql://u@h/db?sslmode=disable&default_transaction_read_only=on"},
+		{"postgres", "host=h dbname=db ", "host=h dbname=db default_transaction_read_only=on"},
+		{"sqlite", "app.db", "app.db?_pragma=query_only(1)"},
+		{"sqlite", "file:app.db?cache=shared", "file:app.db?cache=shared&_pragma=query_only(1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			if got := ReadOnlyDSN(tt.driver, tt.dsn); got != tt.want {
+				t.Errorf("ReadOnlyDSN() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// ReconnectPolicy controls how ExecuteScriptWithOptions recovers from a
+// dropped connection. The zero value aborts on the first connection error.
+type ReconnectPolicy struct {
+	// Attempts is the maximum number of reconnects per statement.
+	Attempts int
+	// Backoff is the wait before the first reconnect; it doubles after each
+	// failed attempt up to MaxBackoff. Defaults to one second.
+	Backoff time.Duration
+	// MaxBackoff caps the wait between attempts. Defaults to 30 seconds.
+	MaxBackoff time.Duration
+}
+
+func (p ReconnectPolicy) delays() (initial, limit time.Duration) {
+	initial, limit = p.Backoff, p.MaxBackoff
+	if initial <= 0 {
+		initial = time.Second
+	}
+	if limit <= 0 {
+		limit = 30 * time.Second
+	}
+	return initial, limit
+}
+
+// ConnectWithRetry is Connect, retried with exponential backoff and jitter
+// up to policy.Attempts times while the server cannot be reached or is still
+// starting up, as when a container starts before its database is ready.
+// Other failures, such as a rejected password, are returned at once.
+// onRetry, if set, is called before each wait.
+func ConnectWithRetry(ctx context.Context, driverName, dsn string, policy ReconnectPolicy, onRetry func(attempt int, wait time.Duration, err error)) (*sql.DB, error) {
+	backoff, limit := policy.delays()
+	for attempt := 1; ; attempt++ {
+		db, err := Connect(ctx, driverName, dsn)
+		if err == nil || !retryableConnectError(ctx, err) || attempt > policy.Attempts {
+			return db, err
+		}
+		wait := jitter(backoff)
+		if onRetry != nil {
+			onRetry(attempt, wait, err)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to ping database: %w", context.Cause(ctx))
+		}
+		backoff = min(backoff*2, limit)
+	}
+}
+
+// retryableConnectError reports whether a failed connect may succeed later:
+// a connection error, or an attempt that timed out, as with connect_timeout,
+// while ctx is still live.
+func retryableConnectError(ctx context.Context, err error) bool {
+	return IsConnectionError(err) || (ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded))
+}
+
+// jitter returns a random duration between d/2 and d, so that replicas
+// started together do not retry in lockstep.
+func jitter(d time.Duration) time.Duration {
+	return d/2 + rand.N(d/2+1) // #nosec G404 -- jitter needs no cryptographic randomness
+}
+
+// IsConnectionError reports whether err means the connection to the server
+// was lost, as opposed to the server rejecting a statement. Cancellation and
+// timeouts are not connection errors, although context.DeadlineExceeded
+// satisfies net.Error.
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var coded interface{ SQLState() string }
+	if errors.As(err, &coded) {
+		return classifySQLState(coded.SQLState()) == CategoryConnection
+	}
+	return false
+}
+
+// WaitReady blocks until the database accepts connections and answers a
+// trivial query, checking every interval until ctx is done. As with
+// ConnectWithRetry, errors other than connection errors are returned at once.
+// onRetry, if set, is called with each connection error before waiting.
+func WaitReady(ctx context.Context, driverName, dsn string, interval time.Duration, onRetry func(err error)) error {
+	policy := ReconnectPolicy{Attempts: math.MaxInt, Backoff: interval, MaxBackoff: interval}
+	var last error
+	retry := func(_ int, _ time.Duration, err error) {
+		last = err
+		if onRetry != nil {
+			onRetry(err)
+		}
+	}
+	for {
+		db, err := ConnectWithRetry(ctx, driverName, dsn, policy, retry)
+		if err == nil {
+			var one int
+			err = db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+			if closeErr := db.Close(); err == nil && closeErr != nil {
+				err = fmt.Errorf("failed to close database: %w", closeErr)
+			}
+			if err == nil {
+				return nil
+			}
+			err = fmt.Errorf("failed to query database: %w", err)
+		}
+		if ctx.Err() != nil {
+			if last == nil {
+				return err
+			}
+			return fmt.Errorf("%w (last error: %v)", context.Cause(ctx), last)
+		}
+		if !IsConnectionError(err) {
+			return err
+		}
+		retry(0, 0, err)
+		select {
+		case <-time.After(jitter(interval)):
+		case <-ctx.Done():
+		}
+	}
+}
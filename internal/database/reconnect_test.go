@@ -0,0 +1,236 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyConnector is a database/sql driver whose connection drops on the
+// configured executions, counted from 1.
+type flakyConnector struct {
+	mu    sync.Mutex
+	drops map[int]bool
+	execs int
+	ran   []string
+}
+
+func (c *flakyConnector) Connect(context.Context) (driver.Conn, error) { return &flakyConn{c: c}, nil }
+func (c *flakyConnector) Driver() driver.Driver                        { return nil }
+
+type flakyConn struct {
+	c   *flakyConnector
+	bad bool
+}
+
+func (f *flakyConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (f *flakyConn) Close() error                        { return nil }
+func (f *flakyConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (f *flakyConn) IsValid() bool                       { return !f.bad }
+
+func (f *flakyConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	f.c.mu.Lock()
+	defer f.c.mu.Unlock()
+	f.c.execs++
+	if f.c.drops[f.c.execs] {
+		f.bad = true
+		return nil, io.ErrUnexpectedEOF
+	}
+	f.c.ran = append(f.c.ran, query)
+	return driver.RowsAffected(1), nil
+}
+
+func TestExecuteScriptReconnect(t *testing.T) {
+	tests := []struct {
+		name     string
+		drops    map[int]bool
+		attempts int
+		wantRan  int
+		wantErr  string
+	}{
+		{
+			name:    "no policy aborts with progress",
+			drops:   map[int]bool{2: true},
+			wantRan: 1,
+			wantErr: "connection lost at statement 2 of 3 (1 completed; statement 2 may or may not have been applied): unexpected EOF",
+		},
+		{
+			name:     "resumes at interrupted statement",
+			drops:    map[int]bool{2: true, 3: true},
+			attempts: 2,
+			wantRan:  3,
+		},
+		{
+			name:     "gives up after attempts",
+			drops:    map[int]bool{3: true, 4: true, 5: true},
+			attempts: 2,
+			wantRan:  2,
+			wantErr:  "connection lost at statement 3 of 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &flakyConnector{drops: tt.drops}
+			db := sql.OpenDB(c)
+			defer func() { _ = db.Close() }()
+
+			var reconnects int
+			err := ExecuteScriptWithOptions(context.Background(), db, "INSERT 1; INSERT 2; INSERT 3;", ExecuteOptions{
+				Reconnect:   ReconnectPolicy{Attempts: tt.attempts, Backoff: time.Millisecond},
+				OnReconnect: func(int, int, error) { reconnects++ },
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("ExecuteScriptWithOptions() error = %v", err)
+			}
+			if tt.wantErr != "" {
+				var execErr *ExecError
+				if !errors.As(err, &execErr) || !execErr.Disconnected {
+					t.Fatalf("ExecuteScriptWithOptions() error = %v, want disconnected *ExecError", err)
+				}
+				if got := err.Error(); !strings.HasPrefix(got, tt.wantErr) {
+					t.Errorf("error = %q, want prefix %q", got, tt.wantErr)
+				}
+			}
+			if len(c.ran) != tt.wantRan {
+				t.Errorf("ran %d statements %v, want %d", len(c.ran), c.ran, tt.wantRan)
+			}
+			if tt.attempts > 0 && reconnects == 0 {
+				t.Error("OnReconnect was not called")
+			}
+		})
+	}
+}
+
+func TestConnectWithRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		driver, dsn string
+		attempts    int
+		wantRetries int
+		wantErr     bool
+	}{
+		{name: "connects first time", driver: "sqlite", dsn: ":memory:", attempts: 3},
+		{name: "unreachable server retried", driver: "postgres", dsn: "postgres://loader@127.0.0.1:1/app?connect_timeout=1",
+			attempts: 2, wantRetries: 2, wantErr: true},
+		{name: "no retries by default", driver: "postgres", dsn: "postgres://loader@127.0.0.1:1/app?connect_timeout=1", wantErr: true},
+		{name: "other errors not retried", driver: "sqlite", dsn: "file:/nonexistent/dir/x.db?mode=ro", attempts: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var retries int
+			policy := ReconnectPolicy{Attempts: tt.attempts, Backoff: time.Millisecond}
+			db, err := ConnectWithRetry(context.Background(), tt.driver, tt.dsn, policy, func(attempt int, wait time.Duration, _ error) {
+				retries++
+				if attempt != retries || wait > time.Duration(attempt)*time.Millisecond*2 {
+					t.Errorf("retry %d: attempt %d, wait %s", retries, attempt, wait)
+				}
+			})
+			if db != nil {
+				_ = db.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConnectWithRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if retries != tt.wantRetries {
+				t.Errorf("retried %d times, want %d", retries, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestConnectWithRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cause := errors.New("shutting down")
+	policy := ReconnectPolicy{Attempts: 5, Backoff: time.Hour}
+	_, err := ConnectWithRetry(ctx, "postgres", "postgres://loader@127.0.0.1:1/app?connect_timeout=1", policy,
+		func(int, time.Duration, error) { cancel(cause) })
+	if !errors.Is(err, cause) {
+		t.Errorf("error = %v, want the cancellation cause", err)
+	}
+}
+
+func TestConnectWithRetryTimeouts(t *testing.T) {
+	// A server that accepts connections but never answers, as while a
+	// database host is still booting.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer func() { _ = conn.Close() }()
+		}
+	}()
+
+	var retries int
+	dsn := "postgres://loader@" + l.Addr().String() + "/app?connect_timeout=1&sslmode=disable"
+	policy := ReconnectPolicy{Attempts: 1, Backoff: time.Millisecond}
+	_, err = ConnectWithRetry(context.Background(), "postgres", dsn, policy, func(int, time.Duration, error) { retries++ })
+	if err == nil || retries != 1 {
+		t.Errorf("ConnectWithRetry() = %v after %d retries, want a timeout after 1 retry", err, retries)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	if err := WaitReady(context.Background(), "sqlite", ":memory:", time.Millisecond, nil); err != nil {
+		t.Errorf("WaitReady() error = %v", err)
+	}
+	if err := WaitReady(context.Background(), "sqlite", "file:/nonexistent/dir/x.db?mode=ro", time.Millisecond, nil); err == nil {
+		t.Error("WaitReady() retried an error that is not a connection error")
+	}
+
+	cause := errors.New("database not ready after 200ms")
+	ctx, cancel := context.WithTimeoutCause(context.Background(), 200*time.Millisecond, cause)
+	defer cancel()
+	var retries int
+	err := WaitReady(ctx, "postgres", "postgres://loader@127.0.0.1:1/app?connect_timeout=1", 10*time.Millisecond,
+		func(error) { retries++ })
+	if !errors.Is(err, cause) || !strings.Contains(err.Error(), "last error") {
+		t.Errorf("error = %v, want the timeout cause and the last error", err)
+	}
+	if retries == 0 {
+		t.Error("WaitReady() did not retry the unreachable server")
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("syntax error"), false},
+		{fmt.Errorf("wrapped: %w", io.ErrUnexpectedEOF), true},
+		{driver.ErrBadConn, true},
+		{sqlStateError("08006"), true},
+		{sqlStateError("57P01"), true},
+		{sqlStateError("42P01"), false},
+		{fmt.Errorf("statement timeout: %w", context.DeadlineExceeded), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsConnectionError(tt.err); got != tt.want {
+			t.Errorf("IsConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "SQLSTATE " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
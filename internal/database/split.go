@@ -0,0 +1,318 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SplitStatements splits a SQL script into its non-empty statements. Only
+// semicolons outside string literals, quoted identifiers, dollar-quoted
+// bodies, and comments end a statement, so PL/pgSQL function definitions
+// survive intact. Inside a CREATE FUNCTION, PROCEDURE, or TRIGGER statement,
+// semicolons within BEGIN ... END are also kept, as SQLite trigger bodies
+// and BEGIN ATOMIC function bodies require. A COPY ... FROM STDIN
+// statement, as pg_dump writes, runs on through its data rows to the \.
+// line ending them. Statements consisting only of comments are dropped. A
+// literal or comment left open runs to the end of the script; SplitScript
+// reports it instead.
+func SplitStatements(script string) []string {
+	statements, _ := split(script)
+	return statements
+}
+
+// SplitScript is SplitStatements for untrusted input. A string literal,
+// quoted identifier, comment, or dollar-quoted body left open at the end of
+// the script is a *SyntaxError with its line and column, rather than the
+// start of one statement swallowing the rest. SplitScript never panics: any
+// failure of the splitter itself, including statements it returns that
+// are not trimmed, in-order pieces of script, is an error.
+func SplitScript(script string) (statements []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			statements, err = nil, fmt.Errorf("internal error splitting script: %v", r)
+		}
+	}()
+	statements, open := split(script)
+	if open != nil {
+		return nil, open
+	}
+	if err := checkSplit(script, statements); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+// checkSplit verifies that statements are non-empty and trimmed, and occur
+// in script in order without overlapping.
+func checkSplit(script string, statements []string) error {
+	offset := 0
+	for i, stmt := range statements {
+		if stmt == "" || strings.TrimSpace(stmt) != stmt {
+			return fmt.Errorf("internal error splitting script: statement %d is not trimmed", i+1)
+		}
+		at := strings.Index(script[offset:], stmt)
+		if at < 0 {
+			return fmt.Errorf("internal error splitting script: statement %d is not part of the script", i+1)
+		}
+		offset += at + len(stmt)
+	}
+	return nil
+}
+
+// StatementLines returns the 1-based line of script each statement's first
+// token is on, past any leading comments, for statements SplitStatements
+// returned for script. A statement that is not found in order has line 0.
+func StatementLines(script string, statements []string) []int {
+	lines := make([]int, len(statements))
+	pos, line := 0, 1
+	for i, stmt := range statements {
+		offset := strings.Index(script[pos:], stmt)
+		if offset < 0 {
+			break
+		}
+		line += strings.Count(script[pos:pos+offset], "\n")
+		t := &tokenizer{s: stmt}
+		t.skip()
+		lines[i] = line + strings.Count(stmt[:t.pos], "\n")
+		line += strings.Count(stmt, "\n")
+		pos += offset + len(stmt)
+	}
+	return lines
+}
+
+// split returns the statements of script and, if a literal or comment is
+// left open at its end, the error locating it.
+func split(script string) ([]string, *SyntaxError) {
+	var (
+		statements []string
+		start      int
+		// first marks that the next word starts the statement, create that
+		// the statement is a CREATE whose object kind is still to come, and
+		// body that it creates a function, procedure, or trigger, whose
+		// BEGIN/CASE ... END nesting depth tracks. copyIn marks a COPY, and
+		// stdin that it reads FROM STDIN, so that its data rows follow the
+		// semicolon.
+		first   = true
+		create  bool
+		definer bool
+		body    bool
+		depth   int
+		content bool
+		copyIn  bool
+		stdin   bool
+		prev    string
+		// open is the unterminated construct that ended the scan, if any.
+		open *SyntaxError
+	)
+	flush := func(end int) {
+		if stmt := strings.TrimSpace(script[start:end]); content && stmt != "" {
+			statements = append(statements, stmt)
+		}
+		start, first, create, definer, body, depth, content = end+1, true, false, false, false, 0, false
+		copyIn, stdin, prev = false, false, ""
+	}
+	unterminated := func(i int, closed bool, what string) {
+		if !closed {
+			open = scriptError(script, i, "unterminated "+what)
+		}
+	}
+
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == ';' && depth == 0 && copyIn && stdin:
+			end, closed := skipCopyData(script, i+1)
+			unterminated(i, closed, "COPY data")
+			flush(end)
+			start = min(start, len(script))
+			i = end
+		case c == ';' && depth == 0:
+			flush(i)
+			i++
+		case strings.HasPrefix(script[i:], "--"):
+			i = skipLineComment(script, i)
+		case strings.HasPrefix(script[i:], "/*"):
+			end, closed := skipBlockComment(script, i)
+			unterminated(i, closed, "block comment")
+			i = end
+		case c == '\'':
+			content = true
+			end, closed := skipQuoted(script, i, isEscapeString(script, i))
+			unterminated(i, closed, "string literal")
+			i = end
+		case c == '"' || c == '`':
+			content = true
+			end, closed := skipQuoted(script, i, false)
+			unterminated(i, closed, "quoted identifier")
+			i = end
+		case c == '$':
+			content = true
+			if tag, ok := dollarTag(script, i); ok {
+				end, closed := skipDollarQuoted(script, i, tag)
+				unterminated(i, closed, "dollar-quoted string "+tag)
+				i = end
+			} else {
+				i++
+			}
+		case isWordByte(c):
+			content = true
+			j := i
+			for j < len(script) && isWordByte(script[j]) {
+				j++
+			}
+			word := strings.ToUpper(script[i:j])
+			switch {
+			case first:
+				first, create, copyIn = false, word == "CREATE", word == "COPY"
+			case create && routineKinds[word]:
+				create, body = false, true
+			case create && word == "DEFINER":
+				definer = true
+			case create && !definer && !createModifiers[word]:
+				create = false
+			case body && (word == "BEGIN" || word == "CASE"):
+				depth++
+			case body && word == "END" && depth > 0:
+				// END IF and END LOOP close blocks that open no depth, and
+				// END CASE closes a CASE, whose word must not open another.
+				switch next, end := nextWord(script, j); next {
+				case "IF", "LOOP", "WHILE", "REPEAT":
+				case "CASE":
+					depth--
+					word, j = next, end
+				default:
+					depth--
+				}
+			case copyIn && prev == "FROM" && word == "STDIN":
+				stdin = true
+			}
+			prev = word
+			i = j
+		default:
+			if !isSpaceByte(c) {
+				content = true
+			}
+			i++
+		}
+	}
+	flush(len(script))
+	return statements, open
+}
+
+// nextWord returns the upper-cased word after any spaces at i, and where it
+// ends, or "" if none starts there.
+func nextWord(script string, i int) (string, int) {
+	for i < len(script) && isSpaceByte(script[i]) {
+		i++
+	}
+	j := i
+	for j < len(script) && isWordByte(script[j]) {
+		j++
+	}
+	return strings.ToUpper(script[i:j]), j
+}
+
+// routineKinds are the CREATE statements whose bodies may hold BEGIN ... END
+// blocks of statements.
+var routineKinds = map[string]bool{"FUNCTION": true, "PROCEDURE": true, "TRIGGER": true}
+
+// createModifiers are the words that may come between CREATE and the kind of
+// object it creates. A MySQL DEFINER clause, whose user may be any words,
+// runs on to the object kind.
+var createModifiers = map[string]bool{"OR": true, "REPLACE": true, "TEMP": true, "TEMPORARY": true, "CONSTRAINT": true}
+
+// scriptError returns a *SyntaxError at byte offset i of script, with a
+// 1-based line and a column counted in characters.
+func scriptError(script string, i int, message string) *SyntaxError {
+	lineStart := strings.LastIndexByte(script[:i], '\n') + 1
+	return &SyntaxError{
+		Message: message,
+		Line:    strings.Count(script[:i], "\n") + 1,
+		Column:  utf8.RuneCountInString(script[lineStart:i]) + 1,
+	}
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func skipLineComment(s string, i int) int {
+	end := strings.IndexByte(s[i:], '\n')
+	if end < 0 {
+		return len(s)
+	}
+	return i + end + 1
+}
+
+// skipBlockComment skips a block comment, which nests in PostgreSQL,
+// reporting whether it was closed.
+func skipBlockComment(s string, i int) (int, bool) {
+	depth := 0
+	for i < len(s) {
+		switch {
+		case strings.HasPrefix(s[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, true
+			}
+		default:
+			i++
+		}
+	}
+	return len(s), false
+}
+
+// isEscapeString reports whether the quote at i opens a PostgreSQL E'...'
+// string, in which backslash escapes the next character.
+func isEscapeString(s string, i int) bool {
+	return i > 0 && (s[i-1] == 'E' || s[i-1] == 'e') && (i < 2 || !isWordByte(s[i-2]))
+}
+
+// skipQuoted skips a quoted string or identifier starting at i, where a
+// doubled quote character stands for itself, reporting whether it was
+// closed.
+func skipQuoted(s string, i int, backslash bool) (int, bool) {
+	q := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case backslash && s[j] == '\\':
+			j++
+		case s[j] == q:
+			if j+1 < len(s) && s[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1, true
+		}
+	}
+	return len(s), false
+}
+
+// dollarTag returns the $tag$ opening a dollar-quoted string at i. Tags
+// follow identifier rules, so $1 placeholders are not mistaken for one.
+func dollarTag(s string, i int) (string, bool) {
+	j := i + 1
+	if j < len(s) && (s[j] >= '0' && s[j] <= '9') {
+		return "", false
+	}
+	for j < len(s) && s[j] != '$' && isWordByte(s[j]) {
+		j++
+	}
+	if j < len(s) && s[j] == '$' {
+		return s[i : j+1], true
+	}
+	return "", false
+}
+
+func skipDollarQuoted(s string, i int, tag string) (int, bool) {
+	end := strings.Index(s[i+len(tag):], tag)
+	if end < 0 {
+		return len(s), false
+	}
+	return i + len(tag) + end + len(tag), true
+}
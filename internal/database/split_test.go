@@ -0,0 +1,217 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "simple",
+			script: " SELECT 1;\n\n; SELECT 2 ;",
+			want:   []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:   "blank",
+			script: "  \n ",
+		},
+		{
+			name:   "string literals",
+			script: "INSERT INTO t VALUES ('a;b', 'it''s;'); SELECT E'x\\';y'; SELECT 'a\\'",
+			want:   []string{"INSERT INTO t VALUES ('a;b', 'it''s;')", "SELECT E'x\\';y'", "SELECT 'a\\'"},
+		},
+		{
+			name:   "quoted identifiers",
+			script: `SELECT "a;b" FROM "t"";"; SELECT ` + "`c;d`",
+			want:   []string{`SELECT "a;b" FROM "t"";"`, "SELECT `c;d`"},
+		},
+		{
+			name:   "comments",
+			script: "-- setup; ignored\nSELECT 1; /* a; /* nested; */ b; */ SELECT 2;\n-- trailing;",
+			want:   []string{"-- setup; ignored\nSELECT 1", "/* a; /* nested; */ b; */ SELECT 2"},
+		},
+		{
+			name: "dollar quoted function",
+			script: "CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  NEW.x := 1;\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\n" +
+				"CREATE FUNCTION g() RETURNS text AS $body$ SELECT '$$;' $body$ LANGUAGE sql;",
+			want: []string{
+				"CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  NEW.x := 1;\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql",
+				"CREATE FUNCTION g() RETURNS text AS $body$ SELECT '$$;' $body$ LANGUAGE sql",
+			},
+		},
+		{
+			name:   "placeholders are not dollar quotes",
+			script: "SELECT $1; SELECT a$b$ FROM t;",
+			want:   []string{"SELECT $1", "SELECT a$b$ FROM t"},
+		},
+		{
+			name: "sqlite trigger body",
+			script: "CREATE TRIGGER tr AFTER INSERT ON t BEGIN\n  UPDATE c SET n = CASE WHEN n > 0 THEN n + 1 ELSE 1 END;\n  DELETE FROM q;\nEND;\n" +
+				"BEGIN; SELECT CASE WHEN 1 THEN 2 END; COMMIT;",
+			want: []string{
+				"CREATE TRIGGER tr AFTER INSERT ON t BEGIN\n  UPDATE c SET n = CASE WHEN n > 0 THEN n + 1 ELSE 1 END;\n  DELETE FROM q;\nEND",
+				"BEGIN", "SELECT CASE WHEN 1 THEN 2 END", "COMMIT",
+			},
+		},
+		{
+			name:   "temporary trigger and begin atomic function bodies",
+			script: "CREATE TEMP TRIGGER tr AFTER DELETE ON t BEGIN DELETE FROM q; END;\nCREATE OR REPLACE FUNCTION f() RETURNS int LANGUAGE sql BEGIN ATOMIC SELECT 1; SELECT 2; END;",
+			want: []string{
+				"CREATE TEMP TRIGGER tr AFTER DELETE ON t BEGIN DELETE FROM q; END",
+				"CREATE OR REPLACE FUNCTION f() RETURNS int LANGUAGE sql BEGIN ATOMIC SELECT 1; SELECT 2; END",
+			},
+		},
+		{
+			name:   "mysql procedure with a definer",
+			script: "CREATE DEFINER=admin@localhost PROCEDURE p() BEGIN SELECT 1; END; SELECT 2;",
+			want:   []string{"CREATE DEFINER=admin@localhost PROCEDURE p() BEGIN SELECT 1; END", "SELECT 2"},
+		},
+		{
+			name: "mysql procedure with nested if, loop, and case blocks",
+			script: "CREATE PROCEDURE p(n INT) BEGIN\n  IF n > 0 THEN\n    SET n = n - 1;\n  END IF;\n  l: LOOP\n    LEAVE l;\n  END LOOP;\n" +
+				"  CASE n WHEN 0 THEN SELECT 0; ELSE SELECT 1; END CASE;\nEND;\nSELECT 2;",
+			want: []string{
+				"CREATE PROCEDURE p(n INT) BEGIN\n  IF n > 0 THEN\n    SET n = n - 1;\n  END IF;\n  l: LOOP\n    LEAVE l;\n  END LOOP;\n" +
+					"  CASE n WHEN 0 THEN SELECT 0; ELSE SELECT 1; END CASE;\nEND",
+				"SELECT 2",
+			},
+		},
+		{
+			name:   "column named begin",
+			script: "CREATE TABLE t (begin int, \"end\" int); INSERT INTO t VALUES (1, 2);",
+			want:   []string{"CREATE TABLE t (begin int, \"end\" int)", "INSERT INTO t VALUES (1, 2)"},
+		},
+		{
+			name:   "case in a view",
+			script: "CREATE VIEW v AS SELECT CASE WHEN x THEN 1 END AS y, CASE x WHEN 1 THEN 2 ELSE 3 END FROM t; SELECT 1;",
+			want:   []string{"CREATE VIEW v AS SELECT CASE WHEN x THEN 1 END AS y, CASE x WHEN 1 THEN 2 ELSE 3 END FROM t", "SELECT 1"},
+		},
+		{
+			name:   "case in a view never closed",
+			script: "CREATE VIEW v AS SELECT CASE WHEN x THEN 1 FROM t; SELECT 1;",
+			want:   []string{"CREATE VIEW v AS SELECT CASE WHEN x THEN 1 FROM t", "SELECT 1"},
+		},
+		{
+			name:   "unterminated quote",
+			script: "SELECT 1; SELECT 'a;b",
+			want:   []string{"SELECT 1", "SELECT 'a;b"},
+		},
+		{
+			name: "copy from stdin",
+			script: "SET x = 1;\nCOPY public.t (id, note) FROM stdin;\n1\ta;b\n2\t\\N\n\\.\r\n" +
+				"COPY t FROM '/tmp/t.csv'; COPY t FROM STDIN WITH (FORMAT csv);\n\\.\nSELECT 1;",
+			want: []string{
+				"SET x = 1",
+				"COPY public.t (id, note) FROM stdin;\n1\ta;b\n2\t\\N\n\\.",
+				"COPY t FROM '/tmp/t.csv'",
+				"COPY t FROM STDIN WITH (FORMAT csv);\n\\.",
+				"SELECT 1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		want    []string
+		wantErr string
+	}{
+		{name: "well formed", script: "SELECT 1; SELECT 'a;b';", want: []string{"SELECT 1", "SELECT 'a;b'"}},
+		{name: "blank", script: " \n "},
+		{name: "unterminated quote", script: "SELECT 1;\nSELECT 'a;b", wantErr: "unterminated string literal on line 2, column 8"},
+		{name: "unterminated identifier", script: `SELECT "a`, wantErr: "unterminated quoted identifier on line 1, column 8"},
+		{name: "unterminated comment", script: "SELECT 1; /* a /* b */", wantErr: "unterminated block comment on line 1, column 11"},
+		{name: "unterminated dollar quote", script: "DO $x$ BEGIN END;", wantErr: "unterminated dollar-quoted string $x$ on line 1, column 4"},
+		{name: "column counts characters", script: "SELECT 'ü', 'x", wantErr: "line 1, column 13"},
+		{name: "unterminated copy data", script: "COPY t FROM stdin;\n1\ta\n", wantErr: "unterminated COPY data on line 1, column 18"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitScript(tt.script)
+			if tt.wantErr != "" {
+				var syntaxErr *SyntaxError
+				if !errors.As(err, &syntaxErr) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("SplitScript() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitScript() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzSplitScript(f *testing.F) {
+	for _, seed := range []string{
+		"SELECT 1; SELECT 2;",
+		"INSERT INTO t VALUES ('a;b', 'it''s;'); SELECT E'x\\';y';",
+		`SELECT "a;b" FROM "t"";"; SELECT ` + "`c;d`",
+		"-- c;\nSELECT 1; /* a; /* b; */ c; */ SELECT 2;",
+		"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;",
+		"CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE c SET n = CASE WHEN n THEN 1 END; END;",
+		"SELECT $1; SELECT a$b$ FROM t;",
+		"SELECT 'open",
+		"COPY t (a, b) FROM stdin;\n1\tx;y\n\\.\nSELECT 1;",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, script string) {
+		statements, err := SplitScript(script)
+		if err != nil {
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("SplitScript(%q) error = %v, want a *SyntaxError", script, err)
+			}
+			if syntaxErr.Line < 1 || syntaxErr.Line > strings.Count(script, "\n")+1 || syntaxErr.Column < 1 {
+				t.Fatalf("SplitScript(%q) error at line %d, column %d is outside the script", script, syntaxErr.Line, syntaxErr.Column)
+			}
+			return
+		}
+		if got := SplitStatements(script); !reflect.DeepEqual(got, statements) {
+			t.Fatalf("SplitStatements(%q) = %q, SplitScript() = %q", script, got, statements)
+		}
+		for _, stmt := range statements {
+			if again := SplitStatements(stmt); len(again) != 1 || again[0] != stmt {
+				t.Fatalf("statement %q of %q splits again into %q", stmt, script, again)
+			}
+		}
+	})
+}
+
+func TestStatementLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []int
+	}{
+		{name: "one line", script: "SELECT 1; SELECT 2;", want: []int{1, 1}},
+		{name: "leading comment", script: "-- seed\n\nINSERT INTO t VALUES (1);\nINSERT INTO t\nVALUES (2);\nSELECT 3;", want: []int{3, 4, 6}},
+		{name: "repeated statement", script: "SELECT 1;\nSELECT 1;\n", want: []int{1, 2}},
+		{name: "function body", script: "CREATE FUNCTION f() RETURNS int AS $$\nBEGIN\n  RETURN 1;\nEND\n$$ LANGUAGE plpgsql;\nSELECT f();", want: []int{1, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StatementLines(tt.script, SplitStatements(tt.script)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StatementLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package database
+
+import (
+	"strings"
+	"unicode"
+)
+
+// StatementInfo describes what a statement does and to which object.
+type StatementInfo struct {
+	// Verb is the upper-cased leading keyword, e.g. INSERT or CREATE.
+	Verb string
+	// ObjectType is the kind of object a DDL statement targets, e.g. TABLE.
+	ObjectType string
+	// Object is the target object name as written, e.g. public."Users".
+	Object string
+}
+
+// statementModifiers are keywords that may appear between CREATE and the
+// object type.
+var statementModifiers = map[string]bool{
+	"OR": true, "REPLACE": true, "GLOBAL": true, "LOCAL": true, "TEMP": true,
+	"TEMPORARY": true, "UNLOGGED": true, "UNIQUE": true, "MATERIALIZED": true,
+	"RECURSIVE": true,
+}
+
+// ddlVerbs are the leading keywords of statements that change the schema
+// or its privileges.
+var ddlVerbs = map[string]bool{
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true,
+	"COMMENT": true, "GRANT": true, "REVOKE": true,
+}
+
+// DDL reports whether the statement changes the schema or its privileges.
+// Such statements take locks that concurrent data changes queue behind.
+func (i StatementInfo) DDL() bool {
+	return ddlVerbs[i.Verb]
+}
+
+// autocommitObjects are the object types that cannot be created, altered,
+// or dropped inside a transaction block.
+var autocommitObjects = map[string]bool{"DATABASE": true, "TABLESPACE": true, "SYSTEM": true}
+
+// RequiresAutocommit reports whether stmt cannot run inside a transaction
+// block: VACUUM, CREATE or DROP DATABASE and TABLESPACE, ALTER SYSTEM, and
+// index builds and drops WITH CONCURRENTLY, on PostgreSQL and, for VACUUM,
+// SQLite alike.
+func RequiresAutocommit(stmt string) bool {
+	info := DescribeStatement(stmt)
+	switch info.Verb {
+	case "VACUUM":
+		return true
+	case "CREATE", "DROP", "ALTER":
+		if autocommitObjects[info.ObjectType] {
+			return true
+		}
+		if info.ObjectType != "INDEX" || info.Verb == "ALTER" {
+			return false
+		}
+	case "REINDEX":
+	default:
+		return false
+	}
+	// CONCURRENTLY follows the object type: CREATE UNIQUE INDEX
+	// CONCURRENTLY, DROP INDEX CONCURRENTLY, REINDEX TABLE CONCURRENTLY.
+	t := &tokenizer{s: stmt}
+	for range 4 {
+		if strings.EqualFold(t.word(), "CONCURRENTLY") {
+			return true
+		}
+	}
+	return false
+}
+
+// DescribeStatement extracts the verb, object type, and target object of a
+// statement. Fields that cannot be determined are left empty.
+func DescribeStatement(stmt string) StatementInfo {
+	t := &tokenizer{s: stmt}
+	verb := strings.ToUpper(t.word())
+	info := StatementInfo{Verb: verb}
+
+	switch verb {
+	case "INSERT", "MERGE":
+		t.keyword("INTO")
+		info.Object = t.name()
+	case "UPDATE":
+		t.keyword("ONLY")
+		info.Object = t.name()
+	case "DELETE":
+		t.keyword("FROM")
+		t.keyword("ONLY")
+		info.Object = t.name()
+	case "TRUNCATE":
+		t.keyword("TABLE")
+		t.keyword("ONLY")
+		info.ObjectType = "TABLE"
+		info.Object = t.name()
+	case "CREATE", "DROP", "ALTER":
+		for {
+			w := strings.ToUpper(t.word())
+			if w == "" {
+				return info
+			}
+			if !statementModifiers[w] {
+				info.ObjectType = w
+				break
+			}
+		}
+		t.keyword("CONCURRENTLY")
+		if t.keyword("IF") {
+			t.keyword("NOT")
+			t.keyword("EXISTS")
+		}
+		t.keyword("ONLY")
+		if name := t.name(); !strings.EqualFold(name, "ON") {
+			info.Object = name
+		}
+	}
+	return info
+}
+
+// SplitName splits an object name as written into its schema and unqualified
+// name, applying PostgreSQL folding rules: unquoted parts are lower-cased and
+// quoted parts are unescaped.
+func SplitName(name string) (schema, object string) {
+	t := &tokenizer{s: name}
+	var parts []string
+	for {
+		part, ok := t.ident()
+		if !ok {
+			break
+		}
+		parts = append(parts, part)
+		if !t.dot() {
+			break
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return "", ""
+	case 1:
+		return "", parts[0]
+	default:
+		return parts[len(parts)-2], parts[len(parts)-1]
+	}
+}
+
+// tokenizer reads keywords and identifiers from the start of a statement,
+// skipping whitespace and comments.
+type tokenizer struct {
+	s   string
+	pos int
+}
+
+func (t *tokenizer) skip() {
+	for t.pos < len(t.s) {
+		rest := t.s[t.pos:]
+		switch {
+		case unicode.IsSpace(rune(rest[0])):
+			t.pos++
+		case strings.HasPrefix(rest, "--"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				t.pos = len(t.s)
+				return
+			}
+			t.pos += end + 1
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				t.pos = len(t.s)
+				return
+			}
+			t.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// word returns the next bare word, or "" if the next token is not a word.
+func (t *tokenizer) word() string {
+	t.skip()
+	start := t.pos
+	for t.pos < len(t.s) && isWordByte(t.s[t.pos]) {
+		t.pos++
+	}
+	return t.s[start:t.pos]
+}
+
+// keyword consumes the next word if it matches kw case-insensitively.
+func (t *tokenizer) keyword(kw string) bool {
+	save := t.pos
+	if strings.EqualFold(t.word(), kw) {
+		return true
+	}
+	t.pos = save
+	return false
+}
+
+// ident reads a bare or double-quoted identifier and returns it folded.
+func (t *tokenizer) ident() (string, bool) {
+	t.skip()
+	if t.pos < len(t.s) && t.s[t.pos] == '"' {
+		var b strings.Builder
+		for i := t.pos + 1; i < len(t.s); i++ {
+			if t.s[i] == '"' {
+				if i+1 < len(t.s) && t.s[i+1] == '"' {
+					b.WriteByte('"')
+					i++
+					continue
+				}
+				t.pos = i + 1
+				return b.String(), true
+			}
+			b.WriteByte(t.s[i])
+		}
+		return "", false
+	}
+	w := t.word()
+	return strings.ToLower(w), w != ""
+}
+
+func (t *tokenizer) dot() bool {
+	t.skip()
+	if t.pos < len(t.s) && t.s[t.pos] == '.' {
+		t.pos++
+		return true
+	}
+	return false
+}
+
+// name reads a possibly qualified object name and returns it as written.
+func (t *tokenizer) name() string {
+	t.skip()
+	start := t.pos
+	for {
+		if _, ok := t.ident(); !ok {
+			break
+		}
+		save := t.pos
+		if !t.dot() {
+			t.pos = save
+			break
+		}
+	}
+	return strings.TrimSpace(t.s[start:t.pos])
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDescribeStatement(t *testing.T) {
+	tests := []struct {
+		name string
+		stmt string
+		want StatementInfo
+	}{
+		{
+			name: "insert",
+			stmt: "INSERT INTO public.users (id) VALUES (1)",
+			want: StatementInfo{Verb: "INSERT", Object: "public.users"},
+		},
+		{
+			name: "insert with leading comments",
+			stmt: "-- seed\n/* users */ insert into \"Users\" VALUES (1)",
+			want: StatementInfo{Verb: "INSERT", Object: `"Users"`},
+		},
+		{
+			name: "update only",
+			stmt: "UPDATE ONLY orders SET total = 0",
+			want: StatementInfo{Verb: "UPDATE", Object: "orders"},
+		},
+		{
+			name: "delete",
+			stmt: "DELETE FROM app.orders WHERE id = 1",
+			want: StatementInfo{Verb: "DELETE", Object: "app.orders"},
+		},
+		{
+			name: "truncate",
+			stmt: "TRUNCATE TABLE events",
+			want: StatementInfo{Verb: "TRUNCATE", ObjectType: "TABLE", Object: "events"},
+		},
+		{
+			name: "create table if not exists",
+			stmt: "CREATE TABLE IF NOT EXISTS app.users (id INT)",
+			want: StatementInfo{Verb: "CREATE", ObjectType: "TABLE", Object: "app.users"},
+		},
+		{
+			name: "create or replace function",
+			stmt: "CREATE OR REPLACE FUNCTION app.touch() RETURNS trigger AS $$ BEGIN END $$ LANGUAGE plpgsql",
+			want: StatementInfo{Verb: "CREATE", ObjectType: "FUNCTION", Object: "app.touch"},
+		},
+		{
+			name: "create temp table",
+			stmt: "CREATE TEMPORARY TABLE scratch (id INT)",
+			want: StatementInfo{Verb: "CREATE", ObjectType: "TABLE", Object: "scratch"},
+		},
+		{
+			name: "create unnamed index",
+			stmt: "CREATE INDEX ON users (email)",
+			want: StatementInfo{Verb: "CREATE", ObjectType: "INDEX"},
+		},
+		{
+			name: "drop if exists",
+			stmt: "DROP TABLE IF EXISTS legacy",
+			want: StatementInfo{Verb: "DROP", ObjectType: "TABLE", Object: "legacy"},
+		},
+		{
+			name: "select",
+			stmt: "SELECT 1",
+			want: StatementInfo{Verb: "SELECT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DescribeStatement(tt.stmt); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DescribeStatement() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatementInfoDDL(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want bool
+	}{
+		{stmt: "CREATE TABLE t (id int)", want: true},
+		{stmt: "alter table t add column x int", want: true},
+		{stmt: "DROP INDEX idx", want: true},
+		{stmt: "TRUNCATE events", want: true},
+		{stmt: "GRANT SELECT ON t TO app", want: true},
+		{stmt: "-- schema\nCOMMENT ON TABLE t IS 'x'", want: true},
+		{stmt: "INSERT INTO t VALUES (1)"},
+		{stmt: "SELECT 1"},
+		{stmt: "WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d"},
+	}
+	for _, tt := range tests {
+		if got := DescribeStatement(tt.stmt).DDL(); got != tt.want {
+			t.Errorf("DescribeStatement(%q).DDL() = %v, want %v", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestRequiresAutocommit(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want bool
+	}{
+		{stmt: "VACUUM ANALYZE events", want: true},
+		{stmt: "create index concurrently idx on t (x)", want: true},
+		{stmt: "CREATE UNIQUE INDEX CONCURRENTLY idx ON t (x)", want: true},
+		{stmt: "DROP INDEX CONCURRENTLY IF EXISTS idx", want: true},
+		{stmt: "REINDEX TABLE CONCURRENTLY events", want: true},
+		{stmt: "CREATE DATABASE reporting", want: true},
+		{stmt: "ALTER SYSTEM SET work_mem = '64MB'", want: true},
+		{stmt: "CREATE INDEX idx ON t (x)"},
+		{stmt: "REINDEX TABLE events"},
+		{stmt: "CREATE TABLE concurrently (id int)"},
+		{stmt: "INSERT INTO t VALUES (1)"},
+	}
+	for _, tt := range tests {
+		if got := RequiresAutocommit(tt.stmt); got != tt.want {
+			t.Errorf("RequiresAutocommit(%q) = %v, want %v", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantSchema string
+		wantObject string
+	}{
+		{in: "users", wantObject: "users"},
+		{in: "Public.Users", wantSchema: "public", wantObject: "users"},
+		{in: `"App"."Order ""Items"""`, wantSchema: "App", wantObject: `Order "Items"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			schema, object := SplitName(tt.in)
+			if schema != tt.wantSchema || object != tt.wantObject {
+				t.Errorf("SplitName(%q) = (%q, %q), want (%q, %q)", tt.in, schema, object, tt.wantSchema, tt.wantObject)
+			}
+		})
+	}
+}
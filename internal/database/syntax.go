@@ -0,0 +1,83 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError is a lexical error found by CheckSyntax.
+type SyntaxError struct {
+	Message string
+	// Line is the 1-based line of the statement the error is on.
+	Line int
+	// Column is the 1-based character column on Line, or zero if unknown.
+	Column int
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%s on line %d, column %d", e.Message, e.Line, e.Column)
+	}
+	return fmt.Sprintf("%s on line %d", e.Message, e.Line)
+}
+
+// CheckSyntax reports the first lexical error in a statement that can be
+// found without a server: an unterminated string literal, quoted
+// identifier, comment, or dollar-quoted body, or unbalanced parentheses.
+// Errors are *SyntaxError.
+func CheckSyntax(stmt string) error {
+	var open []int
+	line := func(i int) int { return strings.Count(stmt[:i], "\n") + 1 }
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		var (
+			end  int
+			ok   = true
+			what string
+		)
+		switch {
+		case strings.HasPrefix(stmt[i:], "--"):
+			end = skipLineComment(stmt, i)
+		case strings.HasPrefix(stmt[i:], "/*"):
+			end, ok = skipBlockComment(stmt, i)
+			what = "block comment"
+		case c == '\'':
+			end, ok = skipQuoted(stmt, i, isEscapeString(stmt, i))
+			what = "string literal"
+		case c == '"' || c == '`':
+			end, ok = skipQuoted(stmt, i, false)
+			what = "quoted identifier"
+		case c == '$':
+			end = i + 1
+			if tag, isTag := dollarTag(stmt, i); isTag {
+				end, ok = skipDollarQuoted(stmt, i, tag)
+				what = "dollar-quoted string " + tag
+			}
+		case isWordByte(c):
+			// Skip whole words, so a$b$ is not read as a dollar quote.
+			end = i
+			for end < len(stmt) && isWordByte(stmt[end]) {
+				end++
+			}
+		case c == '(':
+			open = append(open, i)
+			end = i + 1
+		case c == ')':
+			if len(open) == 0 {
+				return &SyntaxError{Message: "unmatched )", Line: line(i)}
+			}
+			open = open[:len(open)-1]
+			end = i + 1
+		default:
+			end = i + 1
+		}
+		if !ok {
+			return &SyntaxError{Message: "unterminated " + what, Line: line(i)}
+		}
+		i = end
+	}
+	if len(open) > 0 {
+		return &SyntaxError{Message: "unclosed (", Line: line(open[len(open)-1])}
+	}
+	return nil
+}
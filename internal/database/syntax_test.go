@@ -0,0 +1,40 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckSyntax(t *testing.T) {
+	tests := []struct {
+		name    string
+		stmt    string
+		wantErr string
+	}{
+		{name: "valid", stmt: "INSERT INTO t (a, b) VALUES ('x(', \"y)\") -- )"},
+		{name: "dollar quoted body", stmt: "CREATE FUNCTION f() RETURNS int AS $$ SELECT ')' $$ LANGUAGE sql"},
+		{name: "placeholders and identifiers with dollars", stmt: "SELECT $1, a$b$ FROM t"},
+		{name: "escaped quote", stmt: "SELECT E'it\\'s'"},
+		{name: "unterminated string", stmt: "SELECT 1,\n'abc''", wantErr: "unterminated string literal on line 2"},
+		{name: "unterminated identifier", stmt: `SELECT "a FROM t`, wantErr: "unterminated quoted identifier on line 1"},
+		{name: "unterminated comment", stmt: "SELECT 1 /* /* */", wantErr: "unterminated block comment on line 1"},
+		{name: "unterminated dollar quote", stmt: "DO $body$ BEGIN END $$", wantErr: "unterminated dollar-quoted string $body$ on line 1"},
+		{name: "unclosed parenthesis", stmt: "CREATE TABLE t (\n  id int,\n  name text", wantErr: "unclosed ( on line 1"},
+		{name: "unmatched parenthesis", stmt: "SELECT (1))", wantErr: "unmatched ) on line 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSyntax(tt.stmt)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckSyntax() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckSyntax() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TransactionMode controls how the statements of a script are grouped into
+// transactions.
+type TransactionMode string
+
+// Transaction modes.
+const (
+	// TransactionNone runs each statement in autocommit mode.
+	TransactionNone TransactionMode = "none"
+	// TransactionPerStatement runs each statement in its own transaction.
+	TransactionPerStatement TransactionMode = "per-statement"
+	// TransactionAll runs the whole script in one transaction, rolled back
+	// on the first error.
+	TransactionAll TransactionMode = "all"
+)
+
+// ParseTransactionMode validates a user-supplied mode name.
+func ParseTransactionMode(name string) (TransactionMode, error) {
+	switch TransactionMode(name) {
+	case TransactionNone, TransactionPerStatement, TransactionAll:
+		return TransactionMode(name), nil
+	default:
+		return "", fmt.Errorf("unsupported transaction mode %q (use all, per-statement, or none)", name)
+	}
+}
+
+// ParseIsolation validates a user-supplied isolation level name. The empty
+// name is the server's default level.
+func ParseIsolation(name string) (sql.IsolationLevel, error) {
+	switch name {
+	case "":
+		return sql.LevelDefault, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	case "repeatable-read":
+		return sql.LevelRepeatableRead, nil
+	case "read-committed":
+		return sql.LevelReadCommitted, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("unsupported isolation level %q (use serializable, repeatable-read, or read-committed)", name)
+	}
+}
+
+// execStatement runs stmt, wrapped in a transaction begun with txOpts when
+// inTx is set, returning the rows it affected.
+func execStatement(ctx context.Context, e Execer, stmt string, inTx bool, txOpts *sql.TxOptions) (int64, error) {
+	// A COPY commits on its own, so it needs no transaction of its own.
+	if command, data, ok := copyData(stmt); ok {
+		return execCopy(ctx, e, command, data)
+	}
+	if !inTx {
+		res, err := e.ExecContext(ctx, stmt)
+		return rowsAffected(res), err
+	}
+	tx, err := e.(txBeginner).BeginTx(ctx, txOpts)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	return rowsAffected(res), tx.Commit()
+}
+
+// rowsAffected returns the rows a statement affected, or -1 when the driver
+// does not report it.
+func rowsAffected(res sql.Result) int64 {
+	if res == nil {
+		return -1
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// executeInBatches runs statements in transactions of opts.BatchSize,
+// committing each in turn.
+func executeInBatches(ctx context.Context, db txBeginner, statements []string, opts ExecuteOptions) error {
+	batch := 0
+	for first := 0; first < len(statements); first += opts.BatchSize {
+		last := min(first+opts.BatchSize, len(statements))
+		if err := executeInTransaction(ctx, db, statements[first:last], first, len(statements), opts); err != nil {
+			var execErr *ExecError
+			if errors.As(err, &execErr) {
+				execErr.Committed, execErr.Batches = first, batch
+			} else if batch > 0 {
+				err = fmt.Errorf("%w (%d statements committed in %d batches)", err, first, batch)
+			}
+			return err
+		}
+		batch++
+		if opts.OnBatch != nil {
+			opts.OnBatch(batch, last)
+		}
+	}
+	return nil
+}
+
+// RetrySerializationFailures calls run, which must run one transaction, and
+// calls it again with exponential backoff and jitter up to policy.Attempts
+// times while it fails with a serialization failure or deadlock (SQLSTATE
+// 40001 or 40P01). Other failures are returned at once. onRetry, if set, is
+// called before each wait.
+func RetrySerializationFailures(ctx context.Context, policy ReconnectPolicy, onRetry func(attempt int, wait time.Duration, err error), run func() error) error {
+	backoff, limit := policy.delays()
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || Classify(err).Category != CategorySerialization || attempt > policy.Attempts || ctx.Err() != nil {
+			return err
+		}
+		wait := jitter(backoff)
+		if onRetry != nil {
+			onRetry(attempt, wait, err)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry abandoned: %w)", err, context.Cause(ctx))
+		}
+		backoff = min(backoff*2, limit)
+	}
+}
+
+// executeInTransaction runs statements in a single transaction, numbering
+// them from offset+1 of total. A dropped connection loses the transaction,
+// so opts.Reconnect does not apply.
+func executeInTransaction(ctx context.Context, db txBeginner, statements []string, offset, total int, opts ExecuteOptions) error {
+	tx, err := beginPinned(ctx, db, opts.TxOptions)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	for i, stmt := range statements {
+		index := offset + i + 1
+		fail := func(err error) error {
+			_ = tx.Rollback()
+			return &ExecError{Index: index, Total: total, Disconnected: IsConnectionError(err), RolledBack: true, Err: err}
+		}
+		if opts.BeforeStatement != nil {
+			if err := opts.BeforeStatement(index, stmt); err != nil {
+				return fail(err)
+			}
+		}
+		start := time.Now()
+		var rows int64
+		err := withTimeout(ctx, opts.StatementTimeout, func(ctx context.Context) error {
+			return withNotices(ctx, tx, opts.noticeHandler(index), func(e Execer) error {
+				var err error
+				rows, err = execStatement(ctx, e, stmt, false, nil)
+				return err
+			})
+		})
+		if err != nil {
+			return fail(err)
+		}
+		if opts.OnStatement != nil {
+			opts.OnStatement(index, stmt, time.Since(start), rows)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteScriptTransactionModes(t *testing.T) {
+	script := "INSERT INTO t VALUES (1); INSERT INTO t VALUES (2); INSERT INTO missing VALUES (3);"
+
+	tests := []struct {
+		mode           TransactionMode
+		wantRows       int
+		wantRolledBack bool
+	}{
+		{mode: TransactionNone, wantRows: 2},
+		{mode: TransactionPerStatement, wantRows: 2},
+		{mode: TransactionAll, wantRows: 0, wantRolledBack: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "tx.db"))
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			defer func() {
+				if closeErr := db.Close(); closeErr != nil {
+					t.Errorf("Close() error = %v", closeErr)
+				}
+			}()
+			if _, err := db.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+				t.Fatalf("Failed to create table: %v", err)
+			}
+
+			err = ExecuteScriptWithOptions(context.Background(), db, script, ExecuteOptions{Transaction: tt.mode})
+			var execErr *ExecError
+			if !errors.As(err, &execErr) || execErr.Index != 3 {
+				t.Fatalf("ExecuteScriptWithOptions() error = %v, want ExecError at statement 3", err)
+			}
+			if execErr.RolledBack != tt.wantRolledBack {
+				t.Errorf("RolledBack = %v, want %v", execErr.RolledBack, tt.wantRolledBack)
+			}
+			if tt.wantRolledBack && !strings.Contains(err.Error(), "rolled back") {
+				t.Errorf("error %q does not mention the rollback", err)
+			}
+
+			var count int
+			if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+				t.Fatalf("Failed to count rows: %v", err)
+			}
+			if count != tt.wantRows {
+				t.Errorf("table has %d rows, want %d", count, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestExecuteScriptTransactionAllCommits(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "tx.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+
+	err = ExecuteScriptWithOptions(context.Background(), db, "CREATE TABLE t (id INTEGER); INSERT INTO t VALUES (1);", ExecuteOptions{Transaction: TransactionAll})
+	if err != nil {
+		t.Fatalf("ExecuteScriptWithOptions() error = %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("table has %d rows, want 1", count)
+	}
+}
+
+func TestExecuteScriptBatches(t *testing.T) {
+	script := "INSERT INTO t VALUES (1); INSERT INTO t VALUES (2); INSERT INTO t VALUES (3); INSERT INTO t VALUES (4); INSERT INTO t VALUES (5);"
+
+	tests := []struct {
+		name          string
+		script        string
+		batchSize     int
+		wantErr       bool
+		wantRows      int
+		wantBatches   []int
+		wantCommitted int
+	}{
+		{name: "uneven batches", script: script, batchSize: 2, wantRows: 5, wantBatches: []int{2, 4, 5}},
+		{name: "one batch", script: script, batchSize: 10, wantRows: 5, wantBatches: []int{5}},
+		{name: "failure rolls back its batch", script: script + " INSERT INTO missing VALUES (6);", batchSize: 4,
+			wantErr: true, wantRows: 4, wantBatches: []int{4}, wantCommitted: 4},
+		{name: "failure in first batch", script: "INSERT INTO t VALUES (1); INSERT INTO missing VALUES (2);", batchSize: 4,
+			wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "tx.db"))
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			defer func() {
+				if closeErr := db.Close(); closeErr != nil {
+					t.Errorf("Close() error = %v", closeErr)
+				}
+			}()
+			if _, err := db.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+				t.Fatalf("Failed to create table: %v", err)
+			}
+
+			var batches []int
+			opts := ExecuteOptions{BatchSize: tt.batchSize, OnBatch: func(batch, committed int) {
+				if batch != len(batches)+1 {
+					t.Errorf("OnBatch batch = %d, want %d", batch, len(batches)+1)
+				}
+				batches = append(batches, committed)
+			}}
+			err = ExecuteScriptWithOptions(context.Background(), db, tt.script, opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecuteScriptWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var execErr *ExecError
+				if !errors.As(err, &execErr) || !execErr.RolledBack || execErr.Committed != tt.wantCommitted {
+					t.Errorf("error = %#v, want a rolled back ExecError with %d committed", err, tt.wantCommitted)
+				}
+				if tt.wantCommitted > 0 && !strings.Contains(err.Error(), "batch rolled back") {
+					t.Errorf("error %q does not mention the batch rollback", err)
+				}
+			}
+			if fmt.Sprint(batches) != fmt.Sprint(tt.wantBatches) {
+				t.Errorf("committed batches through %v, want %v", batches, tt.wantBatches)
+			}
+
+			var count int
+			if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+				t.Fatalf("Failed to count rows: %v", err)
+			}
+			if count != tt.wantRows {
+				t.Errorf("table has %d rows, want %d", count, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestExecuteScriptRetryTransaction(t *testing.T) {
+	script := "INSERT INTO t VALUES (1); INSERT INTO t VALUES (2);"
+
+	tests := []struct {
+		name        string
+		failures    int
+		code        string
+		attempts    int
+		wantRows    int
+		wantRetries int
+		wantErr     bool
+	}{
+		{name: "succeeds after retries", failures: 2, code: "40001", attempts: 3, wantRows: 2, wantRetries: 2},
+		{name: "deadlock", failures: 1, code: "40P01", attempts: 1, wantRows: 2, wantRetries: 1},
+		{name: "attempts exhausted", failures: 3, code: "40001", attempts: 2, wantRetries: 2, wantErr: true},
+		{name: "no retries by default", failures: 1, code: "40001", wantErr: true},
+		{name: "other failures are not retried", failures: 1, code: "23505", attempts: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "tx.db"))
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			defer func() {
+				if closeErr := db.Close(); closeErr != nil {
+					t.Errorf("Close() error = %v", closeErr)
+				}
+			}()
+			if _, err := db.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+				t.Fatalf("Failed to create table: %v", err)
+			}
+
+			failures, retries := 0, 0
+			err = ExecuteScriptWithOptions(context.Background(), db, script, ExecuteOptions{
+				Transaction:      TransactionAll,
+				RetryTransaction: ReconnectPolicy{Attempts: tt.attempts, Backoff: time.Millisecond},
+				OnRetryTransaction: func(attempt int, _ time.Duration, _ error) {
+					retries++
+					if attempt != retries {
+						t.Errorf("OnRetryTransaction attempt = %d, want %d", attempt, retries)
+					}
+				},
+				BeforeStatement: func(index int, _ string) error {
+					if index == 2 && failures < tt.failures {
+						failures++
+						return sqlStateError(tt.code)
+					}
+					return nil
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecuteScriptWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if retries != tt.wantRetries {
+				t.Errorf("retried %d times, want %d", retries, tt.wantRetries)
+			}
+
+			var count int
+			if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+				t.Fatalf("Failed to count rows: %v", err)
+			}
+			if count != tt.wantRows {
+				t.Errorf("table has %d rows, want %d", count, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestParseIsolation(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    sql.IsolationLevel
+		wantErr bool
+	}{
+		{name: "", want: sql.LevelDefault},
+		{name: "serializable", want: sql.LevelSerializable},
+		{name: "repeatable-read", want: sql.LevelRepeatableRead},
+		{name: "read-committed", want: sql.LevelReadCommitted},
+		{name: "snapshot", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIsolation(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIsolation(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseIsolation(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTransactionMode(t *testing.T) {
+	for _, name := range []string{"all", "per-statement", "none"} {
+		if _, err := ParseTransactionMode(name); err != nil {
+			t.Errorf("ParseTransactionMode(%q) error = %v", name, err)
+		}
+	}
+	if _, err := ParseTransactionMode("batch"); err == nil {
+		t.Error("ParseTransactionMode(batch) expected error")
+	}
+}
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// verifySavepoint is the savepoint Verify undoes its checks to inside a
+// caller's transaction.
+const verifySavepoint = "sql_loader_verify"
+
+// CheckError reports the failed check of a verification script.
+type CheckError struct {
+	// Index is the 1-based index of the failed check.
+	Index int
+	// Total is the number of checks in the script.
+	Total int
+	// Rows is the number of rows the check returned, and First the first of
+	// them, formatted. Both are unset when Err is.
+	Rows  int
+	First string
+	Err   error
+}
+
+func (e *CheckError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("check %d of %d failed: %v", e.Index, e.Total, e.Err)
+	}
+	return fmt.Sprintf("check %d of %d failed: returned %d row(s), first (%s)", e.Index, e.Total, e.Rows, e.First)
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// QueryExecer is an Execer that also runs queries returning rows: a
+// *sql.DB, *sql.Conn, *sql.Tx, or *Tx.
+type QueryExecer interface {
+	Execer
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Verify runs the statements of a verification script on db as checks,
+// returning how many passed. A check fails when its statement fails or,
+// for a query, when it returns any row, so each check selects the rows
+// that violate an expectation. Verify stops at the first failed check with
+// a *CheckError.
+//
+// The checks cannot change what they verify: on a *sql.DB or *sql.Conn
+// they run in a transaction that is rolled back, and inside a transaction
+// they are rolled back to a savepoint taken before the first.
+func Verify(ctx context.Context, db QueryExecer, script string) (int, error) {
+	statements := SplitStatements(script)
+	var passed int
+	err := RolledBack(ctx, db, func(q QueryExecer) error {
+		var err error
+		passed, err = runChecks(ctx, q, statements)
+		return err
+	})
+	return passed, err
+}
+
+// RolledBack runs fn so that nothing it does to db persists: on a *sql.DB
+// or *sql.Conn in a transaction that is rolled back, and inside a
+// transaction behind a savepoint that is rolled back to afterwards.
+func RolledBack(ctx context.Context, db QueryExecer, fn func(QueryExecer) error) error {
+	if b, ok := db.(txBeginner); ok {
+		tx, err := b.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+		return fn(tx)
+	}
+
+	if _, err := db.ExecContext(ctx, "SAVEPOINT "+verifySavepoint); err != nil {
+		return fmt.Errorf("failed to take savepoint: %w", err)
+	}
+	err := fn(db)
+	if _, undoErr := db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+verifySavepoint); undoErr != nil && err == nil {
+		return fmt.Errorf("failed to roll back checks: %w", undoErr)
+	}
+	if _, releaseErr := db.ExecContext(ctx, "RELEASE SAVEPOINT "+verifySavepoint); releaseErr != nil && err == nil {
+		return fmt.Errorf("failed to release savepoint: %w", releaseErr)
+	}
+	return err
+}
+
+func runChecks(ctx context.Context, db QueryExecer, statements []string) (int, error) {
+	for i, stmt := range statements {
+		if err := runCheck(ctx, db, stmt, i+1, len(statements)); err != nil {
+			return i, err
+		}
+	}
+	return len(statements), nil
+}
+
+func runCheck(ctx context.Context, db QueryExecer, stmt string, index, total int) error {
+	rows, err := db.QueryContext(ctx, stmt)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+		return &CheckError{Index: index, Total: total, Err: err}
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return &CheckError{Index: index, Total: total, Err: err}
+	}
+	failed := &CheckError{Index: index, Total: total}
+	for rows.Next() {
+		failed.Rows++
+		if failed.Rows > 1 {
+			continue
+		}
+		values, err := ScanStrings(rows, len(columns))
+		if err != nil {
+			return &CheckError{Index: index, Total: total, Err: err}
+		}
+		fields := make([]string, len(columns))
+		for i, v := range values {
+			fields[i] = columns[i] + "=" + v
+		}
+		failed.First = strings.Join(fields, ", ")
+	}
+	if err := rows.Err(); err != nil {
+		return &CheckError{Index: index, Total: total, Err: err}
+	}
+	if failed.Rows > 0 {
+		return failed
+	}
+	return nil
+}
+
+// ScanStrings scans the current row of rows, which has n columns, formatting
+// each value as text: NULL as "NULL" and byte slices as strings.
+func ScanStrings(rows *sql.Rows, n int) ([]string, error) {
+	values := make([]any, n)
+	ptrs := make([]any, n)
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	fields := make([]string, n)
+	for i, v := range values {
+		switch b := v.(type) {
+		case nil:
+			fields[i] = "NULL"
+		case []byte:
+			fields[i] = string(b)
+		default:
+			fields[i] = fmt.Sprint(v)
+		}
+	}
+	return fields, nil
+}
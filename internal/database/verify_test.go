@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		wantPassed int
+		wantIndex  int
+		wantFirst  string
+		wantErr    bool
+	}{
+		{name: "passing checks", script: "SELECT id FROM t WHERE id < 0; SELECT 1 WHERE 1 = 0;", wantPassed: 2},
+		{name: "row returned", script: "SELECT 1 WHERE 1 = 0; SELECT id, note FROM t WHERE note IS NULL;", wantPassed: 1, wantIndex: 2, wantFirst: "id=2, note=NULL"},
+		{name: "failed statement", script: "SELECT * FROM missing;", wantIndex: 1, wantErr: true},
+		{name: "writes are undone", script: "DELETE FROM t; SELECT id FROM t;", wantPassed: 2},
+	}
+
+	for _, tt := range tests {
+		for _, inTx := range []bool{false, true} {
+			name := tt.name
+			if inTx {
+				name += " in a transaction"
+			}
+			t.Run(name, func(t *testing.T) {
+				db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "verify.db"))
+				if err != nil {
+					t.Fatalf("Failed to open database: %v", err)
+				}
+				defer func() {
+					if closeErr := db.Close(); closeErr != nil {
+						t.Errorf("Close() error = %v", closeErr)
+					}
+				}()
+				if _, err := db.Exec("CREATE TABLE t (id INTEGER, note TEXT); INSERT INTO t VALUES (1, 'a'), (2, NULL);"); err != nil {
+					t.Fatalf("Failed to create table: %v", err)
+				}
+
+				var target QueryExecer = db
+				var tx *sql.Tx
+				if inTx {
+					if tx, err = db.Begin(); err != nil {
+						t.Fatalf("Begin() error = %v", err)
+					}
+					target = tx
+				}
+				passed, err := Verify(context.Background(), target, tt.script)
+				if passed != tt.wantPassed {
+					t.Errorf("Verify() passed = %d, want %d", passed, tt.wantPassed)
+				}
+				var checkErr *CheckError
+				switch {
+				case tt.wantIndex == 0 && err != nil:
+					t.Errorf("Verify() error = %v", err)
+				case tt.wantIndex != 0 && !errors.As(err, &checkErr):
+					t.Errorf("Verify() error = %v, want a CheckError", err)
+				case tt.wantIndex != 0:
+					if checkErr.Index != tt.wantIndex || checkErr.First != tt.wantFirst || (checkErr.Err != nil) != tt.wantErr {
+						t.Errorf("CheckError = %+v, want check %d with first row %q", checkErr, tt.wantIndex, tt.wantFirst)
+					}
+				}
+				if tx != nil {
+					if err := tx.Commit(); err != nil {
+						t.Fatalf("Commit() error = %v", err)
+					}
+				}
+
+				var count int
+				if err := db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count); err != nil {
+					t.Fatalf("Failed to count rows: %v", err)
+				}
+				if count != 2 {
+					t.Errorf("table has %d rows after Verify(), want 2", count)
+				}
+			})
+		}
+	}
+}
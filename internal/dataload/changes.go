@@ -0,0 +1,240 @@
+package dataload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// ChangeOptions controls how ApplyChanges applies a change file.
+type ChangeOptions struct {
+	// Driver is the database driver name, used to pick the placeholder style.
+	Driver string
+	// Table is the target table, optionally schema-qualified.
+	Table string
+	// OpColumn is the input column holding each record's operation:
+	// I (insert), U (update), or D (delete), case-insensitive.
+	OpColumn string
+	// Keys are the columns identifying the target row of updates and
+	// deletes.
+	Keys []string
+	// TxOptions sets the isolation level of the transaction the changes are
+	// applied in. Nil uses the server's default.
+	TxOptions *sql.TxOptions
+}
+
+// ChangeCounts reports the changes ApplyChanges made.
+type ChangeCounts struct {
+	Inserted int64
+	Updated  int64
+	Deleted  int64
+	// Missed counts updates and deletes whose key matched no row.
+	Missed int64
+}
+
+func (c ChangeCounts) String() string {
+	s := fmt.Sprintf("%d inserted, %d updated, %d deleted", c.Inserted, c.Updated, c.Deleted)
+	if c.Missed > 0 {
+		s += fmt.Sprintf(", %d missed", c.Missed)
+	}
+	return s
+}
+
+// ApplyChanges applies every record of r to the target table as an INSERT,
+// UPDATE, or DELETE according to its operation column, in input order and
+// in a single transaction, so a failure or cancelling ctx leaves the table
+// unchanged.
+func ApplyChanges(ctx context.Context, db *sql.DB, r Reader, opts ChangeOptions) (ChangeCounts, error) {
+	var counts ChangeCounts
+	if opts.Table == "" {
+		return counts, fmt.Errorf("table cannot be empty")
+	}
+	if len(opts.Keys) == 0 {
+		return counts, fmt.Errorf("change files need key columns")
+	}
+	plan, err := planChanges(r.Columns(), opts)
+	if err != nil {
+		return counts, err
+	}
+
+	tx, err := db.BeginTx(ctx, opts.TxOptions)
+	if err != nil {
+		return counts, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmts := map[string]*sql.Stmt{}
+	prepare := func(query string) (*sql.Stmt, error) {
+		if stmt, ok := stmts[query]; ok {
+			return stmt, nil
+		}
+		stmt, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		stmts[query] = stmt
+		return stmt, nil
+	}
+
+	for record := int64(1); ; record++ {
+		var values []any
+		values, err = r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ChangeCounts{}, err
+		}
+		if err = plan.apply(ctx, prepare, values, &counts); err != nil {
+			if ctx.Err() != nil {
+				err = context.Cause(ctx)
+			}
+			err = fmt.Errorf("record %d: %w", record, err)
+			return ChangeCounts{}, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return ChangeCounts{}, fmt.Errorf("failed to commit changes: %w", err)
+	}
+	return counts, nil
+}
+
+// changePlan holds the statements and value positions for a change file.
+type changePlan struct {
+	op                     int
+	data, keys, set        []int
+	insert, update, remove string
+}
+
+func planChanges(columns []string, opts ChangeOptions) (*changePlan, error) {
+	index := make(map[string]int, len(columns))
+	for i, name := range columns {
+		index[name] = i
+	}
+	op, ok := index[opts.OpColumn]
+	if !ok {
+		return nil, fmt.Errorf("operation column %q is not in the input", opts.OpColumn)
+	}
+
+	p := &changePlan{op: op}
+	isKey := make(map[string]bool, len(opts.Keys))
+	for _, name := range opts.Keys {
+		i, ok := index[name]
+		if !ok || i == op {
+			return nil, fmt.Errorf("key column %q is not in the input", name)
+		}
+		isKey[name] = true
+		p.keys = append(p.keys, i)
+	}
+	var dataCols, setCols []string
+	for i, name := range columns {
+		if i == op {
+			continue
+		}
+		p.data = append(p.data, i)
+		dataCols = append(dataCols, name)
+		if !isKey[name] {
+			p.set = append(p.set, i)
+			setCols = append(setCols, name)
+		}
+	}
+
+	table := database.QuoteQualified(opts.Table)
+	var n int
+	next := func() string {
+		n++
+		return database.Placeholder(opts.Driver, n)
+	}
+	where := func() string {
+		parts := make([]string, len(opts.Keys))
+		for i, name := range opts.Keys {
+			parts[i] = database.QuoteIdent(name) + " = " + next()
+		}
+		return strings.Join(parts, " AND ")
+	}
+
+	cols, params := make([]string, len(dataCols)), make([]string, len(dataCols))
+	for i, name := range dataCols {
+		cols[i], params[i] = database.QuoteIdent(name), next()
+	}
+	p.insert = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(cols, ", "), strings.Join(params, ", "))
+
+	if len(setCols) > 0 {
+		n = 0
+		set := make([]string, len(setCols))
+		for i, name := range setCols {
+			set[i] = database.QuoteIdent(name) + " = " + next()
+		}
+		p.update = fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(set, ", "), where())
+	}
+
+	n = 0
+	p.remove = fmt.Sprintf("DELETE FROM %s WHERE %s", table, where())
+	return p, nil
+}
+
+func (p *changePlan) apply(ctx context.Context, prepare func(string) (*sql.Stmt, error), values []any, counts *ChangeCounts) error {
+	pick := func(positions ...[]int) []any {
+		var args []any
+		for _, ps := range positions {
+			for _, i := range ps {
+				args = append(args, values[i])
+			}
+		}
+		return args
+	}
+	op, _ := values[p.op].(string)
+	op = strings.ToUpper(strings.TrimSpace(op))
+	if op == "U" || op == "D" {
+		for _, i := range p.keys {
+			if values[i] == nil {
+				return fmt.Errorf("key value is NULL")
+			}
+		}
+	}
+
+	var (
+		query string
+		args  []any
+		count *int64
+	)
+	switch op {
+	case "I":
+		query, args, count = p.insert, pick(p.data), &counts.Inserted
+	case "U":
+		if p.update == "" {
+			return fmt.Errorf("update has no non-key columns to set")
+		}
+		query, args, count = p.update, pick(p.set, p.keys), &counts.Updated
+	case "D":
+		query, args, count = p.remove, pick(p.keys), &counts.Deleted
+	default:
+		return fmt.Errorf("unknown operation %v (use I, U, or D)", values[p.op])
+	}
+
+	stmt, err := prepare(query)
+	if err != nil {
+		return err
+	}
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if op != "I" {
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			counts.Missed++
+			return nil
+		}
+	}
+	*count++
+	return nil
+}
@@ -0,0 +1,90 @@
+package dataload
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestApplyChanges(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`INSERT INTO events (id, name, payload) VALUES (1, 'a', 'x'), (2, 'b', 'y')`); err != nil {
+		t.Fatalf("Failed to seed table: %v", err)
+	}
+
+	input := "op,id,name,payload\nI,3,c,z\nu,1,a2,x2\nD,2,,\nU,9,none,none\nD,9,,\n"
+	r, err := NewReader(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	counts, err := ApplyChanges(context.Background(), db, r, ChangeOptions{Driver: "sqlite", Table: "events", OpColumn: "op", Keys: []string{"id"}})
+	if err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+	if got := counts.String(); got != "1 inserted, 1 updated, 1 deleted, 2 missed" {
+		t.Errorf("ApplyChanges() counts = %s", got)
+	}
+
+	rows, err := db.Query(`SELECT id, name, payload FROM events ORDER BY id`)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var got []string
+	for rows.Next() {
+		var id, name, payload string
+		if err := rows.Scan(&id, &name, &payload); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		got = append(got, id+":"+name+":"+payload)
+	}
+	if want := "1:a2:x2 3:c:z"; strings.Join(got, " ") != want {
+		t.Errorf("table rows = %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestApplyChangesRollsBack(t *testing.T) {
+	db := openTestDB(t)
+
+	input := "op,id,name,payload\nI,1,a,x\nX,2,b,y\n"
+	r, err := NewReader(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	_, err = ApplyChanges(context.Background(), db, r, ChangeOptions{Driver: "sqlite", Table: "events", OpColumn: "op", Keys: []string{"id"}})
+	if err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Fatalf("ApplyChanges() error = %v, want failure at record 2", err)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM events`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("table has %d rows after failed change file, want 0", count)
+	}
+}
+
+func TestApplyChangesValidation(t *testing.T) {
+	db := openTestDB(t)
+	tests := []struct {
+		name string
+		opts ChangeOptions
+	}{
+		{name: "no keys", opts: ChangeOptions{Table: "events", OpColumn: "op"}},
+		{name: "missing op column", opts: ChangeOptions{Table: "events", OpColumn: "kind", Keys: []string{"id"}}},
+		{name: "missing key column", opts: ChangeOptions{Table: "events", OpColumn: "op", Keys: []string{"uuid"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader("op,id\nI,1\n"), FormatCSV)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			tt.opts.Driver = "sqlite"
+			if _, err := ApplyChanges(context.Background(), db, r, tt.opts); err == nil {
+				t.Error("ApplyChanges() expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package dataload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// Copy streams every record of r into the target table with the PostgreSQL
+// COPY protocol, returning the number of rows copied. String values are
+// parsed by the server according to each column's type, as for COPY's text
+// format. db must be opened with the pgx driver. COPY is atomic, so
+// cancelling ctx leaves the table unchanged.
+func Copy(ctx context.Context, db *sql.DB, r Reader, table string) (int64, error) {
+	if table == "" {
+		return 0, fmt.Errorf("table cannot be empty")
+	}
+	columns := r.Columns()
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("input has no columns")
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var rows int64
+	err = conn.Raw(func(driverConn any) error {
+		pc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("COPY requires the PostgreSQL driver")
+		}
+		var err error
+		rows, err = pc.Conn().CopyFrom(ctx, pgx.Identifier(database.SplitQualified(table)), columns, &copySource{r: r, columns: len(columns)})
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+		return 0, fmt.Errorf("failed to copy rows: %w", err)
+	}
+	return rows, nil
+}
+
+// copySource adapts a Reader to pgx.CopyFromSource.
+type copySource struct {
+	r       Reader
+	columns int
+	row     int64
+	values  []any
+	err     error
+}
+
+func (s *copySource) Next() bool {
+	s.values, s.err = s.r.Next()
+	if errors.Is(s.err, io.EOF) {
+		s.err = nil
+		return false
+	}
+	if s.err == nil {
+		s.row++
+		if len(s.values) != s.columns {
+			s.err = fmt.Errorf("row %d has %d values, expected %d", s.row, len(s.values), s.columns)
+		}
+	}
+	return s.err == nil
+}
+
+func (s *copySource) Values() ([]any, error) {
+	return s.values, nil
+}
+
+func (s *copySource) Err() error {
+	return s.err
+}
@@ -0,0 +1,136 @@
+package dataload
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// HeaderMode selects whether the first CSV row names the columns.
+type HeaderMode string
+
+// CSV header modes.
+const (
+	// HeaderAuto treats the first row as a header unless it looks like data.
+	HeaderAuto HeaderMode = "auto"
+	// HeaderPresent always treats the first row as a header.
+	HeaderPresent HeaderMode = "yes"
+	// HeaderAbsent treats every row as data.
+	HeaderAbsent HeaderMode = "no"
+)
+
+// ParseHeaderMode validates a user-supplied header mode.
+func ParseHeaderMode(name string) (HeaderMode, error) {
+	switch HeaderMode(name) {
+	case HeaderAuto, HeaderPresent, HeaderAbsent:
+		return HeaderMode(name), nil
+	default:
+		return "", fmt.Errorf("unsupported header mode %q (use auto, yes, or no)", name)
+	}
+}
+
+// CSVOptions configures NewCSVReader.
+type CSVOptions struct {
+	// Delimiter separates fields; zero means a comma.
+	Delimiter rune
+	// Header selects header handling; the zero value means HeaderPresent.
+	Header HeaderMode
+	// Columns names the columns of input without a header row.
+	Columns []string
+}
+
+// NewCSVReader returns a Reader decoding CSV from r with the given options.
+// A leading UTF-8 byte order mark is skipped.
+func NewCSVReader(r io.Reader, opts CSVOptions) (Reader, error) {
+	r, err := skipBOM(r)
+	if err != nil {
+		return nil, err
+	}
+	return newCSVReader(r, opts)
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	columns []string
+	// pending is a first row that turned out to be data.
+	pending []string
+}
+
+func newCSVReader(r io.Reader, opts CSVOptions) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		cr.Comma = opts.Delimiter
+	}
+	first, err := cr.Read()
+	// encoding/csv skips empty lines but not whitespace-only ones, which
+	// would otherwise become a one-field header.
+	for err == nil && len(first) == 1 && strings.TrimSpace(first[0]) == "" {
+		first, err = cr.Read()
+	}
+	if errors.Is(err, io.EOF) {
+		if opts.Header == HeaderAbsent && len(opts.Columns) > 0 {
+			return &csvReader{r: cr, columns: opts.Columns}, nil
+		}
+		return nil, fmt.Errorf("CSV input has no header row: %w", ErrNoRecords)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	header := true
+	switch opts.Header {
+	case HeaderAbsent:
+		header = false
+	case HeaderAuto:
+		header = slices.Equal(first, opts.Columns) || looksLikeHeader(first)
+	}
+	if header {
+		return &csvReader{r: cr, columns: first}, nil
+	}
+	if len(opts.Columns) == 0 {
+		return nil, fmt.Errorf("CSV input has no header row; column names are required")
+	}
+	if len(first) != len(opts.Columns) {
+		return nil, fmt.Errorf("CSV record has %d fields, expected %d columns", len(first), len(opts.Columns))
+	}
+	return &csvReader{r: cr, columns: opts.Columns, pending: first}, nil
+}
+
+// looksLikeHeader reports whether a row could be a header: every field is a
+// distinct, non-empty name rather than a number, date, or timestamp.
+func looksLikeHeader(row []string) bool {
+	seen := make(map[string]bool, len(row))
+	for _, f := range row {
+		if f == "" || seen[f] || valueKind(f) != TypeText {
+			return false
+		}
+		seen[f] = true
+	}
+	return true
+}
+
+func (c *csvReader) Columns() []string {
+	return c.columns
+}
+
+func (c *csvReader) Next() ([]any, error) {
+	fields := c.pending
+	c.pending = nil
+	if fields == nil {
+		var err error
+		if fields, err = c.r.Read(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("failed to read CSV record: %w", err)
+		}
+	}
+	values := make([]any, len(fields))
+	for i, f := range fields {
+		values[i] = f
+	}
+	return values, nil
+}
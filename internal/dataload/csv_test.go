@@ -0,0 +1,126 @@
+package dataload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNewCSVReader(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		opts        CSVOptions
+		wantColumns string
+		wantRows    string
+		wantErr     bool
+	}{
+		{
+			name:        "semicolon delimiter",
+			input:       "id;name\n1;a\n",
+			opts:        CSVOptions{Delimiter: ';'},
+			wantColumns: "id name",
+			wantRows:    "1 a",
+		},
+		{
+			name:        "auto detects header",
+			input:       "id\tname\n1\ta\n",
+			opts:        CSVOptions{Delimiter: '\t', Header: HeaderAuto, Columns: []string{"x", "y"}},
+			wantColumns: "id name",
+			wantRows:    "1 a",
+		},
+		{
+			name:        "auto detects data row",
+			input:       "1,a\n2,b\n",
+			opts:        CSVOptions{Header: HeaderAuto, Columns: []string{"id", "name"}},
+			wantColumns: "id name",
+			wantRows:    "1 a|2 b",
+		},
+		{
+			name:        "auto matches given columns",
+			input:       "id,name\n1,a\n",
+			opts:        CSVOptions{Header: HeaderAuto, Columns: []string{"id", "name"}},
+			wantColumns: "id name",
+			wantRows:    "1 a",
+		},
+		{
+			name:        "header absent",
+			input:       "id,name\n",
+			opts:        CSVOptions{Header: HeaderAbsent, Columns: []string{"a", "b"}},
+			wantColumns: "a b",
+			wantRows:    "id name",
+		},
+		{
+			name:        "whitespace-only input with columns",
+			input:       "  \n\t\n",
+			opts:        CSVOptions{Header: HeaderAbsent, Columns: []string{"a", "b"}},
+			wantColumns: "a b",
+		},
+		{
+			name:    "header absent without columns",
+			input:   "1,a\n",
+			opts:    CSVOptions{Header: HeaderAbsent},
+			wantErr: true,
+		},
+		{
+			name:    "column count mismatch",
+			input:   "1,a\n",
+			opts:    CSVOptions{Header: HeaderAbsent, Columns: []string{"id"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewCSVReader(strings.NewReader(tt.input), tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCSVReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := strings.Join(r.Columns(), " "); got != tt.wantColumns {
+				t.Errorf("Columns() = %q, want %q", got, tt.wantColumns)
+			}
+			var rows []string
+			for {
+				values, err := r.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				rows = append(rows, strings.TrimSuffix(fmt.Sprintln(values...), "\n"))
+			}
+			if got := strings.Join(rows, "|"); got != tt.wantRows {
+				t.Errorf("records = %q, want %q", got, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestParseHeaderMode(t *testing.T) {
+	for _, name := range []string{"auto", "yes", "no"} {
+		if _, err := ParseHeaderMode(name); err != nil {
+			t.Errorf("ParseHeaderMode(%q) error = %v", name, err)
+		}
+	}
+	if _, err := ParseHeaderMode("maybe"); err == nil {
+		t.Error("ParseHeaderMode(maybe) expected error")
+	}
+}
+
+func TestCopyRequiresPostgres(t *testing.T) {
+	db := openTestDB(t)
+	r, err := NewReader(strings.NewReader("id\n1\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if _, err := Copy(context.Background(), db, r, "events"); err == nil || !strings.Contains(err.Error(), "PostgreSQL") {
+		t.Errorf("Copy() on SQLite error = %v, want driver error", err)
+	}
+}
@@ -0,0 +1,127 @@
+// Package dataload provides functionality for streaming structured records
+// (CSV, JSON Lines) into database tables using batched multi-row INSERTs.
+package dataload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// DefaultBatchSize is the number of rows inserted per statement when
+// Options.BatchSize is not set.
+const DefaultBatchSize = 500
+
+// Options controls how records are written to the target table.
+type Options struct {
+	// Driver is the database driver name, used to pick the placeholder style.
+	Driver string
+	// Table is the target table, optionally schema-qualified.
+	Table string
+	// BatchSize is the maximum number of rows per INSERT statement.
+	BatchSize int
+}
+
+// Load reads every record from r and inserts it into the target table,
+// returning the number of rows inserted. On a *sql.DB each batch commits on
+// its own; pass a *sql.Tx to load all records or none. Cancelling ctx
+// aborts the batch in flight.
+func Load(ctx context.Context, db database.Execer, r Reader, opts Options) (int64, error) {
+	if opts.Table == "" {
+		return 0, fmt.Errorf("table cannot be empty")
+	}
+	columns := r.Columns()
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("input has no columns")
+	}
+
+	limit := database.MaxParams(opts.Driver) / len(columns)
+	if limit < 1 {
+		return 0, fmt.Errorf("input has %d columns, more than the %d parameters one INSERT statement can bind", len(columns), database.MaxParams(opts.Driver))
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	batchSize = min(batchSize, limit)
+
+	var (
+		total int64
+		batch = make([][]any, 0, batchSize)
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		query, args := insertStatement(opts.Driver, opts.Table, columns, batch)
+		if _, err := db.ExecContext(ctx, query, args...); err != nil {
+			if ctx.Err() != nil {
+				err = context.Cause(ctx)
+			}
+			return fmt.Errorf("failed to insert batch ending at row %d: %w", total+int64(len(batch)), err)
+		}
+		total += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		values, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return total, err
+		}
+		if len(values) != len(columns) {
+			return total, fmt.Errorf("row %d has %d values, expected %d", total+int64(len(batch))+1, len(values), len(columns))
+		}
+		batch = append(batch, values)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+// insertStatement builds a multi-row INSERT for the given rows.
+func insertStatement(driver, table string, columns []string, rows [][]any) (string, []any) {
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(database.QuoteQualified(table))
+	b.WriteString(" (")
+	for i, col := range columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(database.QuoteIdent(col))
+	}
+	b.WriteString(") VALUES ")
+
+	args := make([]any, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j, v := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			args = append(args, v)
+			b.WriteString(database.Placeholder(driver, len(args)))
+		}
+		b.WriteString(")")
+	}
+	return b.String(), args
+}
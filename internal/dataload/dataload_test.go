@@ -0,0 +1,308 @@
+package dataload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	})
+	if _, err := db.Exec(`CREATE TABLE events (id INTEGER, name TEXT, payload TEXT)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	return db
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    Format
+		input     string
+		batchSize int
+		want      int64
+		wantErr   bool
+	}{
+		{
+			name:   "jsonl records",
+			format: FormatJSONL,
+			input: `{"id": 1, "name": "a", "payload": {"k": 1}}
+{"id": 2, "name": "b"}
+
+{"id": 3, "name": null}`,
+			batchSize: 2,
+			want:      3,
+		},
+		{
+			name:      "csv records",
+			format:    FormatCSV,
+			input:     "id,name\n1,a\n2,b\n",
+			batchSize: 10,
+			want:      2,
+		},
+		{
+			name:    "jsonl unknown field",
+			format:  FormatJSONL,
+			input:   "{\"id\": 1}\n{\"id\": 2, \"extra\": true}\n",
+			wantErr: true,
+		},
+		{
+			name:    "more columns than parameters",
+			format:  FormatCSV,
+			input:   strings.Repeat("id,", 32766) + "id\n",
+			wantErr: true,
+		},
+		{
+			name:    "csv unknown column",
+			format:  FormatCSV,
+			input:   "id,missing\n1,x\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			r, err := NewReader(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			got, err := Load(context.Background(), db, r, Options{Driver: "sqlite", Table: "events", BatchSize: tt.batchSize})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Load() = %d, want %d", got, tt.want)
+			}
+			var count int64
+			if err := db.QueryRow(`SELECT COUNT(*) FROM events`).Scan(&count); err != nil {
+				t.Fatalf("Failed to count rows: %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("table has %d rows, want %d", count, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadNestedJSON(t *testing.T) {
+	db := openTestDB(t)
+	r, err := NewReader(strings.NewReader(`{"id": 7, "payload": {"k": [1, 2]}}`), FormatJSONL)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if _, err := Load(context.Background(), db, r, Options{Driver: "sqlite", Table: "events"}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var payload string
+	if err := db.QueryRow(`SELECT payload FROM events WHERE id = 7`).Scan(&payload); err != nil {
+		t.Fatalf("Failed to query payload: %v", err)
+	}
+	if payload != `{"k":[1,2]}` {
+		t.Errorf("payload = %q, want %q", payload, `{"k":[1,2]}`)
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "jsonl columns are sorted",
+			format: FormatJSONL,
+			input:  `{"b": 1, "a": 2}`,
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "csv header",
+			format: FormatCSV,
+			input:  "x,y\n",
+			want:   []string{"x", "y"},
+		},
+		{
+			name:   "csv with BOM and CRLF",
+			format: FormatCSV,
+			input:  "\ufeffx,y\r\n1,2\r\n",
+			want:   []string{"x", "y"},
+		},
+		{
+			name:   "jsonl with BOM and CRLF",
+			format: FormatJSONL,
+			input:  "\ufeff{\"a\": 1}\r\n{\"a\": 2}\r\n",
+			want:   []string{"a"},
+		},
+		{
+			name:    "empty jsonl",
+			format:  FormatJSONL,
+			input:   "\n\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty csv",
+			format:  FormatCSV,
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			format:  FormatJSONL,
+			input:   "not json",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tt.input), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewReader() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := strings.Join(r.Columns(), ","); got != strings.Join(tt.want, ",") {
+				t.Errorf("Columns() = %v, want %v", r.Columns(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewReaderReadError(t *testing.T) {
+	input := io.MultiReader(strings.NewReader(`{"a":`), iotest.ErrReader(errors.New("input too large")))
+	_, err := NewReader(input, FormatJSONL)
+	if err == nil || !strings.Contains(err.Error(), "input too large") {
+		t.Errorf("NewReader() error = %v, want the read error", err)
+	}
+}
+
+func TestNewReaderEmpty(t *testing.T) {
+	for _, format := range []Format{FormatCSV, FormatJSONL} {
+		for _, input := range []string{"", "\n\n", " \t\r\n  \n", "\ufeff"} {
+			if _, err := NewReader(strings.NewReader(input), format); !errors.Is(err, ErrNoRecords) {
+				t.Errorf("NewReader(%q, %s) error = %v, want ErrNoRecords", input, format, err)
+			}
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if _, err := ParseFormat("jsonl"); err != nil {
+		t.Errorf("ParseFormat(jsonl) error = %v", err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml) expected error")
+	}
+}
+
+func TestPeek(t *testing.T) {
+	r, err := NewReader(strings.NewReader("id,name\n1,a\n2,b\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	first, r, err := Peek(r)
+	if err != nil {
+		t.Fatalf("Peek() error = %v", err)
+	}
+	if len(first) != 2 || first[0] != "1" {
+		t.Errorf("Peek() first = %v", first)
+	}
+
+	db := openTestDB(t)
+	got, err := Load(context.Background(), db, r, Options{Driver: "sqlite", Table: "events"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got != 2 {
+		t.Errorf("Load() after Peek = %d rows, want 2", got)
+	}
+}
+
+func TestRenameColumns(t *testing.T) {
+	r, err := NewReader(strings.NewReader("ID,Name\n1,a\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	r, err = RenameColumns(r, strings.ToLower)
+	if err != nil {
+		t.Fatalf("RenameColumns() error = %v", err)
+	}
+	if got := strings.Join(r.Columns(), ","); got != "id,name" {
+		t.Errorf("Columns() = %s, want id,name", got)
+	}
+
+	db := openTestDB(t)
+	if got, err := Load(context.Background(), db, r, Options{Driver: "sqlite", Table: "events"}); err != nil || got != 1 {
+		t.Fatalf("Load() = %d, %v, want 1 row", got, err)
+	}
+
+	r, err = NewReader(strings.NewReader("Name,NAME\na,b\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if _, err := RenameColumns(r, strings.ToLower); err == nil {
+		t.Error("RenameColumns() with colliding names expected error")
+	}
+}
+
+func TestReservedWordIdentifiers(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`CREATE TABLE "order" ("user" TEXT, "select" INTEGER, "weird ""name" TEXT)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	const table = `"order"`
+
+	r, err := NewReader(strings.NewReader("user,select,\"weird \"\"name\"\nann,1,x\nbob,2,y\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if _, err := Load(context.Background(), db, r, Options{Driver: "sqlite", Table: table}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	r, err = NewReader(strings.NewReader("op,user,select,\"weird \"\"name\"\nU,ann,10,z\nD,bob,,\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if _, err := ApplyChanges(context.Background(), db, r, ChangeOptions{Driver: "sqlite", Table: table, OpColumn: "op", Keys: []string{"user"}}); err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+
+	if _, err := db.Exec(AddColumnStatement(table, NewColumn{Name: `group "by"`, Type: "TEXT"})); err != nil {
+		t.Fatalf("AddColumnStatement() error = %v", err)
+	}
+
+	var user, weird string
+	var sel int
+	if err := db.QueryRow(`SELECT "user", "select", "weird ""name" FROM "order"`).Scan(&user, &sel, &weird); err != nil {
+		t.Fatalf("Failed to read row: %v", err)
+	}
+	if user != "ann" || sel != 10 || weird != "z" {
+		t.Errorf("row = %s, %d, %s, want ann, 10, z", user, sel, weird)
+	}
+}
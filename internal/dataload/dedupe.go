@@ -0,0 +1,160 @@
+package dataload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DuplicateMode selects what happens to records that repeat a key.
+type DuplicateMode string
+
+// Duplicate key handling modes.
+const (
+	// DuplicateReject fails before loading when any key repeats.
+	DuplicateReject DuplicateMode = "error"
+	// DuplicateKeepFirst loads the first record of each key.
+	DuplicateKeepFirst DuplicateMode = "first"
+	// DuplicateKeepLast loads the last record of each key.
+	DuplicateKeepLast DuplicateMode = "last"
+)
+
+// ParseDuplicateMode validates a user-supplied mode name.
+func ParseDuplicateMode(name string) (DuplicateMode, error) {
+	switch DuplicateMode(name) {
+	case DuplicateReject, DuplicateKeepFirst, DuplicateKeepLast:
+		return DuplicateMode(name), nil
+	default:
+		return "", fmt.Errorf("unsupported duplicate mode %q (use error, first, or last)", name)
+	}
+}
+
+// Duplicate is a key value found on more than one record.
+type Duplicate struct {
+	Key []any
+	// Records are the 1-based positions of the records sharing the key.
+	Records []int64
+}
+
+func (d Duplicate) String() string {
+	key := make([]string, len(d.Key))
+	for i, v := range d.Key {
+		key[i] = fmt.Sprintf("%v", v)
+	}
+	records := make([]string, len(d.Records))
+	for i, n := range d.Records {
+		records[i] = fmt.Sprint(n)
+	}
+	return fmt.Sprintf("key (%s) on records %s", strings.Join(key, ", "), strings.Join(records, ", "))
+}
+
+// Dedupe reads all of r and finds records sharing a value of the key
+// columns, reporting them in order of first appearance. Records with a NULL
+// key are never duplicates, matching unique constraints. The returned Reader
+// yields the records to load in order of first appearance, which is every
+// record for DuplicateReject. The input is buffered in memory.
+func Dedupe(r Reader, keys []string, mode DuplicateMode) (Reader, []Duplicate, error) {
+	columns := r.Columns()
+	index := make(map[string]int, len(columns))
+	for i, name := range columns {
+		index[name] = i
+	}
+	positions := make([]int, len(keys))
+	for i, name := range keys {
+		pos, ok := index[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("key column %q is not in the input", name)
+		}
+		positions[i] = pos
+	}
+
+	type keyState struct{ slot, dup int }
+	var (
+		records [][]any
+		keep    []int
+		seen    = map[string]*keyState{}
+		dups    []Duplicate
+	)
+	for {
+		values, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, values)
+		current := len(records) - 1
+
+		key, ok := keyOf(values, positions)
+		if !ok {
+			keep = append(keep, current)
+			continue
+		}
+		st, found := seen[key]
+		if !found {
+			seen[key] = &keyState{slot: len(keep), dup: -1}
+			keep = append(keep, current)
+			continue
+		}
+		if st.dup < 0 {
+			st.dup = len(dups)
+			k := make([]any, len(positions))
+			for i, pos := range positions {
+				k[i] = values[pos]
+			}
+			dups = append(dups, Duplicate{Key: k, Records: []int64{int64(keep[st.slot]) + 1}})
+		}
+		dups[st.dup].Records = append(dups[st.dup].Records, int64(current)+1)
+		if mode == DuplicateKeepLast {
+			keep[st.slot] = current
+		}
+	}
+
+	sort.Slice(dups, func(i, j int) bool { return dups[i].Records[0] < dups[j].Records[0] })
+
+	if mode == DuplicateReject {
+		return &sliceReader{columns: columns, records: records}, dups, nil
+	}
+	kept := make([][]any, len(keep))
+	for i, n := range keep {
+		kept[i] = records[n]
+	}
+	return &sliceReader{columns: columns, records: kept}, dups, nil
+}
+
+// keyOf encodes the key columns of a record, reporting false when any of
+// them is NULL. Values are compared by their text, so "1" in CSV and 1 in
+// JSON collide just as they would in an integer column.
+func keyOf(values []any, positions []int) (string, bool) {
+	var b strings.Builder
+	for _, pos := range positions {
+		if pos >= len(values) || values[pos] == nil {
+			return "", false
+		}
+		s := fmt.Sprintf("%v", values[pos])
+		fmt.Fprintf(&b, "%d:%s", len(s), s)
+	}
+	return b.String(), true
+}
+
+type sliceReader struct {
+	columns []string
+	records [][]any
+}
+
+func (s *sliceReader) Columns() []string {
+	return s.columns
+}
+
+func (s *sliceReader) Next() ([]any, error) {
+	if len(s.records) == 0 {
+		return nil, io.EOF
+	}
+	next := s.records[0]
+	s.records[0] = nil
+	s.records = s.records[1:]
+	return next, nil
+}
@@ -0,0 +1,86 @@
+package dataload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	input := "id,region,name\n1,eu,a\n2,eu,b\n1,eu,c\n1,us,d\n,eu,e\n,eu,f\n1,eu,g\n2,eu,h\n"
+
+	tests := []struct {
+		name      string
+		mode      DuplicateMode
+		wantNames string
+	}{
+		{name: "error keeps every record", mode: DuplicateReject, wantNames: "a b c d e f g h"},
+		{name: "first", mode: DuplicateKeepFirst, wantNames: "a b d e f"},
+		{name: "last", mode: DuplicateKeepLast, wantNames: "g h d e f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(input), FormatCSV)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			// Empty ids are loaded as NULL and so never collide.
+			nulls, err := ApplyNulls(r, NullPolicy{EmptyAsNull: true})
+			if err != nil {
+				t.Fatalf("ApplyNulls() error = %v", err)
+			}
+			deduped, dups, err := Dedupe(nulls, []string{"id", "region"}, tt.mode)
+			if err != nil {
+				t.Fatalf("Dedupe() error = %v", err)
+			}
+
+			var names []string
+			for {
+				values, err := deduped.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				names = append(names, fmt.Sprint(values[2]))
+			}
+			if got := strings.Join(names, " "); got != tt.wantNames {
+				t.Errorf("records = %q, want %q", got, tt.wantNames)
+			}
+
+			var got []string
+			for _, d := range dups {
+				got = append(got, d.String())
+			}
+			want := "key (1, eu) on records 1, 3, 7; key (2, eu) on records 2, 8"
+			if strings.Join(got, "; ") != want {
+				t.Errorf("duplicates = %q, want %q", strings.Join(got, "; "), want)
+			}
+		})
+	}
+}
+
+func TestDedupeUnknownKey(t *testing.T) {
+	r, err := NewReader(strings.NewReader("id\n1\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if _, _, err := Dedupe(r, []string{"missing"}, DuplicateReject); err == nil {
+		t.Error("Dedupe() with unknown key column expected error")
+	}
+}
+
+func TestParseDuplicateMode(t *testing.T) {
+	for _, name := range []string{"error", "first", "last"} {
+		if _, err := ParseDuplicateMode(name); err != nil {
+			t.Errorf("ParseDuplicateMode(%q) error = %v", name, err)
+		}
+	}
+	if _, err := ParseDuplicateMode("newest"); err == nil {
+		t.Error("ParseDuplicateMode(newest) expected error")
+	}
+}
@@ -0,0 +1,179 @@
+package dataload
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/catalog"
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// EvolveSampleSize is the number of leading records used to infer the types
+// of new columns.
+const EvolveSampleSize = 100
+
+// NewColumn is an input column missing from the target table, with the
+// type inferred for it.
+type NewColumn struct {
+	Name string
+	Type string
+}
+
+// Logical types inferred from data, which TypeMap and the driver defaults
+// map to column types.
+const (
+	TypeInteger   = "integer"
+	TypeNumber    = "number"
+	TypeBoolean   = "boolean"
+	TypeDate      = "date"
+	TypeTimestamp = "timestamp"
+	TypeText      = "text"
+)
+
+// TypeMap overrides the column type used for logical types, e.g.
+// {"timestamp": "timestamptz"}, so house conventions hold for every column
+// sql-loader creates.
+type TypeMap map[string]string
+
+// ParseTypeMap validates the logical type names of a configured mapping.
+func ParseTypeMap(m map[string]string) (TypeMap, error) {
+	for logical, typ := range m {
+		switch logical {
+		case TypeInteger, TypeNumber, TypeBoolean, TypeDate, TypeTimestamp, TypeText:
+		default:
+			return nil, fmt.Errorf("unknown logical type %q in type mapping (use integer, number, boolean, date, timestamp, or text)", logical)
+		}
+		if !validTypeName(typ) {
+			return nil, fmt.Errorf("invalid column type %q for %s in type mapping", typ, logical)
+		}
+	}
+	return TypeMap(m), nil
+}
+
+// validTypeName accepts type names such as "numeric(12, 2)" or "text[]",
+// rejecting anything that could extend the DDL statement it is placed in.
+func validTypeName(typ string) bool {
+	if strings.TrimSpace(typ) == "" {
+		return false
+	}
+	for _, c := range typ {
+		if !(c == ' ' || c == '_' || c == '(' || c == ')' || c == ',' || c == '[' || c == ']' ||
+			c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// MissingColumns returns the input columns that table lacks, in input order,
+// inferring each type from the sample records and mapping it with types.
+func MissingColumns(driver string, table *catalog.Table, columns []string, samples [][]any, types TypeMap) []NewColumn {
+	var missing []NewColumn
+	for i, name := range columns {
+		if _, ok := table.Column(name); ok {
+			continue
+		}
+		values := make([]any, 0, len(samples))
+		for _, record := range samples {
+			if i < len(record) {
+				values = append(values, record[i])
+			}
+		}
+		missing = append(missing, NewColumn{Name: name, Type: ColumnType(driver, InferType(values), types)})
+	}
+	return missing
+}
+
+// InferType returns the narrowest logical type accepting every non-NULL
+// value: an integer, a floating-point number, a boolean, a date, a
+// timestamp, or else text. Columns with no values are text.
+func InferType(values []any) string {
+	kind := ""
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		k := valueKind(v)
+		switch {
+		case kind == "" || kind == k:
+			kind = k
+		case kind == TypeInteger && k == TypeNumber, kind == TypeNumber && k == TypeInteger:
+			kind = TypeNumber
+		case kind == TypeDate && k == TypeTimestamp, kind == TypeTimestamp && k == TypeDate:
+			kind = TypeTimestamp
+		default:
+			kind = TypeText
+		}
+	}
+	if kind == "" {
+		return TypeText
+	}
+	return kind
+}
+
+func valueKind(v any) string {
+	switch val := v.(type) {
+	case int64:
+		return TypeInteger
+	case float64:
+		return TypeNumber
+	case bool:
+		return TypeBoolean
+	case string:
+		s := strings.TrimSpace(val)
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return TypeInteger
+		}
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			return TypeNumber
+		}
+		if _, err := time.Parse(time.DateOnly, s); err == nil {
+			return TypeDate
+		}
+		if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return TypeTimestamp
+		}
+	}
+	return TypeText
+}
+
+// ColumnType maps a logical type to a column type for the driver, unless
+// types overrides it. SQLite has no date or boolean storage class, so those
+// default to its conventional affinities.
+func ColumnType(driver, logical string, types TypeMap) string {
+	if typ, ok := types[logical]; ok {
+		return typ
+	}
+	if driver == "sqlite" {
+		switch logical {
+		case TypeInteger, TypeBoolean:
+			return "INTEGER"
+		case TypeNumber:
+			return "REAL"
+		default:
+			return "TEXT"
+		}
+	}
+	switch logical {
+	case TypeInteger:
+		return "BIGINT"
+	case TypeNumber:
+		return "DOUBLE PRECISION"
+	case TypeBoolean:
+		return "BOOLEAN"
+	case TypeDate:
+		return "DATE"
+	case TypeTimestamp:
+		return "TIMESTAMPTZ"
+	default:
+		return "TEXT"
+	}
+}
+
+// AddColumnStatement returns the ALTER TABLE statement adding c to table.
+// New columns are nullable, since existing rows have no value for them.
+func AddColumnStatement(table string, c NewColumn) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", database.QuoteQualified(table), database.QuoteIdent(c.Name), c.Type)
+}
@@ -0,0 +1,78 @@
+package dataload
+
+import (
+	"testing"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/catalog"
+)
+
+func TestInferType(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []any
+		postgres string
+		sqlite   string
+	}{
+		{name: "csv integers", values: []any{"1", nil, "-20"}, postgres: "BIGINT", sqlite: "INTEGER"},
+		{name: "mixed numbers", values: []any{int64(1), 2.5, "3"}, postgres: "DOUBLE PRECISION", sqlite: "REAL"},
+		{name: "booleans", values: []any{true, false}, postgres: "BOOLEAN", sqlite: "INTEGER"},
+		{name: "dates", values: []any{"2024-01-31", "2024-02-01"}, postgres: "DATE", sqlite: "TEXT"},
+		{name: "dates and timestamps", values: []any{"2024-01-31", "2024-02-01T10:00:00Z"}, postgres: "TIMESTAMPTZ", sqlite: "TEXT"},
+		{name: "text wins", values: []any{"1", "one"}, postgres: "TEXT", sqlite: "TEXT"},
+		{name: "all NULL", values: []any{nil, nil}, postgres: "TEXT", sqlite: "TEXT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logical := InferType(tt.values)
+			if got := ColumnType("postgres", logical, nil); got != tt.postgres {
+				t.Errorf("ColumnType(postgres, %s) = %s, want %s", logical, got, tt.postgres)
+			}
+			if got := ColumnType("sqlite", logical, nil); got != tt.sqlite {
+				t.Errorf("ColumnType(sqlite, %s) = %s, want %s", logical, got, tt.sqlite)
+			}
+		})
+	}
+}
+
+func TestMissingColumns(t *testing.T) {
+	table := &catalog.Table{Name: "feed", Columns: []catalog.Column{{Name: "id", Type: "integer"}}}
+	samples := [][]any{{"1", "x", "2.5"}, {"2", "y", "3"}}
+
+	types := TypeMap{TypeNumber: "numeric(12, 2)"}
+	got := MissingColumns("postgres", table, []string{"id", "label", "score"}, samples, types)
+	want := []NewColumn{{Name: "label", Type: "TEXT"}, {Name: "score", Type: "numeric(12, 2)"}}
+	if len(got) != len(want) {
+		t.Fatalf("MissingColumns() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("MissingColumns()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	stmt := AddColumnStatement("public.feed", got[1])
+	if stmt != `ALTER TABLE "public"."feed" ADD COLUMN "score" numeric(12, 2)` {
+		t.Errorf("AddColumnStatement() = %s", stmt)
+	}
+}
+
+func TestParseTypeMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       map[string]string
+		wantErr bool
+	}{
+		{name: "valid", m: map[string]string{"timestamp": "timestamptz", "number": "numeric(12, 2)", "text": "text[]"}},
+		{name: "unknown logical type", m: map[string]string{"datetime": "timestamptz"}, wantErr: true},
+		{name: "empty type", m: map[string]string{"text": " "}, wantErr: true},
+		{name: "injection", m: map[string]string{"text": "text; DROP TABLE users"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTypeMap(tt.m); (err != nil) != tt.wantErr {
+				t.Errorf("ParseTypeMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package dataload
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FieldMapping maps a field of JSON records, possibly nested, to a column.
+type FieldMapping struct {
+	Column string
+	// Path is the field as written, such as .user.name.
+	Path string
+	// steps are the object keys (strings) and array indexes (ints) leading
+	// to the field.
+	steps []any
+}
+
+// ParseMapping parses a comma-separated list of column=path pairs, such as
+// id=.id,name=.user.name,tag=.tags[0]. A path is a sequence of .key steps
+// and [n] array indexes; write a key holding dots, brackets, or commas as
+// ["key"].
+func ParseMapping(spec string) ([]FieldMapping, error) {
+	var mappings []FieldMapping
+	seen := map[string]bool{}
+	for _, pair := range splitMapping(spec) {
+		column, path, ok := strings.Cut(pair, "=")
+		column, path = strings.TrimSpace(column), strings.TrimSpace(path)
+		if !ok || column == "" || path == "" {
+			return nil, fmt.Errorf("invalid mapping %q (want column=.path)", pair)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("column %q is mapped more than once", column)
+		}
+		seen[column] = true
+		steps, err := parsePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %q for column %q: %w", path, column, err)
+		}
+		mappings = append(mappings, FieldMapping{Column: column, Path: path, steps: steps})
+	}
+	if len(mappings) == 0 {
+		return nil, fmt.Errorf("mapping names no columns")
+	}
+	return mappings, nil
+}
+
+// splitMapping splits spec on the commas outside ["..."] keys.
+func splitMapping(spec string) []string {
+	var (
+		parts []string
+		start int
+		quote bool
+	)
+	for i := 0; i < len(spec); i++ {
+		switch c := spec[i]; {
+		case quote && c == '\\':
+			i++
+		case c == '"':
+			quote = !quote
+		case c == ',' && !quote:
+			parts = append(parts, spec[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, spec[start:])
+}
+
+func parsePath(path string) ([]any, error) {
+	if path[0] != '.' && path[0] != '[' {
+		return nil, fmt.Errorf("a path starts with . or [")
+	}
+	var steps []any
+	for i := 0; i < len(path); {
+		switch {
+		case strings.HasPrefix(path[i:], `.[`):
+			i++
+		case strings.HasPrefix(path[i:], `["`):
+			dec := json.NewDecoder(strings.NewReader(path[i+1:]))
+			var key string
+			if err := dec.Decode(&key); err != nil {
+				return nil, fmt.Errorf("invalid quoted key: %w", err)
+			}
+			i += 1 + int(dec.InputOffset())
+			if i >= len(path) || path[i] != ']' {
+				return nil, fmt.Errorf(`unterminated ["key"]`)
+			}
+			steps = append(steps, key)
+			i++
+		case path[i] == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [index]")
+			}
+			n, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid array index %q", path[i+1:i+end])
+			}
+			steps = append(steps, n)
+			i += end + 1
+		case path[i] == '.':
+			i++
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key")
+			}
+			steps = append(steps, path[i:i+end])
+			i += end
+		default:
+			return nil, fmt.Errorf("unexpected %q", path[i:])
+		}
+	}
+	return steps, nil
+}
+
+// lookup returns the field m maps in obj, or nil when obj lacks it.
+func (m FieldMapping) lookup(obj map[string]any) any {
+	var v any = obj
+	for _, step := range m.steps {
+		switch step := step.(type) {
+		case string:
+			o, ok := v.(map[string]any)
+			if !ok {
+				return nil
+			}
+			v = o[step]
+		case int:
+			a, ok := v.([]any)
+			if !ok || step >= len(a) {
+				return nil
+			}
+			v = a[step]
+		}
+	}
+	return v
+}
@@ -0,0 +1,85 @@
+package dataload
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    [][]any
+		wantErr string
+	}{
+		{spec: "id=.id,name=.user.name", want: [][]any{{"id"}, {"user", "name"}}},
+		{spec: " tag = .tags[0] , first=.[1].x", want: [][]any{{"tags", 0}, {1, "x"}}},
+		{spec: `city=.["address.city"],note=.meta["a,b"]`, want: [][]any{{"address.city"}, {"meta", "a,b"}}},
+		{spec: "id", wantErr: "want column=.path"},
+		{spec: "id=id", wantErr: "a path starts with . or ["},
+		{spec: "id=.a..b", wantErr: "empty key"},
+		{spec: "id=.tags[x]", wantErr: "invalid array index"},
+		{spec: `id=.["a"`, wantErr: `unterminated ["key"]`},
+		{spec: "id=.a,id=.b", wantErr: `column "id" is mapped more than once`},
+		{spec: "", wantErr: "want column=.path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseMapping(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseMapping() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMapping() error = %v", err)
+			}
+			var steps [][]any
+			for _, m := range got {
+				steps = append(steps, m.steps)
+			}
+			if !reflect.DeepEqual(steps, tt.want) {
+				t.Errorf("ParseMapping() steps = %v, want %v", steps, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewJSONLReaderMapping(t *testing.T) {
+	mapping, err := ParseMapping("id=.id,name=.user.name,tag=.tags[1],extra=.user")
+	if err != nil {
+		t.Fatalf("ParseMapping() error = %v", err)
+	}
+	input := `{"id": 1, "user": {"name": "Ada", "age": 36}, "tags": ["a", "b"]}` + "\n" +
+		`{"id": 2, "other": true}` + "\n"
+	r, err := NewJSONLReader(strings.NewReader("\xEF\xBB\xBF"+input), JSONLOptions{Mapping: mapping})
+	if err != nil {
+		t.Fatalf("NewJSONLReader() error = %v", err)
+	}
+	if got := r.Columns(); !reflect.DeepEqual(got, []string{"id", "name", "tag", "extra"}) {
+		t.Errorf("Columns() = %v, want the mapped columns in order", got)
+	}
+	want := [][]any{
+		{int64(1), "Ada", "b", `{"age":36,"name":"Ada"}`},
+		{int64(2), nil, nil, nil},
+	}
+	for i, w := range want {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("Next() %d error = %v", i, err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("Next() %d = %#v, want %#v", i, got, w)
+		}
+	}
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Next() at the end error = %v, want io.EOF", err)
+	}
+
+	if _, err := NewJSONLReader(strings.NewReader("\n"), JSONLOptions{Mapping: mapping}); !errors.Is(err, ErrNoRecords) {
+		t.Errorf("NewJSONLReader() of empty input error = %v, want ErrNoRecords", err)
+	}
+}
@@ -0,0 +1,125 @@
+package dataload
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NullPolicy decides which string values are loaded as NULL. CSV has no
+// NULL of its own, and PostgreSQL and SQLite treat empty strings the same
+// way as any other text, so the policy makes the intent explicit.
+type NullPolicy struct {
+	// EmptyAsNull loads empty strings as NULL instead of "".
+	EmptyAsNull bool
+	// Tokens are sentinel values loaded as NULL, e.g. `\N` or "NA".
+	Tokens []string
+	// Columns overrides the policy for individual columns.
+	Columns map[string]ColumnNullPolicy
+}
+
+// ColumnNullPolicy overrides NullPolicy for one column. Nil fields inherit
+// the global setting.
+type ColumnNullPolicy struct {
+	EmptyAsNull *bool
+	Tokens      []string
+}
+
+// IsZero reports whether the policy leaves every value unchanged.
+func (p NullPolicy) IsZero() bool {
+	return !p.EmptyAsNull && len(p.Tokens) == 0 && len(p.Columns) == 0
+}
+
+// NullReader applies a NullPolicy to the records of a Reader and counts the
+// values it coerced to NULL.
+type NullReader struct {
+	Reader
+	empty   []bool
+	tokens  []map[string]bool
+	coerced []int64
+}
+
+// ApplyNulls wraps r so that values matching the policy become NULL. Policy
+// columns that are not in the input are an error.
+func ApplyNulls(r Reader, p NullPolicy) (*NullReader, error) {
+	columns := r.Columns()
+	index := make(map[string]int, len(columns))
+	for i, name := range columns {
+		index[name] = i
+	}
+	for name := range p.Columns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("NULL policy column %q is not in the input", name)
+		}
+	}
+
+	n := &NullReader{
+		Reader:  r,
+		empty:   make([]bool, len(columns)),
+		tokens:  make([]map[string]bool, len(columns)),
+		coerced: make([]int64, len(columns)),
+	}
+	for i, name := range columns {
+		empty, tokens := p.EmptyAsNull, p.Tokens
+		if col, ok := p.Columns[name]; ok {
+			if col.EmptyAsNull != nil {
+				empty = *col.EmptyAsNull
+			}
+			if col.Tokens != nil {
+				tokens = col.Tokens
+			}
+		}
+		n.empty[i] = empty
+		n.tokens[i] = make(map[string]bool, len(tokens))
+		for _, t := range tokens {
+			n.tokens[i][t] = true
+		}
+	}
+	return n, nil
+}
+
+// Next returns the next record with the policy applied.
+func (n *NullReader) Next() ([]any, error) {
+	values, err := n.Reader.Next()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok || i >= len(n.empty) {
+			continue
+		}
+		if (s == "" && n.empty[i]) || n.tokens[i][s] {
+			values[i] = nil
+			n.coerced[i]++
+		}
+	}
+	return values, nil
+}
+
+// Coerced returns how many values of each column were loaded as NULL,
+// omitting columns with none.
+func (n *NullReader) Coerced() map[string]int64 {
+	counts := map[string]int64{}
+	for i, name := range n.Columns() {
+		if n.coerced[i] > 0 {
+			counts[name] = n.coerced[i]
+		}
+	}
+	return counts
+}
+
+// FormatCounts renders per-column counts as "a: 1, b: 2", sorted by column
+// name.
+func FormatCounts(counts map[string]int64) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s: %d", name, counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
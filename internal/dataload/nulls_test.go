@@ -0,0 +1,64 @@
+package dataload
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestApplyNulls(t *testing.T) {
+	input := "id,name,code,notes\n1,,NA,\n2,bob,\\N,NA\n3,,x,n/a\n"
+	keep := false
+	policy := NullPolicy{
+		EmptyAsNull: true,
+		Tokens:      []string{`\N`},
+		Columns: map[string]ColumnNullPolicy{
+			"code":  {Tokens: []string{"NA"}},
+			"notes": {EmptyAsNull: &keep, Tokens: []string{"NA", "n/a"}},
+		},
+	}
+
+	r, err := NewReader(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	nr, err := ApplyNulls(r, policy)
+	if err != nil {
+		t.Fatalf("ApplyNulls() error = %v", err)
+	}
+
+	want := [][]any{
+		{"1", nil, nil, ""},
+		{"2", "bob", `\N`, nil},
+		{"3", nil, "x", nil},
+	}
+	for i, w := range want {
+		got, err := nr.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		for j := range w {
+			if got[j] != w[j] {
+				t.Errorf("row %d column %d = %#v, want %#v", i+1, j, got[j], w[j])
+			}
+		}
+	}
+	if _, err := nr.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Next() at end error = %v, want io.EOF", err)
+	}
+
+	if got := FormatCounts(nr.Coerced()); got != "code: 1, name: 2, notes: 2" {
+		t.Errorf("Coerced() = %s", got)
+	}
+}
+
+func TestApplyNullsUnknownColumn(t *testing.T) {
+	r, err := NewReader(strings.NewReader("id\n1\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if _, err := ApplyNulls(r, NullPolicy{Columns: map[string]ColumnNullPolicy{"missing": {}}}); err == nil {
+		t.Error("ApplyNulls() with unknown column expected error")
+	}
+}
@@ -0,0 +1,47 @@
+package dataload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Preview writes up to n records of r to w as a table, with each value shown
+// as the literal it would be inserted as: NULL, a quoted string, or a bare
+// number or boolean. It returns the number of records written.
+func Preview(w io.Writer, r Reader, n int) (int, error) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(r.Columns(), "\t"))
+
+	var rows int
+	for rows < n {
+		values, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return rows, err
+		}
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = literal(v)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		rows++
+	}
+	return rows, tw.Flush()
+}
+
+func literal(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return strconv.Quote(val)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
@@ -0,0 +1,40 @@
+package dataload
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	input := `{"id":1,"name":"a\tb","price":9.5,"active":true,"note":null}` + "\n" +
+		`{"id":2,"name":"","price":10,"active":false,"note":"x"}` + "\n" +
+		`{"id":3,"name":"c","price":1,"active":true,"note":"y"}` + "\n"
+	r, err := NewReader(strings.NewReader(input), FormatJSONL)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	var b strings.Builder
+	n, err := Preview(&b, r, 2)
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Preview() = %d rows, want 2", n)
+	}
+
+	want := []string{
+		"active  id  name    note  price",
+		`true    1   "a\tb"  NULL  9.5`,
+		`false   2   ""      "x"   10`,
+	}
+	got := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("Preview() output =\n%s", b.String())
+	}
+	for i := range want {
+		if strings.TrimRight(got[i], " ") != want[i] {
+			t.Errorf("line %d = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package dataload
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Format identifies the encoding of a data stream.
+type Format string
+
+// Supported data formats.
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// ParseFormat validates a user-supplied format name.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case FormatCSV, FormatJSONL:
+		return Format(name), nil
+	default:
+		return "", fmt.Errorf("unsupported format %q (use csv or jsonl)", name)
+	}
+}
+
+// ErrNoRecords is returned, wrapped, for input with no records: zero bytes,
+// whitespace only, or, for CSV, no header row.
+var ErrNoRecords = errors.New("input contains no records")
+
+// Reader yields records from a data stream one at a time.
+type Reader interface {
+	// Columns returns the column names of every record.
+	Columns() []string
+	// Next returns the values of the next record in column order,
+	// or io.EOF when the stream is exhausted.
+	Next() ([]any, error)
+}
+
+// NewReader returns a Reader decoding r in the given format. A leading UTF-8
+// byte order mark, as written by Windows tools such as Excel, is skipped.
+func NewReader(r io.Reader, format Format) (Reader, error) {
+	r, err := skipBOM(r)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r, CSVOptions{})
+	case FormatJSONL:
+		return newJSONLReader(r, JSONLOptions{})
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// Peek returns the first record of r along with a Reader that still yields
+// every record, including the peeked one. The first record is nil when the
+// input has no records.
+func Peek(r Reader) ([]any, Reader, error) {
+	records, r, err := PeekN(r, 1)
+	if err != nil || len(records) == 0 {
+		return nil, r, err
+	}
+	return records[0], r, nil
+}
+
+// PeekN returns up to n leading records of r along with a Reader that still
+// yields every record, including the peeked ones.
+func PeekN(r Reader, n int) ([][]any, Reader, error) {
+	var records [][]any
+	for len(records) < n {
+		values, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, values)
+	}
+	if len(records) == 0 {
+		return nil, r, nil
+	}
+	return records, &peekedReader{Reader: r, pending: records}, nil
+}
+
+type peekedReader struct {
+	Reader
+	pending [][]any
+}
+
+func (p *peekedReader) Next() ([]any, error) {
+	if len(p.pending) > 0 {
+		next := p.pending[0]
+		p.pending = p.pending[1:]
+		return next, nil
+	}
+	return p.Reader.Next()
+}
+
+// RenameColumns returns a Reader yielding the records of r under the column
+// names produced by rename. It fails when two columns would share a name.
+func RenameColumns(r Reader, rename func(string) string) (Reader, error) {
+	columns := make([]string, len(r.Columns()))
+	from := make(map[string]string, len(columns))
+	for i, name := range r.Columns() {
+		columns[i] = rename(name)
+		if prev, ok := from[columns[i]]; ok {
+			return nil, fmt.Errorf("columns %q and %q both become %q", prev, name, columns[i])
+		}
+		from[columns[i]] = name
+	}
+	return &renamedReader{Reader: r, columns: columns}, nil
+}
+
+type renamedReader struct {
+	Reader
+	columns []string
+}
+
+func (r *renamedReader) Columns() []string {
+	return r.columns
+}
+
+func skipBOM(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	prefix, err := br.Peek(3)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	if bytes.Equal(prefix, []byte{0xEF, 0xBB, 0xBF}) {
+		if _, err := br.Discard(3); err != nil {
+			return nil, fmt.Errorf("failed to read input: %w", err)
+		}
+	}
+	return br, nil
+}
+
+// JSONLOptions configures NewJSONLReader.
+type JSONLOptions struct {
+	// Mapping, if set, takes the columns from the fields it maps, in order,
+	// ignoring any others. A record lacking a mapped field has NULL in its
+	// column.
+	Mapping []FieldMapping
+}
+
+// NewJSONLReader returns a Reader decoding JSON Lines from r with the given
+// options. A leading UTF-8 byte order mark is skipped.
+func NewJSONLReader(r io.Reader, opts JSONLOptions) (Reader, error) {
+	r, err := skipBOM(r)
+	if err != nil {
+		return nil, err
+	}
+	return newJSONLReader(r, opts)
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	columns []string
+	index   map[string]int
+	mapping []FieldMapping
+	first   map[string]any
+	line    int
+}
+
+func newJSONLReader(r io.Reader, opts JSONLOptions) (*jsonlReader, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	j := &jsonlReader{scanner: scanner, mapping: opts.Mapping}
+
+	first, err := j.decode()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("JSON Lines %w", ErrNoRecords)
+	}
+	if err != nil {
+		return nil, err
+	}
+	j.first = first
+
+	if len(j.mapping) > 0 {
+		for _, m := range j.mapping {
+			j.columns = append(j.columns, m.Column)
+		}
+		return j, nil
+	}
+	for name := range first {
+		j.columns = append(j.columns, name)
+	}
+	sort.Strings(j.columns)
+	j.index = make(map[string]int, len(j.columns))
+	for i, name := range j.columns {
+		j.index[name] = i
+	}
+	return j, nil
+}
+
+// decode returns the next non-blank line decoded as a JSON object.
+func (j *jsonlReader) decode() (map[string]any, error) {
+	for j.scanner.Scan() {
+		j.line++
+		line := bytes.TrimSpace(j.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			// A failed read still yields the partial last line; report the
+			// read error rather than the truncated JSON it caused.
+			if !j.scanner.Scan() && j.scanner.Err() != nil {
+				return nil, fmt.Errorf("failed to read JSON Lines input: %w", j.scanner.Err())
+			}
+			return nil, fmt.Errorf("line %d: invalid JSON object: %w", j.line, err)
+		}
+		return obj, nil
+	}
+	if err := j.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read JSON Lines input: %w", err)
+	}
+	return nil, io.EOF
+}
+
+func (j *jsonlReader) Columns() []string {
+	return j.columns
+}
+
+func (j *jsonlReader) Next() ([]any, error) {
+	obj := j.first
+	j.first = nil
+	if obj == nil {
+		var err error
+		if obj, err = j.decode(); err != nil {
+			return nil, err
+		}
+	}
+
+	values := make([]any, len(j.columns))
+	if len(j.mapping) > 0 {
+		for i, m := range j.mapping {
+			converted, err := jsonValue(m.lookup(obj))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: field %s: %w", j.line, m.Path, err)
+			}
+			values[i] = converted
+		}
+		return values, nil
+	}
+	for name, v := range obj {
+		i, ok := j.index[name]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unexpected field %q not present in first record", j.line, name)
+		}
+		converted, err := jsonValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: field %q: %w", j.line, name, err)
+		}
+		values[i] = converted
+	}
+	return values, nil
+}
+
+// jsonValue converts a decoded JSON value into a database/sql argument.
+// Nested objects and arrays are stored as their JSON text.
+func jsonValue(v any) (any, error) {
+	switch val := v.(type) {
+	case nil, string, bool:
+		return val, nil
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		if f, err := val.Float64(); err == nil {
+			return f, nil
+		}
+		return val.String(), nil
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+}
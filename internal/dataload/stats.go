@@ -0,0 +1,160 @@
+package dataload
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// MaxDistinct bounds the distinct values tracked per column by StatsReader;
+// beyond it the distinct count is reported as a lower bound.
+const MaxDistinct = 10000
+
+// ColumnStats summarizes the values of one column.
+type ColumnStats struct {
+	Name  string
+	Nulls int64
+	// Distinct counts distinct non-NULL values, up to MaxDistinct.
+	Distinct int64
+	// DistinctCapped reports that Distinct stopped counting at MaxDistinct.
+	DistinctCapped bool
+	// Min and Max are the smallest and largest non-NULL values, compared
+	// numerically when every value is a number and as text otherwise.
+	Min, Max any
+}
+
+// StatsReader collects per-column statistics of the records read through it.
+type StatsReader struct {
+	Reader
+	columns []columnStats
+}
+
+type columnStats struct {
+	nulls    int64
+	distinct map[string]struct{}
+	capped   bool
+	seen     bool
+	// Text and numeric extremes are tracked separately; the numeric ones
+	// are used while every value is a number.
+	numeric           bool
+	minText, maxText  any
+	minNum, maxNum    any
+	lowText, highText string
+	lowNum, highNum   float64
+}
+
+// CollectStats wraps r so that the statistics of every record it yields are
+// recorded.
+func CollectStats(r Reader) *StatsReader {
+	columns := make([]columnStats, len(r.Columns()))
+	for i := range columns {
+		columns[i] = columnStats{distinct: map[string]struct{}{}, numeric: true}
+	}
+	return &StatsReader{Reader: r, columns: columns}
+}
+
+// Next returns the next record, recording its values.
+func (s *StatsReader) Next() ([]any, error) {
+	values, err := s.Reader.Next()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		if i < len(s.columns) {
+			s.columns[i].add(v)
+		}
+	}
+	return values, nil
+}
+
+// Stats returns the statistics of each column in input order.
+func (s *StatsReader) Stats() []ColumnStats {
+	names := s.Columns()
+	stats := make([]ColumnStats, len(s.columns))
+	for i, c := range s.columns {
+		stats[i] = ColumnStats{
+			Name:           names[i],
+			Nulls:          c.nulls,
+			Distinct:       int64(len(c.distinct)),
+			DistinctCapped: c.capped,
+		}
+		if c.numeric {
+			stats[i].Min, stats[i].Max = c.minNum, c.maxNum
+		} else {
+			stats[i].Min, stats[i].Max = c.minText, c.maxText
+		}
+	}
+	return stats
+}
+
+func (c *columnStats) add(v any) {
+	if v == nil {
+		c.nulls++
+		return
+	}
+	text := fmt.Sprintf("%v", v)
+	if _, ok := c.distinct[text]; !ok {
+		if len(c.distinct) < MaxDistinct {
+			c.distinct[text] = struct{}{}
+		} else {
+			c.capped = true
+		}
+	}
+
+	num, isNum := number(v)
+	if !c.seen {
+		c.seen, c.numeric = true, isNum
+		c.minText, c.maxText, c.lowText, c.highText = v, v, text, text
+		c.minNum, c.maxNum, c.lowNum, c.highNum = v, v, num, num
+		return
+	}
+	if text < c.lowText {
+		c.minText, c.lowText = v, text
+	}
+	if text > c.highText {
+		c.maxText, c.highText = v, text
+	}
+	if c.numeric = c.numeric && isNum; c.numeric {
+		if num < c.lowNum {
+			c.minNum, c.lowNum = v, num
+		}
+		if num > c.highNum {
+			c.maxNum, c.highNum = v, num
+		}
+	}
+}
+
+// number reports the numeric value of v, parsing strings so that CSV
+// columns of numbers are ordered numerically.
+func number(v any) (float64, bool) {
+	switch val := v.(type) {
+	case int64:
+		return float64(val), true
+	case float64:
+		return val, true
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// WriteStats writes column statistics as a table.
+func WriteStats(w io.Writer, stats []ColumnStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLUMN\tNULLS\tDISTINCT\tMIN\tMAX")
+	for _, c := range stats {
+		distinct := strconv.FormatInt(c.Distinct, 10)
+		if c.DistinctCapped {
+			distinct += "+"
+		}
+		lo, hi := "-", "-"
+		if c.Min != nil {
+			lo, hi = fmt.Sprintf("%v", c.Min), fmt.Sprintf("%v", c.Max)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", c.Name, c.Nulls, distinct, lo, hi)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,61 @@
+package dataload
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCollectStats(t *testing.T) {
+	input := "id,name,born,code\n9,bob,2001-02-03,\n10,amy,,x\n2,bob,1999-12-31,7\n"
+	r, err := NewReader(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	nulls, err := ApplyNulls(r, NullPolicy{EmptyAsNull: true})
+	if err != nil {
+		t.Fatalf("ApplyNulls() error = %v", err)
+	}
+	sr := CollectStats(nulls)
+	for {
+		if _, err := sr.Next(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+	}
+
+	want := []ColumnStats{
+		{Name: "id", Distinct: 3, Min: "2", Max: "10"},
+		{Name: "name", Distinct: 2, Min: "amy", Max: "bob"},
+		{Name: "born", Nulls: 1, Distinct: 2, Min: "1999-12-31", Max: "2001-02-03"},
+		{Name: "code", Nulls: 1, Distinct: 2, Min: "7", Max: "x"},
+	}
+	got := sr.Stats()
+	if len(got) != len(want) {
+		t.Fatalf("Stats() = %d columns, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Stats()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	var b strings.Builder
+	err := WriteStats(&b, []ColumnStats{
+		{Name: "id", Distinct: MaxDistinct, DistinctCapped: true, Min: int64(1), Max: int64(20000)},
+		{Name: "note", Nulls: 3},
+	})
+	if err != nil {
+		t.Fatalf("WriteStats() error = %v", err)
+	}
+	want := "COLUMN  NULLS  DISTINCT  MIN  MAX\n" +
+		"id      0      10000+    1    20000\n" +
+		"note    3      0         -    -\n"
+	if b.String() != want {
+		t.Errorf("WriteStats() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,241 @@
+package dataload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// DefaultWatermarkTable is the table recording the high-watermark of each
+// incrementally loaded feed.
+const DefaultWatermarkTable = "sql_loader_watermarks"
+
+// watermarkLayouts are the timestamp layouts a watermark value is parsed
+// with, in order.
+var watermarkLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999", "2006-01-02"}
+
+// Watermark is the highest value of a feed's watermark column loaded so
+// far.
+type Watermark struct {
+	Column    string
+	Value     string
+	UpdatedAt time.Time
+}
+
+// ReadWatermark returns the watermark of feed recorded in table, creating
+// the table if needed, or nil when the feed has not been loaded yet.
+//
+// The watermark is read inside tx so the load and the new watermark commit
+// together. On PostgreSQL, tx first waits for a transaction-scoped advisory
+// lock on the feed, so concurrent loads of one feed take turns and each sees
+// the watermark the previous one saved. SQLite allows one writer at a time,
+// so there a concurrent load fails when it saves instead of loading the same
+// records twice.
+func ReadWatermark(ctx context.Context, tx *sql.Tx, driver, table, feed string) (*Watermark, error) {
+	timestamp := "TIMESTAMP"
+	if driver == "postgres" {
+		timestamp = "TIMESTAMPTZ"
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", watermarkLockKey(table, feed)); err != nil {
+			if ctx.Err() != nil {
+				err = context.Cause(ctx)
+			}
+			return nil, fmt.Errorf("failed to lock watermark of feed %s: %w", feed, err)
+		}
+	}
+	stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (feed TEXT PRIMARY KEY, watermark_column TEXT NOT NULL, watermark TEXT NOT NULL, updated_at %s NOT NULL)",
+		database.QuoteQualified(table), timestamp)
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return nil, fmt.Errorf("failed to create watermark table %s: %w", table, err)
+	}
+	query := fmt.Sprintf("SELECT watermark_column, watermark, updated_at FROM %s WHERE feed = %s",
+		database.QuoteQualified(table), database.Placeholder(driver, 1))
+	var w Watermark
+	err := tx.QueryRowContext(ctx, query, feed).Scan(&w.Column, &w.Value, &w.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watermark of feed %s: %w", feed, err)
+	}
+	return &w, nil
+}
+
+// watermarkLockKey derives the advisory lock key of feed in table, so loads
+// of different feeds do not block each other.
+func watermarkLockKey(table, feed string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("sql-loader watermark " + table + "\x00" + feed))
+	return int64(h.Sum64()) // #nosec G115 -- any 64-bit value is a valid key
+}
+
+// SaveWatermark records value as the watermark of feed in table.
+func SaveWatermark(ctx context.Context, db database.Execer, driver, table, feed, column, value string) error {
+	stmt := fmt.Sprintf("INSERT INTO %s (feed, watermark_column, watermark, updated_at) VALUES (%s, %s, %s, %s) "+
+		"ON CONFLICT (feed) DO UPDATE SET watermark_column = excluded.watermark_column, watermark = excluded.watermark, updated_at = excluded.updated_at",
+		database.QuoteQualified(table), database.Placeholder(driver, 1), database.Placeholder(driver, 2),
+		database.Placeholder(driver, 3), database.Placeholder(driver, 4))
+	if _, err := db.ExecContext(ctx, stmt, feed, column, value, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to save watermark of feed %s: %w", feed, err)
+	}
+	return nil
+}
+
+// WatermarkReader yields the records of a Reader whose watermark column is
+// beyond a previous watermark, tracking the highest value it yields.
+type WatermarkReader struct {
+	r       Reader
+	column  string
+	pos     int
+	after   string
+	hasMark bool
+	kind    watermarkKind
+	hasKind bool
+	max     string
+	hasMax  bool
+	record  int64
+	skipped int64
+}
+
+// FilterWatermark returns a WatermarkReader over r keeping the records
+// whose column is greater than after, or every record when after is nil.
+// The first value, after or else the first record's, decides how every
+// value compares: as a number, then as an RFC 3339 or SQL timestamp or
+// date, and otherwise as text. A later value of another kind is an error,
+// as is a record without a value in the column: neither could be ordered
+// against the watermark.
+func FilterWatermark(r Reader, column string, after *string) (*WatermarkReader, error) {
+	pos := -1
+	for i, name := range r.Columns() {
+		if name == column {
+			pos = i
+		}
+	}
+	if pos < 0 {
+		return nil, fmt.Errorf("watermark column %q is not in the input", column)
+	}
+	w := &WatermarkReader{r: r, column: column, pos: pos}
+	if after != nil {
+		w.after, w.hasMark = *after, true
+		w.kind, w.hasKind = watermarkKindOf(*after), true
+	}
+	return w, nil
+}
+
+// Columns returns the columns of the underlying reader.
+func (w *WatermarkReader) Columns() []string {
+	return w.r.Columns()
+}
+
+// Next returns the next record beyond the watermark.
+func (w *WatermarkReader) Next() ([]any, error) {
+	for {
+		values, err := w.r.Next()
+		if err != nil {
+			return values, err
+		}
+		w.record++
+		if w.pos >= len(values) || values[w.pos] == nil {
+			return nil, fmt.Errorf("record %d has no value in watermark column %q", w.record, w.column)
+		}
+		value := fmt.Sprintf("%v", values[w.pos])
+		if !w.hasKind {
+			w.kind, w.hasKind = watermarkKindOf(value), true
+		}
+		if kind := watermarkKindOf(value); w.kind != kindText && kind != w.kind {
+			return nil, fmt.Errorf("record %d has %s %q in watermark column %q, which holds %ss", w.record, kind, value, w.column, w.kind)
+		}
+		if w.hasMark && compareWatermarks(w.kind, value, w.after) <= 0 {
+			w.skipped++
+			continue
+		}
+		if !w.hasMax || compareWatermarks(w.kind, value, w.max) > 0 {
+			w.max, w.hasMax = value, true
+		}
+		return values, nil
+	}
+}
+
+// Max returns the highest watermark value yielded so far, reporting false
+// when no record was yielded.
+func (w *WatermarkReader) Max() (string, bool) {
+	return w.max, w.hasMax
+}
+
+// Column returns the watermark column.
+func (w *WatermarkReader) Column() string {
+	return w.column
+}
+
+// Skipped returns the number of records dropped as at or below the
+// watermark.
+func (w *WatermarkReader) Skipped() int64 {
+	return w.skipped
+}
+
+var _ Reader = (*WatermarkReader)(nil)
+
+// watermarkKind is how watermark values compare.
+type watermarkKind int
+
+const (
+	kindNumber watermarkKind = iota
+	kindTimestamp
+	kindText
+)
+
+// String returns the name of k used in error messages.
+func (k watermarkKind) String() string {
+	switch k {
+	case kindNumber:
+		return "number"
+	case kindTimestamp:
+		return "timestamp"
+	}
+	return "text"
+}
+
+// watermarkKindOf returns the kind of v: a number if it parses as one, then
+// a timestamp, and otherwise text.
+func watermarkKindOf(v string) watermarkKind {
+	if _, ok := new(big.Float).SetString(v); ok {
+		return kindNumber
+	}
+	if _, ok := parseWatermarkTime(v); ok {
+		return kindTimestamp
+	}
+	return kindText
+}
+
+// parseWatermarkTime parses v with the first of watermarkLayouts that fits.
+func parseWatermarkTime(v string) (time.Time, bool) {
+	for _, layout := range watermarkLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// compareWatermarks returns -1, 0, or 1 as a is less than, equal to, or
+// greater than b, comparing both as kind. Both values must be of kind unless
+// kind is kindText, which orders any values.
+func compareWatermarks(kind watermarkKind, a, b string) int {
+	switch kind {
+	case kindNumber:
+		x, _ := new(big.Float).SetString(a)
+		y, _ := new(big.Float).SetString(b)
+		return x.Cmp(y)
+	case kindTimestamp:
+		x, _ := parseWatermarkTime(a)
+		y, _ := parseWatermarkTime(b)
+		return x.Compare(y)
+	}
+	return strings.Compare(a, b)
+}
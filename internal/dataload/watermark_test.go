@@ -0,0 +1,160 @@
+package dataload
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareWatermarks(t *testing.T) {
+	tests := []struct {
+		a, b string
+		kind watermarkKind
+		want int
+	}{
+		{a: "9", b: "10", kind: kindNumber, want: -1},
+		{a: "10.5", b: "10.50", kind: kindNumber, want: 0},
+		{a: "9223372036854775807", b: "9223372036854775806", kind: kindNumber, want: 1},
+		{a: "2024-01-02T12:00:00+01:00", b: "2024-01-02T10:00:00Z", kind: kindTimestamp, want: 1},
+		{a: "2024-01-02 09:00:00", b: "2024-01-02 10:00:00", kind: kindTimestamp, want: -1},
+		{a: "2024-01-09", b: "2024-01-10", kind: kindTimestamp, want: -1},
+		{a: "2024-01-02", b: "2024-01-01T23:00:00Z", kind: kindTimestamp, want: 1},
+		{a: "b", b: "a", kind: kindText, want: 1},
+		{a: "9", b: "10", kind: kindText, want: 1},
+	}
+	for _, tt := range tests {
+		if got := watermarkKindOf(tt.a); tt.kind != kindText && got != tt.kind {
+			t.Errorf("watermarkKindOf(%q) = %s, want %s", tt.a, got, tt.kind)
+		}
+		if got := compareWatermarks(tt.kind, tt.a, tt.b); got != tt.want {
+			t.Errorf("compareWatermarks(%s, %q, %q) = %d, want %d", tt.kind, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFilterWatermark(t *testing.T) {
+	input := `{"id": 1, "seq": 8}
+{"id": 2, "seq": 12}
+{"id": 3, "seq": 10}
+{"id": 4, "seq": 9}
+`
+	tests := []struct {
+		name        string
+		after       *string
+		wantIDs     []int64
+		wantMax     string
+		wantSkipped int64
+	}{
+		{name: "first load", wantIDs: []int64{1, 2, 3, 4}, wantMax: "12"},
+		{name: "beyond the watermark", after: ptr("9"), wantIDs: []int64{2, 3}, wantMax: "12", wantSkipped: 2},
+		{name: "nothing new", after: ptr("12"), wantSkipped: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(input), FormatJSONL)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			w, err := FilterWatermark(r, "seq", tt.after)
+			if err != nil {
+				t.Fatalf("FilterWatermark() error = %v", err)
+			}
+			var ids []int64
+			for {
+				values, err := w.Next()
+				if err != nil {
+					break
+				}
+				ids = append(ids, values[0].(int64))
+			}
+			max, _ := w.Max()
+			if !reflect.DeepEqual(ids, tt.wantIDs) || max != tt.wantMax || w.Skipped() != tt.wantSkipped {
+				t.Errorf("FilterWatermark() ids %v, max %q, skipped %d; want %v, %q, %d", ids, max, w.Skipped(), tt.wantIDs, tt.wantMax, tt.wantSkipped)
+			}
+		})
+	}
+
+	r, _ := NewReader(strings.NewReader(`{"id": 1}`), FormatJSONL)
+	if _, err := FilterWatermark(r, "seq", nil); err == nil || !strings.Contains(err.Error(), `watermark column "seq" is not in the input`) {
+		t.Errorf("FilterWatermark() of a missing column error = %v", err)
+	}
+	mixed := []struct {
+		name, input string
+		after       *string
+		wantErr     string
+	}{
+		{name: "text after numbers", input: "{\"id\": 1, \"seq\": 1}\n{\"id\": 2, \"seq\": \"a\"}\n",
+			wantErr: `record 2 has text "a" in watermark column "seq", which holds numbers`},
+		{name: "number after a timestamp watermark", input: "{\"id\": 1, \"seq\": 5}\n", after: ptr("2024-01-01"),
+			wantErr: `record 1 has number "5" in watermark column "seq", which holds timestamps`},
+	}
+	for _, tt := range mixed {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := NewReader(strings.NewReader(tt.input), FormatJSONL)
+			w, _ := FilterWatermark(r, "seq", tt.after)
+			var err error
+			for err == nil {
+				_, err = w.Next()
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Next() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	r, _ = NewReader(strings.NewReader("{\"id\": 1, \"seq\": 1}\n{\"id\": 2, \"seq\": null}\n"), FormatJSONL)
+	w, _ := FilterWatermark(r, "seq", nil)
+	_, _ = w.Next()
+	if _, err := w.Next(); err == nil || !strings.Contains(err.Error(), `record 2 has no value in watermark column "seq"`) {
+		t.Errorf("Next() of a record without a watermark error = %v", err)
+	}
+}
+
+func TestWatermarkTable(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	read := func(t *testing.T) *Watermark {
+		t.Helper()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("BeginTx() error = %v", err)
+		}
+		mark, err := ReadWatermark(ctx, tx, "sqlite", DefaultWatermarkTable, "events")
+		if err != nil {
+			_ = tx.Rollback()
+			t.Fatalf("ReadWatermark() error = %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+		return mark
+	}
+	if mark := read(t); mark != nil {
+		t.Fatalf("ReadWatermark() of a new feed = %+v, want none", mark)
+	}
+	for _, value := range []string{"10", "12"} {
+		if err := SaveWatermark(ctx, db, "sqlite", DefaultWatermarkTable, "events", "seq", value); err != nil {
+			t.Fatalf("SaveWatermark() error = %v", err)
+		}
+	}
+	if mark := read(t); mark == nil || mark.Column != "seq" || mark.Value != "12" || mark.UpdatedAt.IsZero() {
+		t.Errorf("ReadWatermark() = %+v, want the last saved value", mark)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	if err := SaveWatermark(ctx, tx, "sqlite", DefaultWatermarkTable, "events", "seq", "20"); err != nil {
+		t.Fatalf("SaveWatermark() in a transaction error = %v", err)
+	}
+	_ = tx.Rollback()
+	if mark := read(t); mark == nil || mark.Value != "12" {
+		t.Errorf("ReadWatermark() after a rollback = %+v, want 12", mark)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,295 @@
+// Package doctor provides functionality for diagnosing a database target
+// before a load: connectivity, server version, writability, the migration
+// tracking table, privileges, and lock support.
+package doctor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/migrate"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+// Check outcomes.
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Finding is the result of a single check with an optional actionable hint.
+type Finding struct {
+	Check   string
+	Status  Status
+	Message string
+	Hint    string
+}
+
+// Options controls which checks are run.
+type Options struct {
+	// Schemas are the schemas loads will write to (PostgreSQL only).
+	// Defaults to "public".
+	Schemas []string
+	// MigrationsTable is the migrate tracking table, whose records are
+	// checked and whose migration lock is probed. Defaults to
+	// migrate.DefaultTable.
+	MigrationsTable string
+}
+
+// Diagnose connects to the target and runs every check, returning the
+// findings in order. A failed connection ends the diagnosis early.
+func Diagnose(ctx context.Context, driver, dsn string, opts Options) []Finding {
+	start := time.Now()
+	db, err := database.Connect(ctx, driver, dsn)
+	if err != nil {
+		return []Finding{{
+			Check:   "connectivity",
+			Status:  StatusFail,
+			Message: err.Error(),
+			Hint:    "check the DSN, network access to the host, and that the server is running",
+		}}
+	}
+	defer func() { _ = db.Close() }()
+
+	findings := []Finding{{
+		Check:   "connectivity",
+		Status:  StatusOK,
+		Message: fmt.Sprintf("connected in %s", time.Since(start).Round(time.Millisecond)),
+	}}
+	table := opts.MigrationsTable
+	if table == "" {
+		table = migrate.DefaultTable
+	}
+	findings = append(findings, checkVersion(db, driver), checkWritable(ctx, db, driver), checkTrackingTable(ctx, db, driver, table))
+
+	if driver != "postgres" {
+		findings = append(findings,
+			Finding{Check: "privileges", Status: StatusSkip, Message: fmt.Sprintf("not applicable for %s", driver)},
+			Finding{Check: "advisory-lock", Status: StatusSkip, Message: fmt.Sprintf("not applicable for %s", driver)},
+		)
+		return findings
+	}
+
+	schemas := opts.Schemas
+	if len(schemas) == 0 {
+		schemas = []string{"public"}
+	}
+	for _, schema := range schemas {
+		findings = append(findings, checkSchemaPrivileges(db, schema))
+	}
+	findings = append(findings, checkAdvisoryLock(ctx, db, table))
+	return findings
+}
+
+func checkVersion(db *sql.DB, driver string) Finding {
+	query := "SHOW server_version"
+	if driver == "sqlite" {
+		query = "SELECT sqlite_version()"
+	}
+	var version string
+	if err := db.QueryRow(query).Scan(&version); err != nil {
+		return Finding{Check: "server-version", Status: StatusWarn, Message: fmt.Sprintf("could not determine server version: %v", err)}
+	}
+	return Finding{Check: "server-version", Status: StatusOK, Message: fmt.Sprintf("%s %s", driver, version)}
+}
+
+func checkWritable(ctx context.Context, db *sql.DB, driver string) Finding {
+	if err := database.CheckWritable(ctx, db, driver); err != nil {
+		return Finding{Check: "writable", Status: StatusFail, Message: err.Error(),
+			Hint: "connect to the primary (e.g. add target_session_attrs=read-write to the DSN)"}
+	}
+	return Finding{Check: "writable", Status: StatusOK, Message: "target accepts writes"}
+}
+
+func checkSchemaPrivileges(db *sql.DB, schema string) Finding {
+	check := "privileges:" + schema
+	var (
+		exists, usage, create bool
+		user                  string
+	)
+	err := db.QueryRow(`SELECT current_user,
+		EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1),
+		COALESCE((SELECT has_schema_privilege(oid, 'USAGE') FROM pg_namespace WHERE nspname = $1), false),
+		COALESCE((SELECT has_schema_privilege(oid, 'CREATE') FROM pg_namespace WHERE nspname = $1), false)`,
+		schema).Scan(&user, &exists, &usage, &create)
+	if err != nil {
+		return Finding{Check: check, Status: StatusFail, Message: fmt.Sprintf("failed to query privileges: %v", err)}
+	}
+	quoted := database.QuoteIdent(schema)
+	if !exists {
+		return Finding{Check: check, Status: StatusFail, Message: fmt.Sprintf("schema %s does not exist", schema),
+			Hint: fmt.Sprintf("CREATE SCHEMA %s;", quoted)}
+	}
+
+	var missing []string
+	if !usage {
+		missing = append(missing, "USAGE")
+	}
+	if !create {
+		missing = append(missing, "CREATE")
+	}
+	if len(missing) > 0 {
+		return Finding{Check: check, Status: StatusFail,
+			Message: fmt.Sprintf("role %s is missing %s on schema %s", user, strings.Join(missing, ", "), schema),
+			Hint:    fmt.Sprintf("GRANT %s ON SCHEMA %s TO %s;", strings.Join(missing, ", "), quoted, database.QuoteIdent(user))}
+	}
+
+	rows, err := db.Query(`SELECT c.relname FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p')
+		AND NOT has_table_privilege(c.oid, 'INSERT')
+		ORDER BY c.relname`, schema)
+	if err != nil {
+		return Finding{Check: check, Status: StatusFail, Message: fmt.Sprintf("failed to query table privileges: %v", err)}
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return Finding{Check: check, Status: StatusFail, Message: fmt.Sprintf("failed to scan table privileges: %v", err)}
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return Finding{Check: check, Status: StatusFail, Message: fmt.Sprintf("failed to query table privileges: %v", err)}
+	}
+	if err := rows.Close(); err != nil {
+		return Finding{Check: check, Status: StatusFail, Message: fmt.Sprintf("failed to query table privileges: %v", err)}
+	}
+	if len(tables) > 0 {
+		return Finding{Check: check, Status: StatusWarn,
+			Message: fmt.Sprintf("role %s cannot INSERT into %d table(s) in %s: %s", user, len(tables), schema, strings.Join(tables, ", ")),
+			Hint:    fmt.Sprintf("GRANT INSERT ON ALL TABLES IN SCHEMA %s TO %s;", quoted, database.QuoteIdent(user))}
+	}
+	return Finding{Check: check, Status: StatusOK, Message: fmt.Sprintf("role %s has USAGE, CREATE, and INSERT on %s", user, schema)}
+}
+
+// checkTrackingTable reads the migrations recorded in the tracking table,
+// which migrate creates on its first run, and on PostgreSQL checks that the
+// role can record and revert migrations in it.
+func checkTrackingTable(ctx context.Context, db *sql.DB, driver, table string) Finding {
+	check := "tracking-table"
+	exists, err := migrate.TableExists(ctx, db, driver, table)
+	if err != nil {
+		return Finding{Check: check, Status: StatusFail, Message: fmt.Sprintf("failed to look up tracking table %s: %v", table, err)}
+	}
+	if !exists {
+		return Finding{Check: check, Status: StatusOK, Message: fmt.Sprintf("tracking table %s does not exist yet; migrate creates it", table)}
+	}
+	applied, err := migrate.Applied(ctx, db, table)
+	if err != nil {
+		return Finding{Check: check, Status: StatusFail, Message: err.Error(),
+			Hint: fmt.Sprintf("%s needs version, checksum, and applied_at columns; use -migrations-table if migrations are tracked in another table", table)}
+	}
+
+	var (
+		latest  migrate.Migration
+		missing []string
+	)
+	for _, m := range applied {
+		if m.Checksum == "" {
+			missing = append(missing, m.Version)
+		}
+		if m.AppliedAt.After(latest.AppliedAt) || (m.AppliedAt.Equal(latest.AppliedAt) && m.Version > latest.Version) {
+			latest = m
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return Finding{Check: check, Status: StatusFail,
+			Message: fmt.Sprintf("%d migration(s) in %s have no checksum: %s", len(missing), table, strings.Join(missing, ", ")),
+			Hint:    "migrate refuses these records; restore their checksums or delete them and reapply the migrations"}
+	}
+
+	if driver == "postgres" {
+		var (
+			insert, del bool
+			user        string
+		)
+		err := db.QueryRowContext(ctx, `SELECT current_user, has_table_privilege(to_regclass($1), 'INSERT'), has_table_privilege(to_regclass($1), 'DELETE')`,
+			database.QuoteQualified(table)).Scan(&user, &insert, &del)
+		if err != nil {
+			return Finding{Check: check, Status: StatusFail, Message: fmt.Sprintf("failed to query tracking table privileges: %v", err)}
+		}
+		var privileges []string
+		if !insert {
+			privileges = append(privileges, "INSERT")
+		}
+		if !del {
+			privileges = append(privileges, "DELETE")
+		}
+		if len(privileges) > 0 {
+			return Finding{Check: check, Status: StatusFail,
+				Message: fmt.Sprintf("role %s is missing %s on tracking table %s", user, strings.Join(privileges, ", "), table),
+				Hint:    fmt.Sprintf("GRANT %s ON %s TO %s;", strings.Join(privileges, ", "), database.QuoteQualified(table), database.QuoteIdent(user))}
+		}
+	}
+
+	if len(applied) == 0 {
+		return Finding{Check: check, Status: StatusOK, Message: fmt.Sprintf("tracking table %s records no migrations", table)}
+	}
+	return Finding{Check: check, Status: StatusOK, Message: fmt.Sprintf("%d migration(s) recorded in %s, latest %s applied %s",
+		len(applied), table, latest.Version, latest.AppliedAt.UTC().Format(time.RFC3339))}
+}
+
+// checkAdvisoryLock probes the migration lock migrate takes on table, so a
+// held lock points at a migrate run in progress.
+func checkAdvisoryLock(ctx context.Context, db *sql.DB, table string) Finding {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return Finding{Check: "advisory-lock", Status: StatusFail, Message: fmt.Sprintf("failed to acquire connection: %v", err)}
+	}
+	defer func() { _ = conn.Close() }()
+
+	key := migrate.LockKey(table)
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		return Finding{Check: "advisory-lock", Status: StatusFail, Message: fmt.Sprintf("advisory locks unavailable: %v", err),
+			Hint: "connection poolers in transaction mode (e.g. PgBouncer) do not support session advisory locks"}
+	}
+	if !locked {
+		return Finding{Check: "advisory-lock", Status: StatusWarn, Message: fmt.Sprintf("the migration lock on %s is held by another session", table),
+			Hint: "a sql-loader migrate run may be in progress against this database"}
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key); err != nil {
+		return Finding{Check: "advisory-lock", Status: StatusWarn, Message: fmt.Sprintf("failed to release advisory lock: %v", err)}
+	}
+	return Finding{Check: "advisory-lock", Status: StatusOK, Message: "session advisory locks available"}
+}
+
+// Failed reports whether any finding has failed.
+func Failed(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints findings in a human-readable form.
+func Write(w io.Writer, findings []Finding) error {
+	for _, f := range findings {
+		if _, err := fmt.Fprintf(w, "[%-4s] %s: %s\n", strings.ToUpper(string(f.Status)), f.Check, f.Message); err != nil {
+			return err
+		}
+		if f.Hint != "" {
+			if _, err := fmt.Fprintf(w, "       hint: %s\n", f.Hint); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+func TestDiagnoseSQLite(t *testing.T) {
+	findings := Diagnose(context.Background(), "sqlite", filepath.Join(t.TempDir(), "doctor.db"), Options{})
+	if Failed(findings) {
+		t.Fatalf("Diagnose() failed: %+v", findings)
+	}
+
+	want := map[string]Status{
+		"connectivity":   StatusOK,
+		"server-version": StatusOK,
+		"writable":       StatusOK,
+		"tracking-table": StatusOK,
+		"privileges":     StatusSkip,
+		"advisory-lock":  StatusSkip,
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("got %d findings, want %d: %+v", len(findings), len(want), findings)
+	}
+	for _, f := range findings {
+		if want[f.Check] != f.Status {
+			t.Errorf("check %s status = %s, want %s", f.Check, f.Status, want[f.Check])
+		}
+	}
+}
+
+func TestCheckTrackingTable(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       string
+		table       string
+		wantStatus  Status
+		wantMessage string
+	}{
+		{name: "not created yet", wantStatus: StatusOK, wantMessage: "tracking table schema_migrations does not exist yet"},
+		{name: "empty", setup: trackingTable, wantStatus: StatusOK, wantMessage: "tracking table schema_migrations records no migrations"},
+		{
+			name:        "records migrations",
+			setup:       trackingTable + "INSERT INTO schema_migrations VALUES ('0001.sql', 'a', '2024-01-01 00:00:00'), ('0002.sql', 'b', '2024-01-02 00:00:00');",
+			wantStatus:  StatusOK,
+			wantMessage: "2 migration(s) recorded in schema_migrations, latest 0002.sql applied 2024-01-02T00:00:00Z",
+		},
+		{
+			name:        "qualified table",
+			setup:       "CREATE TABLE deploy (version TEXT PRIMARY KEY, checksum TEXT NOT NULL, applied_at TIMESTAMP NOT NULL);",
+			table:       "main.deploy",
+			wantStatus:  StatusOK,
+			wantMessage: "tracking table main.deploy records no migrations",
+		},
+		{
+			name:        "missing checksum",
+			setup:       trackingTable + "INSERT INTO schema_migrations VALUES ('0001.sql', '', '2024-01-01 00:00:00');",
+			wantStatus:  StatusFail,
+			wantMessage: "1 migration(s) in schema_migrations have no checksum: 0001.sql",
+		},
+		{
+			name:        "not a tracking table",
+			setup:       "CREATE TABLE schema_migrations (id INTEGER);",
+			wantStatus:  StatusFail,
+			wantMessage: "failed to read migration table schema_migrations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := database.Connect(context.Background(), "sqlite", filepath.Join(t.TempDir(), "doctor.db"))
+			if err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer func() { _ = db.Close() }()
+			if _, err := db.Exec(tt.setup); tt.setup != "" && err != nil {
+				t.Fatalf("setup error = %v", err)
+			}
+			table := tt.table
+			if table == "" {
+				table = "schema_migrations"
+			}
+			f := checkTrackingTable(context.Background(), db, "sqlite", table)
+			if f.Status != tt.wantStatus || !strings.Contains(f.Message, tt.wantMessage) {
+				t.Errorf("checkTrackingTable() = %+v, want %s containing %q", f, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
+
+// trackingTable creates a tracking table as migrate does.
+const trackingTable = "CREATE TABLE schema_migrations (version TEXT PRIMARY KEY, checksum TEXT NOT NULL, applied_at TIMESTAMP NOT NULL);"
+
+func TestDiagnoseConnectFailure(t *testing.T) {
+	findings := Diagnose(context.Background(), "sqlite", "", Options{})
+	if !Failed(findings) || len(findings) != 1 || findings[0].Check != "connectivity" {
+		t.Errorf("Diagnose() = %+v, want single failed connectivity finding", findings)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, []Finding{
+		{Check: "connectivity", Status: StatusOK, Message: "connected"},
+		{Check: "privileges:public", Status: StatusFail, Message: "missing CREATE", Hint: "GRANT CREATE ON SCHEMA public TO app;"},
+	})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "[OK  ] connectivity: connected\n" +
+		"[FAIL] privileges:public: missing CREATE\n" +
+		"       hint: GRANT CREATE ON SCHEMA public TO app;\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+// Package dryrun describes what a SQL script would execute, statement by
+// statement, without connecting to a database.
+package dryrun
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// maxSummaryWidth caps the statement text shown per line of a summary.
+const maxSummaryWidth = 80
+
+// Statement is one statement of a plan.
+type Statement struct {
+	// Index is the 1-based position of the statement in the script.
+	Index int
+	// Line is the line of the script the statement starts on.
+	Line int
+	Info database.StatementInfo
+	Text string
+	// Problem is the syntax error found in the statement, if any, with its
+	// line relative to the script.
+	Problem *database.SyntaxError
+}
+
+// Plan is the sequence of statements a script would execute.
+type Plan struct {
+	Source     string
+	Statements []Statement
+}
+
+// Script splits a script into statements exactly as execution would and
+// checks each for syntax errors that can be found without a server.
+func Script(source, script string) Plan {
+	p := Plan{Source: source}
+	offset, line := 0, 1
+	for i, stmt := range database.SplitStatements(script) {
+		// Statements are trimmed substrings of the script, in order.
+		if at := strings.Index(script[offset:], stmt); at >= 0 {
+			line += strings.Count(script[offset:offset+at], "\n")
+			offset += at
+		}
+		s := Statement{Index: i + 1, Line: line, Info: database.DescribeStatement(stmt), Text: stmt}
+		if err := database.CheckSyntax(stmt); errors.As(err, &s.Problem) {
+			s.Problem.Line += line - 1
+		}
+		p.Statements = append(p.Statements, s)
+	}
+	return p
+}
+
+// Problems returns the statements with syntax errors.
+func (p Plan) Problems() []Statement {
+	var problems []Statement
+	for _, s := range p.Statements {
+		if s.Problem != nil {
+			problems = append(problems, s)
+		}
+	}
+	return problems
+}
+
+// Write prints the plan: a table with the first line of each statement, or
+// with full set, every statement as an annotated SQL script.
+func (p Plan) Write(w io.Writer, full bool) error {
+	if _, err := fmt.Fprintf(w, "%s: %s\n", p.Source, p.summary()); err != nil {
+		return err
+	}
+	if full {
+		for _, s := range p.Statements {
+			if _, err := fmt.Fprintf(w, "\n-- Statement %d, line %d\n%s;\n", s.Index, s.Line, s.Text); err != nil {
+				return err
+			}
+		}
+	} else if len(p.Statements) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "#\tLINE\tVERB\tOBJECT\tSTATEMENT")
+		for _, s := range p.Statements {
+			object := s.Info.Object
+			if object == "" {
+				object = "-"
+			}
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n", s.Index, s.Line, s.Info.Verb, object, firstLine(s.Text))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	for _, s := range p.Problems() {
+		if _, err := fmt.Fprintf(w, "Error: %s statement %d: %v\n", p.Source, s.Index, s.Problem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summary counts the statements by verb, e.g. "3 statements (CREATE 1, INSERT 2)".
+func (p Plan) summary() string {
+	verbs := map[string]int{}
+	for _, s := range p.Statements {
+		verb := s.Info.Verb
+		if verb == "" {
+			verb = "OTHER"
+		}
+		verbs[verb]++
+	}
+	names := make([]string, 0, len(verbs))
+	for v := range verbs {
+		names = append(names, v)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, v := range names {
+		parts[i] = fmt.Sprintf("%s %d", v, verbs[v])
+	}
+	s := fmt.Sprintf("%d statements", len(p.Statements))
+	if len(parts) > 0 {
+		s += " (" + strings.Join(parts, ", ") + ")"
+	}
+	return s
+}
+
+// firstLine returns the first line of stmt, shortened to
+// maxSummaryWidth, marking anything cut off with "...".
+func firstLine(stmt string) string {
+	line, rest, more := strings.Cut(stmt, "\n")
+	line = strings.TrimSpace(line)
+	if r := []rune(line); len(r) > maxSummaryWidth {
+		return string(r[:maxSummaryWidth-3]) + "..."
+	}
+	if more && strings.TrimSpace(rest) != "" {
+		return line + " ..."
+	}
+	return line
+}
@@ -0,0 +1,62 @@
+package dryrun
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const script = `-- seed data
+CREATE TABLE users (
+  id INTEGER PRIMARY KEY,
+  name TEXT
+);
+
+INSERT INTO users (name) VALUES ('Alice');
+INSERT INTO users (name) VALUES ('Bob);
+`
+
+func TestScript(t *testing.T) {
+	p := Script("seed.sql", script)
+	if len(p.Statements) != 3 {
+		t.Fatalf("Script() = %d statements, want 3", len(p.Statements))
+	}
+	lines := []int{1, 7, 8}
+	for i, s := range p.Statements {
+		if s.Line != lines[i] {
+			t.Errorf("statement %d line = %d, want %d", s.Index, s.Line, lines[i])
+		}
+	}
+	problems := p.Problems()
+	if len(problems) != 1 || problems[0].Index != 3 {
+		t.Fatalf("Problems() = %+v, want statement 3", problems)
+	}
+	if got := problems[0].Problem.Error(); got != "unterminated string literal on line 8" {
+		t.Errorf("Problem = %q, want it on script line 8", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	p := Script("seed.sql", "CREATE TABLE t (\n  id INTEGER\n);\nINSERT INTO t VALUES (1);")
+
+	var summary bytes.Buffer
+	if err := p.Write(&summary, false); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := `seed.sql: 2 statements (CREATE 1, INSERT 1)
+#  LINE  VERB    OBJECT  STATEMENT
+1  1     CREATE  t       CREATE TABLE t ( ...
+2  4     INSERT  t       INSERT INTO t VALUES (1)
+`
+	if summary.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", summary.String(), want)
+	}
+
+	var full bytes.Buffer
+	if err := p.Write(&full, true); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !strings.Contains(full.String(), "-- Statement 1, line 1\nCREATE TABLE t (\n  id INTEGER\n);\n") {
+		t.Errorf("Write(full) =\n%s", full.String())
+	}
+}
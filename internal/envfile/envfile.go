@@ -0,0 +1,141 @@
+// Package envfile provides functionality for reading dotenv-style files
+// and applying their variables to the process environment.
+package envfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Parse reads KEY=VALUE assignments from r. A leading UTF-8 byte order mark
+// and CRLF line endings, as written by Windows editors, are accepted. Blank lines and lines starting
+// with # are ignored, an optional "export " prefix is accepted, and values may
+// be wrapped in single quotes (taken literally) or double quotes (supporting
+// \n, \t, \", and \\ escapes). Unquoted values may carry a trailing " #" comment.
+func Parse(r io.Reader) (map[string]string, error) {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validKey(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+
+		parsed, err := parseValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		vars[key] = parsed
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	return vars, nil
+}
+
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func parseValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	switch value[0] {
+	case '\'':
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single-quoted value")
+		}
+		return value[1 : end+1], nil
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(value); i++ {
+			c := value[i]
+			switch {
+			case c == '"':
+				return b.String(), nil
+			case c == '\\' && i+1 < len(value):
+				i++
+				switch value[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(value[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double-quoted value")
+	default:
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		return strings.TrimSpace(value), nil
+	}
+}
+
+// Load reads the given env files in order and sets their variables in the
+// process environment. Later files override earlier ones, but variables
+// already present in the environment are never overwritten.
+// #nosec G304 -- File paths are intentionally provided by the user as part of the CLI interface
+func Load(paths ...string) error {
+	merged := map[string]string{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open env file: %w", err)
+		}
+		vars, err := Parse(f)
+		closeErr := f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if closeErr != nil {
+			return fmt.Errorf("failed to close env file: %w", closeErr)
+		}
+		for k, v := range vars {
+			merged[k] = v
+		}
+	}
+
+	for k, v := range merged {
+		if _, exists := os.LookupEnv(k); exists {
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("failed to set %s: %w", k, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package envfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "assignments and comments",
+			input: `# comment
+DATABASE_URL=postgres://localhost/db
+export REGION=eu-west-1
+
+EMPTY=
+INLINE=value # trailing comment`,
+			want: map[string]string{
+				"DATABASE_URL": "postgres://localhost/db",
+				"REGION":       "eu-west-1",
+				"EMPTY":        "",
+				"INLINE":       "value",
+			},
+		},
+		{
+			name:  "quoted values",
+			input: "SINGLE='a $literal # value'\nDOUBLE=\"line1\\nline2 \\\"q\\\"\"",
+			want: map[string]string{
+				"SINGLE": "a $literal # value",
+				"DOUBLE": "line1\nline2 \"q\"",
+			},
+		},
+		{
+			name:  "windows line endings and BOM",
+			input: "\ufeffA=1\r\nB='two'\r\nC=\"3\"\r\n",
+			want:  map[string]string{"A": "1", "B": "two", "C": "3"},
+		},
+		{
+			name:    "missing equals",
+			input:   "NOT_AN_ASSIGNMENT",
+			wantErr: true,
+		},
+		{
+			name:    "invalid key",
+			input:   "1BAD=x",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated quote",
+			input:   `KEY="open`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := filepath.Join(tmpDir, ".env")
+	staging := filepath.Join(tmpDir, ".env.staging")
+	if err := os.WriteFile(base, []byte("ENVFILE_TEST_A=base\nENVFILE_TEST_B=base\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(staging, []byte("ENVFILE_TEST_B=staging\nENVFILE_TEST_C=staging\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	t.Setenv("ENVFILE_TEST_C", "process")
+	t.Setenv("ENVFILE_TEST_A", "")
+	if err := os.Unsetenv("ENVFILE_TEST_A"); err != nil {
+		t.Fatalf("Unsetenv() error = %v", err)
+	}
+	t.Setenv("ENVFILE_TEST_B", "")
+	if err := os.Unsetenv("ENVFILE_TEST_B"); err != nil {
+		t.Fatalf("Unsetenv() error = %v", err)
+	}
+
+	if err := Load(base, staging); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := map[string]string{
+		"ENVFILE_TEST_A": "base",
+		"ENVFILE_TEST_B": "staging",
+		"ENVFILE_TEST_C": "process",
+	}
+	for k, v := range want {
+		if got := os.Getenv(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	if err := Load(filepath.Join(tmpDir, "missing")); err == nil {
+		t.Error("Load() expected error for missing file")
+	}
+}
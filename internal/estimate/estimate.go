@@ -0,0 +1,126 @@
+// Package estimate provides functionality for sizing a load before it runs:
+// statement and row counts, byte sizes, and content checksums.
+package estimate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/dataload"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+)
+
+// Estimate describes the size of a single script or data file.
+type Estimate struct {
+	// Source is the script or data file name.
+	Source string
+	// Bytes is the input size in bytes.
+	Bytes int64
+	// Checksum is the hex-encoded SHA-256 of the input.
+	Checksum string
+	// Statements is the number of statements in a script.
+	Statements int
+	// Verbs counts script statements by leading keyword.
+	Verbs map[string]int
+	// Rows is the number of records in a data file.
+	Rows int64
+	// Duration is the expected run time, if known.
+	Duration time.Duration
+	// Runs is the number of previous runs Duration is based on.
+	Runs int
+}
+
+// Script sizes a SQL script.
+func Script(source, script string) Estimate {
+	e := Estimate{
+		Source:   source,
+		Bytes:    int64(len(script)),
+		Checksum: loader.Checksum(script),
+		Verbs:    map[string]int{},
+	}
+	for _, stmt := range database.SplitStatements(script) {
+		e.Statements++
+		verb := database.DescribeStatement(stmt).Verb
+		if verb == "" {
+			verb = "OTHER"
+		}
+		e.Verbs[verb]++
+	}
+	return e
+}
+
+// Data sizes a data file by reading it to the end.
+func Data(source string, r io.Reader, format dataload.Format) (Estimate, error) {
+	counter := &countingReader{r: r, h: sha256.New()}
+	reader, err := dataload.NewReader(counter, format)
+	if err != nil {
+		return Estimate{}, err
+	}
+	e := Estimate{Source: source}
+	for {
+		if _, err := reader.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return Estimate{}, err
+		}
+		e.Rows++
+	}
+	e.Bytes = counter.n
+	e.Checksum = hex.EncodeToString(counter.h.Sum(nil))
+	return e, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	h hash.Hash
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	c.h.Write(p[:n])
+	return n, err
+}
+
+// Write prints the estimate in a human-readable form.
+func (e Estimate) Write(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", e.Source)
+	fmt.Fprintf(&b, "  Size:       %d bytes\n", e.Bytes)
+	fmt.Fprintf(&b, "  SHA-256:    %s\n", e.Checksum)
+	if e.Verbs != nil {
+		fmt.Fprintf(&b, "  Statements: %d", e.Statements)
+		if len(e.Verbs) > 0 {
+			verbs := make([]string, 0, len(e.Verbs))
+			for v := range e.Verbs {
+				verbs = append(verbs, v)
+			}
+			sort.Strings(verbs)
+			parts := make([]string, len(verbs))
+			for i, v := range verbs {
+				parts[i] = fmt.Sprintf("%s %d", v, e.Verbs[v])
+			}
+			fmt.Fprintf(&b, " (%s)", strings.Join(parts, ", "))
+		}
+		b.WriteString("\n")
+	} else {
+		fmt.Fprintf(&b, "  Rows:       %d\n", e.Rows)
+	}
+	if e.Runs > 0 {
+		fmt.Fprintf(&b, "  Duration:   ~%s (average of %d previous run(s))\n", e.Duration.Round(time.Millisecond), e.Runs)
+	} else {
+		b.WriteString("  Duration:   unknown (no previous runs recorded)\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,64 @@
+package estimate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/dataload"
+)
+
+func TestScript(t *testing.T) {
+	e := Script("seed.sql", "CREATE TABLE t (id INT);\nINSERT INTO t VALUES (1);\nINSERT INTO t VALUES (2);\n")
+	if e.Statements != 3 || e.Verbs["INSERT"] != 2 || e.Verbs["CREATE"] != 1 {
+		t.Errorf("Script() = %+v", e)
+	}
+	if len(e.Checksum) != 64 {
+		t.Errorf("Checksum = %q, want 64 hex characters", e.Checksum)
+	}
+	other := Script("other.sql", "SELECT 1;")
+	if other.Checksum == e.Checksum {
+		t.Error("different scripts produced the same checksum")
+	}
+}
+
+func TestData(t *testing.T) {
+	input := "id,name\n1,a\n2,b\n3,c\n"
+	e, err := Data("users.csv", strings.NewReader(input), dataload.FormatCSV)
+	if err != nil {
+		t.Fatalf("Data() error = %v", err)
+	}
+	if e.Rows != 3 || e.Bytes != int64(len(input)) {
+		t.Errorf("Data() = %+v, want 3 rows and %d bytes", e, len(input))
+	}
+
+	if _, err := Data("bad.jsonl", strings.NewReader("{\n"), dataload.FormatJSONL); err == nil {
+		t.Error("Data() expected error for invalid input")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	e := Script("seed.sql", "INSERT INTO t VALUES (1);")
+	e.Duration = 1500 * time.Millisecond
+	e.Runs = 2
+	if err := e.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, want := range []string{"seed.sql\n", "Size:       25 bytes", "Statements: 1 (INSERT 1)", "Duration:   ~1.5s (average of 2 previous run(s))"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Write() output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := (Estimate{Source: "data.csv", Rows: 10}).Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, want := range []string{"Rows:       10", "unknown (no previous runs recorded)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Write() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,456 @@
+// Package expect provides functionality for row-level expectations: queries
+// that must return exactly the rows listed inline in an expectations file
+// or in a CSV golden file, reported as a diff when they do not, and
+// rewritten from what the queries return to maintain them.
+package expect
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// maxDiffLines caps the lines of a mismatch diff.
+const maxDiffLines = 40
+
+// File is an expectations file.
+type File struct {
+	// Path is the file the expectations were loaded from.
+	Path         string        `yaml:"-"`
+	Expectations []Expectation `yaml:"expectations"`
+
+	doc yaml.Node
+}
+
+// Expectation is a query and the rows it must return, in order.
+type Expectation struct {
+	Name  string `yaml:"name"`
+	Query string `yaml:"query"`
+	// Columns, if set, are the column names the query must return.
+	Columns []string `yaml:"columns"`
+	// Rows are the expected rows, each a list of values written as text,
+	// NULL for a null.
+	Rows [][]string `yaml:"rows"`
+	// Golden names a CSV file, relative to the expectations file, holding
+	// the column names and then the expected rows, in place of Columns and
+	// Rows.
+	Golden string `yaml:"golden"`
+}
+
+// Table is what a query returned, or is expected to return.
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// Result is the outcome of one expectation.
+type Result struct {
+	Expectation *Expectation
+	// Actual is what the query returned, unset when Err is.
+	Actual Table
+	// Diff describes how Actual differs from the expectation, and is empty
+	// when it matches.
+	Diff string
+	// Err is why the query failed.
+	Err error
+}
+
+// Passed reports whether the query ran and returned the expected rows.
+func (r Result) Passed() bool {
+	return r.Err == nil && r.Diff == ""
+}
+
+// Error is the failure of r, or nil if it passed.
+func (r Result) Error() error {
+	switch {
+	case r.Err != nil:
+		return fmt.Errorf("expectation %q failed: %w", r.Expectation.Name, r.Err)
+	case r.Diff != "":
+		return fmt.Errorf("expectation %q returned other rows (- expected, + actual):\n%s", r.Expectation.Name, r.Diff)
+	}
+	return nil
+}
+
+// Load reads the expectations file at path and the golden files it names.
+// check, if set, is called with each path before it is opened; an error
+// aborts the load.
+// #nosec G304 -- File path is intentionally provided by the user as part of the CLI interface
+func Load(path string, check func(path string) error) (*File, error) {
+	if check != nil {
+		if err := check(path); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expectations file: %w", err)
+	}
+	file, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	file.Path = path
+	for i := range file.Expectations {
+		e := &file.Expectations[i]
+		if e.Golden == "" {
+			continue
+		}
+		golden := file.goldenPath(e)
+		if check != nil {
+			if err := check(golden); err != nil {
+				return nil, err
+			}
+		}
+		if e.Columns, e.Rows, err = readGolden(golden); err != nil {
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// Parse decodes and checks a YAML expectations document. Unknown keys are
+// rejected.
+func Parse(data []byte) (*File, error) {
+	file := &File{}
+	if err := yaml.Unmarshal(data, &file.doc); err != nil {
+		return nil, fmt.Errorf("invalid expectations file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid expectations file: %w", err)
+	}
+	if len(file.Expectations) == 0 {
+		return nil, fmt.Errorf("invalid expectations file: no expectations")
+	}
+	names := map[string]bool{}
+	for i, e := range file.Expectations {
+		if err := e.check(); err != nil {
+			return nil, fmt.Errorf("invalid expectation %d: %w", i+1, err)
+		}
+		if names[e.Name] {
+			return nil, fmt.Errorf("invalid expectation %d: name %q is used twice", i+1, e.Name)
+		}
+		names[e.Name] = true
+	}
+	return file, nil
+}
+
+func (e Expectation) check() error {
+	if e.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if strings.TrimSpace(e.Query) == "" {
+		return fmt.Errorf("query is required")
+	}
+	if e.Golden != "" && (e.Columns != nil || e.Rows != nil) {
+		return fmt.Errorf("golden cannot be combined with columns or rows")
+	}
+	for i, row := range e.Rows {
+		if e.Columns != nil && len(row) != len(e.Columns) {
+			return fmt.Errorf("row %d has %d value(s), want one per column (%d)", i+1, len(row), len(e.Columns))
+		}
+	}
+	return nil
+}
+
+// Run runs every expectation on db, returning a result for each, or for
+// those run before ctx was done. The queries cannot change the database:
+// they run as database.RolledBack does.
+func (f *File) Run(ctx context.Context, db database.QueryExecer) ([]Result, error) {
+	var results []Result
+	err := database.RolledBack(ctx, db, func(q database.QueryExecer) error {
+		for i := range f.Expectations {
+			e := &f.Expectations[i]
+			actual, err := query(ctx, q, e.Query)
+			if err != nil {
+				results = append(results, Result{Expectation: e, Err: err})
+				if ctx.Err() != nil {
+					return context.Cause(ctx)
+				}
+				continue
+			}
+			want := Table{Columns: e.Columns, Rows: e.Rows}
+			results = append(results, Result{Expectation: e, Actual: actual, Diff: diff(want, actual)})
+		}
+		return nil
+	})
+	return results, err
+}
+
+// Update rewrites the expectations of the results that ran but did not
+// match with the rows their queries returned: the golden file of each that
+// has one, and the expectations file for inline ones. It returns how many
+// it rewrote.
+func (f *File) Update(results []Result) (int, error) {
+	updated, inline := 0, false
+	for _, r := range results {
+		if r.Err != nil || r.Diff == "" {
+			continue
+		}
+		e := r.Expectation
+		e.Rows = r.Actual.Rows
+		if e.Golden != "" {
+			e.Columns = r.Actual.Columns
+			if err := writeGolden(f.goldenPath(e), r.Actual); err != nil {
+				return updated, err
+			}
+		} else {
+			if e.Columns != nil {
+				e.Columns = r.Actual.Columns
+			}
+			if err := f.setInline(e); err != nil {
+				return updated, err
+			}
+			inline = true
+		}
+		updated++
+	}
+	if inline {
+		var b bytes.Buffer
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		if err := enc.Encode(&f.doc); err != nil {
+			return updated, fmt.Errorf("failed to update %s: %w", f.Path, err)
+		}
+		if err := os.WriteFile(f.Path, b.Bytes(), 0o600); err != nil {
+			return updated, fmt.Errorf("failed to update %s: %w", f.Path, err)
+		}
+	}
+	return updated, nil
+}
+
+// setInline replaces the columns and rows of e in the YAML document,
+// keeping the rest of it, comments included, as written.
+func (f *File) setInline(e *Expectation) error {
+	i := slices.IndexFunc(f.Expectations, func(x Expectation) bool { return x.Name == e.Name })
+	item := mappingValue(documentRoot(&f.doc), "expectations")
+	if i < 0 || item == nil || i >= len(item.Content) {
+		return fmt.Errorf("failed to update %s: expectation %q not found", f.Path, e.Name)
+	}
+	entry := item.Content[i]
+	if e.Columns != nil {
+		setMappingValue(entry, "columns", flowSequence(e.Columns))
+	}
+	rows := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	if old := mappingValue(entry, "rows"); old != nil {
+		rows.Style = old.Style
+	}
+	for _, row := range e.Rows {
+		rows.Content = append(rows.Content, flowSequence(row))
+	}
+	setMappingValue(entry, "rows", rows)
+	return nil
+}
+
+func (f *File) goldenPath(e *Expectation) string {
+	if filepath.IsAbs(e.Golden) {
+		return e.Golden
+	}
+	return filepath.Join(filepath.Dir(f.Path), e.Golden)
+}
+
+func query(ctx context.Context, db database.QueryExecer, stmt string) (Table, error) {
+	rows, err := db.QueryContext(ctx, stmt)
+	if err != nil {
+		return Table{}, err
+	}
+	defer func() { _ = rows.Close() }()
+	columns, err := rows.Columns()
+	if err != nil {
+		return Table{}, err
+	}
+	t := Table{Columns: columns, Rows: [][]string{}}
+	for rows.Next() {
+		values, err := database.ScanStrings(rows, len(columns))
+		if err != nil {
+			return Table{}, err
+		}
+		t.Rows = append(t.Rows, values)
+	}
+	return t, rows.Err()
+}
+
+// diff returns the lines by which actual differs from want, each prefixed
+// with - for an expected row missing from actual, + for an unexpected row,
+// or two spaces for a row both have, or "" if they match. Columns are
+// compared only when want lists them.
+func diff(want, actual Table) string {
+	var lines []string
+	if want.Columns != nil && !slices.Equal(want.Columns, actual.Columns) {
+		lines = append(lines, "- columns: "+record(want.Columns), "+ columns: "+record(actual.Columns))
+	}
+	if !slices.EqualFunc(want.Rows, actual.Rows, slices.Equal) {
+		lines = append(lines, diffRows(want.Rows, actual.Rows)...)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	if len(lines) > maxDiffLines {
+		more := len(lines) - maxDiffLines
+		lines = append(lines[:maxDiffLines], fmt.Sprintf("... %d more line(s)", more))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffRows lines up the rows both tables share, by longest common
+// subsequence, so that one inserted or missing row shows as one line. Past
+// a few thousand rows a side it falls back to comparing row by row.
+func diffRows(want, actual [][]string) []string {
+	a, b := records(want), records(actual)
+	if len(a)*len(b) > 4_000_000 {
+		var lines []string
+		for i := range max(len(a), len(b)) {
+			switch {
+			case i >= len(a):
+				lines = append(lines, "+ "+b[i])
+			case i >= len(b):
+				lines = append(lines, "- "+a[i])
+			case a[i] != b[i]:
+				lines = append(lines, "- "+a[i], "+ "+b[i])
+			}
+		}
+		return lines
+	}
+
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	return lines
+}
+
+func records(rows [][]string) []string {
+	out := make([]string, len(rows))
+	for i, row := range rows {
+		out[i] = record(row)
+	}
+	return out
+}
+
+// record formats values as a CSV line.
+func record(values []string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	_ = w.Write(values)
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// #nosec G304 -- Golden files are named by the user's expectations file
+func readGolden(path string) ([]string, [][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read golden file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read golden file %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("golden file %s has no header row of column names", path)
+	}
+	return records[0], append([][]string{}, records[1:]...), nil
+}
+
+// #nosec G304 -- Golden files are named by the user's expectations file
+func writeGolden(path string, t Table) error {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.Write(t.Columns); err != nil {
+		return fmt.Errorf("failed to update golden file %s: %w", path, err)
+	}
+	if err := w.WriteAll(t.Rows); err != nil {
+		return fmt.Errorf("failed to update golden file %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to update golden file %s: %w", path, err)
+	}
+	return nil
+}
+
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// mappingValue returns the value of key in mapping node m, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key in mapping node m to value, adding it if needed.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// flowSequence returns values as a one-line YAML list. Values are written
+// plain where they decode back into the same string, so numbers read as
+// written, and quoted where YAML would read them as null.
+func flowSequence(values []string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	for _, v := range values {
+		value := &yaml.Node{Kind: yaml.ScalarNode, Value: v}
+		switch v {
+		case "", "~", "null", "Null", "NULL":
+			value.Style = yaml.DoubleQuotedStyle
+		}
+		n.Content = append(n.Content, value)
+	}
+	return n
+}
@@ -0,0 +1,281 @@
+package expect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT);
+		INSERT INTO users VALUES (1, 'ann', 'ann@example.com'), (2, 'bob', NULL), (3, 'cy', 'cy@example.com')`); err != nil {
+		t.Fatalf("Failed to seed database: %v", err)
+	}
+	return db
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{name: "inline", doc: "expectations:\n  - name: users\n    query: SELECT id FROM users\n    columns: [id]\n    rows: [[1], [2]]\n"},
+		{name: "golden", doc: "expectations:\n  - name: users\n    query: SELECT id FROM users\n    golden: users.csv\n"},
+		{name: "no expectations", doc: "expectations: []\n", wantErr: "no expectations"},
+		{name: "unknown key", doc: "expectations:\n  - name: users\n    query: SELECT 1\n    row: [[1]]\n", wantErr: "field row not found"},
+		{name: "no name", doc: "expectations:\n  - query: SELECT 1\n", wantErr: "name is required"},
+		{name: "no query", doc: "expectations:\n  - name: one\n", wantErr: "query is required"},
+		{name: "duplicate name", doc: "expectations:\n  - name: one\n    query: SELECT 1\n  - name: one\n    query: SELECT 2\n", wantErr: `name "one" is used twice`},
+		{name: "golden and rows", doc: "expectations:\n  - name: one\n    query: SELECT 1\n    golden: one.csv\n    rows: [[1]]\n", wantErr: "golden cannot be combined"},
+		{name: "row width", doc: "expectations:\n  - name: one\n    query: SELECT 1, 2\n    columns: [a, b]\n    rows: [[1]]\n", wantErr: "row 1 has 1 value(s)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.doc))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "expect.yaml")
+	writeFile(t, path, "expectations:\n  - name: users\n    query: SELECT id, name FROM users\n    golden: golden/users.csv\n")
+	if err := os.Mkdir(filepath.Join(dir, "golden"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "golden", "users.csv"), "id,name\n1,ann\n2,bob\n")
+
+	var checked []string
+	file, err := Load(path, func(p string) error {
+		checked = append(checked, filepath.Base(p))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	e := file.Expectations[0]
+	if strings.Join(e.Columns, ",") != "id,name" || len(e.Rows) != 2 || e.Rows[1][1] != "bob" {
+		t.Errorf("Load() golden = %v %v, want the CSV header and rows", e.Columns, e.Rows)
+	}
+	if strings.Join(checked, ",") != "expect.yaml,users.csv" {
+		t.Errorf("Load() checked %v, want the expectations and golden files", checked)
+	}
+
+	denied := errors.New("outside -allow-path")
+	if _, err := Load(path, func(p string) error {
+		if strings.HasSuffix(p, ".csv") {
+			return denied
+		}
+		return nil
+	}); !errors.Is(err, denied) {
+		t.Errorf("Load() error = %v, want the check error for the golden file", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	tests := []struct {
+		name     string
+		doc      string
+		wantDiff string
+		wantErr  string
+	}{
+		{
+			name: "match",
+			doc:  "expectations:\n  - name: users\n    query: SELECT id, name, email FROM users ORDER BY id\n    columns: [id, name, email]\n    rows:\n      - [1, ann, ann@example.com]\n      - [2, bob, 'NULL']\n      - [3, cy, cy@example.com]\n",
+		},
+		{
+			name: "rows without columns",
+			doc:  "expectations:\n  - name: users\n    query: SELECT name FROM users ORDER BY id\n    rows: [[ann], [bob], [cy]]\n",
+		},
+		{
+			name:     "missing and extra rows",
+			doc:      "expectations:\n  - name: users\n    query: SELECT id, name FROM users ORDER BY id\n    rows: [[1, ann], [2, bea], [3, cy], [4, dee]]\n",
+			wantDiff: "  1,ann\n- 2,bea\n+ 2,bob\n  3,cy\n- 4,dee",
+		},
+		{
+			name:     "columns",
+			doc:      "expectations:\n  - name: users\n    query: SELECT id, name FROM users WHERE id = 1\n    columns: [id, login]\n    rows: [[1, ann]]\n",
+			wantDiff: "- columns: id,login\n+ columns: id,name",
+		},
+		{
+			name:     "no rows",
+			doc:      "expectations:\n  - name: nobody\n    query: SELECT id FROM users WHERE id > 9\n    rows: [[1]]\n",
+			wantDiff: "- 1",
+		},
+		{
+			name:    "query error",
+			doc:     "expectations:\n  - name: broken\n    query: SELECT nope FROM users\n",
+			wantErr: "no such column",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			results, err := file.Run(ctx, db)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Run() returned %d results, want 1", len(results))
+			}
+			r := results[0]
+			if tt.wantErr != "" {
+				if r.Err == nil || !strings.Contains(r.Err.Error(), tt.wantErr) {
+					t.Errorf("Run() result error = %v, want it to contain %q", r.Err, tt.wantErr)
+				}
+				return
+			}
+			if r.Err != nil {
+				t.Fatalf("Run() result error = %v", r.Err)
+			}
+			if r.Diff != tt.wantDiff {
+				t.Errorf("Run() diff =\n%s\nwant\n%s", r.Diff, tt.wantDiff)
+			}
+			if r.Passed() != (tt.wantDiff == "") || (r.Error() == nil) != r.Passed() {
+				t.Errorf("Run() Passed() = %v, Error() = %v", r.Passed(), r.Error())
+			}
+		})
+	}
+}
+
+func TestRunRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	file, err := Parse([]byte("expectations:\n  - name: deleted\n    query: DELETE FROM users RETURNING id\n    rows: [[1], [2], [3]]\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := file.Run(ctx, db); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM users").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("users has %d rows after Run(), want 3: the queries must be rolled back", n)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "expect.yaml")
+	writeFile(t, path, `# Expectations for the seed data.
+expectations:
+  - name: names
+    query: SELECT name FROM users ORDER BY id
+    rows: [[ann]] # stale
+  - name: emails
+    query: SELECT id, email FROM users WHERE id < 3 ORDER BY id
+    columns: [id, email]
+    rows: []
+  - name: users
+    query: SELECT id, email FROM users ORDER BY id
+    golden: users.csv
+  - name: count
+    query: SELECT count(*) FROM users
+    rows: [[3]]
+`)
+	writeFile(t, filepath.Join(dir, "users.csv"), "id,email\n1,old@example.com\n")
+
+	file, err := Load(path, nil)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	results, err := file.Run(ctx, db)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	updated, err := file.Update(results)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated != 3 {
+		t.Errorf("Update() = %d, want 3", updated)
+	}
+
+	golden, err := os.ReadFile(filepath.Join(dir, "users.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,email\n1,ann@example.com\n2,NULL\n3,cy@example.com\n"; string(golden) != want {
+		t.Errorf("golden file = %q, want %q", golden, want)
+	}
+	doc, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Expectations for the seed data.", "rows: [[ann], [bob], [cy]]", `rows: [[1, ann@example.com], [2, "NULL"]]`, "rows: [[3]]"} {
+		if !strings.Contains(string(doc), want) {
+			t.Errorf("expectations file =\n%s\nwant it to contain %q", doc, want)
+		}
+	}
+
+	file, err = Load(path, nil)
+	if err != nil {
+		t.Fatalf("Load() after Update() error = %v", err)
+	}
+	if results, err = file.Run(ctx, db); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, r := range results {
+		if !r.Passed() {
+			t.Errorf("expectation %q after Update() = %v, want it to pass", r.Expectation.Name, r.Error())
+		}
+	}
+}
+
+func TestDiffCap(t *testing.T) {
+	var want, actual Table
+	for i := range 100 {
+		want.Rows = append(want.Rows, []string{"a", string(rune('0' + i%10))})
+		actual.Rows = append(actual.Rows, []string{"b", string(rune('0' + i%10))})
+	}
+	lines := strings.Split(diff(want, actual), "\n")
+	if len(lines) != maxDiffLines+1 || lines[maxDiffLines] != "... 160 more line(s)" {
+		t.Errorf("diff() = %d lines ending %q, want %d and a count of the rest", len(lines), lines[len(lines)-1], maxDiffLines+1)
+	}
+}
@@ -0,0 +1,269 @@
+// Package grants provides functionality for applying a declarative grants
+// file: the privileges roles hold on schemas and the objects in them,
+// turned into GRANT and REVOKE statements that can be applied after every
+// load.
+package grants
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/config"
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// Object kinds a grant applies to.
+const (
+	OnSchema    = "schema"
+	OnTables    = "tables"
+	OnSequences = "sequences"
+	OnFunctions = "functions"
+)
+
+// privileges lists the privileges each object kind accepts, besides ALL.
+var privileges = map[string][]string{
+	OnSchema:    {"USAGE", "CREATE"},
+	OnTables:    {"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER", "MAINTAIN"},
+	OnSequences: {"USAGE", "SELECT", "UPDATE"},
+	OnFunctions: {"EXECUTE"},
+}
+
+// File is a grants file.
+type File struct {
+	Grants []Grant `yaml:"grants"`
+}
+
+// Grant gives roles privileges on a schema or on objects in it, or, with
+// Revoke, takes them away.
+type Grant struct {
+	Roles  []string `yaml:"roles"`
+	Schema string   `yaml:"schema"`
+	// On is the kind of object: schema, or tables (the default), sequences,
+	// or functions in the schema.
+	On string `yaml:"on"`
+	// Objects names the tables, sequences, or functions; empty means all of
+	// them in the schema.
+	Objects    []string `yaml:"objects"`
+	Privileges []string `yaml:"privileges"`
+	// Revoke takes the privileges away instead of granting them.
+	Revoke bool `yaml:"revoke"`
+	// Exact revokes every privilege the roles hold on the objects before
+	// granting, so that they hold only the privileges listed.
+	Exact bool `yaml:"exact"`
+}
+
+// Load reads the grants file at path. check, if set, is called with the
+// path before the file is opened; an error aborts the load.
+// #nosec G304 -- File path is intentionally provided by the user as part of the CLI interface
+func Load(path string, check func(path string) error) (*File, error) {
+	if check != nil {
+		if err := check(path); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grants file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	file, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// Parse decodes and checks a YAML grants document. Unknown keys are
+// rejected, and ${VAR} references in role, schema, and object names are
+// expanded from the environment, so that one file serves every
+// environment.
+func Parse(r io.Reader) (*File, error) {
+	file := &File{}
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid grants file: %w", err)
+	}
+	for i := range file.Grants {
+		g := &file.Grants[i]
+		g.Schema = config.ExpandEnv(g.Schema)
+		for j := range g.Roles {
+			g.Roles[j] = config.ExpandEnv(g.Roles[j])
+		}
+		for j := range g.Objects {
+			g.Objects[j] = config.ExpandEnv(g.Objects[j])
+		}
+		if err := g.check(); err != nil {
+			return nil, fmt.Errorf("invalid grant %d: %w", i+1, err)
+		}
+	}
+	return file, nil
+}
+
+// check validates g and normalizes its kind and privileges.
+func (g *Grant) check() error {
+	if g.On == "" {
+		g.On = OnTables
+	}
+	g.On = strings.ToLower(g.On)
+	accepted, ok := privileges[g.On]
+	if !ok {
+		return fmt.Errorf("unsupported object kind %q (use schema, tables, sequences, or functions)", g.On)
+	}
+	if len(g.Roles) == 0 {
+		return fmt.Errorf("roles are required")
+	}
+	if slices.Contains(g.Roles, "") {
+		return fmt.Errorf("role names must not be empty (check that the environment variables they use are set)")
+	}
+	if g.Schema == "" {
+		return fmt.Errorf("schema is required")
+	}
+	if g.On == OnSchema && len(g.Objects) > 0 {
+		return fmt.Errorf("objects cannot be given for a schema grant")
+	}
+	if slices.Contains(g.Objects, "") {
+		return fmt.Errorf("object names must not be empty")
+	}
+	if g.On == OnFunctions {
+		for _, name := range g.Objects {
+			if _, args, ok := splitSignature(name); ok && !validArgs(args) {
+				return fmt.Errorf("invalid argument types in function %s", name)
+			}
+		}
+	}
+	if len(g.Privileges) == 0 {
+		return fmt.Errorf("privileges are required")
+	}
+	if g.Revoke && g.Exact {
+		return fmt.Errorf("revoke cannot be combined with exact")
+	}
+	for i, p := range g.Privileges {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "ALL PRIVILEGES" {
+			p = "ALL"
+		}
+		if p != "ALL" && !slices.Contains(accepted, p) {
+			return fmt.Errorf("privilege %s does not apply to %s (use %s, or ALL)", p, g.On, strings.Join(accepted, ", "))
+		}
+		g.Privileges[i] = p
+	}
+	return nil
+}
+
+// Statements returns the GRANT and REVOKE statements applying f on driver,
+// in the order the grants are listed. PostgreSQL grants a privilege a role
+// already holds, and revokes one it lacks, without error, so applying the
+// statements again changes nothing.
+func (f *File) Statements(driver string) ([]string, error) {
+	if driver != "postgres" {
+		return nil, fmt.Errorf("grants require the postgres driver; %s has no roles or privileges", driver)
+	}
+	var statements []string
+	for _, g := range f.Grants {
+		target, roles := g.target(), roleList(g.Roles)
+		privs := strings.Join(g.Privileges, ", ")
+		switch {
+		case g.Revoke:
+			statements = append(statements, fmt.Sprintf("REVOKE %s ON %s FROM %s", privs, target, roles))
+		case g.Exact:
+			statements = append(statements,
+				fmt.Sprintf("REVOKE ALL ON %s FROM %s", target, roles),
+				fmt.Sprintf("GRANT %s ON %s TO %s", privs, target, roles))
+		default:
+			statements = append(statements, fmt.Sprintf("GRANT %s ON %s TO %s", privs, target, roles))
+		}
+	}
+	return statements, nil
+}
+
+// Script returns the statements of f on driver as one script.
+func (f *File) Script(driver string) (string, error) {
+	statements, err := f.Statements(driver)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, stmt := range statements {
+		b.WriteString(stmt)
+		b.WriteString(";\n")
+	}
+	return b.String(), nil
+}
+
+// target returns the objects clause of g.
+func (g *Grant) target() string {
+	schema := database.QuoteIdent(g.Schema)
+	if g.On == OnSchema {
+		return "SCHEMA " + schema
+	}
+	if len(g.Objects) == 0 {
+		return "ALL " + strings.ToUpper(g.On) + " IN SCHEMA " + schema
+	}
+	names := make([]string, len(g.Objects))
+	for i, name := range g.Objects {
+		if g.On == OnFunctions {
+			if base, args, ok := splitSignature(name); ok {
+				names[i] = schema + "." + database.QuoteIdent(base) + args
+				continue
+			}
+		}
+		names[i] = schema + "." + database.QuoteIdent(name)
+	}
+	kind := map[string]string{OnTables: "TABLE", OnSequences: "SEQUENCE", OnFunctions: "FUNCTION"}[g.On]
+	return kind + " " + strings.Join(names, ", ")
+}
+
+// splitSignature splits a function name such as refresh(int, text) into
+// the name and its parenthesized argument types, which pick one overload.
+func splitSignature(name string) (base, args string, ok bool) {
+	i := strings.IndexByte(name, '(')
+	if i <= 0 || !strings.HasSuffix(name, ")") {
+		return name, "", false
+	}
+	return strings.TrimSpace(name[:i]), name[i:], true
+}
+
+// validArgs reports whether args, written into the statement unquoted, is
+// a single balanced parenthesized list that cannot end the statement or
+// open a comment or string.
+func validArgs(args string) bool {
+	if strings.ContainsAny(args, ";'") || strings.Contains(args, "--") || strings.Contains(args, "/*") {
+		return false
+	}
+	depth := 0
+	for i, r := range args {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i != len(args)-1 {
+				return false
+			}
+		}
+		if depth < 0 {
+			return false
+		}
+	}
+	return depth == 0
+}
+
+// roleList quotes roles, except PUBLIC, which names every role.
+func roleList(roles []string) string {
+	quoted := make([]string, len(roles))
+	for i, role := range roles {
+		if strings.EqualFold(role, "public") {
+			quoted[i] = "PUBLIC"
+		} else {
+			quoted[i] = database.QuoteIdent(role)
+		}
+	}
+	return strings.Join(quoted, ", ")
+}
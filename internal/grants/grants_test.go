@@ -0,0 +1,129 @@
+package grants
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		want    []Grant
+		wantErr string
+	}{
+		{
+			name: "defaults and normalization",
+			doc:  "grants:\n  - roles: [app_reader]\n    schema: public\n    privileges: [select, all privileges]\n",
+			want: []Grant{{Roles: []string{"app_reader"}, Schema: "public", On: OnTables, Privileges: []string{"SELECT", "ALL"}}},
+		},
+		{
+			name: "environment variables",
+			doc:  "grants:\n  - roles: [\"${GRANTS_TEST_ROLE}\"]\n    schema: \"${GRANTS_TEST_SCHEMA}\"\n    on: Schema\n    privileges: [USAGE]\n",
+			want: []Grant{{Roles: []string{"reader_staging"}, Schema: "app", On: OnSchema, Privileges: []string{"USAGE"}}},
+		},
+		{name: "empty", doc: "", want: nil},
+		{name: "unknown key", doc: "grants:\n  - role: app\n", wantErr: "field role not found"},
+		{name: "no roles", doc: "grants:\n  - schema: public\n    privileges: [SELECT]\n", wantErr: "grant 1: roles are required"},
+		{name: "unset variable", doc: "grants:\n  - roles: [\"${GRANTS_TEST_UNSET}\"]\n    schema: public\n    privileges: [SELECT]\n", wantErr: "role names must not be empty"},
+		{name: "no schema", doc: "grants:\n  - roles: [app]\n    privileges: [SELECT]\n", wantErr: "schema is required"},
+		{name: "no privileges", doc: "grants:\n  - roles: [app]\n    schema: public\n", wantErr: "privileges are required"},
+		{name: "unknown kind", doc: "grants:\n  - roles: [app]\n    schema: public\n    on: views\n    privileges: [SELECT]\n", wantErr: `unsupported object kind "views"`},
+		{
+			name:    "privilege of another kind",
+			doc:     "grants:\n  - roles: [app]\n    schema: public\n    on: sequences\n    privileges: [INSERT]\n",
+			wantErr: "privilege INSERT does not apply to sequences (use USAGE, SELECT, UPDATE, or ALL)",
+		},
+		{name: "schema objects", doc: "grants:\n  - roles: [app]\n    schema: public\n    on: schema\n    objects: [t]\n    privileges: [USAGE]\n", wantErr: "objects cannot be given"},
+		{
+			name:    "function argument types",
+			doc:     "grants:\n  - roles: [app]\n    schema: public\n    on: functions\n    objects: [\"f(int) TO x; DROP TABLE t; --)\"]\n    privileges: [EXECUTE]\n",
+			wantErr: "invalid argument types in function",
+		},
+		{name: "revoke and exact", doc: "grants:\n  - roles: [app]\n    schema: public\n    privileges: [SELECT]\n    revoke: true\n    exact: true\n", wantErr: "cannot be combined"},
+	}
+	t.Setenv("GRANTS_TEST_ROLE", "reader_staging")
+	t.Setenv("GRANTS_TEST_SCHEMA", "app")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.doc))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Grants, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got.Grants, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatements(t *testing.T) {
+	f := &File{Grants: []Grant{
+		{Roles: []string{"app_reader", "analyst"}, Schema: "public", On: OnSchema, Privileges: []string{"USAGE"}},
+		{Roles: []string{"app_reader"}, Schema: "public", On: OnTables, Privileges: []string{"SELECT"}},
+		{Roles: []string{"app_writer"}, Schema: "sales", On: OnTables, Objects: []string{"orders", "Line Items"}, Privileges: []string{"SELECT", "INSERT"}, Exact: true},
+		{Roles: []string{"app_writer"}, Schema: "sales", On: OnSequences, Privileges: []string{"USAGE", "SELECT"}},
+		{Roles: []string{"app_writer"}, Schema: "sales", On: OnFunctions, Objects: []string{"refresh"}, Privileges: []string{"EXECUTE"}},
+		{Roles: []string{"app_writer"}, Schema: "sales", On: OnFunctions, Objects: []string{"total(int, numeric(10, 2))", "Total()"}, Privileges: []string{"EXECUTE"}},
+		{Roles: []string{"public"}, Schema: "public", On: OnSchema, Privileges: []string{"CREATE"}, Revoke: true},
+	}}
+	got, err := f.Statements("postgres")
+	if err != nil {
+		t.Fatalf("Statements() error = %v", err)
+	}
+	want := []string{
+		`GRANT USAGE ON SCHEMA "public" TO "app_reader", "analyst"`,
+		`GRANT SELECT ON ALL TABLES IN SCHEMA "public" TO "app_reader"`,
+		`REVOKE ALL ON TABLE "sales"."orders", "sales"."Line Items" FROM "app_writer"`,
+		`GRANT SELECT, INSERT ON TABLE "sales"."orders", "sales"."Line Items" TO "app_writer"`,
+		`GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA "sales" TO "app_writer"`,
+		`GRANT EXECUTE ON FUNCTION "sales"."refresh" TO "app_writer"`,
+		`GRANT EXECUTE ON FUNCTION "sales"."total"(int, numeric(10, 2)), "sales"."Total"() TO "app_writer"`,
+		`REVOKE CREATE ON SCHEMA "public" FROM PUBLIC`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Statements() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := f.Statements("sqlite"); err == nil || !strings.Contains(err.Error(), "require the postgres driver") {
+		t.Errorf("Statements(sqlite) error = %v, want the driver rejected", err)
+	}
+}
+
+func TestScript(t *testing.T) {
+	f := &File{Grants: []Grant{{Roles: []string{"app"}, Schema: "public", On: OnSchema, Privileges: []string{"USAGE"}}}}
+	got, err := f.Script("postgres")
+	if err != nil {
+		t.Fatalf("Script() error = %v", err)
+	}
+	if want := "GRANT USAGE ON SCHEMA \"public\" TO \"app\";\n"; got != want {
+		t.Errorf("Script() = %q, want %q", got, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grants.yaml")
+	if err := os.WriteFile(path, []byte("grants:\n  - roles: [app]\n    schema: public\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, nil); err == nil || !strings.Contains(err.Error(), path+": invalid grant 1") {
+		t.Errorf("Load() error = %v, want the path and the grant", err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml"), nil); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+	denied := errors.New("outside the allowed paths")
+	if _, err := Load(path, func(string) error { return denied }); !errors.Is(err, denied) {
+		t.Errorf("Load() error = %v, want the check's error", err)
+	}
+}
@@ -0,0 +1,60 @@
+// Package health serves liveness and readiness endpoints for long-running
+// modes, in the form Kubernetes probes expect.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// checkTimeout bounds each readiness check, so a hung database makes the
+// probe fail instead of time out.
+const checkTimeout = 2 * time.Second
+
+// Check reports whether a dependency is ready; nil means ready.
+type Check func(ctx context.Context) error
+
+// Handler serves /healthz, which succeeds whenever the process can answer,
+// and /readyz, which runs every check and fails with 503 Service
+// Unavailable if any does. Both respond with one "name: status" line per
+// check. Callers may register further endpoints on the returned mux.
+func Handler(checks map[string]Check) *http.ServeMux {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		var (
+			b     strings.Builder
+			ready = true
+		)
+		for _, name := range names {
+			ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+			err := checks[name](ctx)
+			cancel()
+			if err != nil {
+				ready = false
+				fmt.Fprintf(&b, "%s: %v\n", name, err)
+			} else {
+				fmt.Fprintf(&b, "%s: ok\n", name)
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		fmt.Fprint(w, b.String())
+	})
+	return mux
+}
@@ -0,0 +1,38 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name     string
+		path     string
+		checks   map[string]Check
+		wantCode int
+		wantBody string
+	}{
+		{name: "alive", path: "/healthz", checks: map[string]Check{"database": down}, wantCode: http.StatusOK, wantBody: "ok\n"},
+		{name: "ready", path: "/readyz", checks: map[string]Check{"listener": ok, "database": ok}, wantCode: http.StatusOK,
+			wantBody: "database: ok\nlistener: ok\n"},
+		{name: "not ready", path: "/readyz", checks: map[string]Check{"listener": ok, "database": down}, wantCode: http.StatusServiceUnavailable,
+			wantBody: "database: connection refused\nlistener: ok\n"},
+		{name: "unknown path", path: "/metrics", wantCode: http.StatusNotFound, wantBody: "404 page not found\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Handler(tt.checks).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantCode || rec.Body.String() != tt.wantBody {
+				t.Errorf("GET %s = %d %q, want %d %q", tt.path, rec.Code, rec.Body.String(), tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
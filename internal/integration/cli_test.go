@@ -0,0 +1,432 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// scalar returns the single value query yields, as text.
+func scalar(t *testing.T, dsn, query string) string {
+	t.Helper()
+	db, err := database.Connect(context.Background(), "postgres", dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	var value sql.NullString
+	if err := db.QueryRow(query).Scan(&value); err != nil {
+		t.Fatalf("Failed to query %q: %v", query, err)
+	}
+	return value.String
+}
+
+func TestPostgres(t *testing.T) {
+	dsn := startPostgres(t)
+
+	t.Run("script directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"sql/001_schema.sql": "CREATE TABLE people (id int PRIMARY KEY, name text NOT NULL);",
+			"sql/002_seed.sql":   "INSERT INTO people VALUES (1, 'Ada');\nINSERT INTO people VALUES (2, 'Grace');",
+		})
+		out := mustRun(t, dir, "", "-dsn", dsn, "-dir", "sql", "-transaction", "all", "-quiet")
+		if !strings.Contains(out, "2 scripts executed successfully") {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+		if got := scalar(t, dsn, "SELECT count(*) FROM people"); got != "2" {
+			t.Errorf("people has %s rows, want 2", got)
+		}
+	})
+
+	t.Run("repeated and globbed files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"schema.sql":      "CREATE TABLE pets (id int PRIMARY KEY, name text NOT NULL);",
+			"seed/01_cat.sql": "INSERT INTO pets VALUES (1, 'Tom');",
+			"seed/02_dog.sql": "INSERT INTO pets VALUES (2, 'Rex');",
+		})
+		out := mustRun(t, dir, "", "-dsn", dsn, "-file", "schema.sql", "-file", "seed/*.sql", "-file", "seed/01_cat.sql", "-quiet")
+		if !strings.Contains(out, "3 scripts executed successfully") {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+		if got := scalar(t, dsn, "SELECT string_agg(name, ',' ORDER BY id) FROM pets"); got != "Tom,Rex" {
+			t.Errorf("pets = %q, want Tom,Rex", got)
+		}
+		if r := run(t, dir, "", "-dsn", dsn, "-file", "missing/*.sql"); r.err == nil || !strings.Contains(r.stderr, "no matching files") {
+			t.Errorf("a glob matching nothing did not fail:\n%s", r.stderr)
+		}
+	})
+
+	t.Run("parallel files", func(t *testing.T) {
+		dir := t.TempDir()
+		// The data files fail unless the schema file, which commits only
+		// after its sleep, has finished before they start.
+		files := map[string]string{
+			"seeds/0_schema.sql": "SELECT pg_sleep(0.3);",
+			"seeds/z_index.sql":  "CREATE INDEX parallel_a_id ON parallel_a (id);",
+		}
+		for _, name := range []string{"a", "b", "c", "d"} {
+			files["seeds/0_schema.sql"] = "CREATE TABLE parallel_" + name + " (id int);\n" + files["seeds/0_schema.sql"]
+			files["seeds/"+name+".sql"] = "SELECT pg_sleep(0.2);\nINSERT INTO parallel_" + name + " SELECT generate_series(1, 10);"
+		}
+		writeFiles(t, dir, files)
+		out := mustRun(t, dir, "", "-dsn", dsn, "-dir", "seeds", "-parallel", "4", "-max-connections", "3", "-transaction", "file")
+		if !strings.Contains(out, "6 scripts executed successfully") {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+		var order []string
+		for _, line := range strings.Split(out, "\n") {
+			if strings.HasPrefix(line, "Loading SQL script from ") {
+				order = append(order, strings.Fields(line)[4])
+			}
+		}
+		if got := strings.Join(order, ","); got != "seeds/0_schema.sql,seeds/a.sql,seeds/b.sql,seeds/c.sql,seeds/d.sql,seeds/z_index.sql" {
+			t.Errorf("scripts printed in order %s", got)
+		}
+		if got := scalar(t, dsn, "SELECT (SELECT count(*) FROM parallel_a) + (SELECT count(*) FROM parallel_d)"); got != "20" {
+			t.Errorf("loaded %s rows, want 20", got)
+		}
+	})
+
+	t.Run("failed transaction rolls back", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"dup.sql": "INSERT INTO people VALUES (3, 'Edsger');\nINSERT INTO people VALUES (1, 'Ada');",
+		})
+		r := run(t, dir, "", "-dsn", dsn, "-file", "dup.sql", "-transaction", "all", "-log-format", "json", "-deterministic")
+		if r.err == nil {
+			t.Fatal("sql-loader succeeded despite a duplicate key")
+		}
+		if !strings.Contains(r.stdout, `"category":"constraint_violation","sqlstate":"23505"`) {
+			t.Errorf("failure event lacks the error category:\n%s", r.stdout)
+		}
+		if got := scalar(t, dsn, "SELECT count(*) FROM people WHERE id = 3"); got != "0" {
+			t.Errorf("the failed transaction left %s rows behind", got)
+		}
+	})
+
+	t.Run("transaction scopes", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"sql/001_ok.sql":   "INSERT INTO people VALUES (4, 'Barbara');",
+			"sql/002_dup.sql":  "INSERT INTO people VALUES (1, 'Ada');",
+			"sql/003_more.sql": "INSERT INTO people VALUES (5, 'Frances');",
+		})
+		if r := run(t, dir, "", "-dsn", dsn, "-dir", "sql", "-transaction", "all", "-quiet"); r.err == nil {
+			t.Fatal("sql-loader succeeded despite a duplicate key")
+		}
+		if got := scalar(t, dsn, "SELECT count(*) FROM people WHERE id IN (4, 5)"); got != "0" {
+			t.Errorf("-transaction all left %s rows behind", got)
+		}
+		r := run(t, dir, "", "-dsn", dsn, "-dir", "sql", "-transaction", "file", "-on-error", "continue", "-quiet")
+		if r.err == nil || !strings.Contains(r.stderr, "1 of 3 scripts failed: sql/002_dup.sql") {
+			t.Errorf("unexpected result: %v\n%s", r.err, r.stderr)
+		}
+		if got := scalar(t, dsn, "SELECT count(*) FROM people WHERE id IN (4, 5)"); got != "2" {
+			t.Errorf("-transaction file applied %s of the 2 good files", got)
+		}
+	})
+
+	t.Run("isolation and read-only transactions", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"level.sql": "CREATE TABLE isolation_seen AS SELECT current_setting('transaction_isolation') AS level;",
+			"write.sql": "INSERT INTO people VALUES (6, 'Grace');",
+		})
+		mustRun(t, dir, "", "-dsn", dsn, "-file", "level.sql", "-transaction", "file", "-isolation", "serializable", "-quiet")
+		if got := scalar(t, dsn, "SELECT level FROM isolation_seen"); got != "serializable" {
+			t.Errorf("script ran at isolation level %q, want serializable", got)
+		}
+		r := run(t, dir, "", "-dsn", dsn, "-file", "write.sql", "-transaction", "file", "-tx-read-only", "-quiet")
+		if r.err == nil || !strings.Contains(r.stderr, "read-only transaction") {
+			t.Errorf("write in a -tx-read-only transaction: %v\n%s", r.err, r.stderr)
+		}
+	})
+
+	t.Run("post-verify", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"load.sql":   "CREATE TABLE verified (id int PRIMARY KEY, person int); INSERT INTO verified VALUES (1, 99);",
+			"checks.sql": "SELECT v.id FROM verified v LEFT JOIN people p ON p.id = v.person WHERE p.id IS NULL;",
+		})
+		r := run(t, dir, "", "-dsn", dsn, "-file", "load.sql", "-transaction", "all", "-post-verify", "checks.sql", "-quiet")
+		if r.err == nil || !strings.Contains(r.stderr, "check 1 of 1 failed: returned 1 row(s), first (id=1)") {
+			t.Errorf("failed post-verify: %v\n%s", r.err, r.stderr)
+		}
+		if got := scalar(t, dsn, "SELECT to_regclass('verified') IS NULL"); got != "true" {
+			t.Error("a failed post-verify left the run's table behind")
+		}
+		writeFiles(t, dir, map[string]string{"migrations/001_verified.sql": "CREATE TABLE verified (id int PRIMARY KEY, person int);"})
+		out := mustRun(t, dir, "", "migrate", "-dsn", dsn, "-dir", "migrations", "-table", "verified_migrations", "-post-verify", "checks.sql")
+		if !strings.Contains(out, "Post-verify passed: 1 check(s) from checks.sql") {
+			t.Errorf("migrate -post-verify output:\n%s", out)
+		}
+	})
+
+	t.Run("github report format", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"broken.sql": "CREATE TABLE annotated (id int);\n\nINSERT INTO annotated VALUES ('x');\n"})
+		summary := filepath.Join(dir, "summary.md")
+		t.Setenv("GITHUB_STEP_SUMMARY", summary)
+		r := run(t, dir, "", "-dsn", dsn, "-file", "broken.sql", "-transaction", "all", "-report-format", "github", "-quiet")
+		if r.err == nil || !strings.Contains(r.stderr, "::error file=broken.sql,line=3::failed to execute script broken.sql") {
+			t.Errorf("-report-format github: %v\n%s", r.err, r.stderr)
+		}
+		data, err := os.ReadFile(summary)
+		if err != nil || !strings.Contains(string(data), "### sql-loader run failed") {
+			t.Errorf("job summary = %q, %v", data, err)
+		}
+	})
+
+	t.Run("pg_dump COPY blocks", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"dump.sql": "CREATE TABLE dumped (id int, note text);\n" +
+				"COPY public.dumped (id, note) FROM stdin;\n1\tsemi;colon\n2\t\\N\n\\.\n" +
+				"UPDATE dumped SET note = 'none' WHERE note IS NULL;\n",
+		})
+		for _, mode := range []string{"none", "all"} {
+			mustRun(t, dir, "", "-dsn", dsn, "-file", "dump.sql", "-transaction", mode, "-quiet")
+			if got := scalar(t, dsn, "SELECT string_agg(note, ',' ORDER BY id) FROM dumped"); got != "semi;colon,none" {
+				t.Errorf("-transaction %s: notes = %q, want the copied rows", mode, got)
+			}
+			mustRun(t, dir, "DROP TABLE dumped;", "-dsn", dsn, "-file", "-", "-quiet")
+		}
+	})
+
+	t.Run("grants after a load", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"schema.sql": "CREATE ROLE grants_reader;\nCREATE TABLE granted (id int);\nGRANT DELETE ON granted TO grants_reader;",
+			"grants.yaml": "grants:\n" +
+				"  - roles: [grants_reader]\n    schema: public\n    on: schema\n    privileges: [USAGE]\n" +
+				"  - roles: [\"${GRANTS_ROLE}\"]\n    schema: public\n    objects: [granted]\n    privileges: [SELECT]\n    exact: true\n",
+		})
+		t.Setenv("GRANTS_ROLE", "grants_reader")
+		out := mustRun(t, dir, "", "-dsn", dsn, "-file", "schema.sql", "-grants", "grants.yaml")
+		if !strings.Contains(out, "Applied 3 grant statement(s) from grants.yaml") {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+		check := "SELECT has_table_privilege('grants_reader', 'granted', 'SELECT')::text || ',' || has_table_privilege('grants_reader', 'granted', 'DELETE')::text"
+		if got := scalar(t, dsn, check); got != "true,false" {
+			t.Errorf("SELECT,DELETE privileges = %s, want only SELECT", got)
+		}
+		if out := mustRun(t, dir, "", "grants", "-dsn", dsn, "-file", "grants.yaml"); !strings.Contains(out, "Applied 3 grant statement(s)") {
+			t.Errorf("reapplying the grants printed:\n%s", out)
+		}
+	})
+
+	t.Run("set-owner after a load", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"role.sql":   "CREATE ROLE owned_app;",
+			"schema.sql": "CREATE SCHEMA owned;\nCREATE TABLE owned.things (id serial PRIMARY KEY);\nCREATE VIEW owned.thing_ids AS SELECT id FROM owned.things;",
+		})
+		mustRun(t, dir, "", "-dsn", dsn, "-file", "role.sql")
+		out := mustRun(t, dir, "", "-dsn", dsn, "-file", "schema.sql", "-set-owner", "owned_app")
+		if !strings.Contains(out, "Set the owner of 3 created object(s) to owned_app") {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+		check := "SELECT string_agg(relname || '=' || pg_get_userbyid(relowner), ',' ORDER BY relname) FROM pg_class WHERE relnamespace = 'owned'::regnamespace AND relkind IN ('r', 'v', 'S')"
+		if got := scalar(t, dsn, check); got != "thing_ids=owned_app,things=owned_app,things_id_seq=owned_app" {
+			t.Errorf("owners = %s, want owned_app throughout", got)
+		}
+	})
+
+	t.Run("load-csv", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"people.csv": "id,name\n10,Barbara\n11,\"Liskov, B.\"\n"})
+		out := mustRun(t, dir, "", "load-csv", "-dsn", dsn, "-table", "people", "-csv", "people.csv")
+		if !strings.Contains(out, "Imported 2 rows into people") {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+		if got := scalar(t, dsn, "SELECT name FROM people WHERE id = 11"); got != "Liskov, B." {
+			t.Errorf("name = %q, want the quoted field", got)
+		}
+	})
+
+	t.Run("load-data from stdin", func(t *testing.T) {
+		dir := t.TempDir()
+		input := `{"id": 20, "name": "Frances"}` + "\n" + `{"id": 21, "name": "Margaret"}` + "\n"
+		mustRun(t, dir, input, "load-data", "-dsn", dsn, "-table", "people")
+		if got := scalar(t, dsn, "SELECT count(*) FROM people WHERE id >= 20"); got != "2" {
+			t.Errorf("loaded %s rows, want 2", got)
+		}
+	})
+
+	t.Run("load-data with a field mapping", func(t *testing.T) {
+		dir := t.TempDir()
+		input := `{"user": {"id": 30, "profile": {"name": "Radia"}}, "ignored": true}` + "\n" +
+			`{"user": {"id": 31, "profile": {"name": "Adele"}}}` + "\n"
+		mustRun(t, dir, input, "load-data", "-dsn", dsn, "-table", "people", "-map", "id=.user.id,name=.user.profile.name")
+		if got := scalar(t, dsn, "SELECT string_agg(name, ',' ORDER BY id) FROM people WHERE id IN (30, 31)"); got != "Radia,Adele" {
+			t.Errorf("names = %q, want the mapped fields", got)
+		}
+	})
+
+	t.Run("incremental load-data", func(t *testing.T) {
+		dir := t.TempDir()
+		mustRun(t, dir, "CREATE TABLE feed_events (id int, at timestamptz);", "-dsn", dsn)
+		args := []string{"load-data", "-dsn", dsn, "-table", "feed_events", "-incremental", "at"}
+		first := `{"id": 1, "at": "2024-01-01T00:00:00Z"}` + "\n" + `{"id": 2, "at": "2024-01-02T00:00:00Z"}` + "\n"
+		mustRun(t, dir, first, args...)
+		out := mustRun(t, dir, first+`{"id": 3, "at": "2024-01-02T01:00:00Z"}`+"\n", args...)
+		if !strings.Contains(out, "Loaded 1 rows into feed_events") || !strings.Contains(out, "Watermark of feed feed_events is now 2024-01-02T01:00:00Z") {
+			t.Errorf("second run output:\n%s", out)
+		}
+		if got := scalar(t, dsn, "SELECT string_agg(id::text, ',' ORDER BY id) FROM feed_events"); got != "1,2,3" {
+			t.Errorf("ids = %s, want each record loaded once", got)
+		}
+
+		// -stats describes only the records beyond the watermark.
+		out = mustRun(t, dir, `{"id": 3, "at": "2024-01-02T01:00:00Z"}`+"\n"+`{"id": 4, "at": "2024-01-03T00:00:00Z"}`+"\n", append(args, "-stats")...)
+		if !strings.Contains(out, "Loaded 1 rows into feed_events") || !regexp.MustCompile(`(?m)^id\s+0\s+1\s+4\s+4\s*$`).MatchString(out) {
+			t.Errorf("-stats output:\n%s", out)
+		}
+
+		// Concurrent runs of one feed take turns on its watermark.
+		batch := `{"id": 5, "at": "2024-01-04T00:00:00Z"}` + "\n" + `{"id": 6, "at": "2024-01-05T00:00:00Z"}` + "\n"
+		var wg sync.WaitGroup
+		results := make([]result, 4)
+		for i := range results {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = run(t, dir, batch, args...)
+			}()
+		}
+		wg.Wait()
+		for _, r := range results {
+			if r.err != nil {
+				t.Errorf("concurrent run failed: %v\n%s", r.err, r.stderr)
+			}
+		}
+		if got := scalar(t, dsn, "SELECT string_agg(id::text, ',' ORDER BY id) FROM feed_events"); got != "1,2,3,4,5,6" {
+			t.Errorf("ids after concurrent runs = %s, want each record loaded once", got)
+		}
+	})
+
+	t.Run("migrate twice", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"migrations/001_orders.sql": "CREATE TABLE orders (id int PRIMARY KEY);",
+			"migrations/002_person.sql": "ALTER TABLE orders ADD COLUMN person int REFERENCES people (id);",
+		})
+		out := mustRun(t, dir, "", "migrate", "plan", "-dsn", dsn, "-dir", "migrations")
+		if !strings.Contains(out, "2      002_person.sql") || !strings.Contains(out, "2 migration(s) to apply, 0 already applied") {
+			t.Errorf("plan output:\n%s", out)
+		}
+		if out := mustRun(t, dir, "", "migrate", "-dsn", dsn, "-dir", "migrations"); !strings.Contains(out, "2 migration(s) applied, 0 already applied") {
+			t.Errorf("first run output:\n%s", out)
+		}
+		if out := mustRun(t, dir, "", "migrate", "-dsn", dsn, "-dir", "migrations"); !strings.Contains(out, "0 migration(s) applied, 2 already applied") {
+			t.Errorf("second run output:\n%s", out)
+		}
+	})
+
+	t.Run("migrate COPY blocks and notices", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"migrations/001_codes.sql": "CREATE TABLE codes (id int PRIMARY KEY, code text);\n" +
+				"COPY codes (id, code) FROM stdin;\n1\tA\n2\tB\n\\.\n" +
+				"DO $$ BEGIN RAISE WARNING 'codes seeded'; END $$;\n",
+		})
+		r := run(t, dir, "", "migrate", "-dsn", dsn, "-dir", "migrations", "-table", "code_migrations")
+		if r.err != nil {
+			t.Fatalf("migrate: %v\nstdout:\n%s\nstderr:\n%s", r.err, r.stdout, r.stderr)
+		}
+		if got := scalar(t, dsn, "SELECT string_agg(code, ',' ORDER BY id) FROM codes"); got != "A,B" {
+			t.Errorf("codes = %q, want the copied rows", got)
+		}
+		if !strings.Contains(r.stderr, "001_codes.sql statement 3: WARNING: codes seeded") {
+			t.Errorf("stderr does not report the warning:\n%s", r.stderr)
+		}
+	})
+
+	t.Run("migrate -notices all", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"migrations/001_backfill.sql": "DO $$ BEGIN RAISE NOTICE 'backfilled 10 rows'; END $$;",
+		})
+		r := run(t, dir, "", "migrate", "-dsn", dsn, "-dir", "migrations", "-table", "backfill_migrations", "-notices", "all")
+		if r.err != nil {
+			t.Fatalf("migrate: %v\nstderr:\n%s", r.err, r.stderr)
+		}
+		if !strings.Contains(r.stderr, "001_backfill.sql statement 1: NOTICE: backfilled 10 rows") {
+			t.Errorf("stderr does not stream the notice:\n%s", r.stderr)
+		}
+	})
+
+	t.Run("migrate down", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"migrations/001_tags.up.sql":     "CREATE TABLE tags (id int PRIMARY KEY);",
+			"migrations/001_tags.down.sql":   "DROP TABLE tags;",
+			"migrations/002_labels.up.sql":   "ALTER TABLE tags ADD COLUMN label text;",
+			"migrations/002_labels.down.sql": "ALTER TABLE tags DROP COLUMN label;",
+		})
+		args := []string{"-dsn", dsn, "-dir", "migrations", "-table", "tag_migrations"}
+		mustRun(t, dir, "", append([]string{"migrate"}, args...)...)
+		if out := mustRun(t, dir, "", append([]string{"migrate", "down"}, args...)...); !strings.Contains(out, "Reverting 002_labels.up.sql\n1 migration(s) reverted") {
+			t.Errorf("down output:\n%s", out)
+		}
+		if got := scalar(t, dsn, "SELECT count(*) FROM information_schema.columns WHERE table_name = 'tags'"); got != "1" {
+			t.Errorf("tags has %s columns, want the label column dropped", got)
+		}
+		mustRun(t, dir, "", append([]string{"migrate", "down", "-steps", "1"}, args...)...)
+		if got := scalar(t, dsn, "SELECT count(*) FROM tag_migrations"); got != "0" {
+			t.Errorf("tag_migrations has %s rows, want none", got)
+		}
+	})
+
+	t.Run("call procedures and functions", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"routines.sql": "CREATE PROCEDURE grow(IN by int, INOUT total int, OUT note text) LANGUAGE plpgsql AS $$ " +
+				"BEGIN total := total + by; note := 'grown'; END $$;\n" +
+				"CREATE FUNCTION people_named(prefix text, OUT id int, OUT name text) RETURNS SETOF record " +
+				"LANGUAGE sql AS $$ SELECT id, name FROM people WHERE name LIKE prefix || '%' ORDER BY id $$;\n",
+			"args.json": `[2, 40]`,
+		})
+		mustRun(t, dir, "", "-dsn", dsn, "-file", "routines.sql", "-quiet")
+		out := mustRun(t, dir, "", "call", "grow", "-dsn", dsn, "-args-file", "args.json", "-report", "report.json")
+		if !strings.Contains(out, "total = 42\nnote = grown") {
+			t.Errorf("unexpected procedure output:\n%s", out)
+		}
+		if b, err := os.ReadFile(filepath.Join(dir, "report.json")); err != nil || !strings.Contains(string(b), `"total": 42`) {
+			t.Errorf("report lacks the OUT parameters: %v\n%s", err, b)
+		}
+		out = mustRun(t, dir, "", "call", "people_named", "-dsn", dsn, "-arg", "prefix=Ada")
+		if !strings.Contains(out, "id = 1\nname = Ada") {
+			t.Errorf("unexpected function output:\n%s", out)
+		}
+		if r := run(t, dir, "", "call", "grow", "-dsn", dsn, "-arg", "by=1"); r.err == nil || !strings.Contains(r.stderr, "missing argument total") {
+			t.Errorf("a missing argument did not fail:\n%s", r.stderr)
+		}
+	})
+
+	t.Run("wait with connection parts", func(t *testing.T) {
+		dir := t.TempDir()
+		u, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatalf("Failed to parse DSN: %v", err)
+		}
+		t.Setenv("INTEGRATION_PASSWORD", "loader")
+		out := mustRun(t, dir, "", "wait", "-host", u.Hostname(), "-port", u.Port(), "-user", "loader",
+			"-password-env", "INTEGRATION_PASSWORD", "-dbname", "app", "-sslmode", "disable", "-timeout", "10s")
+		if !strings.Contains(out, "Database ready") {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+	})
+}
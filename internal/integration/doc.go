@@ -0,0 +1,9 @@
+// Package integration runs the sql-loader binary end to end against real
+// databases started in Docker containers. Its tests are behind the
+// integration build tag, so that go test ./... never needs Docker:
+//
+//	go test -tags integration ./internal/integration/
+//
+// The tests skip when the docker command is not available. Set
+// SQL_LOADER_POSTGRES_IMAGE to test another PostgreSQL image.
+package integration
@@ -0,0 +1,132 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+const defaultPostgresImage = "postgres:16-alpine"
+
+// binary is the sql-loader binary built by TestMain.
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "sql-loader-integration")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create build directory: %v\n", err)
+		os.Exit(1)
+	}
+	binary = filepath.Join(dir, "sql-loader")
+	build := exec.Command("go", "build", "-o", binary, "../../cmd/sql-loader")
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to build sql-loader: %v\n", err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// startPostgres starts a PostgreSQL container for the test and returns
+// its DSN once it accepts queries. The container is removed on cleanup.
+func startPostgres(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not available")
+	}
+	image := os.Getenv("SQL_LOADER_POSTGRES_IMAGE")
+	if image == "" {
+		image = defaultPostgresImage
+	}
+
+	id := docker(t, "run", "--detach", "--rm",
+		"--env", "POSTGRES_USER=loader", "--env", "POSTGRES_PASSWORD=loader", "--env", "POSTGRES_DB=app",
+		"--publish", "127.0.0.1::5432", image)
+	t.Cleanup(func() {
+		if out, err := exec.Command("docker", "rm", "--force", id).CombinedOutput(); err != nil {
+			t.Logf("Failed to remove container %s: %v: %s", id, err, out)
+		}
+	})
+
+	// docker port prints one line per address, e.g. 127.0.0.1:49153.
+	addr, _, _ := strings.Cut(docker(t, "port", id, "5432/tcp"), "\n")
+	dsn := fmt.Sprintf("postgres://loader:loader@%s/app?sslmode=disable", addr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	if err := database.WaitReady(ctx, "postgres", dsn, 500*time.Millisecond, nil); err != nil {
+		t.Fatalf("PostgreSQL did not start: %v", err)
+	}
+	return dsn
+}
+
+func docker(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("docker", args...).Output()
+	if err != nil {
+		t.Fatalf("docker %s failed: %v", args[0], err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// result is the outcome of one sql-loader invocation.
+type result struct {
+	stdout, stderr string
+	err            error
+}
+
+// run executes sql-loader in dir with args, isolated from the user's
+// config and run history.
+func run(t *testing.T, dir, stdin string, args ...string) result {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"XDG_CONFIG_HOME="+filepath.Join(dir, ".config"),
+		"XDG_STATE_HOME="+filepath.Join(dir, ".state"),
+		"DATABASE_URL=",
+	)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	return result{stdout: stdout.String(), stderr: stderr.String(), err: err}
+}
+
+// mustRun is run failing the test if sql-loader exits non-zero.
+func mustRun(t *testing.T, dir, stdin string, args ...string) string {
+	t.Helper()
+	r := run(t, dir, stdin, args...)
+	if r.err != nil {
+		t.Fatalf("sql-loader %s: %v\nstdout:\n%s\nstderr:\n%s", strings.Join(args, " "), r.err, r.stdout, r.stderr)
+	}
+	return r.stdout
+}
+
+// writeFiles creates files under dir, keyed by relative path.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
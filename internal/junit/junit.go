@@ -0,0 +1,98 @@
+// Package junit provides functionality for writing check results as JUnit
+// XML, the test report format CI systems render as a pass or fail per
+// check.
+package junit
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Suite is a named group of cases, such as the checks of one script.
+type Suite struct {
+	Name  string
+	Cases []Case
+}
+
+// Case is one check. It passed unless Failure or Skipped is set.
+type Case struct {
+	Name string
+	// Classname groups the case, typically by the script it checks.
+	Classname string
+	// Failure is why the case failed.
+	Failure string
+	// Skipped is why the case did not run.
+	Skipped string
+}
+
+type xmlSuites struct {
+	XMLName  xml.Name   `xml:"testsuites"`
+	Name     string     `xml:"name,attr"`
+	Tests    int        `xml:"tests,attr"`
+	Failures int        `xml:"failures,attr"`
+	Skipped  int        `xml:"skipped,attr"`
+	Suites   []xmlSuite `xml:"testsuite"`
+}
+
+type xmlSuite struct {
+	Name     string    `xml:"name,attr"`
+	Tests    int       `xml:"tests,attr"`
+	Failures int       `xml:"failures,attr"`
+	Skipped  int       `xml:"skipped,attr"`
+	Cases    []xmlCase `xml:"testcase"`
+}
+
+type xmlCase struct {
+	Name      string      `xml:"name,attr"`
+	Classname string      `xml:"classname,attr"`
+	Failure   *xmlMessage `xml:"failure"`
+	Skipped   *xmlMessage `xml:"skipped"`
+}
+
+type xmlMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Write writes suites as a JUnit XML document named name.
+func Write(w io.Writer, name string, suites []Suite) error {
+	doc := xmlSuites{Name: name, Suites: []xmlSuite{}}
+	for _, s := range suites {
+		suite := xmlSuite{Name: s.Name, Tests: len(s.Cases)}
+		for _, c := range s.Cases {
+			tc := xmlCase{Name: c.Name, Classname: c.Classname}
+			switch {
+			case c.Failure != "":
+				tc.Failure = message(c.Failure)
+				suite.Failures++
+			case c.Skipped != "":
+				tc.Skipped = &xmlMessage{Message: c.Skipped}
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Skipped += suite.Skipped
+		doc.Suites = append(doc.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// message puts the first line of text in the message attribute, which CI
+// systems show as the headline, and all of it in the body.
+func message(text string) *xmlMessage {
+	line, _, _ := strings.Cut(text, "\n")
+	return &xmlMessage{Message: line, Text: text}
+}
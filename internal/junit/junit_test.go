@@ -0,0 +1,57 @@
+package junit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	suites := []Suite{
+		{Name: "lint", Cases: []Case{
+			{Name: "seed.sql", Classname: "lint"},
+			{Name: "users.sql", Classname: "lint", Failure: "statement 2: duplicate of statement 1 (duplicate-statement)\nstatement 4: duplicate of statement 3 (duplicate-statement)"},
+		}},
+		{Name: "post-verify checks.sql", Cases: []Case{
+			{Name: "check 1 (line 1)", Classname: "checks.sql", Failure: `check 1 of 2 failed: returned 1 row(s), first (id=7, note=<a & b>)`},
+			{Name: "check 2 (line 3)", Classname: "checks.sql", Skipped: "not run: an earlier check failed"},
+		}},
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, "sql-loader", suites); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="sql-loader" tests="4" failures="2" skipped="1">
+  <testsuite name="lint" tests="2" failures="1" skipped="0">
+    <testcase name="seed.sql" classname="lint"></testcase>
+    <testcase name="users.sql" classname="lint">
+      <failure message="statement 2: duplicate of statement 1 (duplicate-statement)">statement 2: duplicate of statement 1 (duplicate-statement)&#xA;statement 4: duplicate of statement 3 (duplicate-statement)</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="post-verify checks.sql" tests="2" failures="1" skipped="1">
+    <testcase name="check 1 (line 1)" classname="checks.sql">
+      <failure message="check 1 of 2 failed: returned 1 row(s), first (id=7, note=&lt;a &amp; b&gt;)">check 1 of 2 failed: returned 1 row(s), first (id=7, note=&lt;a &amp; b&gt;)</failure>
+    </testcase>
+    <testcase name="check 2 (line 3)" classname="checks.sql">
+      <skipped message="not run: an earlier check failed"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if out.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", out.String(), want)
+	}
+	if err := xml.Unmarshal(out.Bytes(), new(xmlSuites)); err != nil {
+		t.Errorf("Write() wrote invalid XML: %v", err)
+	}
+
+	out.Reset()
+	if err := Write(&out, "sql-loader", nil); err != nil {
+		t.Fatalf("Write(nil) error = %v", err)
+	}
+	if want := xml.Header + `<testsuites name="sql-loader" tests="0" failures="0" skipped="0"></testsuites>` + "\n"; out.String() != want {
+		t.Errorf("Write(nil) = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,117 @@
+// Package lint checks SQL scripts for likely mistakes before they run.
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// RuleDuplicateStatement flags a statement that repeats an earlier one.
+const RuleDuplicateStatement = "duplicate-statement"
+
+// Finding is a single lint result.
+type Finding struct {
+	Rule string
+	// Statement is the 1-based index of the offending statement.
+	Statement int
+	// Line is the 1-based line of the script the statement starts on, or
+	// zero when it is not known.
+	Line    int
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("statement %d: %s (%s)", f.Statement, f.Message, f.Rule)
+}
+
+// Script runs every rule over the statements of script.
+func Script(script string) []Finding {
+	statements := database.SplitStatements(script)
+	findings := Duplicates(statements)
+	lines := database.StatementLines(script, statements)
+	for i := range findings {
+		findings[i].Line = lines[findings[i].Statement-1]
+	}
+	return findings
+}
+
+// Duplicates reports statements whose normalized text matches an earlier
+// statement, a common result of concatenating files carelessly.
+func Duplicates(statements []string) []Finding {
+	var findings []Finding
+	seen := make(map[string]int, len(statements))
+	for i, stmt := range statements {
+		key := Normalize(stmt)
+		if key == "" {
+			continue
+		}
+		if first, ok := seen[key]; ok {
+			findings = append(findings, Finding{
+				Rule:      RuleDuplicateStatement,
+				Statement: i + 1,
+				Message:   fmt.Sprintf("duplicate of statement %d", first),
+			})
+			continue
+		}
+		seen[key] = i + 1
+	}
+	return findings
+}
+
+// Normalize returns stmt with comments removed, whitespace collapsed, and
+// text outside quotes lower-cased, so that statements differing only in
+// formatting compare equal. Quoted literals and identifiers are kept as-is.
+func Normalize(stmt string) string {
+	var b strings.Builder
+	space := false
+	write := func(s string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(s)
+	}
+
+	for i := 0; i < len(stmt); i++ {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := i + 1
+			for end < len(stmt) {
+				if stmt[end] == c {
+					if end+1 < len(stmt) && stmt[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			write(stmt[i:min(end+1, len(stmt))])
+			i = end
+		case strings.HasPrefix(stmt[i:], "--"):
+			for i < len(stmt) && stmt[i] != '\n' {
+				i++
+			}
+			space = true
+		case strings.HasPrefix(stmt[i:], "/*"):
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				i = len(stmt)
+			} else {
+				i += end + 3
+			}
+			space = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+		default:
+			if c >= 'A' && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			write(string([]byte{c}))
+		}
+	}
+	return b.String()
+}
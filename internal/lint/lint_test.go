@@ -0,0 +1,46 @@
+package lint
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"INSERT INTO t VALUES (1)", "insert  into t\n\tvalues (1)", true},
+		{"INSERT INTO t VALUES (1) -- seed", "/* seed */ INSERT INTO t VALUES (1)", true},
+		{"INSERT INTO t VALUES ('A')", "INSERT INTO t VALUES ('a')", false},
+		{`SELECT "Name" FROM t`, `SELECT "name" FROM t`, false},
+		{"SELECT 'it''s  here'", "select 'it''s  here'", true},
+		{"SELECT 'a  b'", "SELECT 'a b'", false},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.a) == Normalize(tt.b); got != tt.same {
+			t.Errorf("Normalize(%q) == Normalize(%q) is %v, want %v (%q vs %q)",
+				tt.a, tt.b, got, tt.same, Normalize(tt.a), Normalize(tt.b))
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	script := `
+CREATE TABLE users (id INTEGER, name TEXT);
+INSERT INTO users VALUES (1, 'alice');
+INSERT INTO users VALUES (2, 'bob');
+-- concatenated again
+insert into users values (1, 'alice');
+INSERT INTO users VALUES (2, 'Bob');
+`
+	findings := Script(script)
+	if len(findings) != 1 {
+		t.Fatalf("Script() = %v, want one finding", findings)
+	}
+	f := findings[0]
+	if f.Rule != RuleDuplicateStatement || f.Statement != 4 || f.Line != 6 || f.Message != "duplicate of statement 2" {
+		t.Errorf("Script() finding = %+v", f)
+	}
+	if got := f.String(); got != "statement 4: duplicate of statement 2 (duplicate-statement)" {
+		t.Errorf("String() = %q", got)
+	}
+}
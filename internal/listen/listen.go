@@ -0,0 +1,60 @@
+// Package listen provides a PostgreSQL LISTEN/NOTIFY trigger loop that
+// executes SQL scripts when notifications arrive on a channel.
+package listen
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Notifier waits for notifications on a connection that has issued LISTEN.
+// It is satisfied by *pgx.Conn.
+type Notifier interface {
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+}
+
+// Handler is invoked for every notification received. An error returned by
+// the handler is reported through onError and does not stop the loop.
+type Handler func(ctx context.Context, n *pgconn.Notification) error
+
+// Run waits for notifications and dispatches them to handle until ctx is
+// cancelled or the connection fails.
+func Run(ctx context.Context, notifier Notifier, handle Handler, onError func(*pgconn.Notification, error)) error {
+	for {
+		n, err := notifier.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+		if err := handle(ctx, n); err != nil && onError != nil {
+			onError(n, err)
+		}
+	}
+}
+
+// ResolveScript maps a notification payload naming a script to a path inside
+// the allowlisted directory. Payloads that are absolute, escape the directory,
+// or do not name a .sql file are rejected.
+func ResolveScript(dir, payload string) (string, error) {
+	name := strings.TrimSpace(payload)
+	if name == "" {
+		return "", fmt.Errorf("notification payload does not name a script")
+	}
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("script %q must be relative to the script directory", name)
+	}
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("script %q escapes the script directory", name)
+	}
+	if filepath.Ext(clean) != ".sql" {
+		return "", fmt.Errorf("script %q is not a .sql file", name)
+	}
+	return filepath.Join(dir, clean), nil
+}
@@ -0,0 +1,105 @@
+package listen
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestResolveScript(t *testing.T) {
+	dir := filepath.Join("opt", "sql")
+
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "plain script",
+			payload: "refresh.sql",
+			want:    filepath.Join(dir, "refresh.sql"),
+		},
+		{
+			name:    "nested script",
+			payload: " jobs/nightly.sql ",
+			want:    filepath.Join(dir, "jobs", "nightly.sql"),
+		},
+		{
+			name:    "empty payload",
+			payload: "",
+			wantErr: true,
+		},
+		{
+			name:    "parent traversal",
+			payload: "../../etc/passwd.sql",
+			wantErr: true,
+		},
+		{
+			name:    "absolute path",
+			payload: "/etc/evil.sql",
+			wantErr: true,
+		},
+		{
+			name:    "not a sql file",
+			payload: "refresh.sh",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveScript(dir, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ResolveScript() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ResolveScript() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeNotifier struct {
+	payloads []string
+	cancel   context.CancelFunc
+}
+
+func (f *fakeNotifier) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	if len(f.payloads) == 0 {
+		f.cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	p := f.payloads[0]
+	f.payloads = f.payloads[1:]
+	return &pgconn.Notification{Channel: "refresh", Payload: p}, nil
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notifier := &fakeNotifier{payloads: []string{"a", "fail", "b"}, cancel: cancel}
+	var handled, failed []string
+
+	err := Run(ctx, notifier, func(_ context.Context, n *pgconn.Notification) error {
+		if n.Payload == "fail" {
+			return errors.New("boom")
+		}
+		handled = append(handled, n.Payload)
+		return nil
+	}, func(n *pgconn.Notification, _ error) {
+		failed = append(failed, n.Payload)
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(handled) != 2 || len(failed) != 1 {
+		t.Errorf("handled = %v, failed = %v", handled, failed)
+	}
+}
@@ -0,0 +1,132 @@
+package listen
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Queue runs the scripts named by notifications with bounded concurrency.
+// A script already waiting to run is not queued again, so a burst of
+// notifications for the same script costs one extra run rather than one
+// per notification. A script never runs concurrently with itself.
+type Queue struct {
+	concurrency int
+	handle      func(ctx context.Context, script string) error
+	onError     func(script string, err error)
+	wake        chan struct{}
+
+	mu        sync.Mutex
+	pending   []string
+	running   map[string]bool
+	coalesced int64
+}
+
+// QueueStatus is a snapshot of a Queue.
+type QueueStatus struct {
+	Running []string `json:"running"`
+	Pending []string `json:"pending"`
+	// Coalesced counts the notifications dropped because their script was
+	// already waiting.
+	Coalesced int64 `json:"coalesced"`
+}
+
+// NewQueue returns a Queue running up to concurrency scripts at once with
+// handle. Errors from handle are reported through onError.
+func NewQueue(concurrency int, handle func(ctx context.Context, script string) error, onError func(script string, err error)) *Queue {
+	return &Queue{
+		concurrency: max(concurrency, 1),
+		handle:      handle,
+		onError:     onError,
+		wake:        make(chan struct{}, 1),
+		running:     map[string]bool{},
+	}
+}
+
+// Add queues script, reporting false if it was already waiting.
+func (q *Queue) Add(script string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, p := range q.pending {
+		if p == script {
+			q.coalesced++
+			return false
+		}
+	}
+	q.pending = append(q.pending, script)
+	q.signal()
+	return true
+}
+
+// Run executes queued scripts until ctx is done, then waits for the
+// scripts in flight to return.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range q.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// Status returns the running and pending scripts.
+func (q *Queue) Status() QueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := QueueStatus{Running: []string{}, Pending: append([]string{}, q.pending...), Coalesced: q.coalesced}
+	for script := range q.running {
+		s.Running = append(s.Running, script)
+	}
+	sort.Strings(s.Running)
+	return s
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		script, ok := q.next()
+		if !ok {
+			select {
+			case <-q.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := q.handle(ctx, script); err != nil && q.onError != nil {
+			q.onError(script, err)
+		}
+		q.mu.Lock()
+		delete(q.running, script)
+		q.signal()
+		q.mu.Unlock()
+	}
+}
+
+// next takes the first pending script that is not already running.
+func (q *Queue) next() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, script := range q.pending {
+		if !q.running[script] {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			q.running[script] = true
+			// Let another idle worker look at the rest.
+			if len(q.pending) > 0 {
+				q.signal()
+			}
+			return script, true
+		}
+	}
+	return "", false
+}
+
+// signal wakes one idle worker; q.mu must be held.
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
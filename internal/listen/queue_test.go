@@ -0,0 +1,101 @@
+package listen
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestQueueCoalescesPendingScripts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		ran     []string
+		failed  []string
+		started = make(chan string)
+		release = make(chan struct{})
+	)
+	q := NewQueue(1, func(_ context.Context, script string) error {
+		started <- script
+		<-release
+		mu.Lock()
+		ran = append(ran, script)
+		mu.Unlock()
+		if script == "bad.sql" {
+			return errors.New("boom")
+		}
+		return nil
+	}, func(script string, _ error) {
+		mu.Lock()
+		failed = append(failed, script)
+		mu.Unlock()
+	})
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+
+	q.Add("a.sql")
+	if got := <-started; got != "a.sql" {
+		t.Fatalf("first script = %s, want a.sql", got)
+	}
+	// While a.sql runs, a burst of notifications queues each script once,
+	// including a.sql itself.
+	for _, script := range []string{"a.sql", "b.sql", "a.sql", "b.sql", "bad.sql"} {
+		q.Add(script)
+	}
+	want := QueueStatus{Running: []string{"a.sql"}, Pending: []string{"a.sql", "b.sql", "bad.sql"}, Coalesced: 2}
+	if got := q.Status(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Status() = %+v, want %+v", got, want)
+	}
+
+	for i := range 4 {
+		release <- struct{}{}
+		if i < 3 {
+			<-started
+		}
+	}
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(ran, []string{"a.sql", "a.sql", "b.sql", "bad.sql"}) {
+		t.Errorf("ran %v, want a.sql twice then b.sql and bad.sql", ran)
+	}
+	if !reflect.DeepEqual(failed, []string{"bad.sql"}) {
+		t.Errorf("failed = %v, want [bad.sql]", failed)
+	}
+}
+
+func TestQueueConcurrency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan string, 3)
+	release := make(chan struct{})
+	q := NewQueue(2, func(_ context.Context, script string) error {
+		started <- script
+		<-release
+		return nil
+	}, nil)
+	go q.Run(ctx)
+
+	for _, script := range []string{"a.sql", "b.sql", "c.sql"} {
+		q.Add(script)
+	}
+	<-started
+	<-started
+	if got := q.Status(); len(got.Running) != 2 || !reflect.DeepEqual(got.Pending, []string{"c.sql"}) {
+		t.Errorf("Status() = %+v, want two running and c.sql pending", got)
+	}
+	close(release)
+	if got := <-started; got != "c.sql" {
+		t.Errorf("third script = %s, want c.sql", got)
+	}
+}
@@ -0,0 +1,97 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ParseDigest validates an expected digest of the form sha256:<hex> and
+// returns its lower-case hex part.
+func ParseDigest(digest string) (string, error) {
+	algorithm, sum, ok := strings.Cut(digest, ":")
+	if !ok || algorithm != "sha256" {
+		return "", fmt.Errorf("invalid checksum %q (use sha256:<hex>)", digest)
+	}
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != 32 {
+		return "", fmt.Errorf("invalid checksum %q: want 64 hex digits", digest)
+	}
+	return strings.ToLower(sum), nil
+}
+
+// VerifyChecksum reports an error unless content hashes to sum, the hex
+// SHA-256 digest of the script file as written. The file's byte order mark,
+// if it had one, was stripped on loading, so content matches either with or
+// without it.
+func VerifyChecksum(name, content, sum string) error {
+	if Checksum(content) == sum || Checksum(utf8BOM+content) == sum {
+		return nil
+	}
+	return fmt.Errorf("checksum mismatch for %s: expected sha256:%s, got sha256:%s; refusing to execute", name, sum, Checksum(content))
+}
+
+// Manifest maps script names to the hex SHA-256 digests they must have.
+type Manifest map[string]string
+
+// ReadManifest reads a manifest in the format written by sha256sum: one
+// "<hex>  <path>" line per file. Paths are slash-separated and, for a
+// directory run, relative to the directory.
+// #nosec G304 -- Manifest path is intentionally provided by the user as part of the CLI interface
+func ReadManifest(file string) (Manifest, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksum manifest: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	return parseManifest(f)
+}
+
+func parseManifest(r io.Reader) (Manifest, error) {
+	m := Manifest{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		// sha256sum marks files hashed in binary mode with a leading '*'.
+		name = strings.TrimPrefix(strings.TrimLeft(name, " "), "*")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("checksum manifest line %d: want \"<sha256>  <path>\"", n)
+		}
+		digest, err := ParseDigest("sha256:" + sum)
+		if err != nil {
+			return nil, fmt.Errorf("checksum manifest line %d: %w", n, err)
+		}
+		m[path.Clean(strings.ReplaceAll(name, `\`, "/"))] = digest
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checksum manifest: %w", err)
+	}
+	return m, nil
+}
+
+// Verify checks every script against its manifest entry, by Name when set
+// and otherwise by Path. A script missing from the manifest is an error, so
+// files added beside the signed ones are not executed either.
+func (m Manifest) Verify(scripts []Script) error {
+	for _, s := range scripts {
+		name := s.Name
+		if name == "" {
+			name = path.Clean(strings.ReplaceAll(s.Path, `\`, "/"))
+		}
+		sum, ok := m[name]
+		if !ok {
+			return fmt.Errorf("%s is not listed in the checksum manifest; refusing to execute", s.Path)
+		}
+		if err := VerifyChecksum(s.Path, s.Content, sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
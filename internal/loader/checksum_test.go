@@ -0,0 +1,89 @@
+package loader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDigest(t *testing.T) {
+	sum := Checksum("SELECT 1;")
+	tests := []struct {
+		digest  string
+		want    string
+		wantErr bool
+	}{
+		{digest: "sha256:" + sum, want: sum},
+		{digest: "sha256:" + strings.ToUpper(sum), want: sum},
+		{digest: sum, wantErr: true},
+		{digest: "md5:" + sum, wantErr: true},
+		{digest: "sha256:abc", wantErr: true},
+		{digest: "sha256:" + strings.Repeat("z", 64), wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDigest(tt.digest)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDigest(%q) = %q, %v; want %q, error %v", tt.digest, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	content := "SELECT 1;"
+	tests := []struct {
+		name    string
+		sum     string
+		wantErr bool
+	}{
+		{name: "match", sum: Checksum(content)},
+		{name: "file had a byte order mark", sum: Checksum(utf8BOM + content)},
+		{name: "tampered", sum: Checksum("SELECT 2;"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyChecksum("seed.sql", content, tt.sum)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManifestVerify(t *testing.T) {
+	a, b := "CREATE TABLE a (id INT);", "CREATE TABLE b (id INT);"
+	manifest := "# generated by sha256sum\n" +
+		Checksum(a) + "  001_a.sql\n" +
+		Checksum(b) + " *sub/002_b.sql\n"
+	m, err := parseManifest(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("parseManifest() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		scripts []Script
+		wantErr string
+	}{
+		{name: "all match", scripts: []Script{{Path: "m/001_a.sql", Name: "001_a.sql", Content: a}, {Path: "m/sub/002_b.sql", Name: "sub/002_b.sql", Content: b}}},
+		{name: "single file by path", scripts: []Script{{Path: "./001_a.sql", Content: a}}},
+		{name: "tampered", scripts: []Script{{Path: "m/001_a.sql", Name: "001_a.sql", Content: b}}, wantErr: "checksum mismatch for m/001_a.sql"},
+		{name: "unlisted", scripts: []Script{{Path: "m/003_c.sql", Name: "003_c.sql", Content: a}}, wantErr: "not listed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.Verify(tt.scripts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Verify() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := parseManifest(strings.NewReader("not-a-digest  x.sql\n")); err == nil {
+		t.Error("parseManifest() accepted an invalid digest")
+	}
+}
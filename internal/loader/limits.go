@@ -0,0 +1,124 @@
+package loader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Default limits for scripts, generous for seed files but low enough that a
+// runaway input fails with a clear error instead of exhausting memory.
+const (
+	DefaultMaxScriptSize    = 256 << 20
+	DefaultMaxStatementSize = 64 << 20
+)
+
+// LimitReader returns a reader that fails once r yields more than limit
+// bytes, naming the input in the error. Unlike io.LimitReader, it never
+// truncates silently. A limit of zero or less means no limit.
+func LimitReader(r io.Reader, name string, limit int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, name: name, limit: limit, left: limit}
+}
+
+type limitedReader struct {
+	r           io.Reader
+	name        string
+	limit, left int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.left < 0 {
+		return 0, l.err()
+	}
+	// Read one byte past the limit to tell an input of exactly limit
+	// bytes from a longer one.
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.left < 0 {
+		return n + int(l.left), l.err()
+	}
+	return n, err
+}
+
+func (l *limitedReader) err() error {
+	return fmt.Errorf("%s exceeds the size limit of %s", l.name, FormatSize(l.limit))
+}
+
+// LoadScriptLimit is LoadScript failing if the file is larger than limit
+// bytes, without reading more than that into memory.
+// #nosec G304 -- File path is intentionally provided by the user as part of the CLI interface
+func LoadScriptLimit(path string, limit int64) (string, error) {
+	if limit <= 0 {
+		return LoadScript(path)
+	}
+	if path == "" {
+		return "", fmt.Errorf("script path cannot be empty")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read script file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	return ReadScript(LimitReader(f, path, limit))
+}
+
+// CheckStatementSizes reports the first statement longer than limit bytes.
+// A limit of zero or less means no limit.
+func CheckStatementSizes(name string, statements []string, limit int64) error {
+	if limit <= 0 {
+		return nil
+	}
+	for i, stmt := range statements {
+		if int64(len(stmt)) > limit {
+			return fmt.Errorf("statement %d of %s is %s, over the statement size limit of %s",
+				i+1, name, FormatSize(int64(len(stmt))), FormatSize(limit))
+		}
+	}
+	return nil
+}
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+	{"B", 1},
+}
+
+// ParseSize parses a byte size such as 1048576, 512KiB, 64MiB, or 1GB.
+func ParseSize(s string) (int64, error) {
+	number, unit := strings.TrimSpace(s), int64(1)
+	for _, u := range sizeUnits {
+		if rest, ok := strings.CutSuffix(number, u.suffix); ok {
+			number, unit = strings.TrimSpace(rest), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 || n > (1<<62)/unit {
+		return 0, fmt.Errorf("invalid size %q (use bytes or a unit such as 64MiB)", s)
+	}
+	return n * unit, nil
+}
+
+// FormatSize formats a byte size with the largest binary unit dividing it.
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits[:3] {
+		if n >= u.bytes && n%u.bytes == 0 {
+			return strconv.FormatInt(n/u.bytes, 10) + u.suffix
+		}
+	}
+	if n >= 1<<20 {
+		return strconv.FormatFloat(float64(n)/(1<<20), 'f', 1, 64) + "MiB"
+	}
+	return strconv.FormatInt(n, 10) + " bytes"
+}
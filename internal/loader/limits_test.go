@@ -0,0 +1,104 @@
+package loader
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLimitReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		limit   int64
+		wantErr bool
+	}{
+		{name: "under", input: "SELECT 1;", limit: 100},
+		{name: "exactly at limit", input: "SELECT 1;", limit: 9},
+		{name: "over", input: "SELECT 1;", limit: 8, wantErr: true},
+		{name: "no limit", input: strings.Repeat("x", 1<<16), limit: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(LimitReader(strings.NewReader(tt.input), "seed.sql", tt.limit))
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "seed.sql exceeds the size limit of 8 bytes") {
+					t.Errorf("ReadAll() error = %v, want a size limit error", err)
+				}
+				if int64(len(got)) > tt.limit {
+					t.Errorf("read %d bytes, more than the limit of %d", len(got), tt.limit)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.input {
+				t.Errorf("ReadAll() = %d bytes, %v; want the whole input", len(got), err)
+			}
+		})
+	}
+}
+
+func TestLoadScriptLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seed.sql")
+	if err := os.WriteFile(path, []byte("SELECT 1;"), 0o600); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	if got, err := LoadScriptLimit(path, 1<<10); err != nil || got != "SELECT 1;" {
+		t.Errorf("LoadScriptLimit() = %q, %v", got, err)
+	}
+	if _, err := LoadScriptLimit(path, 4); err == nil {
+		t.Error("LoadScriptLimit() accepted a script over the limit")
+	}
+}
+
+func TestCheckStatementSizes(t *testing.T) {
+	statements := []string{"SELECT 1", "INSERT INTO t VALUES ('" + strings.Repeat("x", 2048) + "')"}
+	if err := CheckStatementSizes("seed.sql", statements, 4096); err != nil {
+		t.Errorf("CheckStatementSizes() error = %v", err)
+	}
+	err := CheckStatementSizes("seed.sql", statements, 1<<10)
+	if err == nil || !strings.Contains(err.Error(), "statement 2 of seed.sql is 2073 bytes, over the statement size limit of 1KiB") {
+		t.Errorf("CheckStatementSizes() error = %v, want statement 2 reported", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "1048576", want: 1 << 20},
+		{in: "512KiB", want: 512 << 10},
+		{in: "64MiB", want: 64 << 20},
+		{in: "1 GiB", want: 1 << 30},
+		{in: "10MB", want: 10e6},
+		{in: "0", want: 0},
+		{in: "64mib", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "lots", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:            "0 bytes",
+		100:          "100 bytes",
+		64 << 20:     "64MiB",
+		1 << 30:      "1GiB",
+		3 << 10:      "3KiB",
+		1<<20 + 1000: "1.0MiB",
+	}
+	for n, want := range tests {
+		if got := FormatSize(n); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -3,11 +3,24 @@
 package loader
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
+// utf8BOM is the byte order mark that Windows editors such as Notepad prepend
+// to UTF-8 files.
+const utf8BOM = "\ufeff"
+
 // LoadScript reads a SQL script file and returns its contents.
+// A leading UTF-8 byte order mark is removed.
 // The path parameter is expected to be a user-provided file path.
 // #nosec G304 -- File path is intentionally provided by the user as part of the CLI interface
 func LoadScript(path string) (string, error) {
@@ -20,5 +33,137 @@ func LoadScript(path string) (string, error) {
 		return "", fmt.Errorf("failed to read script file: %w", err)
 	}
 
-	return string(content), nil
+	return strings.TrimPrefix(string(content), utf8BOM), nil
+}
+
+// ReadScript reads a SQL script from r, such as stdin, removing a leading
+// UTF-8 byte order mark.
+func ReadScript(r io.Reader) (string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read script: %w", err)
+	}
+	return strings.TrimPrefix(string(content), utf8BOM), nil
+}
+
+// Script is a SQL script and the file it was loaded from.
+type Script struct {
+	Path string
+	// Name is the slash-separated path relative to the directory the
+	// script was loaded from, identifying it independently of where the
+	// directory is.
+	Name    string
+	Content string
+}
+
+// LoadDir loads every .sql file in the directory tree rooted at dir, sorted
+// lexically by slash-separated path relative to dir, so numbered files such
+// as 001_users.sql and 002_orders.sql run in order. Hidden files and
+// directories are skipped.
+func LoadDir(dir string) ([]Script, error) {
+	return LoadDirWith(dir, DirOptions{})
+}
+
+// DirOptions controls LoadDirWith.
+type DirOptions struct {
+	// Check, if set, is called with the path of each file before it is
+	// read; an error aborts the load.
+	Check func(path string) error
+	// MaxScriptSize, if positive, is the largest file accepted, in bytes.
+	MaxScriptSize int64
+}
+
+// LoadDirWith is LoadDir with checks on each file it reads.
+func LoadDirWith(dir string, opts DirOptions) ([]Script, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("script directory cannot be empty")
+	}
+
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".sql") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script directory: %w", err)
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		return relativeSlash(dir, paths[i]) < relativeSlash(dir, paths[j])
+	})
+	scripts := make([]Script, len(paths))
+	for i, path := range paths {
+		if opts.Check != nil {
+			if err := opts.Check(path); err != nil {
+				return nil, err
+			}
+		}
+		content, err := LoadScriptLimit(path, opts.MaxScriptSize)
+		if err != nil {
+			return nil, err
+		}
+		scripts[i] = Script{Path: path, Name: relativeSlash(dir, path), Content: content}
+	}
+	return scripts, nil
+}
+
+// LoadFS loads every .sql file under dir in fsys, such as an embed.FS
+// compiled into the binary, with the same ordering and filtering as
+// LoadDir. Paths are slash-separated fsys paths.
+func LoadFS(fsys fs.FS, dir string) ([]Script, error) {
+	var scripts []Script
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.EqualFold(path.Ext(p), ".sql") {
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to read script file: %w", err)
+		}
+		name := p
+		if dir != "." {
+			name = strings.TrimPrefix(p, dir+"/")
+		}
+		scripts = append(scripts, Script{Path: p, Name: name, Content: strings.TrimPrefix(string(content), utf8BOM)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script directory: %w", err)
+	}
+	sort.Slice(scripts, func(i, j int) bool { return scripts[i].Name < scripts[j].Name })
+	return scripts, nil
+}
+
+func relativeSlash(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Checksum returns the hex-encoded SHA-256 digest of a script's contents.
+func Checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
 }
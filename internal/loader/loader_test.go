@@ -1,9 +1,12 @@
 package loader
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestLoadScript(t *testing.T) {
@@ -14,6 +17,11 @@ func TestLoadScript(t *testing.T) {
 		t.Fatalf("Failed to create test file: %v", err)
 	}
 
+	bomScript := filepath.Join(tmpDir, "bom.sql")
+	if err := os.WriteFile(bomScript, []byte("\xef\xbb\xbfSELECT 1;\r\nSELECT 2;\r\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
 	tests := []struct {
 		name    string
 		path    string
@@ -26,6 +34,12 @@ func TestLoadScript(t *testing.T) {
 			want:    "SELECT 1;",
 			wantErr: false,
 		},
+		{
+			name:    "windows script with BOM and CRLF",
+			path:    bomScript,
+			want:    "SELECT 1;\r\nSELECT 2;\r\n",
+			wantErr: false,
+		},
 		{
 			name:    "non-existent file",
 			path:    filepath.Join(tmpDir, "missing.sql"),
@@ -51,3 +65,147 @@ func TestLoadScript(t *testing.T) {
 		})
 	}
 }
+
+func TestChecksum(t *testing.T) {
+	want := "17db4fd369edb9244b9f91d9aeed145c3d04ad8ba6e95d06247f07a63527d11a"
+	if got := Checksum("SELECT 1;"); got != want {
+		t.Errorf("Checksum() = %s, want %s", got, want)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"002_orders.sql":        "SELECT 2;",
+		"001_users.sql":         "SELECT 1;",
+		"010_views/001_a.SQL":   "SELECT 3;",
+		"010_views/notes.txt":   "not sql",
+		".hidden/001_skip.sql":  "SELECT 0;",
+		"003_indexes/.tmp.sql":  "SELECT 0;",
+		"003_indexes/001_b.sql": "SELECT 4;",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	scripts, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir() error = %v", err)
+	}
+	want := []string{"001_users.sql", "002_orders.sql", "003_indexes/001_b.sql", "010_views/001_a.SQL"}
+	if len(scripts) != len(want) {
+		t.Fatalf("LoadDir() returned %d scripts, want %d", len(scripts), len(want))
+	}
+	for i, s := range scripts {
+		if got := filepath.ToSlash(mustRel(t, dir, s.Path)); got != want[i] {
+			t.Errorf("scripts[%d] = %s, want %s", i, got, want[i])
+		}
+		if s.Name != want[i] {
+			t.Errorf("scripts[%d].Name = %s, want %s", i, s.Name, want[i])
+		}
+	}
+	if scripts[0].Content != "SELECT 1;" {
+		t.Errorf("scripts[0].Content = %q", scripts[0].Content)
+	}
+
+	if _, err := LoadDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadDir() on missing directory expected error")
+	}
+
+	var checked []string
+	_, err = LoadDirWith(dir, DirOptions{Check: func(path string) error {
+		checked = append(checked, filepath.ToSlash(mustRel(t, dir, path)))
+		if strings.HasPrefix(filepath.Base(path), "002") {
+			return errors.New("denied")
+		}
+		return nil
+	}})
+	if err == nil || err.Error() != "denied" {
+		t.Errorf("LoadDirWith() error = %v, want the check's error", err)
+	}
+	if strings.Join(checked, ",") != "001_users.sql,002_orders.sql" {
+		t.Errorf("LoadDirWith() checked %v, want the files up to the denied one", checked)
+	}
+	if _, err := LoadDirWith(dir, DirOptions{MaxScriptSize: 4}); err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Errorf("LoadDirWith() error = %v, want a size limit error", err)
+	}
+}
+
+func TestReadScript(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "SELECT 1;\n", want: "SELECT 1;\n"},
+		{input: "\xef\xbb\xbfSELECT 1;", want: "SELECT 1;"},
+		{input: "", want: ""},
+	}
+	for _, tt := range tests {
+		got, err := ReadScript(strings.NewReader(tt.input))
+		if err != nil || got != tt.want {
+			t.Errorf("ReadScript(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/002_orders.sql":      {Data: []byte("SELECT 2;")},
+		"migrations/001_users.sql":       {Data: []byte("\xef\xbb\xbfSELECT 1;")},
+		"migrations/010_views/001_a.SQL": {Data: []byte("SELECT 3;")},
+		"migrations/README.md":           {Data: []byte("not sql")},
+		"migrations/.hidden/001.sql":     {Data: []byte("SELECT 0;")},
+		"other/001.sql":                  {Data: []byte("SELECT 0;")},
+	}
+
+	tests := []struct {
+		dir       string
+		wantNames []string
+	}{
+		{dir: "migrations", wantNames: []string{"001_users.sql", "002_orders.sql", "010_views/001_a.SQL"}},
+		{dir: ".", wantNames: []string{"migrations/001_users.sql", "migrations/002_orders.sql", "migrations/010_views/001_a.SQL", "other/001.sql"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			scripts, err := LoadFS(fsys, tt.dir)
+			if err != nil {
+				t.Fatalf("LoadFS() error = %v", err)
+			}
+			var names []string
+			for _, s := range scripts {
+				names = append(names, s.Name)
+			}
+			if len(names) != len(tt.wantNames) {
+				t.Fatalf("LoadFS() names = %v, want %v", names, tt.wantNames)
+			}
+			for i := range names {
+				if names[i] != tt.wantNames[i] {
+					t.Errorf("LoadFS() names = %v, want %v", names, tt.wantNames)
+					break
+				}
+			}
+			if scripts[0].Content != "SELECT 1;" {
+				t.Errorf("scripts[0].Content = %q, want the BOM removed", scripts[0].Content)
+			}
+		})
+	}
+
+	if _, err := LoadFS(fsys, "missing"); err == nil {
+		t.Error("LoadFS() on missing directory expected error")
+	}
+}
+
+func mustRel(t *testing.T, base, path string) string {
+	t.Helper()
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		t.Fatalf("filepath.Rel() error = %v", err)
+	}
+	return rel
+}
@@ -0,0 +1,300 @@
+// Package migrate applies SQL scripts at most once each, recording every
+// applied script in a tracking table so that reruns skip it, and reverts
+// them with paired down scripts.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+)
+
+// DefaultTable is the tracking table used when Options.Table is empty.
+const DefaultTable = "schema_migrations"
+
+// Suffixes of paired migration files: 0001_users.up.sql applies a change
+// and 0001_users.down.sql reverts it.
+const (
+	UpSuffix   = ".up.sql"
+	DownSuffix = ".down.sql"
+)
+
+// Migration is a script recorded as applied.
+type Migration struct {
+	// Version is the script's Name, its path relative to the migrations
+	// directory.
+	Version   string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Options controls Run.
+type Options struct {
+	// Table is the tracking table, optionally schema-qualified.
+	Table string
+	// Execute is passed to the executor for each script. Its Transaction
+	// must be unset: every script runs in a transaction of its own.
+	Execute database.ExecuteOptions
+	// OnApply, if set, is called before each pending script is applied.
+	OnApply func(version string)
+	// OnRevert, if set, is called before each migration Down reverts.
+	OnRevert func(version string)
+}
+
+// DB is a *sql.DB or a *sql.Conn to run migrations on.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Result lists the versions a run applied and skipped, or Down reverted.
+type Result struct {
+	Applied  []string
+	Skipped  []string
+	Reverted []string
+}
+
+// Run applies the scripts, identified by Name, not yet recorded in the tracking table, in order,
+// creating the table if needed. Each script runs in a transaction together
+// with the insert recording it, so a failed script leaves neither its
+// changes nor a record behind and is retried on the next run. A recorded
+// script whose content has since changed is an error, as silently skipping
+// it would leave the database out of step with the files. Down scripts,
+// named *.down.sql, are never applied by Run.
+func Run(ctx context.Context, db DB, driver string, scripts []loader.Script, opts Options) (Result, error) {
+	var result Result
+	ups, _, err := split(scripts)
+	if err != nil {
+		return result, err
+	}
+	table := opts.Table
+	if table == "" {
+		table = DefaultTable
+	}
+	if err := ensureTable(ctx, db, driver, table); err != nil {
+		return result, err
+	}
+	applied, err := Applied(ctx, db, table)
+	if err != nil {
+		return result, err
+	}
+
+	for _, s := range ups {
+		version, checksum := s.Name, loader.Checksum(s.Content)
+		if m, ok := applied[version]; ok {
+			if err := unchanged(m, checksum); err != nil {
+				return result, err
+			}
+			result.Skipped = append(result.Skipped, version)
+			continue
+		}
+		if opts.OnApply != nil {
+			opts.OnApply(version)
+		}
+		if err := apply(ctx, db, driver, table, version, checksum, s.Content, opts.Execute); err != nil {
+			return result, fmt.Errorf("failed to apply migration %s: %w", version, err)
+		}
+		result.Applied = append(result.Applied, version)
+	}
+	return result, nil
+}
+
+// Down reverts the last steps applied migrations, most recently applied
+// first, by running the down script paired with each: 0001_users.down.sql
+// reverts 0001_users.up.sql. Each down script runs in a transaction
+// together with the delete of its record, so a failed one leaves the
+// migration applied. Every migration to revert is checked before any is
+// reverted: one without a down script, or changed since it was applied, is
+// an error and nothing is reverted.
+func Down(ctx context.Context, db DB, driver string, scripts []loader.Script, steps int, opts Options) (Result, error) {
+	var result Result
+	if steps < 1 {
+		return result, fmt.Errorf("steps must be at least 1")
+	}
+	ups, downs, err := split(scripts)
+	if err != nil {
+		return result, err
+	}
+	table := opts.Table
+	if table == "" {
+		table = DefaultTable
+	}
+	if err := ensureTable(ctx, db, driver, table); err != nil {
+		return result, err
+	}
+	applied, err := Applied(ctx, db, table)
+	if err != nil {
+		return result, err
+	}
+	if steps > len(applied) {
+		return result, fmt.Errorf("cannot revert %d migration(s): only %d are applied", steps, len(applied))
+	}
+
+	latest := make([]Migration, 0, len(applied))
+	for _, m := range applied {
+		latest = append(latest, m)
+	}
+	sort.Slice(latest, func(i, j int) bool {
+		if !latest[i].AppliedAt.Equal(latest[j].AppliedAt) {
+			return latest[i].AppliedAt.After(latest[j].AppliedAt)
+		}
+		return latest[i].Version > latest[j].Version
+	})
+	latest = latest[:steps]
+
+	files := make(map[string]loader.Script, len(ups))
+	for _, s := range ups {
+		files[s.Name] = s
+	}
+	for _, m := range latest {
+		up, ok := files[m.Version]
+		if !ok {
+			return result, fmt.Errorf("cannot revert migration %s: the file is missing", m.Version)
+		}
+		if err := unchanged(m, loader.Checksum(up.Content)); err != nil {
+			return result, err
+		}
+		if _, ok := downs[m.Version]; !ok {
+			return result, fmt.Errorf("cannot revert migration %s: it has no %s script", m.Version, DownSuffix)
+		}
+	}
+
+	for _, m := range latest {
+		if opts.OnRevert != nil {
+			opts.OnRevert(m.Version)
+		}
+		if err := revert(ctx, db, driver, table, m.Version, downs[m.Version].Content, opts.Execute); err != nil {
+			return result, fmt.Errorf("failed to revert migration %s: %w", m.Version, err)
+		}
+		result.Reverted = append(result.Reverted, m.Version)
+	}
+	return result, nil
+}
+
+// split separates the down scripts from the migrations, keying each down
+// script by the version of the .up.sql migration it reverts.
+func split(scripts []loader.Script) ([]loader.Script, map[string]loader.Script, error) {
+	var ups []loader.Script
+	downs := map[string]loader.Script{}
+	names := map[string]bool{}
+	for _, s := range scripts {
+		if base, ok := strings.CutSuffix(s.Name, DownSuffix); ok {
+			downs[base+UpSuffix] = s
+			continue
+		}
+		ups = append(ups, s)
+		names[s.Name] = true
+	}
+	for _, s := range scripts {
+		if base, ok := strings.CutSuffix(s.Name, DownSuffix); ok && !names[base+UpSuffix] {
+			return nil, nil, fmt.Errorf("down script %s has no matching %s migration", s.Name, base+UpSuffix)
+		}
+	}
+	return ups, downs, nil
+}
+
+// unchanged reports an error if m's script no longer has checksum.
+func unchanged(m Migration, checksum string) error {
+	if m.Checksum != checksum {
+		return fmt.Errorf("migration %s has changed since it was applied at %s (checksum %.12s, now %.12s)",
+			m.Version, m.AppliedAt.Format(time.RFC3339), m.Checksum, checksum)
+	}
+	return nil
+}
+
+func ensureTable(ctx context.Context, db DB, driver, table string) error {
+	timestamp := "TIMESTAMP"
+	if driver == "postgres" {
+		timestamp = "TIMESTAMPTZ"
+	}
+	stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version TEXT PRIMARY KEY, checksum TEXT NOT NULL, applied_at %s NOT NULL)",
+		database.QuoteQualified(table), timestamp)
+	if _, err := db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create migration table %s: %w", table, err)
+	}
+	return nil
+}
+
+// Applied returns the migrations recorded in table, keyed by version.
+func Applied(ctx context.Context, db DB, table string) (map[string]Migration, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version, checksum, applied_at FROM "+database.QuoteQualified(table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration table %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	applied := map[string]Migration{}
+	for rows.Next() {
+		var m Migration
+		if err := rows.Scan(&m.Version, &m.Checksum, &m.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read migration table %s: %w", table, err)
+		}
+		applied[m.Version] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read migration table %s: %w", table, err)
+	}
+	return applied, nil
+}
+
+// begin starts the transaction a migration runs in, pinned to its
+// connection so that the script's notices are delivered and its COPY FROM
+// STDIN blocks can run.
+func begin(ctx context.Context, db DB) (*database.Tx, error) {
+	switch db := db.(type) {
+	case *sql.Conn:
+		return database.BeginConnTx(ctx, db, nil)
+	case *sql.DB:
+		return database.BeginTx(ctx, db, nil)
+	}
+	return nil, fmt.Errorf("cannot begin a migration transaction on a %T", db)
+}
+
+func apply(ctx context.Context, db DB, driver, table, version, checksum, script string, opts database.ExecuteOptions) error {
+	tx, err := begin(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := database.ExecuteScriptOn(ctx, tx, script, opts); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	record := fmt.Sprintf("INSERT INTO %s (version, checksum, applied_at) VALUES (%s, %s, %s)",
+		database.QuoteQualified(table), database.Placeholder(driver, 1), database.Placeholder(driver, 2), database.Placeholder(driver, 3))
+	if _, err := tx.ExecContext(ctx, record, version, checksum, time.Now().UTC()); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func revert(ctx context.Context, db DB, driver, table, version, script string, opts database.ExecuteOptions) error {
+	tx, err := begin(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := database.ExecuteScriptOn(ctx, tx, script, opts); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	record := fmt.Sprintf("DELETE FROM %s WHERE version = %s", database.QuoteQualified(table), database.Placeholder(driver, 1))
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to delete migration record: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
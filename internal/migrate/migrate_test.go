@@ -0,0 +1,179 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+
+	_ "modernc.org/sqlite"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+	return db
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	scripts := []loader.Script{
+		{Name: "001_users.sql", Content: "CREATE TABLE users (id INTEGER PRIMARY KEY);"},
+		{Name: "002_seed.sql", Content: "INSERT INTO users VALUES (1); INSERT INTO users VALUES (2);"},
+	}
+
+	tests := []struct {
+		name        string
+		scripts     []loader.Script
+		wantApplied []string
+		wantSkipped []string
+		wantErr     string
+	}{
+		{name: "first run applies everything", scripts: scripts, wantApplied: []string{"001_users.sql", "002_seed.sql"}},
+		{name: "rerun skips applied scripts", scripts: scripts, wantSkipped: []string{"001_users.sql", "002_seed.sql"}},
+		{
+			name:        "new script is applied",
+			scripts:     append(scripts, loader.Script{Name: "003_more.sql", Content: "INSERT INTO users VALUES (3);"}),
+			wantApplied: []string{"003_more.sql"},
+			wantSkipped: []string{"001_users.sql", "002_seed.sql"},
+		},
+		{
+			name:    "failed script is rolled back",
+			scripts: []loader.Script{{Name: "004_bad.sql", Content: "INSERT INTO users VALUES (4); INSERT INTO missing VALUES (1);"}},
+			wantErr: "failed to apply migration 004_bad.sql",
+		},
+		{
+			name:    "changed script is rejected",
+			scripts: []loader.Script{{Name: "002_seed.sql", Content: "INSERT INTO users VALUES (9);"}},
+			wantErr: "migration 002_seed.sql has changed since it was applied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Run(ctx, db, "sqlite", tt.scripts, Options{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Applied, tt.wantApplied) || !reflect.DeepEqual(result.Skipped, tt.wantSkipped) {
+				t.Errorf("Run() = %+v, want applied %v, skipped %v", result, tt.wantApplied, tt.wantSkipped)
+			}
+		})
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil || count != 3 {
+		t.Errorf("users has %d rows, %v; want 3 (the failed script rolled back)", count, err)
+	}
+	applied, err := Applied(ctx, db, DefaultTable)
+	if err != nil {
+		t.Fatalf("Applied() error = %v", err)
+	}
+	if len(applied) != 3 || applied["002_seed.sql"].Checksum != loader.Checksum(scripts[1].Content) || applied["001_users.sql"].AppliedAt.IsZero() {
+		t.Errorf("Applied() = %+v, want the three successful scripts", applied)
+	}
+}
+
+func TestRunCustomTable(t *testing.T) {
+	db := openDB(t)
+	scripts := []loader.Script{{Name: "001.sql", Content: "CREATE TABLE t (id INTEGER);"}}
+	if _, err := Run(context.Background(), db, "sqlite", scripts, Options{Table: "Applied Scripts"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	applied, err := Applied(context.Background(), db, "Applied Scripts")
+	if err != nil || len(applied) != 1 {
+		t.Errorf("Applied() = %v, %v; want one migration", applied, err)
+	}
+}
+
+func TestDown(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	scripts := []loader.Script{
+		{Name: "001_users.up.sql", Content: "CREATE TABLE users (id INTEGER PRIMARY KEY);"},
+		{Name: "001_users.down.sql", Content: "DROP TABLE users;"},
+		{Name: "002_seed.sql", Content: "INSERT INTO users VALUES (1);"},
+		{Name: "003_orders.up.sql", Content: "CREATE TABLE orders (id INTEGER);"},
+		{Name: "003_orders.down.sql", Content: "DROP TABLE orders;"},
+	}
+	result, err := Run(ctx, db, "sqlite", scripts, Options{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := []string{"001_users.up.sql", "002_seed.sql", "003_orders.up.sql"}; !reflect.DeepEqual(result.Applied, want) {
+		t.Fatalf("Run() applied %v, want %v without the down scripts", result.Applied, want)
+	}
+
+	tests := []struct {
+		name         string
+		scripts      []loader.Script
+		steps        int
+		wantReverted []string
+		wantErr      string
+	}{
+		{name: "too many steps", scripts: scripts, steps: 4, wantErr: "only 3 are applied"},
+		{name: "no down script", scripts: scripts, steps: 2, wantErr: "cannot revert migration 002_seed.sql: it has no .down.sql script"},
+		{
+			name:    "changed migration",
+			scripts: append(scripts[:3:3], loader.Script{Name: "003_orders.up.sql", Content: "CREATE TABLE orders (id TEXT);"}, scripts[4]),
+			steps:   1,
+			wantErr: "migration 003_orders.up.sql has changed since it was applied",
+		},
+		{name: "latest first", scripts: scripts, steps: 1, wantReverted: []string{"003_orders.up.sql"}},
+		{name: "stray down script", scripts: append(scripts, loader.Script{Name: "004_x.down.sql"}), steps: 1, wantErr: "down script 004_x.down.sql has no matching 004_x.up.sql migration"},
+		{name: "zero steps", scripts: scripts, steps: 0, wantErr: "steps must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reverting []string
+			result, err := Down(ctx, db, "sqlite", tt.scripts, tt.steps, Options{OnRevert: func(v string) { reverting = append(reverting, v) }})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Down() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(reverting) > 0 {
+					t.Errorf("Down() reverted %v before failing", reverting)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Down() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Reverted, tt.wantReverted) || !reflect.DeepEqual(reverting, tt.wantReverted) {
+				t.Errorf("Down() = %+v, reverting %v; want %v", result, reverting, tt.wantReverted)
+			}
+		})
+	}
+
+	if _, err := db.Exec("SELECT 1 FROM orders"); err == nil {
+		t.Error("orders still exists after its migration was reverted")
+	}
+	applied, err := Applied(ctx, db, DefaultTable)
+	if err != nil {
+		t.Fatalf("Applied() error = %v", err)
+	}
+	if _, ok := applied["003_orders.up.sql"]; ok || len(applied) != 2 {
+		t.Errorf("Applied() = %+v, want the record of the reverted migration deleted", applied)
+	}
+	if result, err := Run(ctx, db, "sqlite", scripts, Options{}); err != nil || !reflect.DeepEqual(result.Applied, []string{"003_orders.up.sql"}) {
+		t.Errorf("Run() after Down() = %+v, %v; want the reverted migration applied again", result, err)
+	}
+}
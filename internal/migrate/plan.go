@@ -0,0 +1,170 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+	"github.com/obstreperous-ai/sql-loader-go/internal/report"
+)
+
+// TransactionFile is the transaction mode of a planned migration: it runs
+// in a transaction of its own together with the insert recording it.
+const TransactionFile = "file"
+
+// Plan lists what Run would do, in a stable form that can be diffed from
+// run to run.
+type Plan struct {
+	// Table is the tracking table the plan was read from.
+	Table string `json:"table"`
+	// Pending are the migrations Run would apply, in order.
+	Pending []PlannedMigration `json:"pending"`
+	// Applied are the versions already recorded, which Run would skip.
+	Applied []string `json:"applied"`
+}
+
+// PlannedMigration is a migration Run would apply.
+type PlannedMigration struct {
+	// Order is the 1-based position the migration is applied in.
+	Order      int    `json:"order"`
+	Version    string `json:"version"`
+	Checksum   string `json:"checksum"`
+	Statements int    `json:"statements"`
+	// Transaction is how the migration runs, always TransactionFile.
+	Transaction string `json:"transaction"`
+	// Warnings name statements that would make the migration fail, such as
+	// those that cannot run inside its transaction.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// PlanRun reports what Run would do with scripts without changing the
+// database: a missing tracking table means nothing has been applied yet.
+// As with Run, a recorded migration whose content has since changed is an
+// error.
+func PlanRun(ctx context.Context, db DB, driver string, scripts []loader.Script, table string) (Plan, error) {
+	if table == "" {
+		table = DefaultTable
+	}
+	plan := Plan{Table: table, Pending: []PlannedMigration{}, Applied: []string{}}
+	ups, _, err := split(scripts)
+	if err != nil {
+		return plan, err
+	}
+	exists, err := TableExists(ctx, db, driver, table)
+	if err != nil {
+		return plan, fmt.Errorf("failed to look up migration table %s: %w", table, err)
+	}
+	applied := map[string]Migration{}
+	if exists {
+		if applied, err = Applied(ctx, db, table); err != nil {
+			return plan, err
+		}
+	}
+
+	for _, s := range ups {
+		checksum := loader.Checksum(s.Content)
+		if m, ok := applied[s.Name]; ok {
+			if err := unchanged(m, checksum); err != nil {
+				return plan, err
+			}
+			plan.Applied = append(plan.Applied, s.Name)
+			continue
+		}
+		statements := database.SplitStatements(s.Content)
+		p := PlannedMigration{
+			Order:       len(plan.Pending) + 1,
+			Version:     s.Name,
+			Checksum:    checksum,
+			Statements:  len(statements),
+			Transaction: TransactionFile,
+		}
+		for i, stmt := range statements {
+			if database.RequiresAutocommit(stmt) {
+				info := database.DescribeStatement(stmt)
+				p.Warnings = append(p.Warnings, fmt.Sprintf("statement %d (%s) cannot run inside the migration's transaction",
+					i+1, strings.TrimSpace(info.Verb+" "+info.ObjectType)))
+			}
+		}
+		plan.Pending = append(plan.Pending, p)
+	}
+	return plan, nil
+}
+
+// TableExists reports whether table, which may be schema-qualified, exists.
+func TableExists(ctx context.Context, db database.Querier, driver, table string) (bool, error) {
+	var exists bool
+	if driver == "postgres" {
+		err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", database.QuoteQualified(table)).Scan(&exists)
+		return exists, err
+	}
+	parts := database.SplitQualified(table)
+	master := "sqlite_master"
+	if len(parts) > 1 {
+		master = database.QuoteIdent(parts[0]) + ".sqlite_master"
+	}
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+master+" WHERE type = 'table' AND name = ?)", parts[len(parts)-1]).Scan(&exists)
+	return exists, err
+}
+
+// WriteJSON writes the plan as indented JSON.
+func (p Plan) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// WriteTable prints a line per pending migration, then any warnings and a
+// summary line.
+func (p Plan) WriteTable(w io.Writer) error {
+	if len(p.Pending) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ORDER\tVERSION\tCHECKSUM\tSTATEMENTS\tTRANSACTION")
+		for _, m := range p.Pending {
+			fmt.Fprintf(tw, "%d\t%s\t%.12s\t%d\t%s\n", m.Order, m.Version, m.Checksum, m.Statements, m.Transaction)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		for _, m := range p.Pending {
+			for _, warning := range m.Warnings {
+				fmt.Fprintf(w, "Warning: %s: %s\n", m.Version, warning)
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d migration(s) to apply, %d already applied (%s)\n", len(p.Pending), len(p.Applied), p.Table)
+	return err
+}
+
+// WriteMarkdown writes the plan in GitHub-flavored markdown, for a CI job
+// summary: a row per pending migration, then any warnings and the summary
+// line.
+func (p Plan) WriteMarkdown(w io.Writer) error {
+	fmt.Fprintln(w, "### sql-loader migration plan")
+	fmt.Fprintln(w)
+	if len(p.Pending) > 0 {
+		fmt.Fprintln(w, "| Order | Version | Checksum | Statements | Transaction |")
+		fmt.Fprintln(w, "| ---: | --- | --- | ---: | --- |")
+		for _, m := range p.Pending {
+			fmt.Fprintf(w, "| %d | %s | `%.12s` | %d | %s |\n", m.Order, report.MarkdownCell(m.Version), m.Checksum, m.Statements, m.Transaction)
+		}
+		fmt.Fprintln(w)
+		warned := false
+		for _, m := range p.Pending {
+			for _, warning := range m.Warnings {
+				fmt.Fprintf(w, "- Warning: %s: %s\n", report.MarkdownCell(m.Version), report.MarkdownCell(warning))
+				warned = true
+			}
+		}
+		if warned {
+			fmt.Fprintln(w)
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d migration(s) to apply, %d already applied (%s)\n", len(p.Pending), len(p.Applied), p.Table)
+	return err
+}
@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+)
+
+func TestPlanRun(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	scripts := []loader.Script{
+		{Name: "001_users.up.sql", Content: "CREATE TABLE users (id INTEGER PRIMARY KEY);"},
+		{Name: "001_users.down.sql", Content: "DROP TABLE users;"},
+		{Name: "002_seed.sql", Content: "INSERT INTO users VALUES (1); INSERT INTO users VALUES (2);"},
+		{Name: "003_vacuum.sql", Content: "DELETE FROM users WHERE id = 2; VACUUM;"},
+	}
+
+	plan, err := PlanRun(ctx, db, "sqlite", scripts, "")
+	if err != nil {
+		t.Fatalf("PlanRun() error = %v", err)
+	}
+	if exists, err := TableExists(ctx, db, "sqlite", DefaultTable); err != nil || exists {
+		t.Errorf("PlanRun() created the tracking table (exists = %v, err = %v)", exists, err)
+	}
+	var versions []string
+	for _, m := range plan.Pending {
+		versions = append(versions, m.Version)
+	}
+	if want := []string{"001_users.up.sql", "002_seed.sql", "003_vacuum.sql"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("Pending = %v, want %v", versions, want)
+	}
+	if m := plan.Pending[1]; m.Order != 2 || m.Statements != 2 || m.Checksum != loader.Checksum(scripts[2].Content) || m.Transaction != TransactionFile {
+		t.Errorf("Pending[1] = %+v", m)
+	}
+	if got := plan.Pending[2].Warnings; len(got) != 1 || !strings.Contains(got[0], "statement 2 (VACUUM)") {
+		t.Errorf("Pending[2].Warnings = %v, want a warning about statement 2", got)
+	}
+
+	if _, err := Run(ctx, db, "sqlite", scripts[:3], Options{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	plan, err = PlanRun(ctx, db, "sqlite", scripts, "")
+	if err != nil {
+		t.Fatalf("PlanRun() error = %v", err)
+	}
+	if len(plan.Pending) != 1 || plan.Pending[0].Version != "003_vacuum.sql" || plan.Pending[0].Order != 1 {
+		t.Errorf("Pending = %+v, want only 003_vacuum.sql", plan.Pending)
+	}
+	if want := []string{"001_users.up.sql", "002_seed.sql"}; !reflect.DeepEqual(plan.Applied, want) {
+		t.Errorf("Applied = %v, want %v", plan.Applied, want)
+	}
+
+	changed := append([]loader.Script{}, scripts...)
+	changed[2].Content = "INSERT INTO users VALUES (9);"
+	if _, err := PlanRun(ctx, db, "sqlite", changed, ""); err == nil || !strings.Contains(err.Error(), "has changed") {
+		t.Errorf("PlanRun() error = %v, want a changed migration error", err)
+	}
+}
+
+func TestPlanWrite(t *testing.T) {
+	plan := Plan{
+		Table: DefaultTable,
+		Pending: []PlannedMigration{
+			{Order: 1, Version: "002_seed.sql", Checksum: "0123456789abcdef", Statements: 2, Transaction: TransactionFile},
+			{Order: 2, Version: "003_index.sql", Checksum: "fedcba9876543210", Statements: 1, Transaction: TransactionFile,
+				Warnings: []string{"statement 1 (CREATE INDEX) cannot run inside the migration's transaction"}},
+		},
+		Applied: []string{"001_users.sql"},
+	}
+
+	var table bytes.Buffer
+	if err := plan.WriteTable(&table); err != nil {
+		t.Fatalf("WriteTable() error = %v", err)
+	}
+	want := "ORDER  VERSION        CHECKSUM      STATEMENTS  TRANSACTION\n" +
+		"1      002_seed.sql   0123456789ab  2           file\n" +
+		"2      003_index.sql  fedcba987654  1           file\n" +
+		"\n" +
+		"Warning: 003_index.sql: statement 1 (CREATE INDEX) cannot run inside the migration's transaction\n" +
+		"2 migration(s) to apply, 1 already applied (schema_migrations)\n"
+	if table.String() != want {
+		t.Errorf("WriteTable() =\n%s\nwant\n%s", table.String(), want)
+	}
+
+	var md bytes.Buffer
+	if err := plan.WriteMarkdown(&md); err != nil {
+		t.Fatalf("WriteMarkdown() error = %v", err)
+	}
+	want = "### sql-loader migration plan\n\n" +
+		"| Order | Version | Checksum | Statements | Transaction |\n" +
+		"| ---: | --- | --- | ---: | --- |\n" +
+		"| 1 | 002_seed.sql | `0123456789ab` | 2 | file |\n" +
+		"| 2 | 003_index.sql | `fedcba987654` | 1 | file |\n" +
+		"\n" +
+		"- Warning: 003_index.sql: statement 1 (CREATE INDEX) cannot run inside the migration's transaction\n" +
+		"\n" +
+		"2 migration(s) to apply, 1 already applied (schema_migrations)\n"
+	if md.String() != want {
+		t.Errorf("WriteMarkdown() =\n%s\nwant\n%s", md.String(), want)
+	}
+
+	var out bytes.Buffer
+	if err := plan.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var decoded Plan
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteJSON() wrote invalid JSON: %v\n%s", err, out.String())
+	}
+	if !reflect.DeepEqual(decoded, plan) {
+		t.Errorf("WriteJSON() round trip = %+v, want %+v", decoded, plan)
+	}
+}
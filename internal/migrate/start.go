@@ -0,0 +1,126 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+	"github.com/obstreperous-ai/sql-loader-go/internal/loader"
+)
+
+// StartOptions controls OnStart.
+type StartOptions struct {
+	// Driver is "postgres" or "sqlite". It defaults to postgres.
+	Driver string
+	// Dir is the directory of migrations within the file system. It
+	// defaults to its root.
+	Dir string
+	// Table is the tracking table. It defaults to DefaultTable.
+	Table string
+	// StatementTimeout, if positive, cancels any statement running longer.
+	StatementTimeout time.Duration
+	// Logger, if set, receives an event for each applied migration and a
+	// summary. Failures are returned, not logged.
+	Logger *slog.Logger
+}
+
+// OnStart applies the pending migrations in fsys, typically an embed.FS,
+// and is meant to be called from a service's main before it starts serving.
+// On PostgreSQL, replicas starting together serialize on an advisory lock
+// keyed by the tracking table, so each migration is applied exactly once
+// and the others wait for it and then find nothing to do.
+//
+// Every migration is applied in a transaction together with its record. If
+// one fails, the migrations before it stay applied, the failed one leaves
+// nothing behind, and the error names it; the service should exit, and the
+// next start resumes with the failed migration.
+func OnStart(ctx context.Context, db *sql.DB, fsys fs.FS, opts StartOptions) error {
+	if opts.Driver == "" {
+		opts.Driver = "postgres"
+	}
+	if opts.Dir == "" {
+		opts.Dir = "."
+	}
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	scripts, err := loader.LoadFS(fsys, opts.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	started := time.Now()
+	var result Result
+	onWait := func() { logger.Info("waiting for another instance to finish migrating", "table", opts.Table) }
+	err = WithLock(ctx, db, opts.Driver, opts.Table, onWait, func(conn *sql.Conn) error {
+		result, err = Run(ctx, conn, opts.Driver, scripts, Options{
+			Table:   opts.Table,
+			Execute: database.ExecuteOptions{StatementTimeout: opts.StatementTimeout},
+			OnApply: func(version string) {
+				logger.Info("applying migration", "version", version)
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("migrations up to date", "applied", len(result.Applied), "skipped", len(result.Skipped),
+		"table", opts.Table, "duration", time.Since(started))
+	return nil
+}
+
+// WithLock calls fn with a dedicated connection that, on PostgreSQL, holds
+// a session advisory lock keyed by the tracking table, so concurrent
+// migrators of one database apply each migration once. onWait, if set, is
+// called when another session holds the lock. SQLite has no advisory
+// locks, so fn runs unlocked.
+func WithLock(ctx context.Context, db *sql.DB, driver, table string, onWait func(), fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if driver == "postgres" {
+		key := LockKey(table)
+		var locked bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+			return fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if !locked {
+			if onWait != nil {
+				onWait()
+			}
+			if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+				if ctx.Err() != nil {
+					err = context.Cause(ctx)
+				}
+				return fmt.Errorf("failed to take migration lock: %w", err)
+			}
+		}
+		// Closing the connection also releases the lock, so a failed
+		// unlock needs no handling.
+		defer func() { _, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key) }()
+	}
+	return fn(conn)
+}
+
+// LockKey derives the advisory lock key from the tracking table's name, so
+// services migrating different tables in one database do not block each
+// other.
+func LockKey(table string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("sql-loader migrate " + table))
+	return int64(h.Sum64()) // #nosec G115 -- any 64-bit value is a valid key
+}
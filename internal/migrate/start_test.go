@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOnStart(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	fsys := fstest.MapFS{
+		"migrations/001_users.sql": {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")},
+		"migrations/002_seed.sql":  {Data: []byte("INSERT INTO users VALUES (1);")},
+	}
+
+	var logs bytes.Buffer
+	opts := StartOptions{Driver: "sqlite", Dir: "migrations", Logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	if err := OnStart(ctx, db, fsys, opts); err != nil {
+		t.Fatalf("OnStart() error = %v", err)
+	}
+	if !strings.Contains(logs.String(), "version=001_users.sql") || !strings.Contains(logs.String(), "applied=2 skipped=0") {
+		t.Errorf("log output missing applied migrations:\n%s", logs.String())
+	}
+
+	// A restart finds nothing to do.
+	logs.Reset()
+	if err := OnStart(ctx, db, fsys, opts); err != nil {
+		t.Fatalf("OnStart() error = %v", err)
+	}
+	if !strings.Contains(logs.String(), "applied=0 skipped=2") {
+		t.Errorf("log output on restart:\n%s", logs.String())
+	}
+
+	// A failing migration leaves the earlier ones applied and is retried
+	// once fixed.
+	fsys["migrations/003_orders.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE orders (id INTEGER); INSERT INTO missing VALUES (1);")}
+	fsys["migrations/004_later.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE later (id INTEGER);")}
+	err := OnStart(ctx, db, fsys, opts)
+	if err == nil || !strings.Contains(err.Error(), "failed to apply migration 003_orders.sql") {
+		t.Fatalf("OnStart() error = %v, want 003_orders.sql to fail", err)
+	}
+	fsys["migrations/003_orders.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE orders (id INTEGER);")}
+	if err := OnStart(ctx, db, fsys, opts); err != nil {
+		t.Fatalf("OnStart() after fix error = %v", err)
+	}
+	applied, err := Applied(ctx, db, DefaultTable)
+	if err != nil || len(applied) != 4 {
+		t.Errorf("Applied() = %v, %v; want four migrations", applied, err)
+	}
+}
+
+func TestLockKey(t *testing.T) {
+	if LockKey("schema_migrations") == LockKey("billing.schema_migrations") {
+		t.Error("LockKey() is the same for different tables")
+	}
+	if LockKey("schema_migrations") != LockKey("schema_migrations") {
+		t.Error("LockKey() is not stable")
+	}
+}
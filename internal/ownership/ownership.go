@@ -0,0 +1,139 @@
+// Package ownership hands the PostgreSQL objects a run creates over to
+// another role, so that objects created by a migration role end up owned by
+// the application role.
+package ownership
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// Object is a schema, or an object in one, owned by the connecting role.
+type Object struct {
+	// Key identifies the object by its catalog and OID.
+	Key string
+	// Kind is the object kind as ALTER names it, such as TABLE or ROUTINE.
+	Kind string
+	// Name is the quoted, schema-qualified name, with the argument types
+	// of a routine.
+	Name string
+}
+
+// Queryer runs a query returning rows. *sql.DB, *sql.Conn, and *sql.Tx
+// satisfy it.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// ownedQuery lists the schemas, relations, types, and routines owned by the
+// current user, leaving out the system schemas, members of extensions, and
+// objects whose owner follows another object's, such as indexes and the
+// sequences of serial and identity columns.
+const ownedQuery = `WITH me AS (SELECT oid FROM pg_roles WHERE rolname = current_user),
+objects AS (
+	SELECT 0 AS rank, n.nspname AS schema, 'pg_namespace:' || n.oid AS key, 'SCHEMA' AS kind, quote_ident(n.nspname) AS name
+	FROM pg_namespace n
+	WHERE n.nspowner = (SELECT oid FROM me)
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_namespace'::regclass AND d.objid = n.oid AND d.deptype = 'e')
+	UNION ALL
+	SELECT 1, n.nspname, 'pg_type:' || t.oid, CASE t.typtype WHEN 'd' THEN 'DOMAIN' ELSE 'TYPE' END,
+		quote_ident(n.nspname) || '.' || quote_ident(t.typname)
+	FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+	WHERE t.typowner = (SELECT oid FROM me) AND t.typtype IN ('d', 'e', 'r')
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_type'::regclass AND d.objid = t.oid AND d.deptype IN ('e', 'i'))
+	UNION ALL
+	SELECT 2, n.nspname, 'pg_class:' || c.oid,
+		CASE c.relkind WHEN 'v' THEN 'VIEW' WHEN 'm' THEN 'MATERIALIZED VIEW' WHEN 'S' THEN 'SEQUENCE'
+			WHEN 'f' THEN 'FOREIGN TABLE' WHEN 'c' THEN 'TYPE' ELSE 'TABLE' END,
+		quote_ident(n.nspname) || '.' || quote_ident(c.relname)
+	FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.relowner = (SELECT oid FROM me) AND c.relkind IN ('r', 'p', 'v', 'm', 'S', 'f', 'c')
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype IN ('e', 'a', 'i'))
+	UNION ALL
+	SELECT 3, n.nspname, 'pg_proc:' || p.oid, CASE p.prokind WHEN 'a' THEN 'AGGREGATE' ELSE 'ROUTINE' END,
+		quote_ident(n.nspname) || '.' || quote_ident(p.proname) || '(' || pg_get_function_identity_arguments(p.oid) || ')'
+	FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+	WHERE p.proowner = (SELECT oid FROM me) AND p.prokind IN ('f', 'p', 'a')
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e')
+)
+SELECT key, kind, name FROM objects o
+WHERE o.schema NOT IN ('information_schema', 'pg_catalog', 'pg_toast')
+	AND o.schema NOT LIKE 'pg\_temp\_%' AND o.schema NOT LIKE 'pg\_toast\_temp\_%'
+ORDER BY rank, name`
+
+// Owned returns the objects the connecting role owns, schemas first.
+func Owned(ctx context.Context, q Queryer) ([]Object, error) {
+	rows, err := q.QueryContext(ctx, ownedQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list owned objects: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var objects []Object
+	for rows.Next() {
+		var o Object
+		if err := rows.Scan(&o.Key, &o.Kind, &o.Name); err != nil {
+			return nil, fmt.Errorf("failed to list owned objects: %w", err)
+		}
+		objects = append(objects, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list owned objects: %w", err)
+	}
+	return objects, nil
+}
+
+// Snapshot is the set of objects the connecting role owned before a run,
+// keyed by Object.Key.
+type Snapshot map[string]bool
+
+// Take records the objects the connecting role owns now.
+func Take(ctx context.Context, q Queryer) (Snapshot, error) {
+	objects, err := Owned(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot(objects), nil
+}
+
+func snapshot(objects []Object) Snapshot {
+	s := make(Snapshot, len(objects))
+	for _, o := range objects {
+		s[o.Key] = true
+	}
+	return s
+}
+
+// Created returns the objects the connecting role owns now but did not own
+// when s was taken: those the run created. q must see the run's changes,
+// so inside a run transaction it must be that transaction.
+func (s Snapshot) Created(ctx context.Context, q Queryer) ([]Object, error) {
+	objects, err := Owned(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return s.created(objects), nil
+}
+
+func (s Snapshot) created(objects []Object) []Object {
+	var created []Object
+	for _, o := range objects {
+		if !s[o.Key] {
+			created = append(created, o)
+		}
+	}
+	return created
+}
+
+// Script returns the ALTER ... OWNER TO statements making role the owner of
+// objects.
+func Script(objects []Object, role string) string {
+	var b strings.Builder
+	for _, o := range objects {
+		fmt.Fprintf(&b, "ALTER %s %s OWNER TO %s;\n", o.Kind, o.Name, database.QuoteIdent(role))
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package ownership
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCreated(t *testing.T) {
+	before := snapshot([]Object{
+		{Key: "pg_namespace:2200", Kind: "SCHEMA", Name: "public"},
+		{Key: "pg_class:16384", Kind: "TABLE", Name: "public.users"},
+	})
+	now := []Object{
+		{Key: "pg_namespace:2200", Kind: "SCHEMA", Name: "public"},
+		{Key: "pg_namespace:16390", Kind: "SCHEMA", Name: "app"},
+		{Key: "pg_class:16384", Kind: "TABLE", Name: "public.users"},
+		{Key: "pg_class:16392", Kind: "VIEW", Name: "app.active_users"},
+	}
+	want := []Object{now[1], now[3]}
+	if got := before.created(now); !reflect.DeepEqual(got, want) {
+		t.Errorf("created() = %+v, want %+v", got, want)
+	}
+	if got := before.created(now[:1]); got != nil {
+		t.Errorf("created() of no new objects = %+v, want none", got)
+	}
+}
+
+func TestScript(t *testing.T) {
+	objects := []Object{
+		{Kind: "SCHEMA", Name: "app"},
+		{Kind: "TABLE", Name: `app."Orders"`},
+		{Kind: "ROUTINE", Name: "app.refresh(since date)"},
+	}
+	want := "ALTER SCHEMA app OWNER TO \"app_owner\";\n" +
+		"ALTER TABLE app.\"Orders\" OWNER TO \"app_owner\";\n" +
+		"ALTER ROUTINE app.refresh(since date) OWNER TO \"app_owner\";\n"
+	if got := Script(objects, "app_owner"); got != want {
+		t.Errorf("Script() = %q, want %q", got, want)
+	}
+	if got := Script(nil, "app_owner"); got != "" {
+		t.Errorf("Script(nil) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,86 @@
+package preflight
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// ExplainLimits are the thresholds of the EXPLAIN guard. Zero disables a
+// limit.
+type ExplainLimits struct {
+	MaxRows float64
+	MaxCost float64
+}
+
+// Enabled reports whether any limit is set.
+func (l ExplainLimits) Enabled() bool {
+	return l.MaxRows > 0 || l.MaxCost > 0
+}
+
+// explainVerbs are the DML statements the guard plans before executing.
+var explainVerbs = map[string]bool{"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true}
+
+// Plan is the planner's estimate for a statement.
+type Plan struct {
+	Rows float64
+	Cost float64
+}
+
+// Explain returns the planner's estimate for a DML statement without
+// executing it. It returns nil for other statements and for drivers other
+// than PostgreSQL.
+func Explain(db *sql.DB, driver, stmt string) (*Plan, error) {
+	if driver != "postgres" || !explainVerbs[database.DescribeStatement(stmt).Verb] {
+		return nil, nil
+	}
+	var out string
+	if err := db.QueryRow("EXPLAIN (FORMAT JSON) " + stmt).Scan(&out); err != nil {
+		return nil, fmt.Errorf("failed to explain statement: %w", err)
+	}
+	return parsePlan(out)
+}
+
+func parsePlan(out string) (*Plan, error) {
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+			Cost float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(out), &plans); err != nil || len(plans) == 0 {
+		return nil, fmt.Errorf("failed to parse EXPLAIN output: %v", err)
+	}
+	return &Plan{Rows: plans[0].Plan.Rows, Cost: plans[0].Plan.Cost}, nil
+}
+
+// CheckPlan returns an error if the plan exceeds the limits.
+func (l ExplainLimits) CheckPlan(p *Plan) error {
+	switch {
+	case p == nil:
+		return nil
+	case l.MaxRows > 0 && p.Rows > l.MaxRows:
+		return fmt.Errorf("estimated %.0f rows exceeds limit of %.0f", p.Rows, l.MaxRows)
+	case l.MaxCost > 0 && p.Cost > l.MaxCost:
+		return fmt.Errorf("estimated cost %.2f exceeds limit of %.2f", p.Cost, l.MaxCost)
+	}
+	return nil
+}
+
+// ExplainGuard returns a hook for database.ExecuteOptions.BeforeStatement
+// that plans each DML statement and aborts before a statement whose
+// estimate exceeds the limits.
+func ExplainGuard(db *sql.DB, driver string, limits ExplainLimits) func(index int, stmt string) error {
+	return func(_ int, stmt string) error {
+		plan, err := Explain(db, driver, stmt)
+		if err != nil {
+			return err
+		}
+		if err := limits.CheckPlan(plan); err != nil {
+			return fmt.Errorf("EXPLAIN guard: %w", err)
+		}
+		return nil
+	}
+}
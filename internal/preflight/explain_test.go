@@ -0,0 +1,57 @@
+package preflight
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestParsePlan(t *testing.T) {
+	out := `[{"Plan": {"Node Type": "ModifyTable", "Startup Cost": 0.00, "Total Cost": 1520.75, "Plan Rows": 120000, "Plan Width": 6}}]`
+	p, err := parsePlan(out)
+	if err != nil {
+		t.Fatalf("parsePlan() error = %v", err)
+	}
+	if p.Rows != 120000 || p.Cost != 1520.75 {
+		t.Errorf("parsePlan() = %+v", p)
+	}
+	if _, err := parsePlan("[]"); err == nil {
+		t.Error("parsePlan([]) expected error")
+	}
+}
+
+func TestCheckPlan(t *testing.T) {
+	plan := &Plan{Rows: 5000, Cost: 80}
+	tests := []struct {
+		name    string
+		limits  ExplainLimits
+		wantErr bool
+	}{
+		{name: "no limits", limits: ExplainLimits{}},
+		{name: "under limits", limits: ExplainLimits{MaxRows: 10000, MaxCost: 100}},
+		{name: "too many rows", limits: ExplainLimits{MaxRows: 1000}, wantErr: true},
+		{name: "too costly", limits: ExplainLimits{MaxCost: 50}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.limits.CheckPlan(plan); (err != nil) != tt.wantErr {
+				t.Errorf("CheckPlan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExplainSkipsNonPostgres(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	guard := ExplainGuard(db, "sqlite", ExplainLimits{MaxRows: 1})
+	if err := guard(1, "DELETE FROM missing"); err != nil {
+		t.Errorf("guard on sqlite error = %v, want nil", err)
+	}
+}
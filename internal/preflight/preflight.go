@@ -0,0 +1,193 @@
+// Package preflight provides checks that run before a load starts, so that
+// problems are reported up front instead of partway through execution.
+package preflight
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// Privileges checked by the privilege preflight. PrivilegeOwner stands for
+// statements such as DROP and ALTER that require ownership of the object.
+const (
+	PrivilegeCreate = "CREATE"
+	PrivilegeOwner  = "OWNER"
+)
+
+// Requirement is a privilege a statement needs on an object.
+type Requirement struct {
+	// Statement is the 1-based index of the statement in the script.
+	Statement int
+	// Privilege is the required privilege, e.g. INSERT or CREATE.
+	Privilege string
+	// ObjectType is TABLE, SCHEMA, or DATABASE.
+	ObjectType string
+	// Object is the object name as written; empty means the current
+	// schema or database.
+	Object string
+	// MayBeMissing reports that the statement tolerates a missing object,
+	// as DROP and ALTER do with IF EXISTS.
+	MayBeMissing bool
+}
+
+func (r Requirement) String() string {
+	target := strings.ToLower(r.ObjectType)
+	switch {
+	case r.Object != "":
+		target += " " + r.Object
+	case r.ObjectType == "SCHEMA":
+		target = "the current schema"
+	case r.ObjectType == "DATABASE":
+		target = "the current database"
+	}
+	if r.Privilege == PrivilegeOwner {
+		return "ownership of " + target
+	}
+	return r.Privilege + " on " + target
+}
+
+// relationTypes are the object types whose ownership is checked for DROP and
+// ALTER statements.
+var relationTypes = map[string]bool{"TABLE": true, "VIEW": true, "SEQUENCE": true, "INDEX": true}
+
+// schemaObjectTypes are the object types whose creation requires CREATE on
+// the containing schema.
+var schemaObjectTypes = map[string]bool{
+	"TABLE": true, "VIEW": true, "SEQUENCE": true, "FUNCTION": true,
+	"PROCEDURE": true, "TYPE": true, "DOMAIN": true,
+}
+
+// Requirements derives the privileges needed by each statement. Objects
+// created earlier in the same script are owned by the running role and are
+// not checked again.
+func Requirements(statements []string) []Requirement {
+	var (
+		reqs    []Requirement
+		created = map[string]bool{}
+		seen    = map[string]bool{}
+	)
+	add := func(r Requirement) {
+		key := r.Privilege + " " + r.ObjectType + " " + objectKey(r.Object)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		reqs = append(reqs, r)
+	}
+
+	for i, stmt := range statements {
+		info := database.DescribeStatement(stmt)
+		n := i + 1
+		switch info.Verb {
+		case "INSERT", "UPDATE", "DELETE", "TRUNCATE":
+			if info.Object != "" && !created[objectKey(info.Object)] {
+				add(Requirement{Statement: n, Privilege: info.Verb, ObjectType: "TABLE", Object: info.Object})
+			}
+		case "CREATE":
+			switch {
+			case info.ObjectType == "SCHEMA":
+				add(Requirement{Statement: n, Privilege: PrivilegeCreate, ObjectType: "DATABASE"})
+				created["schema:"+objectKey(info.Object)] = true
+			case schemaObjectTypes[info.ObjectType] && info.Object != "":
+				schema, _ := database.SplitName(info.Object)
+				if !created["schema:"+schema] {
+					add(Requirement{Statement: n, Privilege: PrivilegeCreate, ObjectType: "SCHEMA", Object: quoteSchema(schema)})
+				}
+				created[objectKey(info.Object)] = true
+			}
+		case "DROP", "ALTER":
+			if relationTypes[info.ObjectType] && info.Object != "" && !created[objectKey(info.Object)] {
+				add(Requirement{Statement: n, Privilege: PrivilegeOwner, ObjectType: "TABLE", Object: info.Object,
+					MayBeMissing: hasIfExists(stmt)})
+			}
+		}
+	}
+	return reqs
+}
+
+// objectKey normalizes an object name for comparison.
+func objectKey(name string) string {
+	schema, object := database.SplitName(name)
+	if schema == "" {
+		return object
+	}
+	return schema + "." + object
+}
+
+func quoteSchema(schema string) string {
+	if schema == "" {
+		return ""
+	}
+	return database.QuoteIdent(schema)
+}
+
+func hasIfExists(stmt string) bool {
+	return strings.Contains(strings.ToUpper(strings.Join(strings.Fields(stmt), " ")), " IF EXISTS ")
+}
+
+// Error reports every unmet requirement found by the preflight.
+type Error struct {
+	Role     string
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("preflight failed for role %s:\n  %s", e.Role, strings.Join(e.Problems, "\n  "))
+}
+
+// CheckPrivileges verifies that the connected role holds every privilege the
+// statements require. It returns an *Error listing each missing privilege.
+// Drivers without a privilege system (SQLite) always pass.
+func CheckPrivileges(db *sql.DB, driver string, statements []string) error {
+	if driver != "postgres" {
+		return nil
+	}
+
+	var role string
+	if err := db.QueryRow("SELECT current_user").Scan(&role); err != nil {
+		return fmt.Errorf("failed to determine current role: %w", err)
+	}
+
+	var problems []string
+	for _, r := range Requirements(statements) {
+		ok, exists, err := checkRequirement(db, r)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", r, err)
+		}
+		switch {
+		case !exists && r.MayBeMissing:
+		case !exists:
+			problems = append(problems, fmt.Sprintf("statement %d: %s %s does not exist", r.Statement, strings.ToLower(r.ObjectType), r.Object))
+		case !ok:
+			problems = append(problems, fmt.Sprintf("statement %d: missing %s", r.Statement, r))
+		}
+	}
+	if len(problems) > 0 {
+		return &Error{Role: role, Problems: problems}
+	}
+	return nil
+}
+
+func checkRequirement(db *sql.DB, r Requirement) (ok, exists bool, err error) {
+	var granted sql.NullBool
+	switch {
+	case r.ObjectType == "DATABASE":
+		err = db.QueryRow("SELECT has_database_privilege(current_database(), 'CREATE')").Scan(&granted)
+	case r.ObjectType == "SCHEMA":
+		_, schema := database.SplitName(r.Object)
+		err = db.QueryRow(`SELECT (SELECT has_schema_privilege(oid, 'CREATE') FROM pg_namespace
+			WHERE nspname = COALESCE(NULLIF($1, ''), current_schema()))`, schema).Scan(&granted)
+	case r.Privilege == PrivilegeOwner:
+		err = db.QueryRow(`SELECT (SELECT pg_has_role(relowner, 'USAGE') FROM pg_class
+			WHERE oid = to_regclass($1))`, r.Object).Scan(&granted)
+	default:
+		err = db.QueryRow(`SELECT has_table_privilege(to_regclass($1), $2)`, r.Object, r.Privilege).Scan(&granted)
+	}
+	if err != nil {
+		return false, false, err
+	}
+	return granted.Bool, granted.Valid, nil
+}
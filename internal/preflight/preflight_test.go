@@ -0,0 +1,96 @@
+package preflight
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestRequirements(t *testing.T) {
+	tests := []struct {
+		name       string
+		statements []string
+		want       []string
+	}{
+		{
+			name:       "dml on existing tables",
+			statements: []string{"INSERT INTO users VALUES (1)", "UPDATE users SET x = 1", "DELETE FROM app.orders"},
+			want:       []string{"1:INSERT on table users", "2:UPDATE on table users", "3:DELETE on table app.orders"},
+		},
+		{
+			name:       "objects created in the script are skipped",
+			statements: []string{"CREATE TABLE app.users (id INT)", "INSERT INTO app.users VALUES (1)", "DROP TABLE App.Users"},
+			want:       []string{`1:CREATE on schema "app"`},
+		},
+		{
+			name:       "create in current schema",
+			statements: []string{"CREATE VIEW v AS SELECT 1"},
+			want:       []string{"1:CREATE on the current schema"},
+		},
+		{
+			name:       "create schema and objects inside it",
+			statements: []string{"CREATE SCHEMA reporting", "CREATE TABLE reporting.daily (d DATE)"},
+			want:       []string{"1:CREATE on the current database"},
+		},
+		{
+			name:       "drop and alter need ownership",
+			statements: []string{"DROP TABLE IF EXISTS legacy", "ALTER TABLE users ADD COLUMN x INT"},
+			want:       []string{"1:ownership of table legacy", "2:ownership of table users"},
+		},
+		{
+			name:       "duplicates reported once",
+			statements: []string{"INSERT INTO users VALUES (1)", "INSERT INTO users VALUES (2)"},
+			want:       []string{"1:INSERT on table users"},
+		},
+		{
+			name:       "selects need nothing",
+			statements: []string{"SELECT 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range Requirements(tt.statements) {
+				got = append(got, fmt.Sprintf("%d:%s", r.Statement, r))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Requirements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirementsIfExists(t *testing.T) {
+	reqs := Requirements([]string{"DROP TABLE IF EXISTS legacy", "DROP TABLE current"})
+	if len(reqs) != 2 || !reqs[0].MayBeMissing || reqs[1].MayBeMissing {
+		t.Errorf("Requirements() = %+v", reqs)
+	}
+}
+
+func TestCheckPrivilegesSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+
+	if err := CheckPrivileges(db, "sqlite", []string{"INSERT INTO missing VALUES (1)"}); err != nil {
+		t.Errorf("CheckPrivileges() error = %v, want nil for sqlite", err)
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := &Error{Role: "loader", Problems: []string{"statement 1: missing INSERT on table users"}}
+	want := "preflight failed for role loader:\n  statement 1: missing INSERT on table users"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,119 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/catalog"
+)
+
+// CheckTable verifies that table exists in the catalog and has every input
+// column, returning an error describing all problems at once. The optional
+// sample record is compared against the column types to produce
+// compatibility warnings; required columns missing from the input are also
+// reported as warnings.
+func CheckTable(cat *catalog.Catalog, table string, columns []string, sample []any) ([]string, error) {
+	t, ok := cat.Table(table)
+	if !ok {
+		return nil, fmt.Errorf("preflight failed: table %s does not exist", table)
+	}
+
+	var missing []string
+	for _, name := range columns {
+		if _, ok := t.Column(name); !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("preflight failed: table %s has no column(s): %s", table, strings.Join(missing, ", "))
+	}
+
+	var warnings []string
+	present := make(map[string]bool, len(columns))
+	for i, name := range columns {
+		present[name] = true
+		if i >= len(sample) {
+			continue
+		}
+		col, _ := t.Column(name)
+		if w := checkValue(col, sample[i]); w != "" {
+			warnings = append(warnings, fmt.Sprintf("column %s (%s): %s", name, col.Type, w))
+		}
+	}
+	for _, col := range t.Columns {
+		if !present[col.Name] && !col.Nullable && col.Default == nil {
+			warnings = append(warnings, fmt.Sprintf("column %s is NOT NULL without a default but is not present in the input", col.Name))
+		}
+	}
+	return warnings, nil
+}
+
+// typeFamily groups database type names for compatibility checks.
+func typeFamily(typ string) string {
+	typ = strings.ToLower(typ)
+	switch {
+	case strings.Contains(typ, "int") || strings.Contains(typ, "serial"):
+		return "integer"
+	case strings.HasPrefix(typ, "numeric"), strings.HasPrefix(typ, "decimal"),
+		strings.HasPrefix(typ, "real"), strings.HasPrefix(typ, "double"), strings.HasPrefix(typ, "float"):
+		return "number"
+	case strings.HasPrefix(typ, "bool"):
+		return "boolean"
+	case strings.HasPrefix(typ, "json"):
+		return "json"
+	default:
+		return ""
+	}
+}
+
+// checkValue returns a warning if v is unlikely to be accepted by col.
+func checkValue(col *catalog.Column, v any) string {
+	if v == nil {
+		if !col.Nullable {
+			return "first record is NULL but the column is NOT NULL"
+		}
+		return ""
+	}
+
+	switch typeFamily(col.Type) {
+	case "integer":
+		switch val := v.(type) {
+		case int64:
+			return ""
+		case float64:
+			return fmt.Sprintf("first record has non-integer number %v", val)
+		case string:
+			if _, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64); err != nil {
+				return fmt.Sprintf("first record value %q is not an integer", val)
+			}
+		case bool:
+			return "first record has a boolean value"
+		}
+	case "number":
+		switch val := v.(type) {
+		case int64, float64:
+			return ""
+		case string:
+			if _, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err != nil {
+				return fmt.Sprintf("first record value %q is not a number", val)
+			}
+		case bool:
+			return "first record has a boolean value"
+		}
+	case "boolean":
+		if val, ok := v.(string); ok {
+			switch strings.ToLower(strings.TrimSpace(val)) {
+			case "t", "f", "true", "false", "1", "0", "yes", "no", "y", "n", "on", "off":
+			default:
+				return fmt.Sprintf("first record value %q is not a boolean", val)
+			}
+		}
+	case "json":
+		if val, ok := v.(string); ok && !json.Valid([]byte(val)) {
+			return "first record value is not valid JSON"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package preflight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/catalog"
+)
+
+func testCatalog() *catalog.Catalog {
+	now := "now()"
+	return &catalog.Catalog{
+		Driver: "postgres",
+		Tables: []catalog.Table{{
+			Schema: "public",
+			Name:   "events",
+			Columns: []catalog.Column{
+				{Name: "id", Type: "bigint", Nullable: false},
+				{Name: "amount", Type: "numeric(10,2)", Nullable: true},
+				{Name: "active", Type: "boolean", Nullable: true},
+				{Name: "payload", Type: "jsonb", Nullable: true},
+				{Name: "created_at", Type: "timestamp with time zone", Nullable: false, Default: &now},
+				{Name: "source", Type: "text", Nullable: false},
+			},
+		}},
+	}
+}
+
+func TestCheckTable(t *testing.T) {
+	tests := []struct {
+		name         string
+		table        string
+		columns      []string
+		sample       []any
+		wantErr      string
+		wantWarnings []string
+	}{
+		{
+			name:    "compatible input",
+			table:   "public.events",
+			columns: []string{"id", "amount", "active", "payload", "source"},
+			sample:  []any{"42", "9.99", "true", `{"a":1}`, "web"},
+		},
+		{
+			name:    "quoted qualified name",
+			table:   `"public"."events"`,
+			columns: []string{"id", "source"},
+			sample:  []any{"1", "web"},
+		},
+		{
+			name:    "missing table",
+			table:   "orders",
+			columns: []string{"id"},
+			wantErr: "table orders does not exist",
+		},
+		{
+			name:    "missing columns",
+			table:   "events",
+			columns: []string{"id", "colour", "size"},
+			wantErr: "has no column(s): colour, size",
+		},
+		{
+			name:    "type mismatches",
+			table:   "events",
+			columns: []string{"id", "amount", "active", "payload", "source"},
+			sample:  []any{"abc", "x", "maybe", "{", nil},
+			wantWarnings: []string{
+				`column id (bigint): first record value "abc" is not an integer`,
+				`column amount (numeric(10,2)): first record value "x" is not a number`,
+				`column active (boolean): first record value "maybe" is not a boolean`,
+				"column payload (jsonb): first record value is not valid JSON",
+				"column source (text): first record is NULL but the column is NOT NULL",
+			},
+		},
+		{
+			name:         "required column absent",
+			table:        "events",
+			columns:      []string{"id"},
+			sample:       []any{int64(1)},
+			wantWarnings: []string{"column source is NOT NULL without a default but is not present in the input"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := CheckTable(testCatalog(), tt.table, tt.columns, tt.sample)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("CheckTable() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckTable() error = %v", err)
+			}
+			if strings.Join(warnings, "\n") != strings.Join(tt.wantWarnings, "\n") {
+				t.Errorf("CheckTable() warnings =\n%s\nwant\n%s", strings.Join(warnings, "\n"), strings.Join(tt.wantWarnings, "\n"))
+			}
+		})
+	}
+}
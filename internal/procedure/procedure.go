@@ -0,0 +1,399 @@
+// Package procedure provides functionality for calling PostgreSQL stored
+// procedures and functions with arguments given by name or position, and
+// capturing the OUT parameters and results they return.
+package procedure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// Parameter modes, as pg_proc.proargmodes records them.
+const (
+	ModeIn       = "i"
+	ModeOut      = "o"
+	ModeInOut    = "b"
+	ModeVariadic = "v"
+	ModeTable    = "t"
+)
+
+// Routine is a stored procedure or function, as the catalog describes it.
+type Routine struct {
+	// Name is the schema-qualified, quoted name.
+	Name string
+	// Procedure is set for a procedure, run with CALL, rather than a
+	// function, whose results are selected.
+	Procedure bool
+	Params    []Param
+	// Defaults is the number of trailing input parameters with defaults.
+	Defaults int
+}
+
+// Param is a parameter of a routine.
+type Param struct {
+	// Name is empty for an unnamed parameter.
+	Name string
+	// Mode is one of the Mode constants.
+	Mode string
+	// Type is the SQL type, such as integer or text.
+	Type string
+}
+
+// input reports whether the caller supplies the parameter.
+func (p Param) input() bool {
+	return p.Mode == ModeIn || p.Mode == ModeInOut || p.Mode == ModeVariadic
+}
+
+// Args are the arguments of a call, given either by name or by position.
+// Values are strings, converted by the server to each parameter's type, or
+// nil for NULL.
+type Args struct {
+	Named      map[string]any
+	Positional []any
+}
+
+// ParseArgs reads arguments from JSON: an object of named arguments or an
+// array of positional ones. Numbers and booleans are passed as written,
+// and objects and arrays as JSON text, for json and jsonb parameters.
+func ParseArgs(r io.Reader) (Args, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var raw any
+	if err := dec.Decode(&raw); err != nil {
+		return Args{}, fmt.Errorf("failed to parse arguments: %w", err)
+	}
+	switch raw := raw.(type) {
+	case map[string]any:
+		args := Args{Named: make(map[string]any, len(raw))}
+		for name, v := range raw {
+			text, err := argText(v)
+			if err != nil {
+				return Args{}, err
+			}
+			args.Named[name] = text
+		}
+		return args, nil
+	case []any:
+		args := Args{Positional: make([]any, len(raw))}
+		for i, v := range raw {
+			text, err := argText(v)
+			if err != nil {
+				return Args{}, err
+			}
+			args.Positional[i] = text
+		}
+		return args, nil
+	default:
+		return Args{}, fmt.Errorf("arguments must be a JSON object of named arguments or an array of positional ones")
+	}
+}
+
+// argText returns the text of a decoded JSON argument, or nil for null.
+func argText(v any) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode argument: %w", err)
+		}
+		return string(b), nil
+	}
+}
+
+// lookupQuery describes a routine named as regproc or, with an argument
+// list, regprocedure input accepts it.
+const lookupQuery = `SELECT quote_ident(n.nspname) || '.' || quote_ident(p.proname),
+       p.prokind::text,
+       p.pronargdefaults,
+       COALESCE(array_to_json(p.proargnames), '[]')::text,
+       COALESCE(array_to_json(p.proargmodes::text[]), '[]')::text,
+       array_to_json(ARRAY(
+           SELECT format_type(a.t, NULL)
+           FROM unnest(COALESCE(p.proallargtypes, p.proargtypes::oid[])) WITH ORDINALITY AS a(t, n)
+           ORDER BY a.n))::text
+FROM pg_proc p
+JOIN pg_namespace n ON n.oid = p.pronamespace
+WHERE p.oid = $1::%s::oid`
+
+// Lookup describes the routine called name, which may be schema-qualified
+// and, to choose among overloads, carry its argument types, as in
+// refresh(date, integer).
+func Lookup(ctx context.Context, q database.Querier, name string) (*Routine, error) {
+	cast := "regproc"
+	if strings.Contains(name, "(") {
+		cast = "regprocedure"
+	}
+	var (
+		r                    Routine
+		kind                 string
+		names, modes, ptypes string
+	)
+	err := q.QueryRowContext(ctx, fmt.Sprintf(lookupQuery, cast), name).
+		Scan(&r.Name, &kind, &r.Defaults, &names, &modes, &ptypes)
+	if err != nil {
+		if strings.Contains(err.Error(), "more than one function named") {
+			return nil, fmt.Errorf("failed to find routine %s: %w (give its argument types, as in %s(integer, text))", name, err, name)
+		}
+		return nil, fmt.Errorf("failed to find routine %s: %w", name, err)
+	}
+	switch kind {
+	case "p":
+		r.Procedure = true
+	case "f":
+	default:
+		return nil, fmt.Errorf("%s is an aggregate or window function, which cannot be called on its own", name)
+	}
+	var paramNames, paramModes, paramTypes []string
+	for _, field := range []struct {
+		text string
+		into *[]string
+	}{{names, &paramNames}, {modes, &paramModes}, {ptypes, &paramTypes}} {
+		if err := json.Unmarshal([]byte(field.text), field.into); err != nil {
+			return nil, fmt.Errorf("failed to read the parameters of %s: %w", name, err)
+		}
+	}
+	for i, typ := range paramTypes {
+		p := Param{Mode: ModeIn, Type: typ}
+		if i < len(paramNames) {
+			p.Name = paramNames[i]
+		}
+		if i < len(paramModes) {
+			p.Mode = paramModes[i]
+		}
+		r.Params = append(r.Params, p)
+	}
+	return &r, nil
+}
+
+// Statement returns the statement calling r with args, and its parameter
+// values. Each argument is cast to its parameter's type. A procedure's OUT
+// parameters are passed as NULL, as PostgreSQL requires.
+func (r *Routine) Statement(args Args) (string, []any, error) {
+	if len(args.Named) > 0 && len(args.Positional) > 0 {
+		return "", nil, fmt.Errorf("arguments must be given either by name or by position, not both")
+	}
+	var inputs []Param
+	for _, p := range r.Params {
+		if p.input() {
+			inputs = append(inputs, p)
+		}
+	}
+	required := len(inputs) - r.Defaults
+
+	var (
+		list   []string
+		values []any
+	)
+	// arg returns the expression passing v to p, named when named is set.
+	// A variadic parameter takes the whole array.
+	arg := func(p Param, v any, named bool) string {
+		values = append(values, v)
+		expr := fmt.Sprintf("$%d::%s", len(values), p.Type)
+		if named {
+			expr = database.QuoteIdent(p.Name) + " => " + expr
+		}
+		if p.Mode == ModeVariadic {
+			expr = "VARIADIC " + expr
+		}
+		return expr
+	}
+	if len(args.Named) > 0 {
+		known := map[string]bool{}
+		for _, p := range inputs {
+			known[p.Name] = p.Name != ""
+		}
+		for _, name := range sortedNames(args.Named) {
+			if !known[name] {
+				return "", nil, fmt.Errorf("%s has no input parameter named %s (it takes %s)", r.Name, name, describe(inputs))
+			}
+		}
+		input := 0
+		for _, p := range r.Params {
+			switch {
+			case p.input():
+				v, ok := args.Named[p.Name]
+				if !ok && input < required {
+					return "", nil, fmt.Errorf("missing argument %s for %s", p.Name, r.Name)
+				}
+				input++
+				if ok {
+					list = append(list, arg(p, v, true))
+				}
+			case p.Mode == ModeOut && r.Procedure:
+				if p.Name == "" {
+					return "", nil, fmt.Errorf("%s has an unnamed OUT parameter; give its arguments by position", r.Name)
+				}
+				list = append(list, database.QuoteIdent(p.Name)+" => NULL")
+			}
+		}
+	} else {
+		if len(args.Positional) < required || len(args.Positional) > len(inputs) {
+			return "", nil, fmt.Errorf("%s takes %s, but %d arguments were given", r.Name, describe(inputs), len(args.Positional))
+		}
+		given, skipped := args.Positional, false
+		for _, p := range r.Params {
+			switch {
+			case p.input() && len(given) > 0:
+				list = append(list, arg(p, given[0], false))
+				given = given[1:]
+			case p.input():
+				// Trailing inputs take their defaults.
+				skipped = true
+			case p.Mode == ModeOut && r.Procedure:
+				if skipped {
+					return "", nil, fmt.Errorf("%s cannot take the defaults of arguments before an OUT parameter by position; give them by name", r.Name)
+				}
+				list = append(list, "NULL")
+			}
+		}
+	}
+
+	call := r.Name + "(" + strings.Join(list, ", ") + ")"
+	if r.Procedure {
+		return "CALL " + call, values, nil
+	}
+	return "SELECT * FROM " + call, values, nil
+}
+
+// describe lists the input parameters for error messages.
+func describe(inputs []Param) string {
+	if len(inputs) == 0 {
+		return "no arguments"
+	}
+	parts := make([]string, len(inputs))
+	for i, p := range inputs {
+		parts[i] = strings.TrimSpace(p.Name + " " + p.Type)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sortedNames(m map[string]any) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Result is what a call returned: a procedure's OUT and INOUT parameters,
+// or a function's result rows, whose columns are its OUT parameters or,
+// for a scalar function, its return value.
+type Result struct {
+	Routine string
+	Columns []string
+	Rows    [][]any
+	Elapsed time.Duration
+}
+
+// Call looks up the routine called name and calls it with args.
+func Call(ctx context.Context, db *sql.DB, name string, args Args) (*Result, error) {
+	r, err := Lookup(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+	stmt, values, err := r.Statement(args)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, stmt, values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", r.Name, err)
+	}
+	defer func() { _ = rows.Close() }()
+	res := &Result{Routine: r.Name}
+	if res.Columns, err = rows.Columns(); err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", r.Name, err)
+	}
+	for rows.Next() {
+		row := make([]any, len(res.Columns))
+		ptrs := make([]any, len(row))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("failed to read the results of %s: %w", r.Name, err)
+		}
+		for i, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[i] = string(b)
+			}
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", r.Name, err)
+	}
+	res.Elapsed = time.Since(start)
+	return res, nil
+}
+
+// Outputs returns the rows of res as objects keyed by column.
+func (res *Result) Outputs() []map[string]any {
+	outputs := make([]map[string]any, len(res.Rows))
+	for i, row := range res.Rows {
+		outputs[i] = make(map[string]any, len(row))
+		for j, v := range row {
+			outputs[i][res.Columns[j]] = v
+		}
+	}
+	return outputs
+}
+
+// Write prints res: one name = value line per column of a single row, or
+// a table for several rows.
+func (res *Result) Write(w io.Writer) error {
+	switch {
+	case len(res.Columns) == 0:
+		_, err := fmt.Fprintf(w, "Called %s\n", res.Routine)
+		return err
+	case len(res.Rows) == 1:
+		for i, col := range res.Columns {
+			if _, err := fmt.Fprintf(w, "%s = %s\n", col, formatValue(res.Rows[0][i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(res.Columns, "\t"))
+	for _, row := range res.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = formatValue(v)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	fmt.Fprintf(tw, "(%d rows)\n", len(res.Rows))
+	return tw.Flush()
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,171 @@
+package procedure
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Args
+		wantErr bool
+	}{
+		{
+			name: "named",
+			in:   `{"since": "2024-01-01", "limit": 10, "dry_run": true, "note": null, "tags": ["a", "b"]}`,
+			want: Args{Named: map[string]any{"since": "2024-01-01", "limit": "10", "dry_run": "true", "note": nil, "tags": `["a","b"]`}},
+		},
+		{name: "positional", in: `[1.50, "x", {"k": 1}]`, want: Args{Positional: []any{"1.50", "x", `{"k":1}`}}},
+		{name: "scalar", in: `42`, wantErr: true},
+		{name: "invalid", in: `{"since":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseArgs(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseArgs() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoutineStatement(t *testing.T) {
+	// archive(IN before date, INOUT moved integer, OUT note text, IN dry_run boolean DEFAULT false)
+	procedure := &Routine{
+		Name:      "public.archive",
+		Procedure: true,
+		Params: []Param{
+			{Name: "before", Mode: ModeIn, Type: "date"},
+			{Name: "moved", Mode: ModeInOut, Type: "integer"},
+			{Name: "note", Mode: ModeOut, Type: "text"},
+			{Name: "dry_run", Mode: ModeIn, Type: "boolean"},
+		},
+		Defaults: 1,
+	}
+	// totals(IN since date, OUT n bigint, OUT amount numeric)
+	function := &Routine{
+		Name: "public.totals",
+		Params: []Param{
+			{Name: "since", Mode: ModeIn, Type: "date"},
+			{Name: "n", Mode: ModeOut, Type: "bigint"},
+			{Name: "amount", Mode: ModeOut, Type: "numeric"},
+		},
+	}
+	variadic := &Routine{Name: "public.tag", Params: []Param{{Name: "tags", Mode: ModeVariadic, Type: "text[]"}}}
+
+	tests := []struct {
+		name       string
+		routine    *Routine
+		args       Args
+		wantStmt   string
+		wantValues []any
+		wantErr    string
+	}{
+		{
+			name:       "procedure by name",
+			routine:    procedure,
+			args:       Args{Named: map[string]any{"before": "2024-01-01", "moved": "0"}},
+			wantStmt:   `CALL public.archive("before" => $1::date, "moved" => $2::integer, "note" => NULL)`,
+			wantValues: []any{"2024-01-01", "0"},
+		},
+		{
+			name:       "procedure by position",
+			routine:    procedure,
+			args:       Args{Positional: []any{"2024-01-01", nil, "true"}},
+			wantStmt:   `CALL public.archive($1::date, $2::integer, NULL, $3::boolean)`,
+			wantValues: []any{"2024-01-01", nil, "true"},
+		},
+		{
+			name:    "default before an OUT parameter by position",
+			routine: &Routine{Name: "p", Procedure: true, Params: []Param{{Name: "a", Mode: ModeIn, Type: "int"}, {Name: "r", Mode: ModeOut, Type: "int"}}, Defaults: 1},
+			args:    Args{Positional: []any{}},
+			wantErr: "give them by name",
+		},
+		{
+			name:       "function",
+			routine:    function,
+			args:       Args{Positional: []any{"2024-01-01"}},
+			wantStmt:   `SELECT * FROM public.totals($1::date)`,
+			wantValues: []any{"2024-01-01"},
+		},
+		{
+			name:       "variadic",
+			routine:    variadic,
+			args:       Args{Named: map[string]any{"tags": "{a,b}"}},
+			wantStmt:   `SELECT * FROM public.tag(VARIADIC "tags" => $1::text[])`,
+			wantValues: []any{"{a,b}"},
+		},
+		{name: "missing argument", routine: procedure, args: Args{Named: map[string]any{"before": "2024-01-01"}}, wantErr: "missing argument moved"},
+		{name: "unknown argument", routine: function, args: Args{Named: map[string]any{"until": "x"}}, wantErr: "no input parameter named until (it takes since date)"},
+		{name: "OUT parameter by name", routine: function, args: Args{Named: map[string]any{"n": "1"}}, wantErr: "no input parameter named n"},
+		{name: "too many", routine: function, args: Args{Positional: []any{"a", "b"}}, wantErr: "2 arguments were given"},
+		{name: "both", routine: function, args: Args{Named: map[string]any{"since": "a"}, Positional: []any{"a"}}, wantErr: "not both"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, values, err := tt.routine.Statement(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Statement() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Statement() error = %v", err)
+			}
+			if stmt != tt.wantStmt {
+				t.Errorf("Statement() = %s, want %s", stmt, tt.wantStmt)
+			}
+			if !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("Statement() values = %#v, want %#v", values, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestResultWrite(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		result Result
+		want   string
+	}{
+		{name: "no columns", result: Result{Routine: "public.purge"}, want: "Called public.purge\n"},
+		{
+			name:   "one row",
+			result: Result{Routine: "public.archive", Columns: []string{"moved", "note"}, Rows: [][]any{{int64(3), nil}}},
+			want:   "moved = 3\nnote = NULL\n",
+		},
+		{
+			name:   "rows",
+			result: Result{Routine: "public.recent", Columns: []string{"id", "at"}, Rows: [][]any{{int64(1), at}, {int64(10), nil}}},
+			want:   "id  at\n1   2024-01-02T03:04:05Z\n10  NULL\n(2 rows)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := tt.result.Write(&b); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Write() = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestResultOutputs(t *testing.T) {
+	res := Result{Columns: []string{"moved", "note"}, Rows: [][]any{{int64(3), "done"}}}
+	want := []map[string]any{{"moved": int64(3), "note": "done"}}
+	if got := res.Outputs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Outputs() = %v, want %v", got, want)
+	}
+}
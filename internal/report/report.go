@@ -0,0 +1,347 @@
+// Package report provides functionality for recording what a run changed:
+// the type, rows affected, and duration of every statement, with totals,
+// the notices the server raised, and the results of stored procedure calls,
+// as a table for people, as JSON for pipelines, and as markdown for CI job
+// summaries.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// Report records the statements of a run, script by script. The methods
+// recording a run do nothing on a nil *Report, so a run need not check
+// whether a report was requested.
+type Report struct {
+	Scripts []*Script `json:"scripts"`
+	Skipped []Skipped `json:"skipped,omitempty"`
+	Calls   []Call    `json:"calls,omitempty"`
+	Totals  Totals    `json:"totals"`
+	// Error is why the run failed, if it did.
+	Error string `json:"error,omitempty"`
+
+	omitDurations bool
+}
+
+// Script records the statements of one script that ran.
+type Script struct {
+	Path         string      `json:"path"`
+	Statements   []Statement `json:"statements"`
+	RowsAffected int64       `json:"rows_affected"`
+	DurationMS   *float64    `json:"duration_ms,omitempty"`
+	// Notices are the notices and warnings the server sent, including those
+	// of a statement that failed.
+	Notices []Notice `json:"notices,omitempty"`
+	// Error is why the script failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// Notice records a notice or warning the server sent while a statement
+// ran.
+type Notice struct {
+	// Statement is the 1-based index of the statement in its script.
+	Statement int `json:"statement"`
+	database.Notice
+}
+
+// Skipped records a script that was not run.
+type Skipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Call records a call of a stored procedure or function.
+type Call struct {
+	Routine string `json:"routine"`
+	// Outputs are the OUT parameters or result rows, keyed by column.
+	Outputs    []map[string]any `json:"outputs"`
+	DurationMS *float64         `json:"duration_ms,omitempty"`
+	// Error is why the call failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// Statement records one completed statement.
+type Statement struct {
+	// Index is the 1-based position of the statement in its script.
+	Index int `json:"index"`
+	// Type is the leading keyword, such as INSERT, or OTHER.
+	Type string `json:"type"`
+	// RowsAffected is nil when the driver does not report it.
+	RowsAffected *int64   `json:"rows_affected,omitempty"`
+	DurationMS   *float64 `json:"duration_ms,omitempty"`
+}
+
+// Totals sums the statements of every script.
+type Totals struct {
+	Scripts      int                   `json:"scripts"`
+	Statements   int                   `json:"statements"`
+	RowsAffected int64                 `json:"rows_affected"`
+	DurationMS   *float64              `json:"duration_ms,omitempty"`
+	ByType       map[string]*TypeTotal `json:"by_type"`
+	Notices      int                   `json:"notices"`
+}
+
+// TypeTotal sums the statements of one type.
+type TypeTotal struct {
+	Statements   int   `json:"statements"`
+	RowsAffected int64 `json:"rows_affected"`
+}
+
+// New returns an empty report. With omitDurations, durations are left out,
+// so that the report of a run can be compared against a golden file.
+func New(omitDurations bool) *Report {
+	return &Report{
+		Totals:        Totals{ByType: map[string]*TypeTotal{}},
+		omitDurations: omitDurations,
+	}
+}
+
+// StartScript begins recording the statements of the script at path.
+func (r *Report) StartScript(path string) {
+	if r == nil {
+		return
+	}
+	r.Scripts = append(r.Scripts, &Script{Path: path, Statements: []Statement{}})
+	r.Totals.Scripts++
+}
+
+// Statement records a completed statement of the current script. Rows is
+// negative when the driver does not report it.
+func (r *Report) Statement(index int, stmt string, elapsed time.Duration, rows int64) {
+	if r == nil || len(r.Scripts) == 0 {
+		return
+	}
+	s := r.Scripts[len(r.Scripts)-1]
+	typ := database.DescribeStatement(stmt).Verb
+	if typ == "" {
+		typ = "OTHER"
+	}
+	st := Statement{Index: index, Type: typ, DurationMS: r.milliseconds(elapsed)}
+	total := r.Totals.ByType[typ]
+	if total == nil {
+		total = &TypeTotal{}
+		r.Totals.ByType[typ] = total
+	}
+	total.Statements++
+	if rows >= 0 {
+		st.RowsAffected = &rows
+		s.RowsAffected += rows
+		total.RowsAffected += rows
+		r.Totals.RowsAffected += rows
+	}
+	s.Statements = append(s.Statements, st)
+	r.Totals.Statements++
+	r.addDuration(&s.DurationMS, elapsed)
+	r.addDuration(&r.Totals.DurationMS, elapsed)
+}
+
+// Notice records a notice statement index of the current script raised.
+func (r *Report) Notice(index int, n database.Notice) {
+	if r == nil || len(r.Scripts) == 0 {
+		return
+	}
+	s := r.Scripts[len(r.Scripts)-1]
+	s.Notices = append(s.Notices, Notice{Statement: index, Notice: n})
+	r.Totals.Notices++
+}
+
+// Call records a call of routine that returned outputs or failed with err.
+func (r *Report) Call(routine string, outputs []map[string]any, elapsed time.Duration, err error) {
+	if r == nil {
+		return
+	}
+	c := Call{Routine: routine, Outputs: outputs, DurationMS: r.milliseconds(elapsed)}
+	if c.Outputs == nil {
+		c.Outputs = []map[string]any{}
+	}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	r.Calls = append(r.Calls, c)
+}
+
+// Skip records that the script at path was not run, and why.
+func (r *Report) Skip(path, reason string) {
+	if r == nil {
+		return
+	}
+	r.Skipped = append(r.Skipped, Skipped{Path: path, Reason: reason})
+}
+
+// FailScript records why the current script failed.
+func (r *Report) FailScript(err error) {
+	if r != nil && len(r.Scripts) > 0 && err != nil {
+		r.Scripts[len(r.Scripts)-1].Error = err.Error()
+	}
+}
+
+// Add appends the scripts, skipped inputs, and calls other recorded to r,
+// with its totals, so that scripts recorded apart, such as those run in
+// parallel, end up in one report in the order they are added.
+func (r *Report) Add(other *Report) {
+	if r == nil || other == nil {
+		return
+	}
+	r.Scripts = append(r.Scripts, other.Scripts...)
+	r.Skipped = append(r.Skipped, other.Skipped...)
+	r.Calls = append(r.Calls, other.Calls...)
+	r.Totals.Scripts += other.Totals.Scripts
+	r.Totals.Statements += other.Totals.Statements
+	r.Totals.RowsAffected += other.Totals.RowsAffected
+	r.Totals.Notices += other.Totals.Notices
+	for typ, t := range other.Totals.ByType {
+		total := r.Totals.ByType[typ]
+		if total == nil {
+			total = &TypeTotal{}
+			r.Totals.ByType[typ] = total
+		}
+		total.Statements += t.Statements
+		total.RowsAffected += t.RowsAffected
+	}
+	if ms := other.Totals.DurationMS; ms != nil && !r.omitDurations {
+		if r.Totals.DurationMS == nil {
+			r.Totals.DurationMS = new(float64)
+		}
+		*r.Totals.DurationMS += *ms
+	}
+}
+
+func (r *Report) milliseconds(d time.Duration) *float64 {
+	if r.omitDurations {
+		return nil
+	}
+	ms := float64(d.Microseconds()) / 1000
+	return &ms
+}
+
+func (r *Report) addDuration(sum **float64, d time.Duration) {
+	ms := r.milliseconds(d)
+	if ms == nil {
+		return
+	}
+	if *sum == nil {
+		*sum = ms
+		return
+	}
+	**sum += *ms
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteTable prints a line per statement followed by totals by type, any
+// notices, and any calls.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCRIPT\tSTATEMENT\tTYPE\tROWS\tDURATION")
+	for _, s := range r.Scripts {
+		for _, st := range s.Statements {
+			rows := "-"
+			if st.RowsAffected != nil {
+				rows = strconv.FormatInt(*st.RowsAffected, 10)
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", s.Path, st.Index, st.Type, rows, formatDuration(st.DurationMS))
+		}
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "TYPE\tSTATEMENTS\tROWS")
+	types := make([]string, 0, len(r.Totals.ByType))
+	for typ := range r.Totals.ByType {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		t := r.Totals.ByType[typ]
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", typ, t.Statements, t.RowsAffected)
+	}
+	fmt.Fprintf(tw, "total\t%d\t%d\n", r.Totals.Statements, r.Totals.RowsAffected)
+	if r.Totals.Notices > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "SCRIPT\tSTATEMENT\tNOTICE")
+		for _, s := range r.Scripts {
+			for _, n := range s.Notices {
+				fmt.Fprintf(tw, "%s\t%d\t%s\n", s.Path, n.Statement, n.Notice)
+			}
+		}
+	}
+	if len(r.Calls) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "CALL\tROWS\tDURATION\tERROR")
+		for _, c := range r.Calls {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", c.Routine, len(c.Outputs), formatDuration(c.DurationMS), c.Error)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if r.Totals.DurationMS != nil {
+		_, err := fmt.Fprintf(w, "Total statement time: %s\n", formatDuration(r.Totals.DurationMS))
+		return err
+	}
+	return nil
+}
+
+// WriteMarkdown writes a summary of the run in GitHub-flavored markdown: a
+// row per script with its statements, rows affected, duration, and result,
+// the skipped inputs, the totals, and the error that failed the run.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	outcome := "succeeded"
+	if r.Error != "" {
+		outcome = "failed"
+	}
+	fmt.Fprintf(w, "### sql-loader run %s\n\n", outcome)
+	if len(r.Scripts) > 0 {
+		fmt.Fprintln(w, "| Script | Statements | Rows | Duration | Result |")
+		fmt.Fprintln(w, "| --- | ---: | ---: | ---: | --- |")
+		for _, s := range r.Scripts {
+			result := "ok"
+			if s.Error != "" {
+				result = "failed"
+			}
+			fmt.Fprintf(w, "| %s | %d | %d | %s | %s |\n", MarkdownCell(s.Path), len(s.Statements), s.RowsAffected, formatDuration(s.DurationMS), result)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, s := range r.Skipped {
+		fmt.Fprintf(w, "- Skipped %s: %s\n", MarkdownCell(s.Path), MarkdownCell(s.Reason))
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d statement(s) in %d script(s), %d row(s) affected\n", r.Totals.Statements, r.Totals.Scripts, r.Totals.RowsAffected)
+	if r.Error == "" {
+		return nil
+	}
+	return WriteMarkdownError(w, r.Error)
+}
+
+// MarkdownCell escapes s for a cell of a markdown table.
+func MarkdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// WriteMarkdownError writes message as a fenced block after a blank line.
+func WriteMarkdownError(w io.Writer, message string) error {
+	_, err := fmt.Fprintf(w, "\n```text\n%s\n```\n", strings.ReplaceAll(message, "```", "'''"))
+	return err
+}
+
+func formatDuration(ms *float64) string {
+	if ms == nil {
+		return "-"
+	}
+	return time.Duration(*ms * float64(time.Millisecond)).Round(time.Microsecond).String()
+}
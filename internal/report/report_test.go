@@ -0,0 +1,181 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+func TestReport(t *testing.T) {
+	r := New(false)
+	r.StartScript("schema.sql")
+	r.Statement(1, "CREATE TABLE t (id int)", 2*time.Millisecond, 0)
+	r.Statement(2, "-- seed\nINSERT INTO t VALUES (1), (2)", time.Millisecond, 2)
+	r.StartScript("data.sql")
+	r.Statement(1, "insert into t values (3)", time.Millisecond, 1)
+	r.Statement(2, "VACUUM", time.Millisecond, -1)
+	r.Statement(3, "(SELECT 1)", time.Millisecond, -1)
+	r.Notice(4, database.Notice{Severity: "WARNING", Message: "value truncated"})
+	r.FailScript(errors.New("boom"))
+	r.Skip("old.sql", "identical script already applied")
+
+	if got := r.Totals; got.Scripts != 2 || got.Statements != 5 || got.RowsAffected != 3 {
+		t.Errorf("Totals = %+v, want 2 scripts, 5 statements, 3 rows", got)
+	}
+	if got := *r.Totals.DurationMS; got != 6 {
+		t.Errorf("Totals.DurationMS = %v, want 6", got)
+	}
+	if got := r.Totals.ByType["INSERT"]; got == nil || got.Statements != 2 || got.RowsAffected != 3 {
+		t.Errorf("ByType[INSERT] = %+v, want 2 statements, 3 rows", got)
+	}
+	if got := r.Totals.ByType["OTHER"]; got == nil || got.Statements != 1 {
+		t.Errorf("ByType[OTHER] = %+v, want the unparsed statement", got)
+	}
+	if got := r.Scripts[1]; got.RowsAffected != 1 || got.Error != "boom" || got.Statements[1].RowsAffected != nil {
+		t.Errorf("Scripts[1] = %+v, want 1 row, the error, and no row count for VACUUM", got)
+	}
+	if got := r.Scripts[1].Notices; r.Totals.Notices != 1 || len(got) != 1 || got[0].Statement != 4 {
+		t.Errorf("Scripts[1].Notices = %+v, want the warning of the failed statement", got)
+	}
+
+	var b strings.Builder
+	if err := r.WriteJSON(&b); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal([]byte(b.String()), &decoded); err != nil {
+		t.Fatalf("WriteJSON() wrote invalid JSON: %v", err)
+	}
+	if len(decoded.Scripts) != 2 || len(decoded.Skipped) != 1 || decoded.Totals.Statements != 5 {
+		t.Errorf("decoded report = %+v", decoded)
+	}
+	if got := decoded.Scripts[1].Notices; len(got) != 1 || got[0].Severity != "WARNING" || got[0].Message != "value truncated" {
+		t.Errorf("decoded notices = %+v", got)
+	}
+
+	b.Reset()
+	if err := r.WriteTable(&b); err != nil {
+		t.Fatalf("WriteTable() error = %v", err)
+	}
+	if !strings.Contains(b.String(), "data.sql  4          WARNING: value truncated") {
+		t.Errorf("WriteTable() = %q, want the notice", b.String())
+	}
+}
+
+func TestReportOmitDurations(t *testing.T) {
+	r := New(true)
+	r.StartScript("seed.sql")
+	r.Statement(1, "INSERT INTO t VALUES (1)", time.Second, 1)
+
+	var b strings.Builder
+	if err := r.WriteJSON(&b); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if strings.Contains(b.String(), "duration_ms") {
+		t.Errorf("WriteJSON() = %s, want no durations", b.String())
+	}
+	b.Reset()
+	if err := r.WriteTable(&b); err != nil {
+		t.Fatalf("WriteTable() error = %v", err)
+	}
+	if strings.Contains(b.String(), "Total statement time") || !strings.Contains(b.String(), "seed.sql  1          INSERT  1     -") {
+		t.Errorf("WriteTable() = %q, want the statement without its duration", b.String())
+	}
+}
+
+func TestReportCall(t *testing.T) {
+	r := New(false)
+	r.Call("public.refresh", []map[string]any{{"total": int64(3)}}, time.Millisecond, nil)
+	r.Call("public.purge", nil, time.Millisecond, errors.New("permission denied"))
+
+	var b strings.Builder
+	if err := r.WriteJSON(&b); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal([]byte(b.String()), &decoded); err != nil {
+		t.Fatalf("WriteJSON() wrote invalid JSON: %v", err)
+	}
+	if got := decoded.Calls; len(got) != 2 || got[0].Outputs[0]["total"] != float64(3) || got[1].Error != "permission denied" {
+		t.Errorf("decoded calls = %+v", got)
+	}
+	if !strings.Contains(b.String(), `"outputs": []`) {
+		t.Errorf("WriteJSON() = %s, want empty outputs for the failed call", b.String())
+	}
+
+	b.Reset()
+	if err := r.WriteTable(&b); err != nil {
+		t.Fatalf("WriteTable() error = %v", err)
+	}
+	if !strings.Contains(b.String(), "public.purge    0     1ms       permission denied") {
+		t.Errorf("WriteTable() = %q, want the failed call", b.String())
+	}
+}
+
+func TestReportAdd(t *testing.T) {
+	r := New(false)
+	r.StartScript("a.sql")
+	r.Statement(1, "INSERT INTO t VALUES (1)", time.Millisecond, 1)
+
+	other := New(false)
+	other.StartScript("b.sql")
+	other.Statement(1, "INSERT INTO t VALUES (2), (3)", 2*time.Millisecond, 2)
+	other.Statement(2, "CREATE INDEX i ON t (id)", time.Millisecond, 0)
+	other.Notice(1, database.Notice{Severity: "NOTICE", Message: "hello"})
+	r.Add(other)
+	r.Add(nil)
+
+	if got := r.Totals; got.Scripts != 2 || got.Statements != 3 || got.RowsAffected != 3 || got.Notices != 1 || *got.DurationMS != 4 {
+		t.Errorf("Totals = %+v, want 2 scripts, 3 statements, 3 rows, 1 notice, 4ms", got)
+	}
+	if got := r.Totals.ByType["INSERT"]; got.Statements != 2 || got.RowsAffected != 3 {
+		t.Errorf("ByType[INSERT] = %+v, want both inserts", got)
+	}
+	if len(r.Scripts) != 2 || r.Scripts[1].Path != "b.sql" {
+		t.Errorf("Scripts = %+v, want a.sql then b.sql", r.Scripts)
+	}
+}
+
+func TestNilReport(t *testing.T) {
+	var r *Report
+	r.StartScript("seed.sql")
+	r.Statement(1, "SELECT 1", time.Millisecond, -1)
+	r.Notice(1, database.Notice{Severity: "NOTICE", Message: "hello"})
+	r.Skip("seed.sql", "empty")
+	r.FailScript(errors.New("boom"))
+	r.Call("refresh", nil, time.Millisecond, nil)
+	r.Add(New(false))
+}
+
+func TestReportWriteMarkdown(t *testing.T) {
+	r := New(true)
+	r.StartScript("a|b.sql")
+	r.Statement(1, "INSERT INTO t VALUES (1), (2)", time.Millisecond, 2)
+	r.StartScript("data.sql")
+	r.Statement(1, "UPDATE t SET id = 3", time.Millisecond, 1)
+	r.FailScript(errors.New("boom"))
+	r.Skip("old.sql", "identical script already applied")
+	r.Error = "failed to execute data.sql:\nboom"
+
+	var b strings.Builder
+	if err := r.WriteMarkdown(&b); err != nil {
+		t.Fatalf("WriteMarkdown() error = %v", err)
+	}
+	want := "### sql-loader run failed\n\n" +
+		"| Script | Statements | Rows | Duration | Result |\n" +
+		"| --- | ---: | ---: | ---: | --- |\n" +
+		"| a\\|b.sql | 1 | 2 | - | ok |\n" +
+		"| data.sql | 1 | 1 | - | failed |\n" +
+		"\n" +
+		"- Skipped old.sql: identical script already applied\n" +
+		"\n" +
+		"2 statement(s) in 2 script(s), 3 row(s) affected\n" +
+		"\n```text\nfailed to execute data.sql:\nboom\n```\n"
+	if b.String() != want {
+		t.Errorf("WriteMarkdown() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,96 @@
+// Package sandbox restricts the files the loader reads to allowlisted
+// directories, so that a script or data argument cannot reach files such as
+// /etc/shadow, directly or through symbolic links.
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sandbox is a set of allowed directories. A nil Sandbox allows every path.
+type Sandbox struct {
+	roots []string
+}
+
+// New returns a Sandbox allowing the directory trees under roots, or nil if
+// roots is empty. Each root must exist.
+func New(roots []string) (*Sandbox, error) {
+	if len(roots) == 0 {
+		return nil, nil
+	}
+	s := &Sandbox{}
+	for _, root := range roots {
+		resolved, err := resolve(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed path %s: %w", root, err)
+		}
+		s.roots = append(s.roots, resolved)
+	}
+	return s, nil
+}
+
+// Check reports an error unless path, after resolving symbolic links, lies
+// within an allowed directory.
+func (s *Sandbox) Check(path string) error {
+	if s == nil {
+		return nil
+	}
+	resolved, err := resolve(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Let the caller's open report the missing file, but only for a
+		// path that would be allowed.
+		resolved, err = resolveMissing(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check %s against the allowed paths: %w", path, err)
+	}
+	for _, root := range s.roots {
+		if within(root, resolved) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is outside the allowed paths (%s)", path, strings.Join(s.roots, ", "))
+}
+
+func resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// resolveMissing resolves the deepest existing ancestor of path and appends
+// the rest.
+func resolveMissing(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	dir, rest := filepath.Split(abs)
+	for {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(filepath.Clean(dir))
+		if parent == filepath.Clean(dir) {
+			return "", err
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = parent
+	}
+}
+
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,69 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	base := t.TempDir()
+	allowed := filepath.Join(base, "sql")
+	outside := filepath.Join(base, "etc")
+	for _, dir := range []string{filepath.Join(allowed, "sub"), outside} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	for _, f := range []string{filepath.Join(allowed, "seed.sql"), filepath.Join(outside, "shadow")} {
+		if err := os.WriteFile(f, []byte("x"), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", f, err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(outside, "shadow"), filepath.Join(allowed, "link.sql")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	s, err := New([]string{allowed})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		path    string
+		allowed bool
+	}{
+		{name: "file in root", path: filepath.Join(allowed, "seed.sql"), allowed: true},
+		{name: "root itself", path: allowed, allowed: true},
+		{name: "missing file in root", path: filepath.Join(allowed, "sub", "new", "x.sql"), allowed: true},
+		{name: "outside", path: filepath.Join(outside, "shadow")},
+		{name: "dot-dot escape", path: filepath.Join(allowed, "..", "etc", "shadow")},
+		{name: "symlink escape", path: filepath.Join(allowed, "link.sql")},
+		{name: "sibling with root as prefix", path: allowed + "-other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Check(tt.path)
+			if tt.allowed && err != nil {
+				t.Errorf("Check() error = %v, want allowed", err)
+			}
+			if !tt.allowed && (err == nil || !strings.Contains(err.Error(), "outside the allowed paths")) {
+				t.Errorf("Check() error = %v, want outside the allowed paths", err)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	s, err := New(nil)
+	if err != nil || s != nil {
+		t.Fatalf("New(nil) = %v, %v; want nil", s, err)
+	}
+	if err := s.Check("/etc/passwd"); err != nil {
+		t.Errorf("nil Sandbox Check() error = %v", err)
+	}
+	if _, err := New([]string{filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("New() accepted a missing root")
+	}
+}
@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(path, []byte("s3cr}t\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	t.Setenv("SECRETS_TEST_USER", "loader")
+	Register("test", func(_ context.Context, ref string) (string, error) {
+		if ref == "broken" {
+			return "", errors.New("store unavailable")
+		}
+		return strings.ToUpper(ref), nil
+	})
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{name: "file", in: "postgres://app:${file:" + path + "}@db/app", want: "postgres://app:s3cr}t@db/app"},
+		{name: "env", in: "postgres://${env:SECRETS_TEST_USER}@db/app", want: "postgres://loader@db/app"},
+		{name: "registered provider", in: "password=${test:token}", want: "password=TOKEN"},
+		{name: "several references", in: "${env:SECRETS_TEST_USER}:${test:pw}", want: "loader:PW"},
+		{name: "plain variable untouched", in: "postgres://${HOST}/app", want: "postgres://${HOST}/app"},
+		{name: "unknown provider untouched", in: "password=${x:y}", want: "password=${x:y}"},
+		{name: "unterminated", in: "password=${file:/x", want: "password=${file:/x"},
+		{name: "missing file", in: "${file:" + path + ".missing}", wantErr: "failed to resolve secret file:"},
+		{name: "unset variable", in: "${env:SECRETS_TEST_UNSET}", wantErr: "not set"},
+		{name: "provider error", in: "${test:broken}", wantErr: "store unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Expand(context.Background(), tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expand() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	if _, err := Resolve(context.Background(), "no-provider"); err == nil {
+		t.Error("Resolve() without a provider succeeded")
+	}
+	if _, err := Resolve(context.Background(), "vault:db/creds"); err == nil || !strings.Contains(err.Error(), "available: env, file") {
+		t.Errorf("Resolve() error = %v, want the available providers", err)
+	}
+}
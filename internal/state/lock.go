@@ -0,0 +1,31 @@
+package state
+
+import (
+	"fmt"
+	"os"
+)
+
+// withLock runs fn while holding an exclusive advisory lock on path, so that
+// concurrent invocations on the same host serialize schema setup and writes.
+func withLock(path string, fn func() error) (err error) {
+	// #nosec G304 -- The lock file sits next to the state file chosen by the user
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open state lock file: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close state lock file: %w", closeErr)
+		}
+	}()
+
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("failed to lock state file: %w", err)
+	}
+	defer func() {
+		if unlockErr := unlockFile(f); unlockErr != nil && err == nil {
+			err = fmt.Errorf("failed to unlock state file: %w", unlockErr)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,318 @@
+// Package state provides a small local store, kept in a SQLite file under the
+// user's state directory, that records past runs with their checksums and
+// per-statement timings.
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	// Register the SQLite driver used for the state file.
+	_ "modernc.org/sqlite"
+)
+
+// Run statuses.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Run kinds.
+const (
+	KindScript = "script"
+	KindData   = "data"
+)
+
+// Run is a single recorded invocation.
+type Run struct {
+	ID        int64
+	Kind      string
+	Source    string
+	Checksum  string
+	Target    string
+	Profile   string
+	StartedAt time.Time
+	Duration  time.Duration
+	Status    string
+	Error     string
+	// ErrorCategory is the database.Category of a failed run's error.
+	ErrorCategory string
+}
+
+// Store is an open state file. Several processes may use the same file at
+// once: the database runs in write-ahead log mode, waits for competing
+// writers instead of failing, and serializes setup and writes across
+// processes with an advisory lock on a sibling ".lock" file.
+type Store struct {
+	db       *sql.DB
+	lockPath string
+}
+
+// busyTimeout is how long a writer waits for a competing writer to finish.
+const busyTimeout = 10 * time.Second
+
+const schemaVersion = 2
+
+// migrations upgrade a state file from the previous version to the keyed
+// version. New files are created with the full schema directly.
+var migrations = map[int]string{
+	2: `ALTER TABLE runs ADD COLUMN error_category TEXT NOT NULL DEFAULT ''`,
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id             INTEGER PRIMARY KEY,
+	kind           TEXT NOT NULL,
+	source         TEXT NOT NULL,
+	checksum       TEXT NOT NULL,
+	target         TEXT NOT NULL,
+	profile        TEXT NOT NULL DEFAULT '',
+	started_at     INTEGER NOT NULL,
+	duration_ms    INTEGER NOT NULL,
+	status         TEXT NOT NULL,
+	error          TEXT NOT NULL DEFAULT '',
+	error_category TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS runs_checksum ON runs (checksum, status);
+CREATE TABLE IF NOT EXISTS statement_timings (
+	run_id      INTEGER NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+	statement   INTEGER NOT NULL,
+	duration_us INTEGER NOT NULL,
+	PRIMARY KEY (run_id, statement)
+);`
+
+// DefaultPath returns $XDG_STATE_HOME/sql-loader/state.db, falling back to
+// ~/.local/state when XDG_STATE_HOME is unset.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate state directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "sql-loader", "state.db"), nil
+}
+
+// Open opens the state file at path, creating it and its directory if needed.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_txlock=immediate",
+		path, busyTimeout.Milliseconds())
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	s := &Store{db: db, lockPath: path + ".lock"}
+	if err := withLock(s.lockPath, s.init); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) init() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read state file version: %w", err)
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("state file version %d is newer than supported version %d", version, schemaVersion)
+	}
+	for v := version + 1; version > 0 && v <= schemaVersion; v++ {
+		if _, err := s.db.Exec(migrations[v]); err != nil {
+			return fmt.Errorf("failed to upgrade state file to version %d: %w", v, err)
+		}
+	}
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to initialize state file: %w", err)
+	}
+	if _, err := s.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		return fmt.Errorf("failed to set state file version: %w", err)
+	}
+	return nil
+}
+
+// Close closes the state file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// RecordRun stores a finished run and its per-statement timings, returning
+// the new run ID.
+func (s *Store) RecordRun(r Run, timings []time.Duration) (id int64, err error) {
+	err = withLock(s.lockPath, func() error {
+		id, err = s.recordRun(r, timings)
+		return err
+	})
+	return id, err
+}
+
+func (s *Store) recordRun(r Run, timings []time.Duration) (id int64, err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin state transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.Exec(`INSERT INTO runs
+		(kind, source, checksum, target, profile, started_at, duration_ms, status, error, error_category)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Kind, r.Source, r.Checksum, r.Target, r.Profile,
+		r.StartedAt.UnixMilli(), r.Duration.Milliseconds(), r.Status, r.Error, r.ErrorCategory)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record run: %w", err)
+	}
+	if id, err = res.LastInsertId(); err != nil {
+		return 0, fmt.Errorf("failed to record run: %w", err)
+	}
+
+	for i, d := range timings {
+		if _, err = tx.Exec(`INSERT INTO statement_timings (run_id, statement, duration_us) VALUES (?, ?, ?)`,
+			id, i+1, d.Microseconds()); err != nil {
+			return 0, fmt.Errorf("failed to record statement timing: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit state transaction: %w", err)
+	}
+	return id, nil
+}
+
+// EstimateDuration returns the average duration of previous successful runs
+// of content with the given checksum, and how many runs it is based on.
+func (s *Store) EstimateDuration(checksum string) (time.Duration, int, error) {
+	var (
+		avg sql.NullFloat64
+		n   int
+	)
+	err := s.db.QueryRow(`SELECT AVG(duration_ms), COUNT(*) FROM runs
+		WHERE checksum = ? AND status = ?`, checksum, StatusSucceeded).Scan(&avg, &n)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query run history: %w", err)
+	}
+	return time.Duration(avg.Float64 * float64(time.Millisecond)), n, nil
+}
+
+// LastApplied returns the most recent successful run of content with the
+// given checksum against target, or nil if there is none.
+func (s *Store) LastApplied(checksum, target string) (*Run, error) {
+	row := s.db.QueryRow(`SELECT `+runColumns+`
+		FROM runs WHERE checksum = ? AND target = ? AND status = ?
+		ORDER BY started_at DESC, id DESC LIMIT 1`, checksum, target, StatusSucceeded)
+	r, err := scanRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query run history: %w", err)
+	}
+	return r, nil
+}
+
+// Filter selects runs for ListRuns. Zero fields match every run.
+type Filter struct {
+	Profile    string
+	Since      time.Time
+	FailedOnly bool
+	// Limit caps the number of runs returned; 0 means no limit.
+	Limit int
+}
+
+// ListRuns returns the runs matching f, most recent first.
+func (s *Store) ListRuns(f Filter) ([]Run, error) {
+	query := `SELECT ` + runColumns + ` FROM runs WHERE 1 = 1`
+	var args []any
+	if f.Profile != "" {
+		query += ` AND profile = ?`
+		args = append(args, f.Profile)
+	}
+	if !f.Since.IsZero() {
+		query += ` AND started_at >= ?`
+		args = append(args, f.Since.UnixMilli())
+	}
+	if f.FailedOnly {
+		query += ` AND status = ?`
+		args = append(args, StatusFailed)
+	}
+	query += ` ORDER BY started_at DESC, id DESC`
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query run history: %w", err)
+	}
+	var runs []Run
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		runs = append(runs, *r)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("failed to query run history: %w", err)
+	}
+	return runs, rows.Err()
+}
+
+const runColumns = `id, kind, source, checksum, target, profile, started_at, duration_ms, status, error, error_category`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRun(row scanner) (*Run, error) {
+	r := &Run{}
+	var startedAt, durationMS int64
+	if err := row.Scan(&r.ID, &r.Kind, &r.Source, &r.Checksum, &r.Target, &r.Profile,
+		&startedAt, &durationMS, &r.Status, &r.Error, &r.ErrorCategory); err != nil {
+		return nil, err
+	}
+	r.StartedAt = time.UnixMilli(startedAt)
+	r.Duration = time.Duration(durationMS) * time.Millisecond
+	return r, nil
+}
+
+// ParseSince interprets a -since value relative to now. It accepts a Go
+// duration ("36h"), a number of days or weeks ("7d", "2w"), or a date
+// ("2024-06-01", local time).
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if n := len(value); n > 1 && (value[n-1] == 'd' || value[n-1] == 'w') {
+		count, err := strconv.Atoi(value[:n-1])
+		if err == nil && count >= 0 {
+			days := count
+			if value[n-1] == 'w' {
+				days *= 7
+			}
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid since value %q (use e.g. 36h, 7d, 2w, or 2024-06-01)", value)
+	}
+	return now.Add(-d), nil
+}
@@ -0,0 +1,264 @@
+package state
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "nested", "state.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if closeErr := s.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	})
+	return s
+}
+
+func TestRecordRunAndEstimate(t *testing.T) {
+	s := openTestStore(t)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	runs := []Run{
+		{Kind: KindScript, Source: "seed.sql", Checksum: "abc", Target: "sqlite:a.db", StartedAt: start, Duration: 2 * time.Second, Status: StatusSucceeded},
+		{Kind: KindScript, Source: "seed.sql", Checksum: "abc", Target: "sqlite:b.db", StartedAt: start.Add(time.Hour), Duration: 4 * time.Second, Status: StatusSucceeded},
+		{Kind: KindScript, Source: "seed.sql", Checksum: "abc", Target: "sqlite:a.db", StartedAt: start.Add(2 * time.Hour), Duration: time.Second, Status: StatusFailed, Error: "boom"},
+	}
+	for _, r := range runs {
+		if _, err := s.RecordRun(r, []time.Duration{time.Millisecond, 2 * time.Millisecond}); err != nil {
+			t.Fatalf("RecordRun() error = %v", err)
+		}
+	}
+
+	d, n, err := s.EstimateDuration("abc")
+	if err != nil {
+		t.Fatalf("EstimateDuration() error = %v", err)
+	}
+	if d != 3*time.Second || n != 2 {
+		t.Errorf("EstimateDuration() = %s over %d runs, want 3s over 2 runs", d, n)
+	}
+
+	if _, n, _ := s.EstimateDuration("unknown"); n != 0 {
+		t.Errorf("EstimateDuration(unknown) runs = %d, want 0", n)
+	}
+}
+
+func TestLastApplied(t *testing.T) {
+	s := openTestStore(t)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	if r, err := s.LastApplied("abc", "sqlite:a.db"); err != nil || r != nil {
+		t.Fatalf("LastApplied() on empty store = %v, %v", r, err)
+	}
+
+	if _, err := s.RecordRun(Run{Kind: KindScript, Source: "seed.sql", Checksum: "abc", Target: "sqlite:a.db",
+		StartedAt: start, Status: StatusFailed}, nil); err != nil {
+		t.Fatalf("RecordRun() error = %v", err)
+	}
+	if r, _ := s.LastApplied("abc", "sqlite:a.db"); r != nil {
+		t.Errorf("LastApplied() after failed run = %+v, want nil", r)
+	}
+
+	id, err := s.RecordRun(Run{Kind: KindScript, Source: "seed.sql", Checksum: "abc", Target: "sqlite:a.db",
+		StartedAt: start.Add(time.Minute), Status: StatusSucceeded}, nil)
+	if err != nil {
+		t.Fatalf("RecordRun() error = %v", err)
+	}
+	r, err := s.LastApplied("abc", "sqlite:a.db")
+	if err != nil || r == nil || r.ID != id || !r.StartedAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("LastApplied() = %+v, %v, want run %d", r, err, id)
+	}
+	if r, _ := s.LastApplied("abc", "sqlite:other.db"); r != nil {
+		t.Errorf("LastApplied() for other target = %+v, want nil", r)
+	}
+}
+
+func TestOpenRejectsNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := s.db.Exec("PRAGMA user_version = 99"); err != nil {
+		t.Fatalf("Failed to bump version: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if s, err := Open(path); err == nil {
+		_ = s.Close()
+		t.Error("Open() expected error for newer state file version")
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", filepath.Join("var", "state"))
+	got, err := DefaultPath()
+	if err != nil {
+		t.Fatalf("DefaultPath() error = %v", err)
+	}
+	if want := filepath.Join("var", "state", "sql-loader", "state.db"); got != want {
+		t.Errorf("DefaultPath() = %s, want %s", got, want)
+	}
+}
+
+func TestListRuns(t *testing.T) {
+	s := openTestStore(t)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	runs := []Run{
+		{Kind: KindScript, Source: "a.sql", Checksum: "a", Target: "sqlite:a.db", Profile: "staging", StartedAt: start, Status: StatusSucceeded},
+		{Kind: KindData, Source: "b.csv", Checksum: "b", Target: "sqlite:a.db", Profile: "staging", StartedAt: start.Add(time.Hour), Status: StatusFailed, Error: "boom"},
+		{Kind: KindScript, Source: "c.sql", Checksum: "c", Target: "sqlite:b.db", Profile: "prod", StartedAt: start.Add(2 * time.Hour), Status: StatusFailed},
+	}
+	for _, r := range runs {
+		if _, err := s.RecordRun(r, nil); err != nil {
+			t.Fatalf("RecordRun() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "all, most recent first", filter: Filter{}, want: []string{"c.sql", "b.csv", "a.sql"}},
+		{name: "profile", filter: Filter{Profile: "staging"}, want: []string{"b.csv", "a.sql"}},
+		{name: "since", filter: Filter{Since: start.Add(time.Hour)}, want: []string{"c.sql", "b.csv"}},
+		{name: "failed only", filter: Filter{Profile: "staging", FailedOnly: true}, want: []string{"b.csv"}},
+		{name: "limit", filter: Filter{Limit: 1}, want: []string{"c.sql"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ListRuns(tt.filter)
+			if err != nil {
+				t.Fatalf("ListRuns() error = %v", err)
+			}
+			var sources []string
+			for _, r := range got {
+				sources = append(sources, r.Source)
+			}
+			if strings.Join(sources, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ListRuns() = %v, want %v", sources, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "7d", want: now.AddDate(0, 0, -7)},
+		{value: "2w", want: now.AddDate(0, 0, -14)},
+		{value: "36h", want: now.Add(-36 * time.Hour)},
+		{value: "2024-06-01", want: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "d", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSince(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSince() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("ParseSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	const writers, runsEach = 8, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := Open(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer func() { _ = s.Close() }()
+			for i := 0; i < runsEach; i++ {
+				if _, err := s.RecordRun(Run{Kind: KindScript, Source: "seed.sql", Checksum: "abc", Target: "sqlite:a.db",
+					StartedAt: time.Now(), Status: StatusSucceeded}, []time.Duration{time.Millisecond}); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent writer error = %v", err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = s.Close() }()
+	if _, n, _ := s.EstimateDuration("abc"); n != writers*runsEach {
+		t.Errorf("recorded %d runs, want %d", n, writers*runsEach)
+	}
+}
+
+func TestOpenUpgradesVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE runs (
+		id INTEGER PRIMARY KEY, kind TEXT NOT NULL, source TEXT NOT NULL, checksum TEXT NOT NULL,
+		target TEXT NOT NULL, profile TEXT NOT NULL DEFAULT '', started_at INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL, status TEXT NOT NULL, error TEXT NOT NULL DEFAULT '');
+		INSERT INTO runs (kind, source, checksum, target, started_at, duration_ms, status)
+		VALUES ('script', 'old.sql', 'abc', 'sqlite:a.db', 0, 10, 'succeeded');
+		PRAGMA user_version = 1`); err != nil {
+		t.Fatalf("Failed to create version 1 state file: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = s.Close() }()
+	if _, err := s.RecordRun(Run{Kind: KindScript, Source: "new.sql", Checksum: "def", Target: "sqlite:a.db",
+		StartedAt: time.Now(), Status: StatusFailed, Error: "dup", ErrorCategory: "constraint_violation"}, nil); err != nil {
+		t.Fatalf("RecordRun() error = %v", err)
+	}
+	runs, err := s.ListRuns(Filter{})
+	if err != nil {
+		t.Fatalf("ListRuns() error = %v", err)
+	}
+	if len(runs) != 2 || runs[0].ErrorCategory != "constraint_violation" || runs[1].Source != "old.sql" {
+		t.Errorf("ListRuns() after upgrade = %+v", runs)
+	}
+}
@@ -0,0 +1,126 @@
+// Package textnorm validates and normalizes the text of script and data
+// files: invalid UTF-8 can be rejected or replaced, and text can be
+// normalized to Unicode NFC, so mojibake never reaches text columns.
+package textnorm
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// InvalidMode selects what happens to invalid UTF-8 byte sequences.
+type InvalidMode string
+
+// Invalid byte handling modes.
+const (
+	// InvalidPass leaves input untouched.
+	InvalidPass InvalidMode = "pass"
+	// InvalidReject fails on the first invalid sequence.
+	InvalidReject InvalidMode = "reject"
+	// InvalidReplace substitutes U+FFFD for each invalid sequence.
+	InvalidReplace InvalidMode = "replace"
+)
+
+// ParseInvalidMode validates a user-supplied mode name.
+func ParseInvalidMode(name string) (InvalidMode, error) {
+	switch InvalidMode(name) {
+	case InvalidPass, InvalidReject, InvalidReplace:
+		return InvalidMode(name), nil
+	default:
+		return "", fmt.Errorf("unsupported invalid UTF-8 mode %q (use pass, reject, or replace)", name)
+	}
+}
+
+// Policy combines invalid byte handling with optional NFC normalization.
+type Policy struct {
+	Invalid InvalidMode
+	NFC     bool
+}
+
+// Enabled reports whether the policy changes or checks anything.
+func (p Policy) Enabled() bool {
+	return p.NFC || (p.Invalid != "" && p.Invalid != InvalidPass)
+}
+
+// Apply applies the policy to a whole text, such as a script.
+func (p Policy) Apply(text string) (string, error) {
+	if !p.Enabled() {
+		return text, nil
+	}
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, p.Reader(bytes.NewReader([]byte(text)))); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Reader returns a reader applying the policy to r line by line, so large
+// data files are streamed rather than buffered.
+func (p Policy) Reader(r io.Reader) io.Reader {
+	if !p.Enabled() {
+		return r
+	}
+	return &reader{policy: p, r: bufio.NewReader(r)}
+}
+
+type reader struct {
+	policy  Policy
+	r       *bufio.Reader
+	pending []byte
+	line    int
+	err     error
+}
+
+func (t *reader) Read(p []byte) (int, error) {
+	for len(t.pending) == 0 {
+		if t.err != nil {
+			return 0, t.err
+		}
+		line, err := t.r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		t.line++
+		if err != nil {
+			t.err = io.EOF
+		}
+		if t.pending, err = t.policy.line(line, t.line); err != nil {
+			t.err = err
+		}
+	}
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+func (p Policy) line(line []byte, number int) ([]byte, error) {
+	if !utf8.Valid(line) {
+		switch p.Invalid {
+		case InvalidReject:
+			return nil, fmt.Errorf("line %d: invalid UTF-8 at byte %d", number, invalidOffset(line)+1)
+		case InvalidReplace:
+			line = bytes.ToValidUTF8(line, []byte("\uFFFD"))
+		}
+	}
+	if p.NFC {
+		line = norm.NFC.Bytes(line)
+	}
+	return line, nil
+}
+
+func invalidOffset(b []byte) int {
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
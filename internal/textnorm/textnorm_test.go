@@ -0,0 +1,71 @@
+package textnorm
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	decomposed := "Cafe\u0301"
+	tests := []struct {
+		name    string
+		policy  Policy
+		input   string
+		want    string
+		wantErr string
+	}{
+		{name: "pass leaves invalid bytes", policy: Policy{Invalid: InvalidPass}, input: "a\xffb", want: "a\xffb"},
+		{name: "replace invalid bytes", policy: Policy{Invalid: InvalidReplace}, input: "ok\nna\xefve\n", want: "ok\nna\uFFFDve\n"},
+		{name: "reject invalid bytes", policy: Policy{Invalid: InvalidReject}, input: "ok\nna\xe9ve\n", wantErr: "line 2: invalid UTF-8 at byte 3"},
+		{name: "valid input passes reject", policy: Policy{Invalid: InvalidReject}, input: "naïve\n", want: "naïve\n"},
+		{name: "normalize to NFC", policy: Policy{NFC: true}, input: decomposed + "\n" + decomposed, want: "Caf\u00e9\nCaf\u00e9"},
+		{name: "replace then normalize", policy: Policy{Invalid: InvalidReplace, NFC: true}, input: decomposed + "\xff", want: "Caf\u00e9\uFFFD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Apply(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Apply() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReaderSmallReads(t *testing.T) {
+	r := Policy{Invalid: InvalidReplace}.Reader(strings.NewReader("ab\xff\ncd"))
+	var out []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		out = append(out, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+	}
+	if string(out) != "ab\uFFFD\ncd" {
+		t.Errorf("read %q", out)
+	}
+}
+
+func TestParseInvalidMode(t *testing.T) {
+	if _, err := ParseInvalidMode("replace"); err != nil {
+		t.Errorf("ParseInvalidMode(replace) error = %v", err)
+	}
+	if _, err := ParseInvalidMode("ignore"); err == nil {
+		t.Error("ParseInvalidMode(ignore) expected error")
+	}
+}
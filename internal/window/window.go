@@ -0,0 +1,123 @@
+// Package window parses maintenance windows such as
+// "Sat 00:00-06:00 Europe/London" and checks whether a time falls inside
+// one.
+package window
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a recurring weekly time range in a time zone.
+type Window struct {
+	spec     string
+	days     [7]bool
+	start    time.Duration
+	end      time.Duration
+	location *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Parse parses a window of the form "DAYS HH:MM-HH:MM [ZONE]". DAYS is a
+// weekday, a comma-separated list ("Sat,Sun"), a range ("Mon-Fri"), or "*"
+// for every day. ZONE is an IANA time zone name and defaults to local time.
+// A window whose end is not after its start runs past midnight into the
+// next day, and the listed days are the days it starts on.
+func Parse(spec string) (*Window, error) {
+	fields := strings.Fields(spec)
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, fmt.Errorf("invalid window %q (use e.g. \"Sat 00:00-06:00 Europe/London\")", spec)
+	}
+	w := &Window{spec: spec, location: time.Local}
+
+	if err := w.parseDays(fields[0]); err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+
+	from, to, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid window %q: time range must be HH:MM-HH:MM", spec)
+	}
+	var err error
+	if w.start, err = parseClock(from); err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+	if w.end, err = parseClock(to); err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+
+	if len(fields) == 3 {
+		if w.location, err = time.LoadLocation(fields[2]); err != nil {
+			return nil, fmt.Errorf("invalid window %q: unknown time zone %q", spec, fields[2])
+		}
+	}
+	return w, nil
+}
+
+func (w *Window) parseDays(s string) error {
+	if s == "*" {
+		for i := range w.days {
+			w.days[i] = true
+		}
+		return nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdays[strings.ToLower(from)]
+		if !ok {
+			return fmt.Errorf("unknown weekday %q", from)
+		}
+		if !isRange {
+			w.days[first] = true
+			continue
+		}
+		last, ok := weekdays[strings.ToLower(to)]
+		if !ok {
+			return fmt.Errorf("unknown weekday %q", to)
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if s == "24:00" {
+			return 24 * time.Hour, nil
+		}
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t falls inside the window.
+func (w *Window) Contains(t time.Time) bool {
+	t = t.In(w.location)
+	// Wall-clock time of day, so DST transitions do not shift the window.
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+
+	if w.start < w.end {
+		return w.days[t.Weekday()] && clock >= w.start && clock < w.end
+	}
+	// Overnight: the evening part belongs to today, the morning part to a
+	// window that started yesterday.
+	yesterday := (t.Weekday() + 6) % 7
+	return (w.days[t.Weekday()] && clock >= w.start) || (w.days[yesterday] && clock < w.end)
+}
+
+// String returns the window as it was specified.
+func (w *Window) String() string {
+	return w.spec
+}
@@ -0,0 +1,64 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContains(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	// 2024-06-01 is a Saturday.
+	sat := func(hour, minute int) time.Time { return time.Date(2024, 6, 1, hour, minute, 0, 0, london) }
+
+	tests := []struct {
+		name string
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{"inside", "Sat 00:00-06:00 Europe/London", sat(3, 0), true},
+		{"at start", "Sat 00:00-06:00 Europe/London", sat(0, 0), true},
+		{"at end", "Sat 00:00-06:00 Europe/London", sat(6, 0), false},
+		{"wrong day", "Sun 00:00-06:00 Europe/London", sat(3, 0), false},
+		{"other zone", "Sat 00:00-06:00 UTC", sat(6, 30), true},
+		{"day range", "Mon-Fri 09:00-17:00 Europe/London", sat(10, 0), false},
+		{"wrapping day range", "Fri-Sun 09:00-17:00 Europe/London", sat(10, 0), true},
+		{"day list", "sun,sat 09:00-17:00 Europe/London", sat(10, 0), true},
+		{"every day", "* 02:00-04:00 Europe/London", sat(2, 30), true},
+		{"overnight evening", "Sat 22:00-02:00 Europe/London", sat(23, 0), true},
+		{"overnight morning after", "Fri 22:00-02:00 Europe/London", sat(1, 0), true},
+		{"overnight morning same day", "Sat 22:00-02:00 Europe/London", sat(1, 0), false},
+		{"until midnight", "Sat 18:00-24:00 Europe/London", sat(23, 59), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := w.Contains(tt.at); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"Sat",
+		"Caturday 00:00-06:00",
+		"Sat 00:00",
+		"Sat 25:00-26:00",
+		"Sat 00:00-06:00 Mars/Olympus",
+		"Sat 00:00-06:00 UTC extra",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) expected error", spec)
+		}
+	}
+}
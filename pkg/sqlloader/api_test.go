@@ -0,0 +1,102 @@
+package sqlloader_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/obstreperous-ai/sql-loader-go/pkg/sqlloader"
+)
+
+// TestAPI pins the exported surface the package doc promises to keep: a
+// removed or renamed identifier, a changed signature, or a removed field
+// fails to compile here. Additions need no change.
+func TestAPI(t *testing.T) {
+	var (
+		_ func(sqlloader.Execer, ...sqlloader.Option) *sqlloader.Executor = sqlloader.NewExecutor
+		_ func(*sqlloader.Executor, context.Context, string) error        = (*sqlloader.Executor).Execute
+
+		_ func(sqlloader.TransactionMode) sqlloader.Option      = sqlloader.WithTransactionMode
+		_ func(func(string) []string) sqlloader.Option          = sqlloader.WithSplitter
+		_ func(*slog.Logger) sqlloader.Option                   = sqlloader.WithLogger
+		_ func(sqlloader.Hooks) sqlloader.Option                = sqlloader.WithHooks
+		_ func(int) sqlloader.Option                            = sqlloader.WithBatchSize
+		_ func(time.Duration) sqlloader.Option                  = sqlloader.WithTimeout
+		_ func(time.Duration) sqlloader.Option                  = sqlloader.WithStatementTimeout
+		_ func(sqlloader.ReconnectPolicy) sqlloader.Option      = sqlloader.WithReconnect
+		_ func(context.Context, *sql.DB) (*sqlloader.Tx, error) = sqlloader.BeginTx
+		_ func(*sqlloader.Tx, func(any) error) error            = (*sqlloader.Tx).Raw
+		_ func(*sqlloader.Tx) error                             = (*sqlloader.Tx).Commit
+		_ func(*sqlloader.Tx) error                             = (*sqlloader.Tx).Rollback
+		_ sqlloader.Execer                                      = (*sqlloader.Tx)(nil)
+
+		_ func(context.Context, string, string) (*sql.DB, error) = sqlloader.Connect
+		_ func(*pgxpool.Pool) *sql.DB                            = sqlloader.FromPool
+		_ func(string) []string                                  = sqlloader.SplitStatements
+		_ func(string) ([]string, error)                         = sqlloader.SplitScript
+
+		_ func(context.Context, *sql.DB, fs.FS, sqlloader.MigrateOptions) error = sqlloader.MigrateOnStart
+		_ string                                                                = sqlloader.DefaultMigrationTable
+
+		_ func(*sql.DB, sqlloader.Options) *sqlloader.Loader                         = sqlloader.NewLoader
+		_ func(*sqlloader.Loader, context.Context, string, io.Reader) (int64, error) = (*sqlloader.Loader).Load
+		_ int                                                                        = sqlloader.DefaultBatchSize
+
+		_ func(string, sqlloader.SecretProvider)               = sqlloader.RegisterSecretProvider
+		_ func(context.Context, string) (string, error)        = sqlloader.ResolveSecret
+		_ func(context.Context, string) (string, error)        = sqlloader.ExpandSecrets
+		_ sqlloader.SecretProvider                             = func(context.Context, string) (string, error) { return "", nil }
+		_ func(string, sqlloader.Opener)                       = sqlloader.RegisterScheme
+		_ func(context.Context, string) (io.ReadCloser, error) = sqlloader.Open
+		_ sqlloader.Opener                                     = func(context.Context, *url.URL) (io.ReadCloser, error) { return nil, nil }
+		_ func(sqlloader.Notice) string                        = sqlloader.Notice.String
+		_ error                                                = (*sqlloader.ExecError)(nil)
+		_ error                                                = (*sqlloader.SyntaxError)(nil)
+		_ func(*sqlloader.ExecError) error                     = (*sqlloader.ExecError).Unwrap
+	)
+
+	_ = sqlloader.Hooks{
+		BeforeStatement:    func(index int, stmt string) error { return nil },
+		AfterStatement:     func(index int, stmt string, elapsed time.Duration, rows int64) {},
+		OnReconnect:        func(index, attempt int, err error) {},
+		OnRetryTransaction: func(attempt int, wait time.Duration, err error) {},
+		OnBatch:            func(batch, committed int) {},
+		OnNotice:           func(index int, n sqlloader.Notice) {},
+	}
+	_ = sqlloader.Notice{Severity: "", Code: "", Message: "", Detail: "", Hint: ""}
+	_ = sqlloader.ReconnectPolicy{Attempts: 0, Backoff: time.Duration(0), MaxBackoff: time.Duration(0)}
+	_ = sqlloader.ExecError{Index: 0, Total: 0, Disconnected: false, RolledBack: false, Committed: 0, Batches: 0, Err: error(nil)}
+	_ = sqlloader.SyntaxError{Message: "", Line: 0, Column: 0}
+	_ = sqlloader.MigrateOptions{Driver: "", Dir: "", Table: "", StatementTimeout: time.Duration(0), Logger: (*slog.Logger)(nil)}
+	_ = sqlloader.Options{Driver: "", Format: sqlloader.Format(""), Delimiter: rune(0), BatchSize: 0}
+	var _ *sql.Tx = sqlloader.Tx{}.Tx
+
+	values := map[string]string{
+		"TransactionNone":         string(sqlloader.TransactionNone),
+		"TransactionPerStatement": string(sqlloader.TransactionPerStatement),
+		"TransactionAll":          string(sqlloader.TransactionAll),
+		"FormatCSV":               string(sqlloader.FormatCSV),
+		"FormatJSONL":             string(sqlloader.FormatJSONL),
+		"DefaultMigrationTable":   sqlloader.DefaultMigrationTable,
+	}
+	want := map[string]string{
+		"TransactionNone":         "none",
+		"TransactionPerStatement": "per-statement",
+		"TransactionAll":          "all",
+		"FormatCSV":               "csv",
+		"FormatJSONL":             "jsonl",
+		"DefaultMigrationTable":   "schema_migrations",
+	}
+	for name, got := range values {
+		if got != want[name] {
+			t.Errorf("%s = %q, want %q", name, got, want[name])
+		}
+	}
+}
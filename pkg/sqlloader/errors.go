@@ -0,0 +1,101 @@
+package sqlloader
+
+import (
+	"errors"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// ExecError reports which statement of a script failed and how far the
+// script got.
+type ExecError struct {
+	// Index is the 1-based index of the failed statement.
+	Index int
+	// Total is the number of statements in the script.
+	Total int
+	// Disconnected reports whether the connection was lost, in which case the
+	// failed statement may or may not have been applied.
+	Disconnected bool
+	// RolledBack reports that the failed statement's transaction was rolled
+	// back: the whole script's, or with WithBatchSize its batch's.
+	RolledBack bool
+	// Committed is the number of statements committed in earlier batches,
+	// which stay applied, and Batches the number of those batches.
+	Committed, Batches int
+	Err                error
+}
+
+func (e *ExecError) Error() string {
+	return (*database.ExecError)(e).Error()
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// SyntaxError locates a string literal or comment left open in a script.
+type SyntaxError struct {
+	Message string
+	// Line is the 1-based line of the statement the error is on.
+	Line int
+	// Column is the 1-based character column on Line, or zero if unknown.
+	Column int
+}
+
+func (e *SyntaxError) Error() string {
+	return (*database.SyntaxError)(e).Error()
+}
+
+// exportError returns err with the internal error types it carries
+// replaced by this package's, so callers can match them with errors.As.
+// An error that is one of them is converted; one that wraps them keeps its
+// message and converts them when asked.
+func exportError(err error) error {
+	var execErr *database.ExecError
+	if errors.As(err, &execErr) {
+		if err == error(execErr) {
+			return (*ExecError)(execErr)
+		}
+		return &exportedError{err: err}
+	}
+	var syntaxErr *database.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		if err == error(syntaxErr) {
+			return (*SyntaxError)(syntaxErr)
+		}
+		return &exportedError{err: err}
+	}
+	return err
+}
+
+// exportedError wraps an error carrying internal error types, presenting
+// them to errors.As as this package's.
+type exportedError struct {
+	err error
+}
+
+func (e *exportedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exportedError) Unwrap() error {
+	return e.err
+}
+
+func (e *exportedError) As(target any) bool {
+	switch target := target.(type) {
+	case **ExecError:
+		var execErr *database.ExecError
+		if errors.As(e.err, &execErr) {
+			*target = (*ExecError)(execErr)
+			return true
+		}
+	case **SyntaxError:
+		var syntaxErr *database.SyntaxError
+		if errors.As(e.err, &syntaxErr) {
+			*target = (*SyntaxError)(syntaxErr)
+			return true
+		}
+	}
+	return false
+}
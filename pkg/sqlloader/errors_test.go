@@ -0,0 +1,53 @@
+package sqlloader
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+func TestExportError(t *testing.T) {
+	boom := errors.New("boom")
+	execErr := &database.ExecError{Index: 2, Total: 3, RolledBack: true, Err: boom}
+	syntaxErr := &database.SyntaxError{Message: "unterminated string literal", Line: 4, Column: 7}
+	other := errors.New("failed to begin transaction")
+
+	tests := []struct {
+		name       string
+		err        error
+		wantExec   bool
+		wantSyntax bool
+	}{
+		{name: "nil", err: nil},
+		{name: "exec error", err: execErr, wantExec: true},
+		{name: "wrapped exec error", err: fmt.Errorf("%w (retry abandoned: %w)", execErr, other), wantExec: true},
+		{name: "syntax error", err: syntaxErr, wantSyntax: true},
+		{name: "wrapped syntax error", err: fmt.Errorf("failed to parse: %w", syntaxErr), wantSyntax: true},
+		{name: "other error", err: other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exportError(tt.err)
+			if (got == nil) != (tt.err == nil) || (got != nil && got.Error() != tt.err.Error()) {
+				t.Fatalf("exportError() = %v, want the message of %v", got, tt.err)
+			}
+			var exec *ExecError
+			if errors.As(got, &exec) != tt.wantExec {
+				t.Errorf("errors.As(*ExecError) = %v, want %v", !tt.wantExec, tt.wantExec)
+			} else if tt.wantExec && (exec.Index != 2 || !exec.RolledBack) {
+				t.Errorf("ExecError = %+v, want statement 2 rolled back", exec)
+			}
+			var syntax *SyntaxError
+			if errors.As(got, &syntax) != tt.wantSyntax {
+				t.Errorf("errors.As(*SyntaxError) = %v, want %v", !tt.wantSyntax, tt.wantSyntax)
+			} else if tt.wantSyntax && (syntax.Line != 4 || syntax.Column != 7) {
+				t.Errorf("SyntaxError = %+v, want line 4, column 7", syntax)
+			}
+			if tt.wantExec && !errors.Is(got, boom) {
+				t.Errorf("errors.Is(%v, boom) = false, want the statement's error", got)
+			}
+		})
+	}
+}
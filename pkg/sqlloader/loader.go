@@ -0,0 +1,68 @@
+package sqlloader
+
+import (
+	"context"
+	"database/sql"
+	"io"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/dataload"
+)
+
+// Format identifies the encoding of a data stream.
+type Format string
+
+// Supported data formats.
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// DefaultBatchSize is the number of rows inserted per statement when
+// Options.BatchSize is not set.
+const DefaultBatchSize = dataload.DefaultBatchSize
+
+// Options configures a Loader.
+type Options struct {
+	// Driver is "postgres" or "sqlite".
+	Driver string
+	// Format is the encoding of the input; it defaults to FormatCSV. CSV
+	// input must start with a header row naming the columns.
+	Format Format
+	// Delimiter separates CSV fields; zero means a comma.
+	Delimiter rune
+	// BatchSize is the maximum number of rows per INSERT statement.
+	BatchSize int
+}
+
+// Loader inserts CSV or JSON Lines records into tables.
+type Loader struct {
+	db   *sql.DB
+	opts Options
+}
+
+// NewLoader returns a Loader writing to db.
+func NewLoader(db *sql.DB, opts Options) *Loader {
+	if opts.Format == "" {
+		opts.Format = FormatCSV
+	}
+	return &Loader{db: db, opts: opts}
+}
+
+// Load reads every record from r and inserts it into table, optionally
+// schema-qualified, returning the number of rows inserted. Each batch
+// commits on its own; cancelling ctx aborts the batch in flight.
+func (l *Loader) Load(ctx context.Context, table string, r io.Reader) (int64, error) {
+	var (
+		records dataload.Reader
+		err     error
+	)
+	if l.opts.Format == FormatCSV {
+		records, err = dataload.NewCSVReader(r, dataload.CSVOptions{Delimiter: l.opts.Delimiter})
+	} else {
+		records, err = dataload.NewReader(r, dataload.Format(l.opts.Format))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return dataload.Load(ctx, l.db, records, dataload.Options{Driver: l.opts.Driver, Table: table, BatchSize: l.opts.BatchSize})
+}
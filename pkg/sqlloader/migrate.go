@@ -0,0 +1,40 @@
+package sqlloader
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"log/slog"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/migrate"
+)
+
+// MigrateOptions controls MigrateOnStart.
+type MigrateOptions struct {
+	// Driver is "postgres" or "sqlite". It defaults to postgres.
+	Driver string
+	// Dir is the directory of migrations within the file system. It
+	// defaults to its root.
+	Dir string
+	// Table is the tracking table. It defaults to DefaultMigrationTable.
+	Table string
+	// StatementTimeout, if positive, cancels any statement running longer.
+	StatementTimeout time.Duration
+	// Logger, if set, receives an event for each applied migration and a
+	// summary. Failures are returned, not logged.
+	Logger *slog.Logger
+}
+
+// DefaultMigrationTable records applied migrations when
+// MigrateOptions.Table is empty.
+const DefaultMigrationTable = migrate.DefaultTable
+
+// MigrateOnStart applies the pending migrations in fsys, typically an
+// embed.FS, from a service's main before it starts serving. Replicas
+// starting together on PostgreSQL take turns on an advisory lock, so each
+// migration is applied once. A failed migration leaves the earlier ones
+// applied and itself rolled back, and is retried on the next start.
+func MigrateOnStart(ctx context.Context, db *sql.DB, fsys fs.FS, opts MigrateOptions) error {
+	return exportError(migrate.OnStart(ctx, db, fsys, migrate.StartOptions(opts)))
+}
@@ -0,0 +1,29 @@
+package sqlloader
+
+import (
+	"context"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/secrets"
+)
+
+// SecretProvider returns the secret a reference names, such as a key in a
+// secret store.
+type SecretProvider func(ctx context.Context, ref string) (string, error)
+
+// RegisterSecretProvider makes ${name:ref} references in DSNs resolve
+// through provider, alongside the built-in env and file providers. Openers
+// registered with RegisterScheme can share it through ResolveSecret.
+func RegisterSecretProvider(name string, provider SecretProvider) {
+	secrets.Register(name, secrets.Provider(provider))
+}
+
+// ResolveSecret returns the secret named by a "provider:ref" reference.
+func ResolveSecret(ctx context.Context, reference string) (string, error) {
+	return secrets.Resolve(ctx, reference)
+}
+
+// ExpandSecrets replaces the ${provider:ref} references in s, such as a
+// DSN, with the secrets they name.
+func ExpandSecrets(ctx context.Context, s string) (string, error) {
+	return secrets.Expand(ctx, s)
+}
@@ -0,0 +1,244 @@
+// Package sqlloader lets Go programs run SQL scripts, load data files, and
+// apply migrations in-process, exactly as the sql-loader command does,
+// instead of shelling out to the binary.
+//
+// # Stability
+//
+// Everything exported by this package is stable: identifiers are not
+// removed or renamed, function signatures do not change, and existing
+// options and fields keep their meaning. New capabilities arrive as new
+// Option functions, new fields whose zero value preserves the old
+// behaviour, or new functions. The packages under internal/ carry no such
+// promise and may change in any release.
+package sqlloader
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/database"
+)
+
+// Executor runs SQL scripts on a *sql.DB, *sql.Conn, *sql.Tx, or *Tx.
+type Executor struct {
+	x *database.Executor
+}
+
+// Execute runs script. A failed statement is reported as an *ExecError,
+// found with errors.As.
+func (x *Executor) Execute(ctx context.Context, script string) error {
+	return exportError(x.x.Execute(ctx, script))
+}
+
+// Option configures an Executor.
+type Option func(*executorConfig)
+
+type executorConfig struct {
+	opts []database.Option
+}
+
+func option(opt database.Option) Option {
+	return func(c *executorConfig) { c.opts = append(c.opts, opt) }
+}
+
+// Hooks are callbacks an Executor invokes around each statement.
+type Hooks struct {
+	// BeforeStatement is called before each statement; an error aborts the
+	// script without executing the statement.
+	BeforeStatement func(index int, stmt string) error
+	// AfterStatement is called after each statement completes with its
+	// execution time and rows affected, or -1 when the driver does not
+	// report one.
+	AfterStatement func(index int, stmt string, elapsed time.Duration, rows int64)
+	// OnReconnect is called before each reconnect attempt.
+	OnReconnect func(index, attempt int, err error)
+	// OnRetryTransaction is called before each rerun of a transaction.
+	OnRetryTransaction func(attempt int, wait time.Duration, err error)
+	// OnBatch is called after each batch commits when WithBatchSize is set,
+	// with the number of statements committed so far.
+	OnBatch func(batch, committed int)
+	// OnNotice is called with each notice or warning a PostgreSQL server
+	// sends while a statement runs, on connections opened by Connect.
+	OnNotice func(index int, n Notice)
+}
+
+// Execer is satisfied by *sql.DB, *sql.Conn, *sql.Tx, and *Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Notice is a notice or warning the server sent while a statement ran,
+// delivered to Hooks.OnNotice.
+type Notice struct {
+	// Severity is WARNING, NOTICE, INFO, LOG, or DEBUG.
+	Severity string `json:"severity"`
+	// Code is the SQLSTATE of the message.
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// String formats n on one line, as psql prints it.
+func (n Notice) String() string {
+	return database.Notice(n).String()
+}
+
+// ReconnectPolicy controls recovery when the connection drops mid-script.
+type ReconnectPolicy struct {
+	// Attempts is the maximum number of reconnects per statement.
+	Attempts int
+	// Backoff is the wait before the first reconnect; it doubles after each
+	// failed attempt up to MaxBackoff. Defaults to one second.
+	Backoff time.Duration
+	// MaxBackoff caps the wait between attempts. Defaults to 30 seconds.
+	MaxBackoff time.Duration
+}
+
+// TransactionMode controls how the statements of a script are grouped into
+// transactions.
+type TransactionMode string
+
+// Transaction modes.
+const (
+	// TransactionNone runs each statement in autocommit mode.
+	TransactionNone TransactionMode = "none"
+	// TransactionPerStatement runs each statement in its own transaction.
+	TransactionPerStatement TransactionMode = "per-statement"
+	// TransactionAll runs the whole script in one transaction, rolled back
+	// on the first error.
+	TransactionAll TransactionMode = "all"
+)
+
+// NewExecutor returns an Executor for db. A *sql.Tx or *Tx runs scripts
+// inside the caller's transaction and a *sql.Conn pins them to one
+// session; see WithTransactionMode for the modes each accepts.
+func NewExecutor(db Execer, opts ...Option) *Executor {
+	var c executorConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	var target database.Execer = db
+	if tx, ok := db.(*Tx); ok {
+		target = tx.tx
+	}
+	return &Executor{x: database.NewExecutor(target, c.opts...)}
+}
+
+// WithTransactionMode groups statements into transactions. Modes other
+// than TransactionNone need a *sql.DB or *sql.Conn.
+func WithTransactionMode(mode TransactionMode) Option {
+	return option(database.WithTransactionMode(database.TransactionMode(mode)))
+}
+
+// WithSplitter replaces the default statement splitter, which understands
+// quoting, comments, dollar-quoted bodies, and trigger bodies.
+func WithSplitter(split func(script string) []string) Option {
+	return option(database.WithSplitter(split))
+}
+
+// WithLogger logs each statement at debug level and each reconnect attempt
+// as a warning.
+func WithLogger(logger *slog.Logger) Option {
+	return option(database.WithLogger(logger))
+}
+
+// WithHooks sets callbacks run around each statement.
+func WithHooks(hooks Hooks) Option {
+	internal := database.Hooks{
+		BeforeStatement:    hooks.BeforeStatement,
+		AfterStatement:     hooks.AfterStatement,
+		OnReconnect:        hooks.OnReconnect,
+		OnRetryTransaction: hooks.OnRetryTransaction,
+		OnBatch:            hooks.OnBatch,
+	}
+	if onNotice := hooks.OnNotice; onNotice != nil {
+		internal.OnNotice = func(index int, n database.Notice) { onNotice(index, Notice(n)) }
+	}
+	return option(database.WithHooks(internal))
+}
+
+// WithBatchSize commits every n statements, so a failure rolls back only
+// the batch it occurred in.
+func WithBatchSize(n int) Option {
+	return option(database.WithBatchSize(n))
+}
+
+// WithTimeout bounds each call to Execute.
+func WithTimeout(d time.Duration) Option {
+	return option(database.WithTimeout(d))
+}
+
+// WithStatementTimeout bounds each statement.
+func WithStatementTimeout(d time.Duration) Option {
+	return option(database.WithStatementTimeout(d))
+}
+
+// WithReconnect retries statements that fail because the connection
+// dropped. It applies only to a *sql.DB.
+func WithReconnect(policy ReconnectPolicy) Option {
+	return option(database.WithReconnect(database.ReconnectPolicy(policy)))
+}
+
+// Tx is a transaction pinned to its connection. Unlike a *sql.Tx, scripts
+// run in it may contain COPY FROM STDIN blocks.
+type Tx struct {
+	*sql.Tx
+	tx *database.Tx
+}
+
+// BeginTx starts a Tx on a connection of db. Commit or Rollback releases the
+// connection.
+func BeginTx(ctx context.Context, db *sql.DB) (*Tx, error) {
+	tx, err := database.BeginTx(ctx, db, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx.Tx, tx: tx}, nil
+}
+
+// Raw runs f on the driver connection of the transaction.
+func (t *Tx) Raw(f func(driverConn any) error) error {
+	return t.tx.Raw(f)
+}
+
+// Commit commits the transaction and releases its connection.
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback rolls the transaction back and releases its connection.
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// Connect opens a database for driver "postgres" or "sqlite" and checks
+// that it is reachable. Programs with their own pool can pass it to
+// NewExecutor directly instead.
+func Connect(ctx context.Context, driver, dsn string) (*sql.DB, error) {
+	return database.Connect(ctx, driver, dsn)
+}
+
+// FromPool returns a *sql.DB borrowing connections from a pgx pool.
+// Closing it does not close the pool.
+func FromPool(pool *pgxpool.Pool) *sql.DB {
+	return database.FromPool(pool)
+}
+
+// SplitStatements splits a script into statements as an Executor does by
+// default.
+func SplitStatements(script string) []string {
+	return database.SplitStatements(script)
+}
+
+// SplitScript is SplitStatements for untrusted input, failing with a
+// *SyntaxError when a string literal or comment is left open at the end of
+// the script. It never panics.
+func SplitScript(script string) ([]string, error) {
+	statements, err := database.SplitScript(script)
+	return statements, exportError(err)
+}
@@ -0,0 +1,81 @@
+package sqlloader_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/obstreperous-ai/sql-loader-go/pkg/sqlloader"
+
+	_ "modernc.org/sqlite"
+)
+
+// TestEmbedding exercises the package the way a service embedding it
+// would: migrate at startup, load a data file, then run a script.
+func TestEmbedding(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlloader.Connect(ctx, "sqlite", filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("Close() error = %v", closeErr)
+		}
+	}()
+
+	migrations := fstest.MapFS{
+		"001_users.sql": {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);")},
+	}
+	if err := sqlloader.MigrateOnStart(ctx, db, migrations, sqlloader.MigrateOptions{Driver: "sqlite"}); err != nil {
+		t.Fatalf("MigrateOnStart() error = %v", err)
+	}
+
+	loader := sqlloader.NewLoader(db, sqlloader.Options{Driver: "sqlite", Delimiter: ';'})
+	rows, err := loader.Load(ctx, "users", strings.NewReader("id;name\n1;ada\n2;grace\n"))
+	if err != nil || rows != 2 {
+		t.Fatalf("Load() = %d, %v; want 2 rows", rows, err)
+	}
+
+	var affected int64
+	x := sqlloader.NewExecutor(db,
+		sqlloader.WithTransactionMode(sqlloader.TransactionAll),
+		sqlloader.WithHooks(sqlloader.Hooks{
+			AfterStatement: func(_ int, _ string, _ time.Duration, rows int64) { affected += rows },
+		}),
+	)
+	if err := x.Execute(ctx, "UPDATE users SET name = upper(name); DELETE FROM users WHERE id = 2;"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if affected != 3 {
+		t.Errorf("statements affected %d rows, want 3", affected)
+	}
+
+	err = x.Execute(ctx, "INSERT INTO users VALUES (3, 'x'); INSERT INTO users VALUES (1, 'duplicate');")
+	var execErr *sqlloader.ExecError
+	if !errors.As(err, &execErr) || execErr.Index != 2 || !execErr.RolledBack {
+		t.Fatalf("Execute() error = %v, want statement 2 to fail and roll back", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil || count != 1 {
+		t.Errorf("users has %d rows, %v; want 1", count, err)
+	}
+
+	tx, err := sqlloader.BeginTx(ctx, db)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	if err := sqlloader.NewExecutor(tx).Execute(ctx, "INSERT INTO users VALUES (4, 'tx');"); err != nil {
+		t.Fatalf("Execute() in Tx error = %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil || count != 1 {
+		t.Errorf("users has %d rows after rollback, %v; want 1", count, err)
+	}
+}
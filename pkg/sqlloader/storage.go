@@ -0,0 +1,25 @@
+package sqlloader
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	"github.com/obstreperous-ai/sql-loader-go/internal/storage"
+)
+
+// Opener opens the object at a URL for reading.
+type Opener func(ctx context.Context, u *url.URL) (io.ReadCloser, error)
+
+// RegisterScheme makes Open handle URLs with scheme, such as s3 or gs,
+// replacing any opener already registered for it. Local paths and file,
+// http, and https URLs are handled without registration.
+func RegisterScheme(scheme string, open Opener) {
+	storage.Register(scheme, storage.Opener(open))
+}
+
+// Open opens a script or data source by location: a local path or a URL
+// with a supported scheme.
+func Open(ctx context.Context, location string) (io.ReadCloser, error) {
+	return storage.Open(ctx, location)
+}